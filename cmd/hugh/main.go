@@ -15,6 +15,8 @@ import (
 	"gopkg.in/natefinch/lumberjack.v2"
 
 	"github.com/spf13/viper"
+	"github.com/wheelibin/hugh/internal/calendar"
+	"github.com/wheelibin/hugh/internal/concurrency"
 	"github.com/wheelibin/hugh/internal/config"
 	"github.com/wheelibin/hugh/internal/hue"
 	"github.com/wheelibin/hugh/internal/hugh"
@@ -23,9 +25,28 @@ import (
 	"github.com/wheelibin/hugh/internal/physicalStateManager"
 	"github.com/wheelibin/hugh/internal/repos"
 	"github.com/wheelibin/hugh/internal/schedule"
+	"github.com/wheelibin/hugh/internal/validate"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "pair":
+			runPair(os.Args[2:])
+			return
+		case "validate":
+			runValidate(os.Args[2:])
+			return
+		case "preview":
+			runPreview(os.Args[2:])
+			return
+		}
+	}
+
+	run()
+}
+
+func run() {
 	// read the config file
 	config.InitialiseConfig()
 
@@ -55,12 +76,34 @@ func main() {
 	})
 	logger.Info("hugh starting")
 
+	// check the config, reporting every problem before any of them stop hugh part way through starting
+	problems, err := validate.File(viper.ConfigFileUsed())
+	if err != nil {
+		logger.Fatalf("error checking config, unable to continue: %v", err)
+	}
+	for _, p := range problems {
+		if p.Warning {
+			logger.Warn(p.String())
+		} else {
+			logger.Error(p.String())
+		}
+	}
+	if validate.HasErrors(problems) {
+		logger.Fatal("the config has errors, unable to continue (`hugh validate` lists them)")
+	}
+
 	// read schedules from config
-	var schedules []models.Schedule
-	if err := viper.UnmarshalKey("schedules", &schedules); err != nil {
+	schedules, err := config.Schedules()
+	if err != nil {
 		logger.Fatalf("error reading schedule from config, unable to continue: %v", err)
 	}
 
+	// and the switch/dial bindings
+	var bindings []models.Binding
+	if err := viper.UnmarshalKey("bindings", &bindings); err != nil {
+		logger.Fatalf("error reading bindings from config, unable to continue: %v", err)
+	}
+
 	// setup and connect to database
 	var dataSource string
 	if debugMode {
@@ -73,20 +116,67 @@ func main() {
 		logger.Fatal(err)
 	}
 	defer db.Close()
+	// each connection to an in-memory database gets its own database, so only ever use one
+	db.SetMaxOpenConns(1)
 	lrepo, err := repos.NewLightRepo(logger, db)
 	if err != nil {
 		logger.Fatalf("error creating database schema, unable to continue: %v", err)
 	}
 
-	// wire up various dependencies
-	hueService := hue.NewHueAPIService(logger)
-	scheduleService := schedule.NewScheduleService(logger, lrepo)
-	psm := physicalstatemanager.NewPhysicalStateManager(logger, hueService, lrepo)
-	lsm := logicalstatemanager.NewLogicalStateManager(logger, lrepo, scheduleService, psm)
+	bridges, err := config.Bridges()
+	if err != nil {
+		logger.Fatalf("error reading bridges from config, unable to continue: %v", err)
+	}
 
-	hugh := hugh.NewHugh(logger, schedules, lsm, psm)
+	// wire up various dependencies, each bridge has its own connection, rate limits and state managers
+	scheduleService := schedule.NewScheduleService(logger, lrepo)
 	ctx, cancel := context.WithCancel(context.Background())
 
+	// calendar events can change the day pattern of schedules, or disable them
+	calendarConfig, err := config.Calendar()
+	if err != nil {
+		logger.Fatalf("error reading calendar from config, unable to continue: %v", err)
+	}
+	if calendarConfig != nil {
+		cal := calendar.NewCalendar(logger, *calendarConfig, config.CalendarCachePath())
+		if err := cal.Load(); err != nil {
+			logger.Error(err)
+		}
+		go cal.Run(ctx)
+		scheduleService.SetCalendar(cal)
+	}
+
+	hughBridges := []hugh.Bridge{}
+	for _, bridge := range bridges {
+		bridgeLogger := logger.With("bridge", bridge.Name)
+
+		transport, err := hue.NewBridgeTransport(hue.TLSOptions{
+			Mode:     bridge.Certificate,
+			Bridge:   bridge.Name,
+			BridgeID: bridge.ID,
+			PinFile:  config.PinFilePath(),
+		})
+		if err != nil {
+			logger.Fatalf("error configuring connection to bridge %s, unable to continue: %v", bridge.Name, err)
+		}
+		if bridge.ID == "" && bridge.Certificate != hue.CertificateModeInsecure {
+			bridgeLogger.Warn("The bridge id is not configured so the bridge certificate can't be matched to it, run `hugh pair` to set it")
+		}
+
+		bridgeRepo := lrepo.ForBridge(bridge.Name)
+		hueService := hue.NewHueAPIService(bridgeLogger, bridge, transport)
+		commandScheduler := concurrency.NewCommandScheduler(concurrency.HueBridgeRateLimits, hue.ShouldBackOff)
+		psm := physicalstatemanager.NewPhysicalStateManager(bridgeLogger, hueService, bridgeRepo, commandScheduler)
+		lsm := logicalstatemanager.NewLogicalStateManager(bridgeLogger, bridgeRepo, scheduleService, psm)
+
+		// every command sent to the bridge is queued through its scheduler
+		go commandScheduler.Run(ctx)
+
+		hughBridges = append(hughBridges, hugh.Bridge{Name: bridge.Name, LogicalStateManager: lsm, PhysicalStateManager: psm})
+	}
+
+	hugh := hugh.NewHugh(logger, schedules, bindings, hughBridges)
+
 	// init hugh, will discover lights for configured schedules
 	err = hugh.Initialise()
 	if err != nil {
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/wheelibin/hugh/internal/config"
+	"github.com/wheelibin/hugh/internal/hue"
+	"github.com/wheelibin/hugh/internal/models"
+)
+
+// runPair finds a bridge, waits for its link button to be pressed and saves the new application key to the config file
+func runPair(args []string) {
+	flags := flag.NewFlagSet("pair", flag.ExitOnError)
+	bridgeIP := flags.String("ip", "", "pair with the bridge at this address instead of discovering one")
+	name := flags.String("name", "", "the name to give the bridge in the bridges list of the config (defaults to the bridge's own name)")
+	timeout := flags.Duration("timeout", 60*time.Second, "how long to wait for the link button to be pressed")
+	_ = flags.Parse(args)
+
+	logger := log.NewWithOptions(os.Stdout, log.Options{Level: log.InfoLevel})
+
+	if err := config.InitialiseConfigForPairing(); err != nil {
+		logger.Fatalf("error reading config file: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	var bridge hue.DiscoveredBridge
+	if *bridgeIP != "" {
+		b, err := hue.ProbeBridge(ctx, *bridgeIP)
+		if err != nil {
+			logger.Fatalf("unable to reach a hue bridge at %s: %v", *bridgeIP, err)
+		}
+		bridge = b
+	} else {
+		logger.Info("Searching for hue bridges...")
+		bridges, err := hue.DiscoverBridges(ctx, 5*time.Second)
+		if err != nil {
+			logger.Fatalf("error discovering bridges: %v", err)
+		}
+		switch len(bridges) {
+		case 0:
+			logger.Fatal("no hue bridges found, try again with --ip")
+		case 1:
+			bridge = bridges[0]
+		default:
+			for _, b := range bridges {
+				logger.Info("Found bridge", "name", b.Name, "id", b.ID, "ip", b.IP)
+			}
+			logger.Fatal("more than one bridge found, choose one with --ip")
+		}
+	}
+
+	logger.Info("Found bridge, press its link button to pair...", "name", bridge.Name, "id", bridge.ID, "ip", bridge.IP)
+
+	hostname, _ := os.Hostname()
+	result, err := hue.Pair(ctx, bridge.IP, hue.DeviceType("hugh", hostname), 2*time.Second)
+	if err != nil {
+		logger.Fatalf("pairing failed: %v", err)
+	}
+
+	bridgeName := *name
+	if bridgeName == "" {
+		bridgeName = bridge.Name
+	}
+
+	path, err := config.SaveBridgeCredentials(models.Bridge{
+		Name:           bridgeName,
+		IP:             bridge.IP,
+		ApplicationKey: result.ApplicationKey,
+		ID:             bridge.ID,
+	})
+	if err != nil {
+		logger.Fatalf("paired, but unable to save the application key (%s): %v", result.ApplicationKey, err)
+	}
+
+	logger.Info("Paired with bridge", "config", path)
+}
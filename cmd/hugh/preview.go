@@ -0,0 +1,122 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/wheelibin/hugh/internal/calendar"
+	"github.com/wheelibin/hugh/internal/config"
+	"github.com/wheelibin/hugh/internal/models"
+	"github.com/wheelibin/hugh/internal/preview"
+	"github.com/wheelibin/hugh/internal/schedule"
+)
+
+// runPreview prints the timeline of a schedule through a day (or the year), optionally writing it as an svg or csv
+// chart, so day patterns can be tuned without waiting a day to watch the lights
+func runPreview(args []string) {
+	flags := flag.NewFlagSet("preview", flag.ExitOnError)
+	scheduleName := flags.String("schedule", "", "the name of the schedule to preview")
+	dateString := flags.String("date", time.Now().Format("2006-01-02"), "the date to preview (YYYY-MM-DD)")
+	year := flags.Bool("year", false, "preview the 21st of each month of the date's year, to see how the day shifts through the seasons")
+	every := flags.Duration("every", 30*time.Minute, "how much of the day each row (or character with --year) of the timeline covers")
+	out := flags.String("out", "", "also write the preview to this .svg or .csv file")
+	_ = flags.Parse(args)
+
+	logger := log.NewWithOptions(os.Stderr, log.Options{Level: log.WarnLevel})
+
+	if *scheduleName == "" {
+		logger.Fatal("choose the schedule to preview with --schedule")
+	}
+	date, err := time.ParseInLocation("2006-01-02", *dateString, time.Local)
+	if err != nil {
+		logger.Fatalf("invalid date %q, expected YYYY-MM-DD", *dateString)
+	}
+	if *every < time.Minute || *every > 24*time.Hour {
+		logger.Fatalf("invalid --every %s, expected from 1m to 24h", *every)
+	}
+	format := strings.ToLower(filepath.Ext(*out))
+	if *out != "" && format != ".svg" && format != ".csv" {
+		logger.Fatalf("unable to write %s, expected a .svg or .csv file", *out)
+	}
+
+	config.InitialiseConfig()
+	schedules, err := config.Schedules()
+	if err != nil {
+		logger.Fatalf("error reading schedules from config: %v", err)
+	}
+	var sch *models.Schedule
+	for i := range schedules {
+		if strings.EqualFold(schedules[i].Name, *scheduleName) {
+			sch = &schedules[i]
+		}
+	}
+	if sch == nil {
+		logger.Fatalf("unknown schedule %s", *scheduleName)
+	}
+
+	// the lights follow any calendar, so the preview does too
+	scheduleService := schedule.NewScheduleService(logger, nil)
+	calendarConfig, err := config.Calendar()
+	if err != nil {
+		logger.Fatalf("error reading calendar from config: %v", err)
+	}
+	if calendarConfig != nil {
+		cal := calendar.NewCalendar(logger, *calendarConfig, config.CalendarCachePath())
+		if err := cal.Load(); err != nil {
+			logger.Error(err)
+		}
+		scheduleService.SetCalendar(cal)
+	}
+
+	var days []preview.Day
+	if *year {
+		days, err = preview.ForYear(scheduleService, *sch, date.Year())
+	} else {
+		var day preview.Day
+		day, err = preview.ForDay(scheduleService, *sch, date)
+		days = []preview.Day{day}
+	}
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	if *year {
+		err = preview.WriteYearTimeline(os.Stdout, days, *every)
+	} else {
+		err = preview.WriteTimeline(os.Stdout, days[0], *every)
+	}
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	if *out == "" {
+		return
+	}
+	if err := writeFile(*out, func(w io.Writer) error {
+		if format == ".csv" {
+			return preview.WriteCSV(w, days)
+		}
+		return preview.WriteSVG(w, days)
+	}); err != nil {
+		logger.Fatalf("error writing %s: %v", *out, err)
+	}
+	fmt.Printf("\nwritten to %s\n", *out)
+}
+
+func writeFile(path string, write func(w io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/charmbracelet/log"
+	"github.com/wheelibin/hugh/internal/config"
+	"github.com/wheelibin/hugh/internal/validate"
+)
+
+// runValidate checks the config file, printing every problem found with its line, exiting with 1 if there are errors
+func runValidate(args []string) {
+	flags := flag.NewFlagSet("validate", flag.ExitOnError)
+	path := flags.String("config", "", "the config file to check (defaults to the one hugh would read)")
+	_ = flags.Parse(args)
+
+	logger := log.NewWithOptions(os.Stderr, log.Options{Level: log.InfoLevel})
+
+	if *path == "" {
+		found, err := config.FindConfigFile()
+		if err != nil {
+			logger.Fatalf("error reading config file: %v", err)
+		}
+		*path = found
+	}
+
+	problems, err := validate.File(*path)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	for _, p := range problems {
+		fmt.Println(p)
+	}
+	if len(problems) == 0 {
+		fmt.Printf("%s: no problems found\n", *path)
+	}
+	if validate.HasErrors(problems) {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,169 @@
+package calendar
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/wheelibin/hugh/internal/constants"
+	"github.com/wheelibin/hugh/internal/models"
+)
+
+// Calendar reads the events of an ics calendar, from a file or a url, and picks the mode a schedule is in from the
+// events that are on
+type Calendar struct {
+	logger *log.Logger
+	config models.Calendar
+	// where a calendar downloaded from a url is kept, for when it can't be reached
+	cachePath string
+	client    *http.Client
+
+	mu     sync.RWMutex
+	events []Event
+}
+
+func NewCalendar(logger *log.Logger, config models.Calendar, cachePath string) *Calendar {
+	return &Calendar{
+		logger:    logger,
+		config:    config,
+		cachePath: cachePath,
+		client:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Load reads the calendar again, keeping the events from the last read if it can't be read
+func (c *Calendar) Load() error {
+	data, err := c.read()
+	if err != nil {
+		return err
+	}
+
+	events, errs := Parse(bytes.NewReader(data))
+	for _, err := range errs {
+		c.logger.Warn("Ignoring calendar event", "err", err)
+	}
+
+	c.mu.Lock()
+	c.events = events
+	c.mu.Unlock()
+
+	c.logger.Info("Read calendar", "events", len(events))
+	return nil
+}
+
+func (c *Calendar) read() ([]byte, error) {
+	if c.config.File != "" {
+		data, err := os.ReadFile(c.config.File)
+		if err != nil {
+			return nil, fmt.Errorf("error reading calendar: %w", err)
+		}
+		return data, nil
+	}
+
+	if c.config.URL == "" {
+		return nil, errors.New("the calendar needs a file or url")
+	}
+
+	data, fetchErr := c.fetch()
+	if fetchErr == nil {
+		if err := os.WriteFile(c.cachePath, data, 0o600); err != nil {
+			c.logger.Warn("Unable to cache calendar", "path", c.cachePath, "err", err)
+		}
+		return data, nil
+	}
+
+	// fall back to the last copy downloaded
+	data, err := os.ReadFile(c.cachePath)
+	if err != nil {
+		return nil, fmt.Errorf("error downloading calendar: %w", fetchErr)
+	}
+	c.logger.Warn("Unable to download calendar, using the cached copy", "err", fetchErr)
+	return data, nil
+}
+
+func (c *Calendar) fetch() ([]byte, error) {
+	res, err := c.client.Get(c.config.URL)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", res.Status)
+	}
+	return io.ReadAll(res.Body)
+}
+
+// Run reads the calendar again every refresh interval until the context is cancelled
+func (c *Calendar) Run(ctx context.Context) {
+	refresh := c.config.Refresh
+	if refresh <= 0 {
+		refresh = constants.DefaultCalendarRefresh
+	}
+
+	ticker := time.NewTicker(refresh)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.Load(); err != nil {
+				c.logger.Error(err)
+			}
+		}
+	}
+}
+
+// ModeFor returns the mode of the first event on at t matching one of the schedule's modes, nil if there isn't one
+func (c *Calendar) ModeFor(scheduleName string, t time.Time) *models.CalendarMode {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, event := range c.events {
+		if !event.ActiveAt(t) {
+			continue
+		}
+		for i, mode := range c.config.Modes {
+			if appliesTo(mode, scheduleName) && matches(mode, event) {
+				return &c.config.Modes[i]
+			}
+		}
+	}
+	return nil
+}
+
+func appliesTo(mode models.CalendarMode, scheduleName string) bool {
+	if len(mode.Schedules) == 0 {
+		return true
+	}
+	for _, name := range mode.Schedules {
+		if strings.EqualFold(name, scheduleName) {
+			return true
+		}
+	}
+	return false
+}
+
+func matches(mode models.CalendarMode, event Event) bool {
+	if mode.Match == "" {
+		return false
+	}
+	if strings.Contains(strings.ToLower(event.Summary), strings.ToLower(mode.Match)) {
+		return true
+	}
+	for _, category := range event.Categories {
+		if strings.EqualFold(category, mode.Match) {
+			return true
+		}
+	}
+	return false
+}
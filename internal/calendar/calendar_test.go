@@ -0,0 +1,174 @@
+package calendar_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wheelibin/hugh/internal/calendar"
+	"github.com/wheelibin/hugh/internal/models"
+)
+
+const fixture = "testdata/calendar.ics"
+
+func Test_Parse(t *testing.T) {
+	f, err := os.Open(fixture)
+	require.NoError(t, err)
+	defer f.Close()
+
+	events, errs := calendar.Parse(f)
+
+	// the broken event is reported, the rest are read
+	assert.Len(t, errs, 1)
+	require.Len(t, events, 6)
+
+	holiday := events[0]
+	assert.Equal(t, "Summer Holiday", holiday.Summary)
+	assert.True(t, holiday.AllDay)
+	assert.Equal(t, time.Date(2026, 8, 1, 0, 0, 0, 0, time.Local), holiday.Start)
+	assert.Equal(t, time.Date(2026, 8, 15, 0, 0, 0, 0, time.Local), holiday.End)
+
+	party := events[1]
+	assert.Equal(t, "Birthday party, kitchen", party.Summary)
+	assert.Equal(t, []string{"PARTY", "GUESTS"}, party.Categories)
+	london, err := time.LoadLocation("Europe/London")
+	require.NoError(t, err)
+	assert.True(t, party.Start.Equal(time.Date(2026, 9, 12, 18, 0, 0, 0, london)))
+	assert.Equal(t, 5*time.Hour, party.End.Sub(party.Start))
+
+	// folded onto two lines
+	assert.Equal(t, "Night shift", events[2].Summary)
+
+	// the alarm's summary and duration aren't the event's
+	dinner := events[3]
+	assert.Equal(t, "Dinner guests", dinner.Summary)
+	assert.Equal(t, 3*time.Hour, dinner.End.Sub(dinner.Start))
+
+	// a changed occurrence is an event of its own
+	assert.Equal(t, "yoga@hugh", events[4].UID)
+	assert.Equal(t, "yoga@hugh", events[5].UID)
+	assert.Equal(t, "Yoga (evening)", events[5].Summary)
+}
+
+func Test_Event_ActiveAt(t *testing.T) {
+	f, err := os.Open(fixture)
+	require.NoError(t, err)
+	defer f.Close()
+	events, _ := calendar.Parse(f)
+	holiday, party, nightShift, yoga, movedYoga := events[0], events[1], events[2], events[4], events[5]
+
+	utc := func(month time.Month, day int, hour int) time.Time {
+		return time.Date(2026, month, day, hour, 0, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name   string
+		event  calendar.Event
+		t      time.Time
+		active bool
+	}{
+		{name: "all day: first day", event: holiday, t: time.Date(2026, 8, 1, 0, 0, 0, 0, time.Local), active: true},
+		{name: "all day: last day", event: holiday, t: time.Date(2026, 8, 14, 23, 59, 0, 0, time.Local), active: true},
+		{name: "all day: the end is exclusive", event: holiday, t: time.Date(2026, 8, 15, 0, 0, 0, 0, time.Local), active: false},
+		{name: "duration: during", event: party, t: utc(9, 12, 20), active: true},
+		{name: "duration: after", event: party, t: utc(9, 12, 22), active: false},
+		{name: "weekly: first occurrence", event: nightShift, t: utc(1, 5, 23), active: true},
+		{name: "weekly: into the next morning", event: nightShift, t: utc(1, 8, 5), active: true},
+		{name: "weekly: a day it doesn't repeat on", event: nightShift, t: utc(1, 6, 23), active: false},
+		{name: "weekly: last occurrence", event: nightShift, t: utc(1, 21, 23), active: true},
+		{name: "weekly: after the count is reached", event: nightShift, t: utc(1, 26, 23), active: false},
+		{name: "weekly: before the start", event: nightShift, t: utc(1, 4, 23), active: false},
+		{name: "exdate: an occurrence", event: yoga, t: utc(3, 2, 7), active: true},
+		{name: "exdate: the excluded occurrence", event: yoga, t: utc(3, 9, 7), active: false},
+		{name: "exdate: still counted", event: yoga, t: utc(3, 23, 7), active: true},
+		{name: "exdate: after the count is reached", event: yoga, t: utc(3, 30, 6), active: false},
+		{name: "recurrence id: the replaced occurrence", event: yoga, t: utc(3, 16, 7), active: false},
+		{name: "recurrence id: the replacement", event: movedYoga, t: utc(3, 17, 19), active: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.active, test.event.ActiveAt(test.t))
+		})
+	}
+}
+
+func Test_Calendar_ModeFor(t *testing.T) {
+	logger := log.NewWithOptions(os.Stderr, log.Options{Level: log.FatalLevel})
+	config := models.Calendar{
+		File: fixture,
+		Modes: []models.CalendarMode{
+			{Match: "holiday", Disable: true, Schedules: []string{"Office"}},
+			{Match: "party", DayPattern: "party"},
+			{Match: "night shift", DayPattern: "sleepIn", Schedules: []string{"Bedroom"}},
+		},
+	}
+
+	c := calendar.NewCalendar(logger, config, filepath.Join(t.TempDir(), "cache.ics"))
+	require.NoError(t, c.Load())
+
+	onHoliday := time.Date(2026, 8, 3, 12, 0, 0, 0, time.Local)
+	atParty := time.Date(2026, 9, 12, 19, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		schedule string
+		t        time.Time
+		expected *models.CalendarMode
+	}{
+		{name: "matching the name", schedule: "Office", t: onHoliday, expected: &config.Modes[0]},
+		{name: "a schedule the mode isn't for", schedule: "Kitchen", t: onHoliday, expected: nil},
+		{name: "matching a category, for every schedule", schedule: "Kitchen", t: atParty, expected: &config.Modes[1]},
+		{name: "no events on", schedule: "Office", t: time.Date(2026, 10, 1, 12, 0, 0, 0, time.Local), expected: nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, c.ModeFor(test.schedule, test.t))
+		})
+	}
+}
+
+func Test_Calendar_Load_URL(t *testing.T) {
+	logger := log.NewWithOptions(os.Stderr, log.Options{Level: log.FatalLevel})
+	data, err := os.ReadFile(fixture)
+	require.NoError(t, err)
+
+	available := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !available {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write(data)
+	}))
+	defer server.Close()
+
+	cachePath := filepath.Join(t.TempDir(), "cache.ics")
+	config := models.Calendar{URL: server.URL, Modes: []models.CalendarMode{{Match: "holiday", Disable: true}}}
+	onHoliday := time.Date(2026, 8, 3, 12, 0, 0, 0, time.Local)
+
+	// downloaded and cached
+	c := calendar.NewCalendar(logger, config, cachePath)
+	require.NoError(t, c.Load())
+	assert.NotNil(t, c.ModeFor("Office", onHoliday))
+	cached, err := os.ReadFile(cachePath)
+	require.NoError(t, err)
+	assert.Equal(t, data, cached)
+
+	// read from the cache when the url can't be reached
+	available = false
+	c = calendar.NewCalendar(logger, config, cachePath)
+	require.NoError(t, c.Load())
+	assert.NotNil(t, c.ModeFor("Office", onHoliday))
+
+	// and an error when there is no cache either
+	c = calendar.NewCalendar(logger, config, filepath.Join(t.TempDir(), "missing.ics"))
+	assert.Error(t, c.Load())
+}
@@ -0,0 +1,446 @@
+package calendar
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// the most occurrences of a repeating event looked through, so a rule without an end can't loop forever
+const maxOccurrences = 100000
+
+// Event is a VEVENT read from an ics calendar
+type Event struct {
+	UID        string
+	Summary    string
+	Categories []string
+	Start      time.Time
+	// exclusive
+	End time.Time
+	// all day events start and end at local midnight, whatever the time zone
+	AllDay bool
+
+	rule *recurrence
+	// the starts of the occurrences that are left out (EXDATE) or replaced by an event of their own (RECURRENCE-ID)
+	exceptions []time.Time
+	// the start of the occurrence of a repeating event that this event replaces
+	recurrenceID *time.Time
+}
+
+// how a repeating event repeats (a subset of RRULE: FREQ, INTERVAL, COUNT, UNTIL and, for weekly events, BYDAY)
+type recurrence struct {
+	freq     string
+	interval int
+	count    int
+	until    time.Time
+	byDay    []time.Weekday
+}
+
+// ActiveAt returns whether an occurrence of the event is on at t
+func (e Event) ActiveAt(t time.Time) bool {
+	if e.rule == nil {
+		return !t.Before(e.Start) && t.Before(e.End)
+	}
+
+	active := false
+	e.occurrences(func(start time.Time, end time.Time) bool {
+		if start.After(t) {
+			return false
+		}
+		if t.Before(end) {
+			active = true
+			return false
+		}
+		return true
+	})
+	return active
+}
+
+// calls next with the start and end of each occurrence in turn until it returns false or they run out
+func (e Event) occurrences(next func(start time.Time, end time.Time) bool) {
+	days := int(e.End.Sub(e.Start).Hours()/24 + 0.5)
+	duration := e.End.Sub(e.Start)
+	occurrence := func(start time.Time) (time.Time, time.Time) {
+		if e.AllDay {
+			return start, start.AddDate(0, 0, days)
+		}
+		return start, start.Add(duration)
+	}
+
+	r := e.rule
+	n := 0
+	for i := 0; i < maxOccurrences; i++ {
+		var start time.Time
+		switch r.freq {
+		case "DAILY":
+			start = e.Start.AddDate(0, 0, i*r.interval)
+		case "WEEKLY":
+			if len(r.byDay) == 0 {
+				start = e.Start.AddDate(0, 0, 7*i*r.interval)
+				break
+			}
+			// each day from the start, keeping those on the days of the weeks the event repeats in
+			start = e.Start.AddDate(0, 0, i)
+			weeks := int(weekStart(start).Sub(weekStart(e.Start)).Hours()/(24*7) + 0.5)
+			if weeks%r.interval != 0 || !containsWeekday(r.byDay, start.Weekday()) {
+				continue
+			}
+		case "MONTHLY":
+			start = e.Start.AddDate(0, i*r.interval, 0)
+		case "YEARLY":
+			start = e.Start.AddDate(i*r.interval, 0, 0)
+		default:
+			return
+		}
+
+		if !r.until.IsZero() && start.After(r.until) {
+			return
+		}
+		n++
+		if r.count > 0 && n > r.count {
+			return
+		}
+		if e.excepted(start) {
+			continue
+		}
+		if !next(occurrence(start)) {
+			return
+		}
+	}
+}
+
+// whether the occurrence starting at start has been left out or replaced
+func (e Event) excepted(start time.Time) bool {
+	for _, exception := range e.exceptions {
+		if exception.Equal(start) {
+			return true
+		}
+	}
+	return false
+}
+
+// the monday of the week of t
+func weekStart(t time.Time) time.Time {
+	offset := (int(t.Weekday()) + 6) % 7
+	return time.Date(t.Year(), t.Month(), t.Day()-offset, 0, 0, 0, 0, t.Location())
+}
+
+func containsWeekday(days []time.Weekday, day time.Weekday) bool {
+	for _, d := range days {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+// Parse reads the events of an ics calendar, events that can't be read are returned as errors alongside the rest
+func Parse(r io.Reader) ([]Event, []error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, []error{err}
+	}
+
+	events := []Event{}
+	errs := []error{}
+
+	var props []property
+	inEvent := false
+	// the components nested in the event being read (e.g. a VALARM), their properties aren't the event's
+	nested := 0
+	for _, line := range lines {
+		switch {
+		case line == "BEGIN:VEVENT" && !inEvent:
+			inEvent = true
+			nested = 0
+			props = nil
+		case line == "END:VEVENT" && inEvent && nested == 0:
+			inEvent = false
+			event, err := parseEvent(props)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			events = append(events, event)
+		case !inEvent:
+		case strings.HasPrefix(line, "BEGIN:"):
+			nested++
+		case strings.HasPrefix(line, "END:"):
+			nested--
+		case nested == 0:
+			props = append(props, parseProperty(line))
+		}
+	}
+
+	return applyRecurrenceIDs(events), errs
+}
+
+// leaves the occurrences of repeating events that have been changed out of them, the changed occurrences are
+// events of their own with the same UID and a RECURRENCE-ID of the start of the occurrence they replace
+func applyRecurrenceIDs(events []Event) []Event {
+	for _, override := range events {
+		if override.recurrenceID == nil || override.UID == "" {
+			continue
+		}
+		for i := range events {
+			if events[i].UID == override.UID && events[i].rule != nil && events[i].recurrenceID == nil {
+				events[i].exceptions = append(events[i].exceptions, *override.recurrenceID)
+			}
+		}
+	}
+	return events
+}
+
+// the lines of the calendar, with long lines (continued on lines starting with a space or tab) joined back together
+func unfold(r io.Reader) ([]string, error) {
+	lines := []string{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+// a content line, e.g. DTSTART;TZID=Europe/London:20261225T090000
+type property struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+func parseProperty(line string) property {
+	nameAndParams, value, _ := strings.Cut(line, ":")
+	parts := strings.Split(nameAndParams, ";")
+	p := property{name: strings.ToUpper(parts[0]), params: map[string]string{}, value: value}
+	for _, param := range parts[1:] {
+		key, val, _ := strings.Cut(param, "=")
+		p.params[strings.ToUpper(key)] = strings.Trim(val, `"`)
+	}
+	return p
+}
+
+func parseEvent(props []property) (Event, error) {
+	event := Event{}
+	var (
+		end      *time.Time
+		duration *time.Duration
+		rrule    string
+	)
+
+	for _, p := range props {
+		var err error
+		switch p.name {
+		case "UID":
+			event.UID = p.value
+		case "SUMMARY":
+			event.Summary = unescape(p.value)
+		case "CATEGORIES":
+			for _, c := range splitUnescaped(p.value) {
+				event.Categories = append(event.Categories, unescape(c))
+			}
+		case "DTSTART":
+			event.Start, event.AllDay, err = parseDateTime(p)
+		case "DTEND":
+			var t time.Time
+			t, _, err = parseDateTime(p)
+			end = &t
+		case "DURATION":
+			var d time.Duration
+			d, err = parseDuration(p.value)
+			duration = &d
+		case "RRULE":
+			rrule = p.value
+		case "EXDATE":
+			for _, value := range strings.Split(p.value, ",") {
+				var t time.Time
+				t, _, err = parseDateTime(property{name: p.name, params: p.params, value: value})
+				if err != nil {
+					break
+				}
+				event.exceptions = append(event.exceptions, t)
+			}
+		case "RECURRENCE-ID":
+			var t time.Time
+			t, _, err = parseDateTime(p)
+			event.recurrenceID = &t
+		}
+		if err != nil {
+			return Event{}, fmt.Errorf("event %q: %s: %w", event.Summary, p.name, err)
+		}
+	}
+
+	if event.Start.IsZero() {
+		return Event{}, fmt.Errorf("event %q has no DTSTART", event.Summary)
+	}
+	switch {
+	case end != nil:
+		event.End = *end
+	case duration != nil && event.AllDay:
+		event.End = event.Start.AddDate(0, 0, int(duration.Hours()/24))
+	case duration != nil:
+		event.End = event.Start.Add(*duration)
+	case event.AllDay:
+		// a single day
+		event.End = event.Start.AddDate(0, 0, 1)
+	default:
+		event.End = event.Start
+	}
+
+	if rrule != "" {
+		rule, err := parseRecurrence(rrule)
+		if err != nil {
+			return Event{}, fmt.Errorf("event %q: RRULE: %w", event.Summary, err)
+		}
+		event.rule = rule
+	}
+
+	return event, nil
+}
+
+// reads a DATE or DATE-TIME value, in UTC (trailing Z), the time zone of its TZID or local time
+func parseDateTime(p property) (time.Time, bool, error) {
+	if p.params["VALUE"] == "DATE" || len(p.value) == len("20060102") {
+		t, err := time.ParseInLocation("20060102", p.value, time.Local)
+		return t, true, err
+	}
+
+	if strings.HasSuffix(p.value, "Z") {
+		t, err := time.Parse("20060102T150405Z", p.value)
+		return t, false, err
+	}
+
+	location := time.Local
+	if tzid := p.params["TZID"]; tzid != "" {
+		if l, err := time.LoadLocation(tzid); err == nil {
+			location = l
+		}
+	}
+	t, err := time.ParseInLocation("20060102T150405", p.value, location)
+	return t, false, err
+}
+
+// reads a DURATION value, e.g. P1D, PT1H30M or P2W
+func parseDuration(value string) (time.Duration, error) {
+	v := strings.TrimPrefix(strings.TrimPrefix(value, "+"), "P")
+	if v == value || v == "" {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+
+	var d time.Duration
+	inTime := false
+	number := ""
+	for _, c := range v {
+		switch {
+		case c >= '0' && c <= '9':
+			number += string(c)
+		case c == 'T':
+			inTime = true
+		default:
+			n, err := strconv.Atoi(number)
+			if err != nil {
+				return 0, fmt.Errorf("invalid duration %q", value)
+			}
+			number = ""
+			switch {
+			case c == 'W' && !inTime:
+				d += time.Duration(n) * 7 * 24 * time.Hour
+			case c == 'D' && !inTime:
+				d += time.Duration(n) * 24 * time.Hour
+			case c == 'H' && inTime:
+				d += time.Duration(n) * time.Hour
+			case c == 'M' && inTime:
+				d += time.Duration(n) * time.Minute
+			case c == 'S' && inTime:
+				d += time.Duration(n) * time.Second
+			default:
+				return 0, fmt.Errorf("invalid duration %q", value)
+			}
+		}
+	}
+	if number != "" {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	return d, nil
+}
+
+var weekdays = map[string]time.Weekday{
+	"MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday, "TH": time.Thursday,
+	"FR": time.Friday, "SA": time.Saturday, "SU": time.Sunday,
+}
+
+func parseRecurrence(value string) (*recurrence, error) {
+	r := &recurrence{interval: 1}
+	for _, part := range strings.Split(value, ";") {
+		key, val, _ := strings.Cut(part, "=")
+		var err error
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			r.freq = strings.ToUpper(val)
+		case "INTERVAL":
+			r.interval, err = strconv.Atoi(val)
+		case "COUNT":
+			r.count, err = strconv.Atoi(val)
+		case "UNTIL":
+			r.until, _, err = parseDateTime(property{value: val, params: map[string]string{}})
+		case "BYDAY":
+			for _, day := range strings.Split(val, ",") {
+				weekday, found := weekdays[strings.ToUpper(day)]
+				if !found {
+					return nil, fmt.Errorf("unsupported BYDAY %q", day)
+				}
+				r.byDay = append(r.byDay, weekday)
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q", key, val)
+		}
+	}
+
+	switch r.freq {
+	case "DAILY", "WEEKLY", "MONTHLY", "YEARLY":
+	default:
+		return nil, fmt.Errorf("unsupported FREQ %q", r.freq)
+	}
+	if r.interval < 1 {
+		return nil, fmt.Errorf("invalid INTERVAL %d", r.interval)
+	}
+	if len(r.byDay) > 0 && r.freq != "WEEKLY" {
+		return nil, fmt.Errorf("BYDAY is only supported for WEEKLY events")
+	}
+	return r, nil
+}
+
+// splits a list value at the commas that aren't escaped
+func splitUnescaped(value string) []string {
+	parts := []string{}
+	current := ""
+	escaped := false
+	for _, c := range value {
+		switch {
+		case escaped:
+			current += `\` + string(c)
+			escaped = false
+		case c == '\\':
+			escaped = true
+		case c == ',':
+			parts = append(parts, current)
+			current = ""
+		default:
+			current += string(c)
+		}
+	}
+	return append(parts, current)
+}
+
+func unescape(value string) string {
+	return strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(value)
+}
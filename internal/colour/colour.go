@@ -0,0 +1,182 @@
+// Package colour converts between the ways colours are written in the config and the CIE xy
+// chromaticity hue lights are controlled with.
+package colour
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// a CIE 1931 chromaticity, brightness is controlled separately
+type XY struct {
+	X float64
+	Y float64
+}
+
+// colour names that can be used in place of a hex value
+var named = map[string]string{
+	"red":       "#ff0000",
+	"crimson":   "#dc143c",
+	"coral":     "#ff7f50",
+	"salmon":    "#fa8072",
+	"orange":    "#ffa500",
+	"amber":     "#ffbf00",
+	"gold":      "#ffd700",
+	"yellow":    "#ffff00",
+	"lime":      "#00ff00",
+	"green":     "#008000",
+	"teal":      "#008080",
+	"turquoise": "#40e0d0",
+	"cyan":      "#00ffff",
+	"blue":      "#0000ff",
+	"navy":      "#000080",
+	"indigo":    "#4b0082",
+	"purple":    "#800080",
+	"violet":    "#ee82ee",
+	"magenta":   "#ff00ff",
+	"pink":      "#ffc0cb",
+	"hotpink":   "#ff69b4",
+	"white":     "#ffffff",
+}
+
+// Parse reads a hex (#ff8800) or named (orange) colour
+func Parse(s string) (XY, error) {
+	value := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(s), " ", ""))
+	if hex, isNamed := named[value]; isNamed {
+		value = hex
+	}
+	value = strings.TrimPrefix(value, "#")
+
+	if len(value) != 6 {
+		return XY{}, fmt.Errorf("invalid colour %q, expected a hex value like #ff8800 or a colour name", s)
+	}
+	rgb, err := strconv.ParseUint(value, 16, 32)
+	if err != nil {
+		return XY{}, fmt.Errorf("invalid colour %q, expected a hex value like #ff8800 or a colour name", s)
+	}
+	if rgb == 0 {
+		return XY{}, fmt.Errorf("invalid colour %q, black has no chromaticity (use brightness or off instead)", s)
+	}
+
+	return FromRGB(uint8(rgb>>16), uint8(rgb>>8), uint8(rgb)), nil
+}
+
+// FromRGB converts an sRGB colour
+func FromRGB(r, g, b uint8) XY {
+	rl, gl, bl := linearise(r), linearise(g), linearise(b)
+
+	// sRGB (D65) to XYZ
+	x := rl*0.4124564 + gl*0.3575761 + bl*0.1804375
+	y := rl*0.2126729 + gl*0.7151522 + bl*0.0721750
+	z := rl*0.0193339 + gl*0.1191920 + bl*0.9503041
+
+	return fromXYZ(x, y, z)
+}
+
+func linearise(c uint8) float64 {
+	v := float64(c) / 255
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+// FromKelvin returns the chromaticity of a colour temperature on the Planckian locus (Kim et al.)
+func FromKelvin(kelvin int) XY {
+	t := math.Max(1667, math.Min(25000, float64(kelvin)))
+
+	var x float64
+	if t <= 4000 {
+		x = -0.2661239e9/(t*t*t) - 0.2343589e6/(t*t) + 0.8776956e3/t + 0.179910
+	} else {
+		x = -3.0258469e9/(t*t*t) + 2.1070379e6/(t*t) + 0.2226347e3/t + 0.240390
+	}
+
+	var y float64
+	switch {
+	case t <= 2222:
+		y = -1.1063814*x*x*x - 1.34811020*x*x + 2.18555832*x - 0.20219683
+	case t <= 4000:
+		y = -0.9549476*x*x*x - 1.37418593*x*x + 2.09137015*x - 0.16748867
+	default:
+		y = 3.0817580*x*x*x - 5.87338670*x*x + 3.75112997*x - 0.37001483
+	}
+
+	return XY{X: x, Y: y}
+}
+
+// Kelvin returns the nearest colour temperature (McCamy's approximation), used for lights that can't show colour
+func (c XY) Kelvin() int {
+	n := (c.X - 0.3320) / (0.1858 - c.Y)
+	return int(math.Round(449*n*n*n + 3525*n*n + 6823.3*n + 5520.33))
+}
+
+// Interpolate blends between two colours in the OKLab perceptual colour space, progress being from 0 (a) to 1 (b)
+func Interpolate(a XY, b XY, progress float64) XY {
+	la, aa, ba := a.oklab()
+	lb, ab, bb := b.oklab()
+	return fromOklab(
+		la+(lb-la)*progress,
+		aa+(ab-aa)*progress,
+		ba+(bb-ba)*progress,
+	)
+}
+
+// converts to OKLab at a luminance of 1
+func (c XY) oklab() (float64, float64, float64) {
+	x, y, z := c.X/c.Y, 1.0, (1-c.X-c.Y)/c.Y
+
+	l := math.Cbrt(0.8189330101*x + 0.3618667424*y - 0.1288597137*z)
+	m := math.Cbrt(0.0329845436*x + 0.9293118715*y + 0.0361456387*z)
+	s := math.Cbrt(0.0482003018*x + 0.2643662691*y + 0.6338517070*z)
+
+	return 0.2104542553*l + 0.7936177850*m - 0.0040720468*s,
+		1.9779984951*l - 2.4285922050*m + 0.4505937099*s,
+		0.0259040371*l + 0.7827717662*m - 0.8086757660*s
+}
+
+func fromOklab(lightness, a, b float64) XY {
+	l := lightness + 0.3963377774*a + 0.2158037573*b
+	m := lightness - 0.1055613458*a - 0.0638541728*b
+	s := lightness - 0.0894841775*a - 1.2914855480*b
+	l, m, s = l*l*l, m*m*m, s*s*s
+
+	return fromXYZ(
+		1.2270138511*l-0.5577999807*m+0.2812561490*s,
+		-0.0405801784*l+1.1122568696*m-0.0716766787*s,
+		-0.0763812845*l-0.4214819784*m+1.5861632204*s,
+	)
+}
+
+func fromXYZ(x, y, z float64) XY {
+	sum := x + y + z
+	if sum == 0 {
+		return XY{}
+	}
+	return XY{X: x / sum, Y: y / sum}
+}
+
+// Lightness converts a brightness (0-100, taken as relative luminance) to CIE L* (0-100), which is even to the eye
+func Lightness(brightness float64) float64 {
+	y := brightness / 100
+	if y > lightnessEpsilon {
+		return 116*math.Cbrt(y) - 16
+	}
+	return y * lightnessKappa
+}
+
+// BrightnessFromLightness converts CIE L* (0-100) back to a brightness (0-100)
+func BrightnessFromLightness(lightness float64) float64 {
+	if lightness > lightnessKappa*lightnessEpsilon {
+		return 100 * math.Pow((lightness+16)/116, 3)
+	}
+	return 100 * lightness / lightnessKappa
+}
+
+// the CIE constants, (6/29)^3 and (29/3)^3
+const (
+	lightnessEpsilon = 216.0 / 24389.0
+	lightnessKappa   = 24389.0 / 27.0
+)
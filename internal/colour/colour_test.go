@@ -0,0 +1,90 @@
+package colour_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wheelibin/hugh/internal/colour"
+)
+
+func Test_Parse(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		value    string
+		expected colour.XY
+		err      bool
+	}{
+		{name: "hex", value: "#ff0000", expected: colour.XY{X: 0.6401, Y: 0.3300}},
+		{name: "hex without #", value: "0000FF", expected: colour.XY{X: 0.1500, Y: 0.0600}},
+		{name: "white is the D65 white point", value: "#ffffff", expected: colour.XY{X: 0.3127, Y: 0.3290}},
+		{name: "named", value: "Lime", expected: colour.XY{X: 0.3000, Y: 0.6000}},
+		{name: "named with spaces", value: "hot pink", expected: colour.FromRGB(0xff, 0x69, 0xb4)},
+		{name: "unknown name", value: "sunset", err: true},
+		{name: "short hex", value: "#fff", err: true},
+		{name: "black", value: "#000000", err: true},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			xy, err := colour.Parse(test.value)
+			if test.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.InDelta(t, test.expected.X, xy.X, 0.0005)
+			assert.InDelta(t, test.expected.Y, xy.Y, 0.0005)
+		})
+	}
+}
+
+// McCamy is within ~1% across the range hue lights support, a little more at the warm end
+func Test_Kelvin(t *testing.T) {
+	for _, kelvin := range []int{2000, 2700, 4000, 6500} {
+		assert.InDelta(t, kelvin, colour.FromKelvin(kelvin).Kelvin(), float64(kelvin)*0.02, "%vK", kelvin)
+	}
+}
+
+func Test_Interpolate(t *testing.T) {
+	red, _ := colour.Parse("red")
+	blue, _ := colour.Parse("blue")
+
+	start := colour.Interpolate(red, blue, 0)
+	end := colour.Interpolate(red, blue, 1)
+	middle := colour.Interpolate(red, blue, 0.5)
+
+	assert.InDelta(t, red.X, start.X, 0.0001)
+	assert.InDelta(t, red.Y, start.Y, 0.0001)
+	assert.InDelta(t, blue.X, end.X, 0.0001)
+	assert.InDelta(t, blue.Y, end.Y, 0.0001)
+	// a straight line through xy would pass through a washed out pink, OKLab keeps to a saturated purple
+	assert.True(t, colour.GamutC.Contains(middle))
+	assert.Less(t, middle.Y, (red.Y+blue.Y)/2)
+}
+
+func Test_Gamut_Clamp(t *testing.T) {
+	inside := colour.XY{X: 0.4, Y: 0.4}
+	assert.Equal(t, inside, colour.GamutB.Clamp(inside))
+
+	// a saturated green beyond gamut B is moved onto its red-green edge
+	green := colour.XY{X: 0.17, Y: 0.7}
+	clamped := colour.GamutB.Clamp(green)
+	assert.False(t, colour.GamutB.Contains(green))
+	assert.NotEqual(t, green, clamped)
+	assert.InDelta(t, 0.409, clamped.X, 0.01)
+	assert.InDelta(t, 0.518, clamped.Y, 0.01)
+}
+
+func Test_Lightness(t *testing.T) {
+	assert.InDelta(t, 0, colour.Lightness(0), 0.0001)
+	assert.InDelta(t, 100, colour.Lightness(100), 0.0001)
+	// 18% grey looks half as bright
+	assert.InDelta(t, 50, colour.Lightness(18.42), 0.01)
+
+	for _, brightness := range []float64{0, 0.5, 1, 18, 50, 100} {
+		assert.InDelta(t, brightness, colour.BrightnessFromLightness(colour.Lightness(brightness)), 0.0001)
+	}
+}
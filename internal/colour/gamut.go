@@ -0,0 +1,71 @@
+package colour
+
+import "strings"
+
+// the triangle of colours a light can produce
+type Gamut struct {
+	Red   XY
+	Green XY
+	Blue  XY
+}
+
+// the gamuts hue lights report through their gamut_type
+var (
+	// early colour lights, e.g. LivingColors and LightStrips
+	GamutA = Gamut{Red: XY{0.704, 0.296}, Green: XY{0.2151, 0.7106}, Blue: XY{0.138, 0.08}}
+	// first generation hue bulbs
+	GamutB = Gamut{Red: XY{0.675, 0.322}, Green: XY{0.409, 0.518}, Blue: XY{0.167, 0.04}}
+	// current hue colour lights
+	GamutC = Gamut{Red: XY{0.6915, 0.3083}, Green: XY{0.17, 0.7}, Blue: XY{0.1532, 0.0475}}
+)
+
+// GamutForType returns the gamut for a gamut_type, gamut C for anything unrecognised
+func GamutForType(gamutType string) Gamut {
+	switch strings.ToUpper(gamutType) {
+	case "A":
+		return GamutA
+	case "B":
+		return GamutB
+	default:
+		return GamutC
+	}
+}
+
+func (g Gamut) Contains(c XY) bool {
+	d1 := cross(c, g.Red, g.Green)
+	d2 := cross(c, g.Green, g.Blue)
+	d3 := cross(c, g.Blue, g.Red)
+	hasNegative := d1 < 0 || d2 < 0 || d3 < 0
+	hasPositive := d1 > 0 || d2 > 0 || d3 > 0
+	return !(hasNegative && hasPositive)
+}
+
+// Clamp returns the colour if the light can produce it, otherwise the closest colour it can
+func (g Gamut) Clamp(c XY) XY {
+	if g.Contains(c) {
+		return c
+	}
+
+	closest := closestOnEdge(c, g.Red, g.Green)
+	for _, p := range []XY{closestOnEdge(c, g.Green, g.Blue), closestOnEdge(c, g.Blue, g.Red)} {
+		if distanceSquared(c, p) < distanceSquared(c, closest) {
+			closest = p
+		}
+	}
+	return closest
+}
+
+func cross(p, a, b XY) float64 {
+	return (p.X-b.X)*(a.Y-b.Y) - (a.X-b.X)*(p.Y-b.Y)
+}
+
+func closestOnEdge(p, a, b XY) XY {
+	abX, abY := b.X-a.X, b.Y-a.Y
+	t := ((p.X-a.X)*abX + (p.Y-a.Y)*abY) / (abX*abX + abY*abY)
+	t = max(0, min(1, t))
+	return XY{X: a.X + abX*t, Y: a.Y + abY*t}
+}
+
+func distanceSquared(a, b XY) float64 {
+	return (a.X-b.X)*(a.X-b.X) + (a.Y-b.Y)*(a.Y-b.Y)
+}
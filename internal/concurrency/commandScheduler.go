@@ -0,0 +1,303 @@
+package concurrency
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+type CommandKind int
+
+const (
+	// a command addressed to a single light (or scene)
+	LightCommand CommandKind = iota
+	// a command broadcast to a room/zone
+	GroupCommand
+)
+
+type RateLimits struct {
+	LightCommandsPerSecond float64
+	GroupCommandsPerSecond float64
+
+	// how long to wait after the first rate limited response, doubled on each consecutive one up to MaxBackoff
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// the limits Signify recommends staying under for a single bridge
+var HueBridgeRateLimits = RateLimits{
+	LightCommandsPerSecond: 10,
+	GroupCommandsPerSecond: 1,
+	InitialBackoff:         time.Second,
+	MaxBackoff:             30 * time.Second,
+}
+
+// returned to callers still waiting on a command when the scheduler is stopped
+var ErrSchedulerStopped = errors.New("command scheduler stopped")
+
+type command struct {
+	kind    CommandKind
+	key     string
+	run     func(ctx context.Context) error
+	waiters []waiter
+}
+
+// a submitter of a command, waiting on its result for as long as its context lasts
+type waiter struct {
+	ctx    context.Context
+	result chan error
+}
+
+// drops the waiters that have given up, telling them why
+func (c *command) dropCancelledWaiters() {
+	waiting := c.waiters[:0]
+	for _, w := range c.waiters {
+		if err := w.ctx.Err(); err != nil {
+			w.result <- err
+			continue
+		}
+		waiting = append(waiting, w)
+	}
+	c.waiters = waiting
+}
+
+// the context the command is sent with, cancelled when the scheduler stops or once every submitter has given up
+func (c *command) context(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(parent)
+
+	var mu sync.Mutex
+	remaining := len(c.waiters)
+	stops := make([]func() bool, 0, len(c.waiters))
+	for _, w := range c.waiters {
+		stops = append(stops, context.AfterFunc(w.ctx, func() {
+			mu.Lock()
+			defer mu.Unlock()
+			remaining--
+			if remaining == 0 {
+				cancel()
+			}
+		}))
+	}
+
+	return ctx, func() {
+		for _, stop := range stops {
+			stop()
+		}
+		cancel()
+	}
+}
+
+// CommandScheduler sends commands to a bridge one at a time within its rate limits.
+// There is a single slot per key, so a command submitted while an older one for the same key is
+// still queued replaces it (keeping its place in the queue) and both submitters receive the result.
+type CommandScheduler struct {
+	limits        RateLimits
+	isRateLimited func(err error) bool
+
+	mu           sync.Mutex
+	queues       map[CommandKind][]string
+	pending      map[string]*command
+	nextAllowed  map[CommandKind]time.Time
+	backoff      time.Duration
+	backoffUntil time.Time
+	stopped      bool
+	wake         chan struct{}
+}
+
+func NewCommandScheduler(limits RateLimits, isRateLimited func(err error) bool) *CommandScheduler {
+	if isRateLimited == nil {
+		isRateLimited = func(error) bool { return false }
+	}
+	return &CommandScheduler{
+		limits:        limits,
+		isRateLimited: isRateLimited,
+		queues:        map[CommandKind][]string{},
+		pending:       map[string]*command{},
+		nextAllowed:   map[CommandKind]time.Time{},
+		wake:          make(chan struct{}, 1),
+	}
+}
+
+// Submit queues a command, the returned channel receives its result once it has been sent.
+// The command is dropped without being sent if the context (and that of everyone else waiting on it) is done first,
+// and is sent with a context that is cancelled once they all are.
+func (s *CommandScheduler) Submit(ctx context.Context, kind CommandKind, key string, run func(ctx context.Context) error) <-chan error {
+	result := make(chan error, 1)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stopped {
+		result <- ErrSchedulerStopped
+		return result
+	}
+
+	if cmd, queued := s.pending[key]; queued {
+		// latest wins, the stale command is never sent
+		cmd.run = run
+		cmd.waiters = append(cmd.waiters, waiter{ctx: ctx, result: result})
+		return result
+	}
+
+	s.pending[key] = &command{kind: kind, key: key, run: run, waiters: []waiter{{ctx: ctx, result: result}}}
+	s.queues[kind] = append(s.queues[kind], key)
+	s.notify()
+
+	return result
+}
+
+// Do submits a command and waits for its result
+func (s *CommandScheduler) Do(ctx context.Context, kind CommandKind, key string, run func(ctx context.Context) error) error {
+	select {
+	case err := <-s.Submit(ctx, kind, key, run):
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Run sends queued commands until the context is cancelled
+func (s *CommandScheduler) Run(ctx context.Context) {
+	defer s.stop()
+
+	for {
+		cmd, wait := s.next(time.Now())
+
+		if cmd == nil {
+			var timer *time.Timer
+			var timeout <-chan time.Time
+			if wait > 0 {
+				timer = time.NewTimer(wait)
+				timeout = timer.C
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-s.wake:
+			case <-timeout:
+			}
+			if timer != nil {
+				timer.Stop()
+			}
+			continue
+		}
+
+		cmdCtx, cancel := cmd.context(ctx)
+		err := cmd.run(cmdCtx)
+		cancel()
+		s.complete(cmd, err, time.Now())
+	}
+}
+
+// pops the next command that is allowed to be sent, or returns how long until one will be,
+// commands nobody is waiting for any more are dropped on the way
+func (s *CommandScheduler) next(now time.Time) (*command, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for {
+		cmd, wait := s.pop(now)
+		if cmd == nil {
+			return nil, wait
+		}
+		cmd.dropCancelledWaiters()
+		if len(cmd.waiters) > 0 {
+			s.nextAllowed[cmd.kind] = now.Add(s.interval(cmd.kind))
+			return cmd, 0
+		}
+	}
+}
+
+// takes the command at the front of the queue that is next allowed to be sent, if it is allowed to be sent now
+func (s *CommandScheduler) pop(now time.Time) (*command, time.Duration) {
+	var (
+		nextKind  CommandKind
+		nextReady time.Time
+		found     bool
+	)
+	for _, kind := range []CommandKind{LightCommand, GroupCommand} {
+		if len(s.queues[kind]) == 0 {
+			continue
+		}
+		ready := s.nextAllowed[kind]
+		if s.backoffUntil.After(ready) {
+			ready = s.backoffUntil
+		}
+		if !found || ready.Before(nextReady) {
+			nextKind, nextReady, found = kind, ready, true
+		}
+	}
+
+	if !found {
+		return nil, 0
+	}
+	if nextReady.After(now) {
+		return nil, nextReady.Sub(now)
+	}
+
+	key := s.queues[nextKind][0]
+	s.queues[nextKind] = s.queues[nextKind][1:]
+	cmd := s.pending[key]
+	delete(s.pending, key)
+
+	return cmd, 0
+}
+
+func (s *CommandScheduler) complete(cmd *command, err error, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.isRateLimited(err) && !s.stopped {
+		s.backoff = min(max(s.backoff*2, s.limits.InitialBackoff), s.limits.MaxBackoff)
+		s.backoffUntil = now.Add(s.backoff)
+
+		if newer, queued := s.pending[cmd.key]; queued {
+			// a newer command for the same key arrived while this one was being sent, it can answer for both
+			newer.waiters = append(newer.waiters, cmd.waiters...)
+			return
+		}
+		// retry at the front of the queue once the backoff has passed
+		s.pending[cmd.key] = cmd
+		s.queues[cmd.kind] = append([]string{cmd.key}, s.queues[cmd.kind]...)
+		return
+	}
+
+	s.backoff = 0
+	for _, w := range cmd.waiters {
+		w.result <- err
+	}
+}
+
+func (s *CommandScheduler) interval(kind CommandKind) time.Duration {
+	perSecond := s.limits.LightCommandsPerSecond
+	if kind == GroupCommand {
+		perSecond = s.limits.GroupCommandsPerSecond
+	}
+	if perSecond <= 0 {
+		return 0
+	}
+	return time.Duration(float64(time.Second) / perSecond)
+}
+
+func (s *CommandScheduler) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// fails everything still queued so no caller is left waiting
+func (s *CommandScheduler) stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stopped = true
+	for key, cmd := range s.pending {
+		for _, w := range cmd.waiters {
+			w.result <- ErrSchedulerStopped
+		}
+		delete(s.pending, key)
+	}
+	s.queues = map[CommandKind][]string{}
+}
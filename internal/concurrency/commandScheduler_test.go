@@ -0,0 +1,182 @@
+package concurrency_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wheelibin/hugh/internal/concurrency"
+)
+
+var errRateLimited = errors.New("rate limited")
+
+func startScheduler(t *testing.T, limits concurrency.RateLimits) *concurrency.CommandScheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	s := concurrency.NewCommandScheduler(limits, func(err error) bool { return errors.Is(err, errRateLimited) })
+	go s.Run(ctx)
+	return s
+}
+
+func Test_CommandScheduler(t *testing.T) {
+
+	t.Run("should report each command's result to its caller", func(t *testing.T) {
+		t.Parallel()
+		s := startScheduler(t, concurrency.RateLimits{})
+
+		ok := s.Submit(context.Background(), concurrency.LightCommand, "a", func(context.Context) error { return nil })
+		failed := s.Submit(context.Background(), concurrency.LightCommand, "b", func(context.Context) error { return errors.New("boom") })
+
+		assert.NoError(t, <-ok)
+		assert.EqualError(t, <-failed, "boom")
+	})
+
+	t.Run("should only send the latest command queued for a key", func(t *testing.T) {
+		t.Parallel()
+		s := startScheduler(t, concurrency.RateLimits{LightCommandsPerSecond: 10})
+
+		var mu sync.Mutex
+		sent := []string{}
+		record := func(v string) func(context.Context) error {
+			return func(context.Context) error {
+				mu.Lock()
+				defer mu.Unlock()
+				sent = append(sent, v)
+				return nil
+			}
+		}
+
+		// "a" is sent straight away, the rest queue behind it while the limiter waits
+		first := s.Submit(context.Background(), concurrency.LightCommand, "a", record("a1"))
+		stale := s.Submit(context.Background(), concurrency.LightCommand, "b", record("b1"))
+		latest := s.Submit(context.Background(), concurrency.LightCommand, "b", record("b2"))
+
+		assert.NoError(t, <-first)
+		assert.NoError(t, <-stale)
+		assert.NoError(t, <-latest)
+		assert.Equal(t, []string{"a1", "b2"}, sent)
+	})
+
+	t.Run("should keep to the rate limit for each kind of command", func(t *testing.T) {
+		t.Parallel()
+		s := startScheduler(t, concurrency.RateLimits{GroupCommandsPerSecond: 20})
+
+		start := time.Now()
+		results := []<-chan error{}
+		for _, key := range []string{"g1", "g2", "g3"} {
+			results = append(results, s.Submit(context.Background(), concurrency.GroupCommand, key, func(context.Context) error { return nil }))
+		}
+		for _, r := range results {
+			<-r
+		}
+
+		// the second and third commands each wait 50ms
+		assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+	})
+
+	t.Run("should back off and retry a rate limited command", func(t *testing.T) {
+		t.Parallel()
+		s := startScheduler(t, concurrency.RateLimits{InitialBackoff: 20 * time.Millisecond, MaxBackoff: time.Second})
+
+		attempts := 0
+		start := time.Now()
+		err := s.Do(context.Background(), concurrency.LightCommand, "a", func(context.Context) error {
+			attempts++
+			if attempts < 3 {
+				return errRateLimited
+			}
+			return nil
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 3, attempts)
+		// 20ms then 40ms
+		assert.GreaterOrEqual(t, time.Since(start), 60*time.Millisecond)
+	})
+
+	t.Run("should fail queued commands when stopped", func(t *testing.T) {
+		t.Parallel()
+		ctx, cancel := context.WithCancel(context.Background())
+		s := concurrency.NewCommandScheduler(concurrency.RateLimits{LightCommandsPerSecond: 0.1}, nil)
+		done := make(chan struct{})
+		go func() {
+			s.Run(ctx)
+			close(done)
+		}()
+
+		first := s.Submit(context.Background(), concurrency.LightCommand, "a", func(context.Context) error { return nil })
+		<-first
+		queued := s.Submit(context.Background(), concurrency.LightCommand, "b", func(context.Context) error { return nil })
+		cancel()
+		<-done
+
+		assert.ErrorIs(t, <-queued, concurrency.ErrSchedulerStopped)
+	})
+
+	t.Run("should never send a command once its caller has given up", func(t *testing.T) {
+		t.Parallel()
+		s := startScheduler(t, concurrency.RateLimits{LightCommandsPerSecond: 20})
+
+		var mu sync.Mutex
+		sent := []string{}
+		record := func(v string) func(context.Context) error {
+			return func(context.Context) error {
+				mu.Lock()
+				defer mu.Unlock()
+				sent = append(sent, v)
+				return nil
+			}
+		}
+
+		// "b" queues behind "a" while the limiter waits, and is given up on before its turn
+		first := s.Submit(context.Background(), concurrency.LightCommand, "a", record("a"))
+		ctx, cancel := context.WithCancel(context.Background())
+		cancelled := s.Submit(ctx, concurrency.LightCommand, "b", record("b"))
+		cancel()
+		last := s.Submit(context.Background(), concurrency.LightCommand, "c", record("c"))
+
+		assert.NoError(t, <-first)
+		assert.ErrorIs(t, <-cancelled, context.Canceled)
+		assert.NoError(t, <-last)
+		assert.Equal(t, []string{"a", "c"}, sent)
+	})
+
+	t.Run("should still send a command someone else is waiting on", func(t *testing.T) {
+		t.Parallel()
+		s := startScheduler(t, concurrency.RateLimits{LightCommandsPerSecond: 20})
+
+		<-s.Submit(context.Background(), concurrency.LightCommand, "a", func(context.Context) error { return nil })
+		ctx, cancel := context.WithCancel(context.Background())
+		cancelled := s.Submit(ctx, concurrency.LightCommand, "b", func(context.Context) error { return nil })
+		sent := false
+		waiting := s.Submit(context.Background(), concurrency.LightCommand, "b", func(context.Context) error {
+			sent = true
+			return nil
+		})
+		cancel()
+
+		assert.ErrorIs(t, <-cancelled, context.Canceled)
+		assert.NoError(t, <-waiting)
+		assert.True(t, sent)
+	})
+
+	t.Run("should cancel the context a command is sent with once its caller gives up", func(t *testing.T) {
+		t.Parallel()
+		s := startScheduler(t, concurrency.RateLimits{})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		started := make(chan struct{})
+		result := s.Submit(ctx, concurrency.LightCommand, "a", func(ctx context.Context) error {
+			close(started)
+			<-ctx.Done()
+			return ctx.Err()
+		})
+		<-started
+		cancel()
+
+		assert.ErrorIs(t, <-result, context.Canceled)
+	})
+}
@@ -1,21 +1,250 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 
 	"github.com/charmbracelet/log"
+	"github.com/mitchellh/mapstructure"
 	"github.com/spf13/viper"
+	"github.com/wheelibin/hugh/internal/models"
+	"gopkg.in/yaml.v3"
 )
 
+// the name given to the bridge configured with the top level bridgeIp/hueApplicationKey keys
+const DefaultBridgeName = "default"
+
 func InitialiseConfig() {
-	viper.SetConfigName("config")
-	viper.SetConfigType("yaml")
-	viper.AddConfigPath("$HOME/.config/hugh/")
-	viper.AddConfigPath("/etc/hugh/")
-	viper.AddConfigPath(".")    // optionally look for config in the working directory
+	setConfigLocations()
 	err := viper.ReadInConfig() // Find and read the config file
 	if err != nil {             // Handle errors reading the config file
 		log.Error(err)
 		panic(fmt.Errorf("fatal error config file: %w", err))
 	}
 }
+
+// reads the config file if there is one, pairing is allowed to run before hugh has been configured
+func InitialiseConfigForPairing() error {
+	setConfigLocations()
+	err := viper.ReadInConfig()
+	if errors.As(err, &viper.ConfigFileNotFoundError{}) {
+		return nil
+	}
+	return err
+}
+
+// FindConfigFile reads the config file, returning its path
+func FindConfigFile() (string, error) {
+	setConfigLocations()
+	if err := viper.ReadInConfig(); err != nil {
+		return "", err
+	}
+	return viper.ConfigFileUsed(), nil
+}
+
+func setConfigLocations() {
+	viper.SetConfigName("config")
+	viper.SetConfigType("yaml")
+	viper.AddConfigPath("$HOME/.config/hugh/")
+	viper.AddConfigPath("/etc/hugh/")
+	viper.AddConfigPath(".") // optionally look for config in the working directory
+}
+
+// Bridges returns the configured bridges, from the bridges list or the single bridge top level keys
+func Bridges() ([]models.Bridge, error) {
+	var bridges []models.Bridge
+	if viper.IsSet("bridges") {
+		if err := viper.UnmarshalKey("bridges", &bridges); err != nil {
+			return nil, fmt.Errorf("error reading bridges from config: %w", err)
+		}
+	} else if viper.IsSet("bridgeIp") {
+		bridges = append(bridges, models.Bridge{
+			Name:           DefaultBridgeName,
+			IP:             viper.GetString("bridgeIp"),
+			ApplicationKey: viper.GetString("hueApplicationKey"),
+			ID:             viper.GetString("bridgeId"),
+			Certificate:    viper.GetString("bridgeCertificate"),
+		})
+	}
+
+	if len(bridges) == 0 {
+		return nil, errors.New("no bridges configured, run `hugh pair` to add one")
+	}
+
+	names := map[string]bool{}
+	for i, b := range bridges {
+		if b.Name == "" {
+			return nil, fmt.Errorf("bridge %d has no name", i+1)
+		}
+		if names[b.Name] {
+			return nil, fmt.Errorf("bridge name %s is used more than once", b.Name)
+		}
+		if b.IP == "" || b.ApplicationKey == "" {
+			return nil, fmt.Errorf("bridge %s needs an ip and applicationKey, run `hugh pair` to set them", b.Name)
+		}
+		names[b.Name] = true
+	}
+
+	return bridges, nil
+}
+
+// Schedules returns the configured schedules
+func Schedules() ([]models.Schedule, error) {
+	var schedules []models.Schedule
+	if err := viper.UnmarshalKey("schedules", &schedules, viper.DecodeHook(decodeHook)); err != nil {
+		return nil, err
+	}
+	return schedules, nil
+}
+
+// Calendar returns the configured calendar, nil if there isn't one
+func Calendar() (*models.Calendar, error) {
+	if !viper.IsSet("calendar") {
+		return nil, nil
+	}
+	var cal models.Calendar
+	if err := viper.UnmarshalKey("calendar", &cal, viper.DecodeHook(decodeHook)); err != nil {
+		return nil, err
+	}
+	if cal.File == "" && cal.URL == "" {
+		return nil, errors.New("the calendar needs a file or url")
+	}
+	return &cal, nil
+}
+
+// viper's default hooks, plus reading a single day pattern name as the default of a DayPatternSelector
+var decodeHook = mapstructure.ComposeDecodeHookFunc(
+	mapstructure.StringToTimeDurationHookFunc(),
+	mapstructure.StringToSliceHookFunc(","),
+	dayPatternSelectorHook,
+)
+
+func dayPatternSelectorHook(from reflect.Type, to reflect.Type, data any) (any, error) {
+	if to != reflect.TypeOf(models.DayPatternSelector{}) || from.Kind() != reflect.String {
+		return data, nil
+	}
+	return models.DayPatternSelector{models.DayPatternDefault: data.(string)}, nil
+}
+
+// the file trust-on-first-use bridge certificate fingerprints are stored in, next to the config file
+func PinFilePath() string {
+	return filepath.Join(configDir(), "bridge-pins.json")
+}
+
+// the file a calendar downloaded from a url is cached in, next to the config file
+func CalendarCachePath() string {
+	return filepath.Join(configDir(), "calendar-cache.ics")
+}
+
+// the directory of the config file in use, the working directory if there isn't one
+func configDir() string {
+	if used := viper.ConfigFileUsed(); used != "" {
+		return filepath.Dir(used)
+	}
+	return "."
+}
+
+// writes the bridge connection details into the config file in use (or a new one in the user's config dir),
+// editing the yaml in place so existing comments and ordering are kept.
+// If the config has a bridges list the bridge is added to it (or its entry updated), otherwise the top level keys are set.
+func SaveBridgeCredentials(bridge models.Bridge) (string, error) {
+	path := viper.ConfigFileUsed()
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		path = filepath.Join(home, ".config", "hugh", "config.yaml")
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return "", err
+		}
+	}
+
+	doc := yaml.Node{}
+	existing, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", err
+	}
+	if err := yaml.Unmarshal(existing, &doc); err != nil {
+		return "", fmt.Errorf("error parsing %s: %w", path, err)
+	}
+	if len(doc.Content) == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return "", fmt.Errorf("error updating %s: top level is not a mapping", path)
+	}
+
+	if bridges := mappingValue(root, "bridges"); bridges != nil && bridges.Kind == yaml.SequenceNode {
+		entry := findBridgeEntry(bridges, bridge)
+		if entry == nil {
+			entry = &yaml.Node{Kind: yaml.MappingNode}
+			bridges.Content = append(bridges.Content, entry)
+			setMappingValue(entry, "name", bridge.Name)
+		}
+		setMappingValue(entry, "ip", bridge.IP)
+		setMappingValue(entry, "applicationKey", bridge.ApplicationKey)
+		setMappingValue(entry, "id", bridge.ID)
+	} else {
+		setMappingValue(root, "bridgeIp", bridge.IP)
+		setMappingValue(root, "hueApplicationKey", bridge.ApplicationKey)
+		setMappingValue(root, "bridgeId", bridge.ID)
+	}
+
+	out, err := yaml.Marshal(&doc)
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, out, 0o600); err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
+
+// finds the bridges list entry for the bridge, matching on id or, failing that, name
+func findBridgeEntry(bridges *yaml.Node, bridge models.Bridge) *yaml.Node {
+	for _, entry := range bridges.Content {
+		if id := mappingValue(entry, "id"); id != nil && bridge.ID != "" && strings.EqualFold(id.Value, bridge.ID) {
+			return entry
+		}
+	}
+	for _, entry := range bridges.Content {
+		if name := mappingValue(entry, "name"); name != nil && name.Value == bridge.Name {
+			return entry
+		}
+	}
+	return nil
+}
+
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	if mapping.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i < len(mapping.Content)-1; i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func setMappingValue(mapping *yaml.Node, key string, value string) {
+	for i := 0; i < len(mapping.Content)-1; i += 2 {
+		if mapping.Content[i].Value == key {
+			mapping.Content[i+1].Kind = yaml.ScalarNode
+			mapping.Content[i+1].Tag = "!!str"
+			mapping.Content[i+1].Value = value
+			return
+		}
+	}
+	mapping.Content = append(mapping.Content,
+		&yaml.Node{Kind: yaml.ScalarNode, Value: key},
+		&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value},
+	)
+}
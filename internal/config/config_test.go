@@ -0,0 +1,119 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"github.com/wheelibin/hugh/internal/config"
+	"github.com/wheelibin/hugh/internal/models"
+)
+
+// writes the config to a file and reads it as the config in use
+func useConfig(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	viper.SetConfigFile(path)
+	require.NoError(t, viper.ReadInConfig())
+	return path
+}
+
+func readConfig(t *testing.T, path string) (string, map[string]any) {
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	values := map[string]any{}
+	require.NoError(t, yaml.Unmarshal(data, &values))
+	return string(data), values
+}
+
+func Test_SaveBridgeCredentials(t *testing.T) {
+
+	t.Run("should set the top level keys, keeping comments and other keys", func(t *testing.T) {
+		path := useConfig(t, `debugMode: false
+# the bridge to control
+bridgeIp: 192.168.1.2 # found by hugh pair
+hueApplicationKey: old-key
+schedules:
+  # the kitchen follows the sun
+  - name: Kitchen
+    dayPattern: circadian
+`)
+
+		saved, err := config.SaveBridgeCredentials(models.Bridge{Name: "Hue Bridge", IP: "192.168.1.10", ApplicationKey: "new-key", ID: "001788fffe6a2b3c"})
+		require.NoError(t, err)
+		assert.Equal(t, path, saved)
+
+		content, values := readConfig(t, path)
+		assert.Contains(t, content, "# the bridge to control")
+		assert.Contains(t, content, "# found by hugh pair")
+		assert.Contains(t, content, "# the kitchen follows the sun")
+		assert.Equal(t, map[string]any{
+			"debugMode":         false,
+			"bridgeIp":          "192.168.1.10",
+			"hueApplicationKey": "new-key",
+			"bridgeId":          "001788fffe6a2b3c",
+			"schedules":         []any{map[string]any{"name": "Kitchen", "dayPattern": "circadian"}},
+		}, values)
+	})
+
+	t.Run("should update the entry of a bridge in the bridges list", func(t *testing.T) {
+		path := useConfig(t, `bridges:
+  # upstairs
+  - name: house
+    ip: 192.168.1.2
+    applicationKey: old-key
+    id: 001788FFFE6A2B3C
+    certificate: pin
+  - name: garage
+    ip: 192.168.1.3
+    applicationKey: garage-key
+`)
+
+		// found by id, whatever it is called
+		_, err := config.SaveBridgeCredentials(models.Bridge{Name: "Hue Bridge", IP: "192.168.1.10", ApplicationKey: "new-key", ID: "001788fffe6a2b3c"})
+		require.NoError(t, err)
+
+		content, values := readConfig(t, path)
+		assert.Contains(t, content, "# upstairs")
+		assert.Equal(t, []any{
+			map[string]any{"name": "house", "ip": "192.168.1.10", "applicationKey": "new-key", "id": "001788fffe6a2b3c", "certificate": "pin"},
+			map[string]any{"name": "garage", "ip": "192.168.1.3", "applicationKey": "garage-key"},
+		}, values["bridges"])
+	})
+
+	t.Run("should add a new bridge to the bridges list", func(t *testing.T) {
+		path := useConfig(t, `bridges:
+  - name: house
+    ip: 192.168.1.2
+    applicationKey: house-key
+`)
+
+		_, err := config.SaveBridgeCredentials(models.Bridge{Name: "garage", IP: "192.168.1.3", ApplicationKey: "garage-key", ID: "001788fffe4d5e6f"})
+		require.NoError(t, err)
+
+		_, values := readConfig(t, path)
+		assert.Equal(t, []any{
+			map[string]any{"name": "house", "ip": "192.168.1.2", "applicationKey": "house-key"},
+			map[string]any{"name": "garage", "ip": "192.168.1.3", "applicationKey": "garage-key", "id": "001788fffe4d5e6f"},
+		}, values["bridges"])
+
+		// and is read back by hugh
+		viper.Reset()
+		viper.SetConfigFile(path)
+		require.NoError(t, viper.ReadInConfig())
+		bridges, err := config.Bridges()
+		require.NoError(t, err)
+		assert.Equal(t, []models.Bridge{
+			{Name: "house", IP: "192.168.1.2", ApplicationKey: "house-key"},
+			{Name: "garage", IP: "192.168.1.3", ApplicationKey: "garage-key", ID: "001788fffe4d5e6f"},
+		}, bridges)
+	})
+}
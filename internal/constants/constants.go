@@ -7,12 +7,72 @@ const MaxLightOverrideMinutes = 120
 
 // bridge events
 const EventBatchTypeUpdate = "update"
+const EventBatchTypeAdd = "add"
+const EventBatchTypeDelete = "delete"
 
 const EventTypeZigbeeConnectivity = "zigbee_connectivity"
 const EventStatusConnectivityIssue = "connectivity_issue"
 const EventStatusConnected = "connected"
 
 const EventTypeLight = "light"
+const EventTypeDevice = "device"
+const EventTypeRoom = "room"
+const EventTypeZone = "zone"
+const EventTypeScene = "scene"
+const EventTypeMotion = "motion"
+const EventTypeContact = "contact"
+const EventTypeLightLevel = "light_level"
+const EventTypeButton = "button"
+const EventTypeRelativeRotary = "relative_rotary"
+
+const ButtonEventShortRelease = "short_release"
+const RotaryDirectionClockwise = "clock_wise"
+
+// the actions switch buttons and dials can be bound to
+const (
+	// clears the overrides and adjustments of the schedule's lights, returning them to the schedule
+	ActionResume = "resume"
+	// leaves the schedule's lights as they are for a while
+	ActionPause = "pause"
+	// skips to the next step of the day pattern
+	ActionNextStep = "next"
+	// fades the schedule's lights off, keeping them off until the next step
+	ActionWindDown = "windDown"
+	// turning the dial raises or lowers the brightness for the rest of the interval
+	ActionBrightness = "brightness"
+)
+
+const DefaultPauseDuration = 2 * time.Hour
+const DefaultWindDownDuration = 30 * time.Minute
+
+// how much brightness (%) each step a dial reports is worth
+const BrightnessPerRotaryStep = 0.1
+
+// the contact_report state of an open door/window
+const ContactStateOpen = "no_contact"
+
+// how long the lights of a schedule with occupancy sensors stay on after the last motion
+const DefaultOccupancyTimeout = 10 * time.Minute
+
+// how long lights take to fade off when a room becomes vacant
+const VacancyTransition = 30 * time.Second
+
+// how far the light level has to move, as a fraction of the target, before daylight dimming follows it
+const DefaultDaylightHysteresis = 0.1
+
+// how long to wait after a light, room, zone or scene changes before rediscovering, so a burst of changes (e.g.
+// a new bulb joining a room) is picked up in one go
+const RediscoveryDelay = time.Second
+
+// how often the power-on behaviour of a light is updated to follow its target, it is stored in the bulb so isn't
+// written with every update
+const DefaultPowerupInterval = 15 * time.Minute
+
+// when the lighting day starts, steps at earlier times belonging to the day before
+const DefaultDayBoundary = "00:00"
+
+// how often a calendar is read again to pick up changes
+const DefaultCalendarRefresh = time.Hour
 
 const HughUpdateWindow = 2 * time.Second
 const OverrideToleranceBrightness = 1
@@ -0,0 +1,182 @@
+package hue
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const mdnsService = "_hue._tcp.local."
+
+var mdnsAddr = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: 5353}
+
+// a bridge found on the local network
+type DiscoveredBridge struct {
+	IP        string
+	ID        string `json:"bridgeid"`
+	Name      string `json:"name"`
+	ModelID   string `json:"modelid"`
+	Version   string `json:"apiversion"`
+	SWVersion string `json:"swversion"`
+}
+
+// DiscoverBridges browses the LAN for _hue._tcp services and confirms each candidate
+// by probing its unauthenticated /api/config endpoint
+func DiscoverBridges(ctx context.Context, timeout time.Duration) ([]DiscoveredBridge, error) {
+	ips, err := browseMDNS(ctx, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	bridges := []DiscoveredBridge{}
+	for _, ip := range ips {
+		bridge, err := ProbeBridge(ctx, ip)
+		if err != nil {
+			// something answered for _hue._tcp but isn't a bridge we can talk to
+			continue
+		}
+		bridges = append(bridges, bridge)
+	}
+
+	return bridges, nil
+}
+
+// ProbeBridge reads the public config of the bridge at the given ip
+func ProbeBridge(ctx context.Context, ip string) (DiscoveredBridge, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("https://%s/api/config", ip), nil)
+	if err != nil {
+		return DiscoveredBridge{}, err
+	}
+
+	resp, err := pairingClient().Do(req)
+	if err != nil {
+		return DiscoveredBridge{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return DiscoveredBridge{}, fmt.Errorf("unexpected status probing %s: %s", ip, resp.Status)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return DiscoveredBridge{}, err
+	}
+
+	bridge := DiscoveredBridge{}
+	if err := json.Unmarshal(body, &bridge); err != nil {
+		return DiscoveredBridge{}, fmt.Errorf("error parsing config from %s: %w", ip, err)
+	}
+	if bridge.ID == "" {
+		return DiscoveredBridge{}, fmt.Errorf("%s did not report a bridge id", ip)
+	}
+	bridge.IP = ip
+
+	return bridge, nil
+}
+
+// sends a single mDNS PTR query and collects the addresses of everything that answers before the timeout
+func browseMDNS(ctx context.Context, timeout time.Duration) ([]string, error) {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4zero, Port: 0})
+	if err != nil {
+		return nil, fmt.Errorf("error opening mDNS socket: %w", err)
+	}
+	defer conn.Close()
+
+	query, err := mdnsQuery()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.WriteToUDP(query, mdnsAddr); err != nil {
+		return nil, fmt.Errorf("error sending mDNS query: %w", err)
+	}
+
+	deadline := time.Now().Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	_ = conn.SetReadDeadline(deadline)
+
+	seen := map[string]bool{}
+	ips := []string{}
+	buf := make([]byte, 9000)
+	for {
+		n, from, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			// read deadline reached
+			break
+		}
+		for _, ip := range hueAddressesFromResponse(buf[:n], from) {
+			if !seen[ip] {
+				seen[ip] = true
+				ips = append(ips, ip)
+			}
+		}
+	}
+
+	return ips, nil
+}
+
+func mdnsQuery() ([]byte, error) {
+	name, err := dnsmessage.NewName(mdnsService)
+	if err != nil {
+		return nil, err
+	}
+	msg := dnsmessage.Message{
+		Questions: []dnsmessage.Question{{
+			Name: name,
+			Type: dnsmessage.TypePTR,
+			// top bit requests a unicast response, so replies come straight back to our socket
+			Class: dnsmessage.ClassINET | 1<<15,
+		}},
+	}
+	return msg.Pack()
+}
+
+// returns the A records from an mDNS response that answers for the hue service,
+// falling back to the sender address when the responder omits them
+func hueAddressesFromResponse(packet []byte, from *net.UDPAddr) []string {
+	var msg dnsmessage.Message
+	if err := msg.Unpack(packet); err != nil || !msg.Header.Response {
+		return nil
+	}
+
+	isHue := false
+	ips := []string{}
+	for _, rr := range append(msg.Answers, msg.Additionals...) {
+		switch body := rr.Body.(type) {
+		case *dnsmessage.PTRResource:
+			if rr.Header.Name.String() == mdnsService {
+				isHue = true
+			}
+		case *dnsmessage.AResource:
+			ips = append(ips, net.IP(body.A[:]).String())
+		}
+	}
+
+	if !isHue {
+		return nil
+	}
+	if len(ips) == 0 && from != nil {
+		ips = append(ips, from.IP.String())
+	}
+	return ips
+}
+
+// bridges present a certificate for their own id rather than their ip, so discovery and
+// pairing (which happen before we know the id) can't verify it
+func pairingClient() *http.Client {
+	return &http.Client{
+		Timeout: 5 * time.Second,
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		},
+	}
+}
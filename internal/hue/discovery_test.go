@@ -0,0 +1,101 @@
+package hue
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// builds an mDNS response advertising the service on the instance, with an A record for each ip
+func mdnsResponse(t *testing.T, service string, instance string, ips ...net.IP) []byte {
+	header := func(name string, rtype dnsmessage.Type) dnsmessage.ResourceHeader {
+		return dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName(name), Type: rtype, Class: dnsmessage.ClassINET, TTL: 120}
+	}
+	host := strings.ReplaceAll(instance, " ", "-") + ".local."
+	instanceName := instance + "." + service
+
+	msg := dnsmessage.Message{
+		Header: dnsmessage.Header{Response: true, Authoritative: true},
+		Answers: []dnsmessage.Resource{
+			{Header: header(service, dnsmessage.TypePTR), Body: &dnsmessage.PTRResource{PTR: dnsmessage.MustNewName(instanceName)}},
+		},
+		Additionals: []dnsmessage.Resource{
+			{Header: header(instanceName, dnsmessage.TypeSRV), Body: &dnsmessage.SRVResource{Port: 443, Target: dnsmessage.MustNewName(host)}},
+			{Header: header(instanceName, dnsmessage.TypeTXT), Body: &dnsmessage.TXTResource{TXT: []string{"bridgeid=001788fffe6a2b3c", "modelid=BSB002"}}},
+		},
+	}
+	for _, ip := range ips {
+		var a [4]byte
+		copy(a[:], ip.To4())
+		msg.Additionals = append(msg.Additionals, dnsmessage.Resource{Header: header(host, dnsmessage.TypeA), Body: &dnsmessage.AResource{A: a}})
+	}
+
+	packet, err := msg.Pack()
+	require.NoError(t, err)
+	return packet
+}
+
+func Test_hueAddressesFromResponse(t *testing.T) {
+	from := &net.UDPAddr{IP: net.IPv4(192, 168, 1, 20), Port: 5353}
+
+	t.Run("should return the addresses of the hue bridge", func(t *testing.T) {
+		packet := mdnsResponse(t, mdnsService, "Hue Bridge - 6A2B3C", net.IPv4(192, 168, 1, 10))
+
+		assert.Equal(t, []string{"192.168.1.10"}, hueAddressesFromResponse(packet, from))
+	})
+
+	t.Run("should fall back to the sender when the response has no address", func(t *testing.T) {
+		packet := mdnsResponse(t, mdnsService, "Hue Bridge - 6A2B3C")
+
+		assert.Equal(t, []string{"192.168.1.20"}, hueAddressesFromResponse(packet, from))
+	})
+
+	t.Run("should ignore other services", func(t *testing.T) {
+		packet := mdnsResponse(t, "_googlecast._tcp.local.", "Kitchen speaker", net.IPv4(192, 168, 1, 30))
+
+		assert.Empty(t, hueAddressesFromResponse(packet, from))
+	})
+
+	t.Run("should ignore queries and packets that aren't dns", func(t *testing.T) {
+		query, err := mdnsQuery()
+		require.NoError(t, err)
+
+		assert.Empty(t, hueAddressesFromResponse(query, from))
+		assert.Empty(t, hueAddressesFromResponse([]byte("not dns"), from))
+	})
+}
+
+func Test_ProbeBridge(t *testing.T) {
+
+	t.Run("should read the bridge's public config", func(t *testing.T) {
+		server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/api/config", r.URL.Path)
+			_, _ = w.Write([]byte(`{"name":"Hue Bridge","bridgeid":"001788FFFE6A2B3C","modelid":"BSB002","apiversion":"1.60.0","swversion":"1960135040"}`))
+		}))
+		defer server.Close()
+		ip := strings.TrimPrefix(server.URL, "https://")
+
+		bridge, err := ProbeBridge(context.Background(), ip)
+
+		require.NoError(t, err)
+		assert.Equal(t, DiscoveredBridge{IP: ip, ID: "001788FFFE6A2B3C", Name: "Hue Bridge", ModelID: "BSB002", Version: "1.60.0", SWVersion: "1960135040"}, bridge)
+	})
+
+	t.Run("should reject something that isn't a bridge", func(t *testing.T) {
+		server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"name":"Printer"}`))
+		}))
+		defer server.Close()
+
+		_, err := ProbeBridge(context.Background(), strings.TrimPrefix(server.URL, "https://"))
+
+		assert.ErrorContains(t, err, "did not report a bridge id")
+	})
+}
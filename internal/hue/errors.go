@@ -0,0 +1,151 @@
+package hue
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+type ErrorKind int
+
+const (
+	ErrorKindUnknown ErrorKind = iota
+	// the bridge accepted the command but the device didn't respond
+	ErrorKindUnreachable
+	// the application key is missing or has been revoked
+	ErrorKindUnauthorised
+	// too many commands have been sent to the bridge
+	ErrorKindRateLimited
+	// the resource doesn't exist (e.g. the light was removed from the bridge)
+	ErrorKindNotFound
+	// the bridge is temporarily unable to handle the request
+	ErrorKindBridgeBusy
+	// the bridge rejected the request body
+	ErrorKindValidation
+)
+
+func (k ErrorKind) String() string {
+	switch k {
+	case ErrorKindUnreachable:
+		return "unreachable"
+	case ErrorKindUnauthorised:
+		return "unauthorised"
+	case ErrorKindRateLimited:
+		return "rate limited"
+	case ErrorKindNotFound:
+		return "not found"
+	case ErrorKindBridgeBusy:
+		return "bridge busy"
+	case ErrorKindValidation:
+		return "validation"
+	default:
+		return "unknown"
+	}
+}
+
+// an error reported by the CLIP v2 API, either through the status code or the "errors" array of the response
+type APIError struct {
+	Kind         ErrorKind
+	StatusCode   int
+	Method       string
+	URL          string
+	Descriptions []string
+}
+
+func (e *APIError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "hue api error (%s)", e.Kind)
+	if e.StatusCode != 0 {
+		fmt.Fprintf(&b, ": %s %s returned %d", e.Method, e.URL, e.StatusCode)
+	}
+	if len(e.Descriptions) > 0 {
+		fmt.Fprintf(&b, ": %s", strings.Join(e.Descriptions, "; "))
+	}
+	if e.Kind == ErrorKindUnauthorised {
+		b.WriteString(" (run `hugh pair` to pair again)")
+	}
+	return b.String()
+}
+
+// errors of the same kind match, so callers can use errors.Is(err, hue.ErrUnreachable)
+func (e *APIError) Is(target error) bool {
+	t, ok := target.(*APIError)
+	return ok && t.Kind == e.Kind
+}
+
+var (
+	ErrUnreachable  = &APIError{Kind: ErrorKindUnreachable}
+	ErrUnauthorised = &APIError{Kind: ErrorKindUnauthorised}
+	ErrRateLimited  = &APIError{Kind: ErrorKindRateLimited}
+	ErrNotFound     = &APIError{Kind: ErrorKindNotFound}
+	ErrBridgeBusy   = &APIError{Kind: ErrorKindBridgeBusy}
+	ErrValidation   = &APIError{Kind: ErrorKindValidation}
+)
+
+// whether the bridge is asking us to slow down, used by the command scheduler to back off
+func ShouldBackOff(err error) bool {
+	return errors.Is(err, ErrRateLimited) || errors.Is(err, ErrBridgeBusy)
+}
+
+// the part of every CLIP v2 response that reports problems
+type errorsResponse struct {
+	Errors []HueError `json:"errors"`
+}
+
+// checks the status code and the errors array of a response, returning nil if the request fully succeeded
+func parseResponseError(method string, url string, statusCode int, body []byte) error {
+	resp := errorsResponse{}
+	// error bodies aren't always json (e.g. from the bridge's web server), in which case we rely on the status
+	_ = json.Unmarshal(body, &resp)
+
+	descriptions := []string{}
+	for _, e := range resp.Errors {
+		descriptions = append(descriptions, e.Description)
+	}
+
+	kind := ErrorKindUnknown
+	switch {
+	case statusCode == http.StatusOK && len(descriptions) == 0:
+		return nil
+	case statusCode == http.StatusOK || statusCode == http.StatusMultiStatus:
+		// partial success, the errors array describes the commands that didn't take effect
+		kind = kindFromDescriptions(descriptions)
+	case statusCode == http.StatusBadRequest:
+		kind = ErrorKindValidation
+	case statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden:
+		kind = ErrorKindUnauthorised
+	case statusCode == http.StatusNotFound:
+		kind = ErrorKindNotFound
+	case statusCode == http.StatusTooManyRequests:
+		kind = ErrorKindRateLimited
+	case statusCode == http.StatusServiceUnavailable || statusCode == http.StatusInsufficientStorage:
+		kind = ErrorKindBridgeBusy
+	}
+
+	return &APIError{
+		Kind:         kind,
+		StatusCode:   statusCode,
+		Method:       method,
+		URL:          url,
+		Descriptions: descriptions,
+	}
+}
+
+func kindFromDescriptions(descriptions []string) ErrorKind {
+	for _, d := range descriptions {
+		d = strings.ToLower(d)
+		switch {
+		case strings.Contains(d, "communication issues"), strings.Contains(d, "unreachable"):
+			return ErrorKindUnreachable
+		case strings.Contains(d, "not found"):
+			return ErrorKindNotFound
+		}
+	}
+	if len(descriptions) > 0 {
+		return ErrorKindValidation
+	}
+	// a 207 without any explanation, historically this has meant the light is powered down
+	return ErrorKindUnreachable
+}
@@ -0,0 +1,85 @@
+package hue
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseResponseError(t *testing.T) {
+
+	tests := []struct {
+		name       string
+		statusCode int
+		body       string
+		expected   error
+	}{
+		{
+			name:       "success",
+			statusCode: 200,
+			body:       `{"errors":[],"data":[{"rid":"123","rtype":"light"}]}`,
+			expected:   nil,
+		},
+		{
+			name:       "multi-status with communication issue",
+			statusCode: 207,
+			body:       `{"errors":[{"description":"device (light) has communication issues, command (.on.on) may not have effect"}],"data":[{"rid":"123","rtype":"light"}]}`,
+			expected:   ErrUnreachable,
+		},
+		{
+			name:       "multi-status without errors",
+			statusCode: 207,
+			body:       `{"errors":[],"data":[]}`,
+			expected:   ErrUnreachable,
+		},
+		{
+			name:       "bad request",
+			statusCode: 400,
+			body:       `{"errors":[{"description":"invalid value, 900, for property dimming.brightness"}],"data":[]}`,
+			expected:   ErrValidation,
+		},
+		{
+			name:       "forbidden",
+			statusCode: 403,
+			body:       `{"errors":[{"description":"unauthorized user"}],"data":[]}`,
+			expected:   ErrUnauthorised,
+		},
+		{
+			name:       "not found",
+			statusCode: 404,
+			body:       `{"errors":[{"description":"Not Found"}],"data":[]}`,
+			expected:   ErrNotFound,
+		},
+		{
+			name:       "rate limited, non json body",
+			statusCode: 429,
+			body:       `Too Many Requests`,
+			expected:   ErrRateLimited,
+		},
+		{
+			name:       "busy",
+			statusCode: 503,
+			body:       ``,
+			expected:   ErrBridgeBusy,
+		},
+	}
+
+	for _, c := range tests {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+			err := parseResponseError("PUT", "/clip/v2/resource/light/123", c.statusCode, []byte(c.body))
+			if c.expected == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, c.expected)
+
+			var apiErr *APIError
+			assert.True(t, errors.As(err, &apiErr))
+			assert.Equal(t, c.statusCode, apiErr.StatusCode)
+		})
+	}
+
+}
@@ -2,51 +2,115 @@ package hue
 
 import (
 	"bytes"
-	"crypto/tls"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
+	"sync"
 
 	"github.com/charmbracelet/log"
+	sse "github.com/r3labs/sse/v2"
 	"github.com/samber/lo"
-	"github.com/spf13/viper"
+	"github.com/wheelibin/hugh/internal/colour"
+	"github.com/wheelibin/hugh/internal/constants"
 	"github.com/wheelibin/hugh/internal/models"
 )
 
 type HueAPIService struct {
-	logger *log.Logger
+	logger    *log.Logger
+	bridge    models.Bridge
+	transport http.RoundTripper
+	client    *http.Client
+
+	// the bridge's resources, loaded on first use and kept up to date by ApplyEvent
+	cache *ResourceCache
+
+	// the gamut of each colour light read by GetLight, so targets can be mapped into what the light can show
+	gamutsMu sync.Mutex
+	gamuts   map[string]*colour.Gamut
 }
 
-func NewHueAPIService(logger *log.Logger) *HueAPIService {
-	return &HueAPIService{logger}
+func NewHueAPIService(logger *log.Logger, bridge models.Bridge, transport http.RoundTripper) *HueAPIService {
+	return &HueAPIService{
+		logger:    logger,
+		bridge:    bridge,
+		transport: transport,
+		client:    &http.Client{Transport: transport},
+		cache:     NewResourceCache(),
+		gamuts:    map[string]*colour.Gamut{},
+	}
+}
+
+// NewEventStreamClient returns an unconnected client for the bridge's event stream, sharing the api transport
+func (h *HueAPIService) NewEventStreamClient() *sse.Client {
+	client := sse.NewClient(fmt.Sprintf("https://%s/eventstream/clip/v2", h.bridge.IP))
+	client.Connection.Transport = h.transport
+	client.Headers["hue-application-key"] = h.bridge.ApplicationKey
+	return client
 }
 
 func (h *HueAPIService) GET(url string) ([]byte, error) {
-	return h.makeRequest("GET", url, nil)
+	return h.makeRequest(context.Background(), "GET", url, nil)
 }
 
-func (h *HueAPIService) PUT(url string, body []byte) ([]byte, error) {
-	return h.makeRequest("PUT", url, body)
+// PUT sends the body to the bridge, giving up when the context is cancelled
+func (h *HueAPIService) PUT(ctx context.Context, url string, body []byte) ([]byte, error) {
+	return h.makeRequest(ctx, "PUT", url, body)
 }
 
-func (h *HueAPIService) GetRooms() ([]models.HughGroup, error) {
+// LoadResources reads the lights, devices, rooms, zones and scenes from the bridge into the resource cache,
+// replacing anything already cached
+func (h *HueAPIService) LoadResources() error {
+	resources := map[string][]resource{}
+	for _, rtype := range cachedResourceTypes {
+		body, err := h.GET(fmt.Sprintf("/clip/v2/resource/%s", rtype))
+		if err != nil {
+			return fmt.Errorf("error reading %s resources from hue bridge: %w", rtype, err)
+		}
+		respBody := struct {
+			Data []resource `json:"data"`
+		}{}
+		if err := json.Unmarshal(body, &respBody); err != nil {
+			return fmt.Errorf("error parsing %s response: %w", rtype, err)
+		}
+		resources[rtype] = respBody.Data
+	}
 
-	body, err := h.GET("/clip/v2/resource/room")
-	if err != nil {
-		return nil, fmt.Errorf("error reading rooms from hue bridge: %w", err)
+	h.cache.Load(resources)
+	return nil
+}
+
+// Resources returns the resource cache, loading it if it hasn't been yet
+func (h *HueAPIService) Resources() (*ResourceCache, error) {
+	if !h.cache.Loaded() {
+		if err := h.LoadResources(); err != nil {
+			return nil, err
+		}
 	}
+	return h.cache, nil
+}
 
-	respBody := GroupResponse{}
-	if err := json.Unmarshal(body, &respBody); err != nil {
-		return nil, fmt.Errorf("error parsing room response: %w", err)
+// ApplyEvent keeps the resource cache up to date with a batch of events from the event stream
+func (h *HueAPIService) ApplyEvent(event *sse.Event) {
+	if err := h.cache.ApplyEvent(event); err != nil {
+		h.logger.Error("error applying event to the resource cache", "err", err)
+	}
+}
+
+func (h *HueAPIService) GetRooms() ([]models.HughGroup, error) {
+	resources, err := h.Resources()
+	if err != nil {
+		return nil, err
 	}
 
-	hughGroups := lo.Map(respBody.Data, func(room HueDeviceGroup, _ int) models.HughGroup {
+	hughGroups := lo.Map(resources.Rooms(), func(room HueDeviceGroup, _ int) models.HughGroup {
 		return models.HughGroup{
-			Name:      room.Metadata.Name,
-			DeviceIds: lo.Map(room.Children, func(c HueDeviceService, _ int) string { return c.RID }),
+			Name:                  room.Metadata.Name,
+			DeviceIds:             lo.Map(room.Children, func(c HueDeviceService, _ int) string { return c.RID }),
+			GroupedLightServiceId: groupedLightServiceId(room),
 		}
 	})
 
@@ -54,37 +118,40 @@ func (h *HueAPIService) GetRooms() ([]models.HughGroup, error) {
 }
 
 func (h *HueAPIService) GetZones() ([]models.HughGroup, error) {
-
-	body, err := h.GET("/clip/v2/resource/zone")
+	resources, err := h.Resources()
 	if err != nil {
-		return nil, fmt.Errorf("error reading zones from hue bridge: %w", err)
-	}
-
-	respBody := GroupResponse{}
-	if err := json.Unmarshal(body, &respBody); err != nil {
-		return nil, fmt.Errorf("error parsing zone response: %w", err)
+		return nil, err
 	}
 
-	hughGroups := lo.Map(respBody.Data, func(zone HueDeviceGroup, _ int) models.HughGroup {
+	hughGroups := lo.Map(resources.Zones(), func(zone HueDeviceGroup, _ int) models.HughGroup {
 		return models.HughGroup{
-			Name:            zone.Metadata.Name,
-			LightServiceIds: lo.Map(zone.Children, func(c HueDeviceService, _ int) string { return c.RID }),
+			Name:                  zone.Metadata.Name,
+			LightServiceIds:       lo.Map(zone.Children, func(c HueDeviceService, _ int) string { return c.RID }),
+			GroupedLightServiceId: groupedLightServiceId(zone),
 		}
 	})
 
 	return hughGroups, nil
 }
 
+// returns the id of the grouped_light service that controls all the lights in a room/zone
+func groupedLightServiceId(group HueDeviceGroup) string {
+	svc, _ := lo.Find(group.Services, func(s HueDeviceService) bool {
+		return s.RType == "grouped_light"
+	})
+	return svc.RID
+}
+
 func (h *HueAPIService) GetAllGroups() ([]models.HughGroup, error) {
 
 	rooms, err := h.GetRooms()
 	if err != nil {
-		h.logger.Error(err)
+		return nil, err
 	}
 
 	zones, err := h.GetZones()
 	if err != nil {
-		h.logger.Error(err)
+		return nil, err
 	}
 
 	var all []models.HughGroup
@@ -95,23 +162,18 @@ func (h *HueAPIService) GetAllGroups() ([]models.HughGroup, error) {
 }
 
 func (h *HueAPIService) GetScenes() ([]HueScene, error) {
-
-	body, err := h.GET("/clip/v2/resource/scene")
+	resources, err := h.Resources()
 	if err != nil {
 		return nil, err
 	}
-
-	respBody := SceneResponse{}
-	if err := json.Unmarshal(body, &respBody); err != nil {
-		h.logger.Error(err)
-		return nil, err
-	}
-
-	return respBody.Data, nil
+	return resources.Scenes(), nil
 }
 
 func (h *HueAPIService) DiscoverLights(schedules []models.Schedule) ([]models.HughLight, error) {
-	allGroups, _ := h.GetAllGroups()
+	allGroups, err := h.GetAllGroups()
+	if err != nil {
+		return nil, err
+	}
 
 	lights := []models.HughLight{}
 
@@ -130,8 +192,10 @@ func (h *HueAPIService) DiscoverLights(schedules []models.Schedule) ([]models.Hu
 
 					// tag the light with the schedule
 					grpLight.ScheduleName = schedule.Name
-					grpLight.AutoOnFrom = schedule.AutoOn.From
-					grpLight.AutoOnTo = schedule.AutoOn.To
+					if schedule.AutoOn != nil {
+						grpLight.AutoOnFrom = schedule.AutoOn.From
+						grpLight.AutoOnTo = schedule.AutoOn.To
+					}
 					grpLight.GroupName = groupName
 
 					// add it
@@ -149,41 +213,131 @@ func (h *HueAPIService) DiscoverLights(schedules []models.Schedule) ([]models.Hu
 	return uniqueLights, nil
 }
 
-func (h *HueAPIService) GetLightsForGroup(group models.HughGroup) ([]models.HughLight, error) {
+// DiscoverGroups returns the rooms/zones used by the schedules, with the light service ids of every light in each
+func (h *HueAPIService) DiscoverGroups(schedules []models.Schedule) ([]models.HughGroup, error) {
+	allGroups, err := h.GetAllGroups()
+	if err != nil {
+		return nil, err
+	}
 
-	var lightServiceIds []string
+	groups := []models.HughGroup{}
 
-	if len(group.LightServiceIds) > 0 {
-		// zones only contain light service ids
-		lightServiceIds = append(lightServiceIds, group.LightServiceIds...)
-	}
+	for _, schedule := range schedules {
 
-	if len(group.DeviceIds) > 0 {
-		// rooms have device ids
-		for _, deviceId := range group.DeviceIds {
-			// read the device
-			body, err := h.GET(fmt.Sprintf("/clip/v2/resource/device/%s", deviceId))
-			if err != nil {
-				h.logger.Warnf("Unable to read device with id %s", deviceId)
+		scheduleGroupNames := []string{}
+		scheduleGroupNames = append(scheduleGroupNames, schedule.Rooms...)
+		scheduleGroupNames = append(scheduleGroupNames, schedule.Zones...)
+
+		for _, groupName := range scheduleGroupNames {
+			grp, found := lo.Find(allGroups, func(group models.HughGroup) bool { return group.Name == groupName })
+			if found && grp.GroupedLightServiceId != "" {
+				groups = append(groups, models.HughGroup{
+					Name:                  grp.Name,
+					LightServiceIds:       h.GetLightServiceIdsForGroup(grp),
+					GroupedLightServiceId: grp.GroupedLightServiceId,
+				})
 			}
+		}
+	}
+
+	// de-dupe
+	uniqueGroups := lo.UniqBy(groups, func(g models.HughGroup) string {
+		return g.GroupedLightServiceId
+	})
+
+	return uniqueGroups, nil
+}
+
+// DiscoverSensors returns the sensor services of the devices the schedules name, the motion and contact services
+// for occupancy and the light_level service for daylight dimming
+func (h *HueAPIService) DiscoverSensors(schedules []models.Schedule) ([]models.HughSensor, error) {
+	resources, err := h.Resources()
+	if err != nil {
+		return nil, err
+	}
+	devices := resources.Devices()
 
-			respBody := DevicesResponse{}
-			if err := json.Unmarshal(body, &respBody); err != nil {
-				h.logger.Error(err)
+	sensors := []models.HughSensor{}
+
+	// adds the services of the named device with one of the types
+	addSensor := func(schedule models.Schedule, sensorName string, rtypes ...string) {
+		device, found := lo.Find(devices, func(d HueDevice) bool { return d.Metadata.Name == sensorName })
+		if !found {
+			h.logger.Warn("Unable to find sensor", "name", sensorName, "schedule", schedule.Name)
+			return
+		}
+
+		services := lo.Filter(device.Services, func(s HueDeviceService, _ int) bool { return lo.Contains(rtypes, s.RType) })
+		if len(services) == 0 {
+			h.logger.Warn("Sensor has no suitable service", "name", sensorName, "schedule", schedule.Name, "types", rtypes)
+		}
+		for _, s := range services {
+			sensors = append(sensors, models.HughSensor{ID: s.RID, Type: s.RType, ScheduleName: schedule.Name})
+		}
+	}
+
+	for _, schedule := range schedules {
+		if schedule.Occupancy != nil {
+			for _, sensorName := range schedule.Occupancy.Sensors {
+				addSensor(schedule, sensorName, constants.EventTypeMotion, constants.EventTypeContact)
 			}
+		}
+		if schedule.Daylight != nil {
+			addSensor(schedule, schedule.Daylight.Sensor, constants.EventTypeLightLevel)
+		}
+	}
 
-			// get the light service id
-			device := respBody.Data[0]
-			svcLight, isLight := lo.Find(device.Services, func(service HueDeviceService) bool {
-				return service.RType == "light"
-			})
+	return sensors, nil
+}
 
-			if isLight {
-				lightServiceIds = append(lightServiceIds, svcLight.RID)
+// DiscoverBindings returns the button and relative_rotary services of the switches and dials the bindings name
+func (h *HueAPIService) DiscoverBindings(bindings []models.Binding) ([]models.HughBinding, error) {
+	resources, err := h.Resources()
+	if err != nil {
+		return nil, err
+	}
+	devices := resources.Devices()
+
+	hughBindings := []models.HughBinding{}
+	for _, binding := range bindings {
+		device, found := lo.Find(devices, func(d HueDevice) bool { return d.Metadata.Name == binding.Device })
+		if !found {
+			h.logger.Warn("Unable to find switch", "name", binding.Device, "schedule", binding.Schedule)
+			continue
+		}
+
+		hughBinding := models.HughBinding{Action: binding.Action, ScheduleName: binding.Schedule, Duration: binding.Duration}
+
+		if binding.Action == constants.ActionBrightness {
+			dial, found := lo.Find(device.Services, func(s HueDeviceService) bool { return s.RType == constants.EventTypeRelativeRotary })
+			if !found {
+				h.logger.Warn("Switch has no dial", "name", binding.Device, "schedule", binding.Schedule)
+				continue
+			}
+			hughBinding.ID = dial.RID
+		} else {
+			buttons := lo.Filter(device.Services, func(s HueDeviceService, _ int) bool { return s.RType == constants.EventTypeButton })
+			if binding.Button < 1 || binding.Button > len(buttons) {
+				h.logger.Warn("Switch has no such button", "name", binding.Device, "button", binding.Button, "schedule", binding.Schedule)
+				continue
+			}
+			hughBinding.ID = buttons[binding.Button-1].RID
+			hughBinding.Event = binding.Event
+			if hughBinding.Event == "" {
+				hughBinding.Event = constants.ButtonEventShortRelease
 			}
 		}
+
+		hughBindings = append(hughBindings, hughBinding)
 	}
 
+	return hughBindings, nil
+}
+
+func (h *HueAPIService) GetLightsForGroup(group models.HughGroup) ([]models.HughLight, error) {
+
+	lightServiceIds := h.GetLightServiceIdsForGroup(group)
+
 	groupLights := lo.FilterMap(lightServiceIds, func(lightServiceId string, _ int) (models.HughLight, bool) {
 
 		// get the light
@@ -201,37 +355,68 @@ func (h *HueAPIService) GetLightsForGroup(group models.HughGroup) ([]models.Hugh
 
 }
 
-func (h *HueAPIService) GetLight(id string) (models.HughLight, error) {
+func (h *HueAPIService) GetLightServiceIdsForGroup(group models.HughGroup) []string {
 
-	// get the light
-	body, err := h.GET(fmt.Sprintf("/clip/v2/resource/light/%s", id))
-	if err != nil {
-		return models.HughLight{}, err
+	var lightServiceIds []string
+
+	if len(group.LightServiceIds) > 0 {
+		// zones only contain light service ids
+		lightServiceIds = append(lightServiceIds, group.LightServiceIds...)
 	}
-	lresp := LightResponse{}
-	if err := json.Unmarshal(body, &lresp); err != nil {
-		h.logger.Error(err)
-		return models.HughLight{}, err
+
+	if len(group.DeviceIds) > 0 {
+		// rooms have device ids
+		resources, err := h.Resources()
+		if err != nil {
+			h.logger.Error(err)
+			return lightServiceIds
+		}
+		for _, deviceId := range group.DeviceIds {
+			device, found := resources.Device(deviceId)
+			if !found {
+				h.logger.Warnf("Unable to find device with id %s", deviceId)
+				continue
+			}
+
+			// get the light service id
+			svcLight, isLight := lo.Find(device.Services, func(service HueDeviceService) bool {
+				return service.RType == "light"
+			})
+
+			if isLight {
+				lightServiceIds = append(lightServiceIds, svcLight.RID)
+			}
+		}
 	}
-	light := lresp.Data[0]
 
-	// get the device
-	body, err = h.GET(fmt.Sprintf("/clip/v2/resource/device/%s", light.Owner.DeviceID))
+	return lightServiceIds
+}
+
+func (h *HueAPIService) GetLight(id string) (models.HughLight, error) {
+	resources, err := h.Resources()
 	if err != nil {
 		return models.HughLight{}, err
 	}
-	dresp := DevicesResponse{}
-	if err := json.Unmarshal(body, &dresp); err != nil {
-		h.logger.Error(err)
-		return models.HughLight{}, err
+
+	light, found := resources.Light(id)
+	if !found {
+		return models.HughLight{}, fmt.Errorf("light (%s): %w", id, ErrNotFound)
+	}
+	device, found := resources.Device(light.Owner.DeviceID)
+	if !found {
+		return models.HughLight{}, fmt.Errorf("device (%s) of light (%s): %w", light.Owner.DeviceID, id, ErrNotFound)
 	}
-	device := dresp.Data[0]
 
 	// get zigbee service
 	zbService, _ := lo.Find(device.Services, func(s HueDeviceService) bool {
 		return s.RType == "zigbee_connectivity"
 	})
 
+	gamut := lightGamut(light)
+	h.gamutsMu.Lock()
+	h.gamuts[id] = gamut
+	h.gamutsMu.Unlock()
+
 	return models.HughLight{
 		Id:                       light.Id,
 		Name:                     light.Metadata.Name,
@@ -241,20 +426,77 @@ func (h *HueAPIService) GetLight(id string) (models.HughLight, error) {
 		On:                       light.On.On,
 		MinColorTemperatuerMirek: light.ColorTemperature.MirekBounds.Min,
 		MaxColorTemperatuerMirek: light.ColorTemperature.MirekBounds.Max,
+		Gamut:                    gamut,
 	}, nil
 
 }
 
-func (h *HueAPIService) UpdateLightState(lsID string, target models.LightState) error {
-	h.logger.Debug(lsID, "target", target)
-	var requestBody []byte
-	if target.On {
-		requestBody = []byte(fmt.Sprintf(`{ "dimming": { "brightness":%v }, "color_temperature": { "mirek": %v }, "on": { "on": true } }`, target.Brightness, target.TemperatureMirek))
-	} else {
-		requestBody = []byte(`{ "on": { "on": false } }`)
+// the colours a light can show, nil if it has no colour support
+func lightGamut(light HueLight) *colour.Gamut {
+	if light.Color == nil {
+		return nil
+	}
+	if light.Color.Gamut != nil {
+		return &colour.Gamut{
+			Red:   colour.XY{X: light.Color.Gamut.Red.X, Y: light.Color.Gamut.Red.Y},
+			Green: colour.XY{X: light.Color.Gamut.Green.X, Y: light.Color.Gamut.Green.Y},
+			Blue:  colour.XY{X: light.Color.Gamut.Blue.X, Y: light.Color.Gamut.Blue.Y},
+		}
 	}
+	gamut := colour.GamutForType(light.Color.GamutType)
+	return &gamut
+}
+
+// the gamut of a light read by GetLight, nil for lights without colour support (or that haven't been read)
+func (h *HueAPIService) gamut(lsID string) *colour.Gamut {
+	h.gamutsMu.Lock()
+	defer h.gamutsMu.Unlock()
+	return h.gamuts[lsID]
+}
 
-	body, err := h.PUT(fmt.Sprintf("/clip/v2/resource/light/%s", lsID), requestBody)
+// GetLightStatuses reads the current state of every light on the bridge
+func (h *HueAPIService) GetLightStatuses() ([]models.LightStatus, error) {
+
+	body, err := h.GET("/clip/v2/resource/light")
+	if err != nil {
+		return nil, fmt.Errorf("error reading lights from hue bridge: %w", err)
+	}
+	lresp := LightResponse{}
+	if err := json.Unmarshal(body, &lresp); err != nil {
+		return nil, fmt.Errorf("error parsing light response: %w", err)
+	}
+
+	body, err = h.GET("/clip/v2/resource/zigbee_connectivity")
+	if err != nil {
+		return nil, fmt.Errorf("error reading zigbee connectivity from hue bridge: %w", err)
+	}
+	zresp := ZigbeeConnectivityResponse{}
+	if err := json.Unmarshal(body, &zresp); err != nil {
+		return nil, fmt.Errorf("error parsing zigbee connectivity response: %w", err)
+	}
+	// lights and their connectivity are both services of the same device
+	connectivity := lo.KeyBy(zresp.Data, func(z HueZigbeeConnectivity) string { return z.Owner.DeviceID })
+
+	return lo.Map(lresp.Data, func(light HueLight, _ int) models.LightStatus {
+		zigbee := connectivity[light.Owner.DeviceID]
+		status := models.LightStatus{
+			LightServiceId:  light.Id,
+			ZigbeeServiceID: zigbee.Id,
+			On:              light.On.On,
+			Brightness:      int(math.Round(light.Dimming.Brightness)),
+			Reachable:       zigbee.Status != constants.EventStatusConnectivityIssue,
+		}
+		if light.ColorTemperature.Mirek != nil {
+			status.TemperatureMirek = *light.ColorTemperature.Mirek
+		}
+		return status
+	}), nil
+}
+
+func (h *HueAPIService) UpdateLightState(ctx context.Context, lsID string, target models.LightState) error {
+	h.logger.Debug(lsID, "target", target)
+
+	body, err := h.PUT(ctx, fmt.Sprintf("/clip/v2/resource/light/%s", lsID), lightStateRequestBody(target, h.gamut(lsID)))
 	if err != nil {
 		return err
 	}
@@ -268,24 +510,57 @@ func (h *HueAPIService) UpdateLightState(lsID string, target models.LightState)
 
 }
 
-func (h *HueAPIService) UpdateSceneState(ID string, target models.LightState) error {
+// UpdateLightPowerup sets what the light does when it is powered on (e.g. at a wall switch) to switch on at the target
+func (h *HueAPIService) UpdateLightPowerup(ctx context.Context, lsID string, target models.LightState) error {
+	h.logger.Debug(lsID, "powerup", target)
+
+	_, err := h.PUT(ctx, fmt.Sprintf("/clip/v2/resource/light/%s", lsID), lightPowerupRequestBody(target, h.gamut(lsID)))
+	return err
+}
+
+// UpdateGroupedLightState sends a single command to every light in a room/zone,
+// colours are sent as their nearest colour temperature as the lights in a group can have different gamuts
+func (h *HueAPIService) UpdateGroupedLightState(ctx context.Context, groupedLightID string, target models.LightState) error {
+	h.logger.Debug(groupedLightID, "target", target)
 
-	b, err := h.GET(fmt.Sprintf("/clip/v2/resource/scene/%s", ID))
+	_, err := h.PUT(ctx, fmt.Sprintf("/clip/v2/resource/grouped_light/%s", groupedLightID), lightStateRequestBody(target, nil))
 	if err != nil {
 		return err
 	}
 
-	respBody := SceneResponse{}
-	if err := json.Unmarshal(b, &respBody); err != nil {
+	return nil
+}
+
+func (h *HueAPIService) UpdateSceneState(ctx context.Context, ID string, target models.LightState) error {
+
+	resources, err := h.Resources()
+	if err != nil {
 		return err
 	}
-	scene := respBody.Data[0]
+	scene, found := resources.Scene(ID)
+	if !found {
+		return fmt.Errorf("scene (%s): %w", ID, ErrNotFound)
+	}
 
-	for _, a := range scene.Actions {
+	for i, a := range scene.Actions {
 		a.Action.On.On = target.On
 		if target.On {
 			a.Action.Dimming.Brightness = float64(target.Brightness)
-			a.Action.ColorTemperature.Mirek = target.TemperatureMirek
+			if gamut := h.gamut(a.Target.RID); target.Colour != nil && gamut != nil {
+				xy := gamut.Clamp(*target.Colour)
+				scene.Actions[i].Action.ColorTemperature = nil
+				scene.Actions[i].Action.Color = &struct {
+					XY HueXY `json:"xy"`
+				}{XY: HueXY{X: xy.X, Y: xy.Y}}
+			} else {
+				if a.Action.ColorTemperature == nil {
+					scene.Actions[i].Action.ColorTemperature = &struct {
+						Mirek int `json:"mirek"`
+					}{}
+				}
+				scene.Actions[i].Action.ColorTemperature.Mirek = target.TemperatureMirek
+				scene.Actions[i].Action.Color = nil
+			}
 		}
 	}
 
@@ -299,7 +574,7 @@ func (h *HueAPIService) UpdateSceneState(ID string, target models.LightState) er
 		return err
 	}
 
-	_, err = h.PUT(fmt.Sprintf("/clip/v2/resource/scene/%s", scene.Id), data)
+	_, err = h.PUT(ctx, fmt.Sprintf("/clip/v2/resource/scene/%s", scene.Id), data)
 	if err != nil {
 		h.logger.Error(err)
 		return err
@@ -309,40 +584,71 @@ func (h *HueAPIService) UpdateSceneState(ID string, target models.LightState) er
 
 }
 
-func (h *HueAPIService) makeRequest(verb string, url string, body []byte) ([]byte, error) {
+// builds the body of a light or grouped_light PUT for the target state,
+// a colour target is mapped into the gamut or, for lights without one, sent as the nearest colour temperature
+func lightStateRequestBody(target models.LightState, gamut *colour.Gamut) []byte {
+	dynamics := ""
+	if target.Transition > 0 {
+		dynamics = fmt.Sprintf(`, "dynamics": { "duration": %d }`, target.Transition.Milliseconds())
+	}
+	if target.On && target.Colour != nil && gamut != nil {
+		xy := gamut.Clamp(*target.Colour)
+		return []byte(fmt.Sprintf(`{ "dimming": { "brightness":%v }, "color": { "xy": { "x": %.4f, "y": %.4f } }, "on": { "on": true }%s }`, target.Brightness, xy.X, xy.Y, dynamics))
+	}
+	if target.On {
+		return []byte(fmt.Sprintf(`{ "dimming": { "brightness":%v }, "color_temperature": { "mirek": %v }, "on": { "on": true }%s }`, target.Brightness, target.TemperatureMirek, dynamics))
+	}
+	return []byte(fmt.Sprintf(`{ "on": { "on": false }%s }`, dynamics))
+}
+
+// builds the body of a light PUT setting its powerup to a custom preset of the target, on at the target's brightness
+// and colour (temperature)
+func lightPowerupRequestBody(target models.LightState, gamut *colour.Gamut) []byte {
+	colourMode := fmt.Sprintf(`"mode": "color_temperature", "color_temperature": { "mirek": %v }`, target.TemperatureMirek)
+	if target.Colour != nil && gamut != nil {
+		xy := gamut.Clamp(*target.Colour)
+		colourMode = fmt.Sprintf(`"mode": "color", "color": { "xy": { "x": %.4f, "y": %.4f } }`, xy.X, xy.Y)
+	}
+	return []byte(fmt.Sprintf(`{ "powerup": { "preset": "custom", "on": { "mode": "on", "on": { "on": true } }, "dimming": { "mode": "dimming", "dimming": { "brightness": %v } }, "color": { %s } } }`, target.Brightness, colourMode))
+}
+
+func (h *HueAPIService) makeRequest(ctx context.Context, verb string, url string, body []byte) ([]byte, error) {
 
 	bodyReader := bytes.NewReader(body)
-	req, err := http.NewRequest(verb, fmt.Sprintf("https://%s%s", viper.GetString("bridgeIp"), url), bodyReader)
+	req, err := http.NewRequestWithContext(ctx, verb, fmt.Sprintf("https://%s%s", h.bridge.IP, url), bodyReader)
 	if err != nil {
 		return nil, err
 	}
 
 	// set headers
-	req.Header.Set("hue-application-key", viper.GetString("hueApplicationKey"))
-	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-	}
-	client := &http.Client{Transport: tr}
+	req.Header.Set("hue-application-key", h.bridge.ApplicationKey)
 
 	// make the request
-	resp, err := client.Do(req)
+	resp, err := h.client.Do(req)
+	if err != nil {
+		if ctx.Err() == nil {
+			h.logger.Error(err)
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	responseBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		h.logger.Error(err)
 		return nil, err
 	}
 
-	switch resp.StatusCode {
-	case 200:
-		// all good
-		responseBody, _ := io.ReadAll(resp.Body)
+	err = parseResponseError(verb, url, resp.StatusCode, responseBody)
+	switch {
+	case err == nil:
 		return responseBody, nil
-	case 207:
-		// not sure under what circumstances this gets returned
-		// it seems like the light can be powered down and the bridge still responds with a 200
-		return nil, errors.New("unreachable")
+	case errors.Is(err, ErrUnauthorised):
+		h.logger.Error("The Hue bridge rejected hugh's application key, run `hugh pair` to pair again", "url", url, "status", resp.Status)
+	case errors.Is(err, ErrUnreachable), errors.Is(err, ErrRateLimited):
+		// expected from time to time, left to the caller to handle
 	default:
-		h.logger.Error("Error making Hue API call", "url", url, "status", resp.Status)
-		return nil, err
+		h.logger.Error("Error making Hue API call", "url", url, "status", resp.Status, "err", err)
 	}
 
+	return nil, err
 }
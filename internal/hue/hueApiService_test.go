@@ -0,0 +1,380 @@
+package hue
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wheelibin/hugh/internal/colour"
+	"github.com/wheelibin/hugh/internal/huetest"
+	"github.com/wheelibin/hugh/internal/models"
+)
+
+func newTestService(t *testing.T, bridge *huetest.Bridge) *HueAPIService {
+	config := bridge.Config("test")
+	transport, err := NewBridgeTransport(TLSOptions{BridgeID: config.ID, RootCAs: bridge.RootCAs()})
+	require.NoError(t, err)
+	return NewHueAPIService(log.New(io.Discard), config, transport)
+}
+
+func Test_DiscoverLights(t *testing.T) {
+	bridge := huetest.NewBridge(t)
+	kitchen1 := bridge.AddLight("Kitchen 1")
+	kitchen2 := bridge.AddLight("Kitchen 2")
+	hall := bridge.AddLight("Hall")
+	bridge.AddLight("Bedroom")
+	bridge.AddRoom("Kitchen", kitchen1, kitchen2)
+	bridge.AddZone("Downstairs", kitchen2, hall)
+
+	service := newTestService(t, bridge)
+
+	lights, err := service.DiscoverLights([]models.Schedule{
+		{Name: "Kitchen", Rooms: []string{"Kitchen"}},
+		{Name: "Downstairs", Zones: []string{"Downstairs"}},
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Kitchen 1", "Kitchen 2", "Hall"}, []string{lights[0].Name, lights[1].Name, lights[2].Name})
+	assert.Equal(t, kitchen1.ID, lights[0].LightServiceId)
+	assert.Equal(t, kitchen1.ZigbeeID, lights[0].ZigbeeServiceID)
+	assert.Equal(t, kitchen1.DeviceID, lights[0].DeviceID)
+	assert.Equal(t, "Kitchen", lights[1].ScheduleName, "a light in more than one schedule belongs to the first")
+	assert.Equal(t, "Downstairs", lights[2].ScheduleName)
+	assert.Equal(t, 153, lights[0].MinColorTemperatuerMirek)
+	assert.Equal(t, 454, lights[0].MaxColorTemperatuerMirek)
+}
+
+func Test_DiscoverGroups(t *testing.T) {
+	bridge := huetest.NewBridge(t)
+	kitchen1 := bridge.AddLight("Kitchen 1")
+	kitchen2 := bridge.AddLight("Kitchen 2")
+	hall := bridge.AddLight("Hall")
+	room := bridge.AddRoom("Kitchen", kitchen1, kitchen2)
+	zone := bridge.AddZone("Downstairs", kitchen2, hall)
+
+	service := newTestService(t, bridge)
+
+	groups, err := service.DiscoverGroups([]models.Schedule{{Name: "All", Rooms: []string{"Kitchen"}, Zones: []string{"Downstairs"}}})
+
+	require.NoError(t, err)
+	require.Len(t, groups, 2)
+	assert.Equal(t, room.GroupedLightID, groups[0].GroupedLightServiceId)
+	assert.Equal(t, []string{kitchen1.ID, kitchen2.ID}, groups[0].LightServiceIds)
+	assert.Equal(t, zone.GroupedLightID, groups[1].GroupedLightServiceId)
+	assert.Equal(t, []string{kitchen2.ID, hall.ID}, groups[1].LightServiceIds)
+}
+
+func Test_DiscoverSensors(t *testing.T) {
+	bridge := huetest.NewBridge(t)
+	motion := bridge.AddMotionSensor("Hall sensor")
+	contact := bridge.AddContactSensor("Front door")
+	bridge.AddMotionSensor("Landing sensor")
+
+	service := newTestService(t, bridge)
+
+	sensors, err := service.DiscoverSensors([]models.Schedule{
+		{
+			Name:      "Hall",
+			Occupancy: &models.ScheduleOccupancy{Sensors: []string{"Hall sensor", "Front door", "Missing"}},
+			Daylight:  &models.ScheduleDaylight{Sensor: "Hall sensor"},
+		},
+		{Name: "Landing"},
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, []models.HughSensor{
+		{ID: motion.ID, Type: "motion", ScheduleName: "Hall"},
+		{ID: contact.ID, Type: "contact", ScheduleName: "Hall"},
+		{ID: motion.LightLevelID, Type: "light_level", ScheduleName: "Hall"},
+	}, sensors)
+}
+
+func Test_DiscoverBindings(t *testing.T) {
+	bridge := huetest.NewBridge(t)
+	dimmer := bridge.AddDimmerSwitch("Kitchen switch")
+	dial := bridge.AddTapDial("Kitchen dial")
+
+	service := newTestService(t, bridge)
+
+	bindings, err := service.DiscoverBindings([]models.Binding{
+		{Device: "Kitchen switch", Button: 1, Action: "resume", Schedule: "Kitchen"},
+		{Device: "Kitchen switch", Button: 4, Event: "long_press", Action: "pause", Schedule: "Kitchen", Duration: time.Hour},
+		{Device: "Kitchen dial", Action: "brightness", Schedule: "Kitchen"},
+		// no such button, no dial and no such switch
+		{Device: "Kitchen switch", Button: 5, Action: "next", Schedule: "Kitchen"},
+		{Device: "Kitchen switch", Action: "brightness", Schedule: "Kitchen"},
+		{Device: "Missing", Button: 1, Action: "resume", Schedule: "Kitchen"},
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, []models.HughBinding{
+		{ID: dimmer.ButtonIDs[0], Event: "short_release", Action: "resume", ScheduleName: "Kitchen"},
+		{ID: dimmer.ButtonIDs[3], Event: "long_press", Action: "pause", ScheduleName: "Kitchen", Duration: time.Hour},
+		{ID: dial.RotaryID, Action: "brightness", ScheduleName: "Kitchen"},
+	}, bindings)
+}
+
+func Test_DiscoverLights_ReadsEachResourceTypeOnce(t *testing.T) {
+	bridge := huetest.NewBridge(t)
+	kitchen1 := bridge.AddLight("Kitchen 1")
+	kitchen2 := bridge.AddLight("Kitchen 2")
+	hall := bridge.AddLight("Hall")
+	bridge.AddRoom("Kitchen", kitchen1, kitchen2)
+	bridge.AddZone("Downstairs", kitchen2, hall)
+	service := newTestService(t, bridge)
+
+	_, err := service.DiscoverLights([]models.Schedule{{Name: "All", Rooms: []string{"Kitchen"}, Zones: []string{"Downstairs"}}})
+	require.NoError(t, err)
+	_, err = service.DiscoverGroups([]models.Schedule{{Name: "All", Rooms: []string{"Kitchen"}, Zones: []string{"Downstairs"}}})
+	require.NoError(t, err)
+
+	paths := lo.Map(bridge.Requests(), func(r huetest.Request, _ int) string { return r.Path })
+	assert.ElementsMatch(t, []string{
+		"/clip/v2/resource/light",
+		"/clip/v2/resource/device",
+		"/clip/v2/resource/room",
+		"/clip/v2/resource/zone",
+		"/clip/v2/resource/scene",
+	}, paths)
+}
+
+func Test_DiscoverLights_MissingResources(t *testing.T) {
+	bridge := huetest.NewBridge(t)
+	kitchen := bridge.AddLight("Kitchen")
+	// a room listing a device that has since been removed
+	bridge.AddRoom("Kitchen", kitchen, huetest.Light{DeviceID: "00000000-0000-0000-0000-000000000000"})
+	service := newTestService(t, bridge)
+
+	lights, err := service.DiscoverLights([]models.Schedule{{Name: "Kitchen", Rooms: []string{"Kitchen"}}})
+
+	require.NoError(t, err)
+	require.Len(t, lights, 1)
+	assert.Equal(t, kitchen.ID, lights[0].LightServiceId)
+
+	_, err = service.GetLight("00000000-0000-0000-0000-000000000000")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func Test_UpdateLightState(t *testing.T) {
+	target := models.LightState{On: true, Brightness: 40, TemperatureMirek: 300}
+
+	t.Run("should apply the target to the light", func(t *testing.T) {
+		bridge := huetest.NewBridge(t)
+		light := bridge.AddLight("Kitchen")
+
+		err := newTestService(t, bridge).UpdateLightState(context.Background(), light.ID, target)
+
+		assert.NoError(t, err)
+		assert.Equal(t, huetest.LightState{On: true, Brightness: 40, Mirek: 300}, bridge.Light(light))
+	})
+
+	t.Run("should only switch the light off when the target is off", func(t *testing.T) {
+		bridge := huetest.NewBridge(t)
+		light := bridge.AddLight("Kitchen")
+
+		err := newTestService(t, bridge).UpdateLightState(context.Background(), light.ID, models.LightState{On: false, Brightness: 40})
+
+		assert.NoError(t, err)
+		assert.Equal(t, huetest.LightState{On: false, Brightness: 100, Mirek: 366}, bridge.Light(light))
+	})
+
+	t.Run("should send the transition as the dynamics duration", func(t *testing.T) {
+		bridge := huetest.NewBridge(t)
+		light := bridge.AddLight("Kitchen")
+
+		err := newTestService(t, bridge).UpdateLightState(context.Background(), light.ID, models.LightState{On: true, Brightness: 40, TemperatureMirek: 300, Transition: time.Minute})
+		require.NoError(t, err)
+
+		body := struct {
+			Dynamics struct {
+				Duration int `json:"duration"`
+			} `json:"dynamics"`
+		}{}
+		requests := bridge.Requests()
+		require.NoError(t, json.Unmarshal(requests[len(requests)-1].Body, &body))
+		assert.Equal(t, 60000, body.Dynamics.Duration)
+	})
+
+	t.Run("should return unreachable when the light has communication issues", func(t *testing.T) {
+		bridge := huetest.NewBridge(t)
+		light := bridge.AddLight("Kitchen")
+		bridge.SetReachable(light, false)
+
+		err := newTestService(t, bridge).UpdateLightState(context.Background(), light.ID, target)
+
+		assert.ErrorIs(t, err, ErrUnreachable)
+	})
+
+	t.Run("should return rate limited on a 429", func(t *testing.T) {
+		bridge := huetest.NewBridge(t)
+		light := bridge.AddLight("Kitchen")
+		bridge.InjectFault(huetest.Fault{Method: http.MethodPut, StatusCode: http.StatusTooManyRequests, Times: 1})
+		service := newTestService(t, bridge)
+
+		assert.ErrorIs(t, service.UpdateLightState(context.Background(), light.ID, target), ErrRateLimited)
+		assert.NoError(t, service.UpdateLightState(context.Background(), light.ID, target), "the fault should only apply once")
+	})
+
+	t.Run("should return not found for a light that has been removed", func(t *testing.T) {
+		bridge := huetest.NewBridge(t)
+
+		err := newTestService(t, bridge).UpdateLightState(context.Background(), "00000000-0000-0000-0000-000000000000", target)
+
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("should not send the update once the context is cancelled", func(t *testing.T) {
+		bridge := huetest.NewBridge(t)
+		light := bridge.AddLight("Kitchen")
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := newTestService(t, bridge).UpdateLightState(ctx, light.ID, target)
+
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Empty(t, bridge.Requests())
+	})
+
+	t.Run("should return unauthorised when the application key is rejected", func(t *testing.T) {
+		bridge := huetest.NewBridge(t)
+		light := bridge.AddLight("Kitchen")
+		config := bridge.Config("test")
+		config.ApplicationKey = "revoked"
+		transport, err := NewBridgeTransport(TLSOptions{BridgeID: config.ID, RootCAs: bridge.RootCAs()})
+		require.NoError(t, err)
+
+		err = NewHueAPIService(log.New(io.Discard), config, transport).UpdateLightState(context.Background(), light.ID, target)
+
+		assert.ErrorIs(t, err, ErrUnauthorised)
+	})
+}
+
+func Test_UpdateLightPowerup(t *testing.T) {
+
+	t.Run("should set the light to come on at the target when powered on", func(t *testing.T) {
+		bridge := huetest.NewBridge(t)
+		light := bridge.AddLight("Kitchen")
+
+		err := newTestService(t, bridge).UpdateLightPowerup(context.Background(), light.ID, models.LightState{On: true, Brightness: 40, TemperatureMirek: 300})
+
+		assert.NoError(t, err)
+		assert.Equal(t, huetest.LightState{On: true, Brightness: 40, Mirek: 300}, bridge.Powerup(light))
+		// without changing the light itself
+		assert.Equal(t, huetest.LightState{On: true, Brightness: 100, Mirek: 366}, bridge.Light(light))
+	})
+
+	t.Run("colour target: should set the colour for colour lights", func(t *testing.T) {
+		bridge := huetest.NewBridge(t)
+		light := bridge.AddColourLight("Kitchen", "C")
+		service := newTestService(t, bridge)
+		_, err := service.GetLight(light.ID)
+		require.NoError(t, err)
+
+		err = service.UpdateLightPowerup(context.Background(), light.ID, models.LightState{On: true, Brightness: 40, TemperatureMirek: 300, Colour: &colour.XY{X: 0.3, Y: 0.3}})
+
+		assert.NoError(t, err)
+		assert.Equal(t, huetest.LightState{On: true, Brightness: 40, X: 0.3, Y: 0.3}, bridge.Powerup(light))
+	})
+}
+
+func Test_UpdateLightState_Colour(t *testing.T) {
+	// a saturated green outside of gamut B
+	target := models.LightState{On: true, Brightness: 40, TemperatureMirek: 300, Colour: &colour.XY{X: 0.17, Y: 0.7}}
+
+	t.Run("should send the colour mapped into the light's gamut", func(t *testing.T) {
+		bridge := huetest.NewBridge(t)
+		light := bridge.AddColourLight("Lamp", "B")
+		service := newTestService(t, bridge)
+		_, err := service.GetLight(light.ID)
+		require.NoError(t, err)
+
+		err = service.UpdateLightState(context.Background(), light.ID, target)
+
+		assert.NoError(t, err)
+		state := bridge.Light(light)
+		expected := colour.GamutB.Clamp(*target.Colour)
+		assert.InDelta(t, expected.X, state.X, 0.0001)
+		assert.InDelta(t, expected.Y, state.Y, 0.0001)
+		assert.Equal(t, 366, state.Mirek, "the colour temperature shouldn't be sent with a colour")
+	})
+
+	t.Run("should send the nearest colour temperature to lights without colour", func(t *testing.T) {
+		bridge := huetest.NewBridge(t)
+		light := bridge.AddLight("Kitchen")
+		service := newTestService(t, bridge)
+		_, err := service.GetLight(light.ID)
+		require.NoError(t, err)
+
+		err = service.UpdateLightState(context.Background(), light.ID, target)
+
+		assert.NoError(t, err)
+		assert.Equal(t, huetest.LightState{On: true, Brightness: 40, Mirek: 300}, bridge.Light(light))
+	})
+}
+
+func Test_UpdateGroupedLightState(t *testing.T) {
+	bridge := huetest.NewBridge(t)
+	kitchen1 := bridge.AddLight("Kitchen 1")
+	kitchen2 := bridge.AddLight("Kitchen 2")
+	room := bridge.AddRoom("Kitchen", kitchen1, kitchen2)
+
+	err := newTestService(t, bridge).UpdateGroupedLightState(context.Background(), room.GroupedLightID, models.LightState{On: true, Brightness: 40, TemperatureMirek: 300})
+
+	assert.NoError(t, err)
+	assert.Equal(t, huetest.LightState{On: true, Brightness: 40, Mirek: 300}, bridge.Light(kitchen1))
+	assert.Equal(t, huetest.LightState{On: true, Brightness: 40, Mirek: 300}, bridge.Light(kitchen2))
+}
+
+func Test_UpdateSceneState(t *testing.T) {
+	bridge := huetest.NewBridge(t)
+	kitchen1 := bridge.AddLight("Kitchen 1")
+	kitchen2 := bridge.AddLight("Kitchen 2")
+	sceneID := bridge.AddScene("Hugh_Kitchen", bridge.AddRoom("Kitchen", kitchen1, kitchen2))
+	service := newTestService(t, bridge)
+
+	err := service.UpdateSceneState(context.Background(), sceneID, models.LightState{On: true, Brightness: 40, TemperatureMirek: 300})
+	require.NoError(t, err)
+
+	// the cache is kept up to date by events, there's no event stream here so read the scene again
+	require.NoError(t, service.LoadResources())
+	scenes, err := service.GetScenes()
+	require.NoError(t, err)
+	require.Len(t, scenes, 1)
+	require.Len(t, scenes[0].Actions, 2)
+	for _, a := range scenes[0].Actions {
+		assert.True(t, a.Action.On.On)
+		assert.Equal(t, 40.0, a.Action.Dimming.Brightness)
+		assert.Equal(t, 300, a.Action.ColorTemperature.Mirek)
+	}
+}
+
+func Test_UpdateSceneState_Colour(t *testing.T) {
+	bridge := huetest.NewBridge(t)
+	lamp := bridge.AddColourLight("Lamp", "C")
+	kitchen := bridge.AddLight("Kitchen")
+	sceneID := bridge.AddScene("Hugh_Kitchen", bridge.AddRoom("Kitchen", lamp, kitchen))
+	service := newTestService(t, bridge)
+	_, err := service.DiscoverLights([]models.Schedule{{Name: "Kitchen", Rooms: []string{"Kitchen"}}})
+	require.NoError(t, err)
+
+	err = service.UpdateSceneState(context.Background(), sceneID, models.LightState{On: true, Brightness: 40, TemperatureMirek: 300, Colour: &colour.XY{X: 0.6, Y: 0.3}})
+	require.NoError(t, err)
+
+	// the cache is kept up to date by events, there's no event stream here so read the scene again
+	require.NoError(t, service.LoadResources())
+	scenes, err := service.GetScenes()
+	require.NoError(t, err)
+	actions := scenes[0].Actions
+	require.Len(t, actions, 2)
+	assert.Equal(t, lamp.ID, actions[0].Target.RID)
+	assert.Equal(t, HueXY{X: 0.6, Y: 0.3}, actions[0].Action.Color.XY)
+	assert.Equal(t, 300, actions[1].Action.ColorTemperature.Mirek)
+	assert.Nil(t, actions[1].Action.Color)
+}
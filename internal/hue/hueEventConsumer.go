@@ -1,35 +1,34 @@
 package hue
 
 import (
-	"crypto/tls"
 	"fmt"
 	"net/http"
 
 	"github.com/charmbracelet/log"
 	sse "github.com/r3labs/sse/v2"
-	"github.com/spf13/viper"
+	"github.com/wheelibin/hugh/internal/models"
 )
 
 type HueEventConsumer struct {
 	Logger *log.Logger
 
+	bridge       models.Bridge
+	transport    http.RoundTripper
 	client       *sse.Client
 	eventChannel chan *sse.Event
 }
 
-func NewHueEventConsumer(logger *log.Logger) *HueEventConsumer {
-	return &HueEventConsumer{Logger: logger}
+func NewHueEventConsumer(logger *log.Logger, bridge models.Bridge, transport http.RoundTripper) *HueEventConsumer {
+	return &HueEventConsumer{Logger: logger, bridge: bridge, transport: transport}
 }
 
 func (h *HueEventConsumer) Subscribe(eventChannel chan *sse.Event) {
 
 	h.eventChannel = eventChannel
-	h.client = sse.NewClient(fmt.Sprintf("https://%s/eventstream/clip/v2", viper.GetString("bridgeIp")))
+	h.client = sse.NewClient(fmt.Sprintf("https://%s/eventstream/clip/v2", h.bridge.IP))
 
-	h.client.Connection.Transport = &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-	}
-	h.client.Headers["hue-application-key"] = viper.GetString("hueApplicationKey")
+	h.client.Connection.Transport = h.transport
+	h.client.Headers["hue-application-key"] = h.bridge.ApplicationKey
 
 	h.client.OnConnect(func(_ *sse.Client) {
 		h.Logger.Info("Connected to HUE bridge, listening for events...")
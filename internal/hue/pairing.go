@@ -0,0 +1,119 @@
+package hue
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const linkButtonNotPressed = 101
+
+// the longest application and device names the bridge accepts in a devicetype
+const (
+	maxApplicationNameLength = 20
+	maxDeviceNameLength      = 19
+)
+
+// the credentials issued by a bridge once the link button has been pressed
+type PairingResult struct {
+	ApplicationKey string `json:"username"`
+	ClientKey      string `json:"clientkey"`
+}
+
+type pairingResponse []struct {
+	Success *PairingResult `json:"success"`
+	Error   *struct {
+		Type        int    `json:"type"`
+		Description string `json:"description"`
+	} `json:"error"`
+}
+
+// Pair repeatedly asks the bridge for a new application key until the link button is pressed,
+// the bridge rejects the request for another reason or the context is cancelled
+func Pair(ctx context.Context, ip string, deviceType string, pollInterval time.Duration) (PairingResult, error) {
+	requestBody, err := json.Marshal(map[string]any{
+		"devicetype":        deviceType,
+		"generateclientkey": true,
+	})
+	if err != nil {
+		return PairingResult{}, err
+	}
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		result, err := requestApplicationKey(ctx, ip, requestBody)
+		if err == nil {
+			return result, nil
+		}
+		if ctx.Err() != nil {
+			// ran out of time while the request was in flight
+			return PairingResult{}, fmt.Errorf("link button was not pressed in time: %w", ctx.Err())
+		}
+		if !errors.Is(err, errLinkButtonNotPressed) {
+			return PairingResult{}, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return PairingResult{}, fmt.Errorf("link button was not pressed in time: %w", ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// DeviceType builds the devicetype an application key is requested with, "<application>#<device>", cut to the
+// lengths the bridge accepts
+func DeviceType(application string, device string) string {
+	return fmt.Sprintf("%s#%s", truncate(application, maxApplicationNameLength), truncate(device, maxDeviceNameLength))
+}
+
+func truncate(s string, length int) string {
+	runes := []rune(s)
+	if len(runes) > length {
+		return string(runes[:length])
+	}
+	return s
+}
+
+var errLinkButtonNotPressed = errors.New("link button not pressed")
+
+func requestApplicationKey(ctx context.Context, ip string, requestBody []byte) (PairingResult, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("https://%s/api", ip), bytes.NewReader(requestBody))
+	if err != nil {
+		return PairingResult{}, err
+	}
+
+	resp, err := pairingClient().Do(req)
+	if err != nil {
+		return PairingResult{}, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return PairingResult{}, err
+	}
+
+	respBody := pairingResponse{}
+	if err := json.Unmarshal(body, &respBody); err != nil || len(respBody) == 0 {
+		return PairingResult{}, fmt.Errorf("unexpected pairing response from %s: %s", ip, body)
+	}
+
+	switch {
+	case respBody[0].Success != nil:
+		return *respBody[0].Success, nil
+	case respBody[0].Error != nil && respBody[0].Error.Type == linkButtonNotPressed:
+		return PairingResult{}, errLinkButtonNotPressed
+	case respBody[0].Error != nil:
+		return PairingResult{}, fmt.Errorf("bridge refused pairing: %s", respBody[0].Error.Description)
+	default:
+		return PairingResult{}, fmt.Errorf("unexpected pairing response from %s: %s", ip, body)
+	}
+}
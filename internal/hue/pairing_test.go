@@ -0,0 +1,89 @@
+package hue
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// a bridge that answers pairing requests with the responses in turn, repeating the last one
+func pairingBridge(t *testing.T, responses ...string) (ip string, requests func() []map[string]any) {
+	var mu sync.Mutex
+	received := []map[string]any{}
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/api", r.URL.Path)
+		body := map[string]any{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+
+		mu.Lock()
+		received = append(received, body)
+		response := responses[min(len(received), len(responses))-1]
+		mu.Unlock()
+
+		_, _ = w.Write([]byte(response))
+	}))
+	t.Cleanup(server.Close)
+
+	return strings.TrimPrefix(server.URL, "https://"), func() []map[string]any {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]map[string]any{}, received...)
+	}
+}
+
+const linkButtonNotPressedResponse = `[{"error":{"type":101,"address":"","description":"link button not pressed"}}]`
+
+func Test_Pair(t *testing.T) {
+
+	t.Run("should keep asking until the link button is pressed", func(t *testing.T) {
+		ip, requests := pairingBridge(t,
+			linkButtonNotPressedResponse,
+			linkButtonNotPressedResponse,
+			linkButtonNotPressedResponse,
+			`[{"success":{"username":"h9YL8D5O4eEuP-6Oe4bF146QfYWbLVR717zJKAEo","clientkey":"33DDAF4E2C9B1A2D4F8C0B6E3A7D5C1F"}}]`,
+		)
+
+		result, err := Pair(context.Background(), ip, "hugh#test", time.Millisecond)
+
+		require.NoError(t, err)
+		assert.Equal(t, PairingResult{ApplicationKey: "h9YL8D5O4eEuP-6Oe4bF146QfYWbLVR717zJKAEo", ClientKey: "33DDAF4E2C9B1A2D4F8C0B6E3A7D5C1F"}, result)
+		require.Len(t, requests(), 4)
+		assert.Equal(t, map[string]any{"devicetype": "hugh#test", "generateclientkey": true}, requests()[0])
+	})
+
+	t.Run("should give up when the link button isn't pressed in time", func(t *testing.T) {
+		ip, _ := pairingBridge(t, linkButtonNotPressedResponse)
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		_, err := Pair(ctx, ip, "hugh#test", time.Millisecond)
+
+		assert.ErrorContains(t, err, "link button was not pressed in time")
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("should stop when the bridge refuses for another reason", func(t *testing.T) {
+		ip, requests := pairingBridge(t, `[{"error":{"type":7,"address":"/devicetype","description":"invalid value, hugh#test, for parameter, devicetype"}}]`)
+
+		_, err := Pair(context.Background(), ip, "hugh#test", time.Millisecond)
+
+		assert.EqualError(t, err, "bridge refused pairing: invalid value, hugh#test, for parameter, devicetype")
+		assert.Len(t, requests(), 1)
+	})
+}
+
+func Test_DeviceType(t *testing.T) {
+	assert.Equal(t, "hugh#kitchen-pi", DeviceType("hugh", "kitchen-pi"))
+	// the bridge refuses device names over 19 characters
+	assert.Equal(t, "hugh#raspberrypi-livingr", DeviceType("hugh", "raspberrypi-livingroom.local"))
+}
@@ -0,0 +1,203 @@
+package hue
+
+import (
+	"encoding/json"
+	"sync"
+
+	sse "github.com/r3labs/sse/v2"
+	"github.com/samber/lo"
+)
+
+// the resource types held by the cache
+const (
+	ResourceTypeLight  = "light"
+	ResourceTypeDevice = "device"
+	ResourceTypeRoom   = "room"
+	ResourceTypeZone   = "zone"
+	ResourceTypeScene  = "scene"
+)
+
+var cachedResourceTypes = []string{ResourceTypeLight, ResourceTypeDevice, ResourceTypeRoom, ResourceTypeZone, ResourceTypeScene}
+
+// a resource as json, kept untyped so partial update events can be merged into it
+type resource = map[string]any
+
+// ResourceCache is a copy of the bridge's lights, devices, rooms, zones and scenes, loaded in bulk and kept up to
+// date with the add/update/delete events from the event stream
+type ResourceCache struct {
+	mu     sync.RWMutex
+	loaded bool
+	// resources by type, then id
+	resources map[string]map[string]resource
+	// ids by type in the order the bridge listed them
+	order map[string][]string
+}
+
+func NewResourceCache() *ResourceCache {
+	return &ResourceCache{resources: map[string]map[string]resource{}, order: map[string][]string{}}
+}
+
+func (c *ResourceCache) Loaded() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.loaded
+}
+
+// Load replaces the cached resources with those read from the bridge, by type
+func (c *ResourceCache) Load(resourcesByType map[string][]resource) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.resources = map[string]map[string]resource{}
+	c.order = map[string][]string{}
+	for rtype, resources := range resourcesByType {
+		for _, r := range resources {
+			c.add(rtype, r)
+		}
+	}
+	c.loaded = true
+}
+
+// ApplyEvent updates the cache with a batch of events from the event stream, events for other types are ignored
+func (c *ResourceCache) ApplyEvent(event *sse.Event) error {
+	batches := []struct {
+		Type string     `json:"type"`
+		Data []resource `json:"data"`
+	}{}
+	if err := json.Unmarshal(event.Data, &batches); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, batch := range batches {
+		for _, r := range batch.Data {
+			rtype, _ := r["type"].(string)
+			id, _ := r["id"].(string)
+			if !lo.Contains(cachedResourceTypes, rtype) {
+				continue
+			}
+
+			switch batch.Type {
+			case "add":
+				c.add(rtype, r)
+			case "update":
+				// partial, only the changed properties are sent
+				if existing, found := c.resources[rtype][id]; found {
+					merge(existing, r)
+				}
+			case "delete":
+				c.delete(rtype, id)
+			}
+		}
+	}
+	return nil
+}
+
+func (c *ResourceCache) Light(id string) (HueLight, bool) {
+	return lookup[HueLight](c, ResourceTypeLight, id)
+}
+
+func (c *ResourceCache) Device(id string) (HueDevice, bool) {
+	return lookup[HueDevice](c, ResourceTypeDevice, id)
+}
+
+func (c *ResourceCache) Scene(id string) (HueScene, bool) {
+	return lookup[HueScene](c, ResourceTypeScene, id)
+}
+
+func (c *ResourceCache) Devices() []HueDevice {
+	return list[HueDevice](c, ResourceTypeDevice)
+}
+
+func (c *ResourceCache) Lights() []HueLight {
+	return list[HueLight](c, ResourceTypeLight)
+}
+
+func (c *ResourceCache) Rooms() []HueDeviceGroup {
+	return list[HueDeviceGroup](c, ResourceTypeRoom)
+}
+
+func (c *ResourceCache) Zones() []HueDeviceGroup {
+	return list[HueDeviceGroup](c, ResourceTypeZone)
+}
+
+func (c *ResourceCache) Scenes() []HueScene {
+	return list[HueScene](c, ResourceTypeScene)
+}
+
+// must be called with the lock held
+func (c *ResourceCache) add(rtype string, r resource) {
+	id, _ := r["id"].(string)
+	if c.resources[rtype] == nil {
+		c.resources[rtype] = map[string]resource{}
+	}
+	if _, exists := c.resources[rtype][id]; !exists {
+		c.order[rtype] = append(c.order[rtype], id)
+	}
+	c.resources[rtype][id] = r
+}
+
+// must be called with the lock held
+func (c *ResourceCache) delete(rtype string, id string) {
+	if _, exists := c.resources[rtype][id]; !exists {
+		return
+	}
+	delete(c.resources[rtype], id)
+	for i, orderedID := range c.order[rtype] {
+		if orderedID == id {
+			c.order[rtype] = append(c.order[rtype][:i:i], c.order[rtype][i+1:]...)
+			break
+		}
+	}
+}
+
+// decodes a copy of the resource, so callers never share anything with the cache
+func lookup[T any](c *ResourceCache, rtype string, id string) (T, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var typed T
+	r, found := c.resources[rtype][id]
+	if !found {
+		return typed, false
+	}
+	err := decode(r, &typed)
+	return typed, err == nil
+}
+
+func list[T any](c *ResourceCache, rtype string) []T {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	typed := []T{}
+	for _, id := range c.order[rtype] {
+		var t T
+		if decode(c.resources[rtype][id], &t) == nil {
+			typed = append(typed, t)
+		}
+	}
+	return typed
+}
+
+func decode(r resource, v any) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// deep merges src into dst, src values that aren't objects replace those in dst
+func merge(dst resource, src resource) {
+	for k, v := range src {
+		srcObject, srcIsObject := v.(resource)
+		dstObject, dstIsObject := dst[k].(resource)
+		if srcIsObject && dstIsObject {
+			merge(dstObject, srcObject)
+			continue
+		}
+		dst[k] = v
+	}
+}
@@ -0,0 +1,85 @@
+package hue
+
+import (
+	"testing"
+
+	sse "github.com/r3labs/sse/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newLoadedCache() *ResourceCache {
+	cache := NewResourceCache()
+	cache.Load(map[string][]resource{
+		ResourceTypeLight: {
+			{"id": "l1", "type": "light", "metadata": resource{"name": "Kitchen"}, "on": resource{"on": true}, "dimming": resource{"brightness": 100.0}},
+			{"id": "l2", "type": "light", "metadata": resource{"name": "Hall"}, "on": resource{"on": true}},
+		},
+		ResourceTypeRoom: {
+			{"id": "r1", "type": "room", "metadata": resource{"name": "Kitchen"}},
+		},
+	})
+	return cache
+}
+
+func Test_ResourceCache_ApplyEvent(t *testing.T) {
+
+	t.Run("update: should merge the changed properties", func(t *testing.T) {
+		cache := newLoadedCache()
+
+		err := cache.ApplyEvent(&sse.Event{Data: []byte(`[{"type":"update","data":[{"id":"l1","type":"light","dimming":{"brightness":40}}]}]`)})
+
+		require.NoError(t, err)
+		light, found := cache.Light("l1")
+		require.True(t, found)
+		assert.Equal(t, 40.0, light.Dimming.Brightness)
+		assert.True(t, light.On.On, "properties not in the event should be kept")
+		assert.Equal(t, "Kitchen", light.Metadata.Name)
+	})
+
+	t.Run("add: should add the resource", func(t *testing.T) {
+		cache := newLoadedCache()
+
+		err := cache.ApplyEvent(&sse.Event{Data: []byte(`[{"type":"add","data":[{"id":"z1","type":"zone","metadata":{"name":"Downstairs"}}]}]`)})
+
+		require.NoError(t, err)
+		zones := cache.Zones()
+		require.Len(t, zones, 1)
+		assert.Equal(t, "Downstairs", zones[0].Metadata.Name)
+	})
+
+	t.Run("delete: should remove the resource", func(t *testing.T) {
+		cache := newLoadedCache()
+
+		err := cache.ApplyEvent(&sse.Event{Data: []byte(`[{"type":"delete","data":[{"id":"l1","type":"light"}]}]`)})
+
+		require.NoError(t, err)
+		_, found := cache.Light("l1")
+		assert.False(t, found)
+		assert.Len(t, cache.Lights(), 1)
+	})
+
+	t.Run("other resource types: should be ignored", func(t *testing.T) {
+		cache := newLoadedCache()
+
+		err := cache.ApplyEvent(&sse.Event{Data: []byte(`[{"type":"add","data":[{"id":"b1","type":"button"}]}]`)})
+
+		require.NoError(t, err)
+		assert.Len(t, cache.Lights(), 2)
+	})
+}
+
+func Test_ResourceCache_Lookups(t *testing.T) {
+	cache := newLoadedCache()
+
+	lights := cache.Lights()
+	assert.Equal(t, []string{"Kitchen", "Hall"}, []string{lights[0].Metadata.Name, lights[1].Metadata.Name}, "should keep the bridge's order")
+
+	// changing a returned resource shouldn't change the cache
+	lights[0].Metadata.Name = "Changed"
+	light, _ := cache.Light("l1")
+	assert.Equal(t, "Kitchen", light.Metadata.Name)
+
+	_, found := cache.Device("d1")
+	assert.False(t, found)
+}
@@ -22,12 +22,40 @@ type HueLight struct {
 	Owner struct {
 		DeviceID string `json:"rid"`
 	} `json:"owner"`
+	Dimming struct {
+		Brightness float64 `json:"brightness"`
+	} `json:"dimming"`
 	ColorTemperature struct {
+		// null while the light is showing a colour
+		Mirek       *int `json:"mirek"`
 		MirekBounds struct {
 			Min int `json:"mirek_minimum"`
 			Max int `json:"mirek_maximum"`
 		} `json:"mirek_schema"`
 	} `json:"color_temperature"`
+	// only present for lights that support colour
+	Color *struct {
+		XY    HueXY `json:"xy"`
+		Gamut *struct {
+			Red   HueXY `json:"red"`
+			Green HueXY `json:"green"`
+			Blue  HueXY `json:"blue"`
+		} `json:"gamut"`
+		GamutType string `json:"gamut_type"`
+	} `json:"color"`
+}
+
+type HueXY struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+type HueZigbeeConnectivity struct {
+	Id    string `json:"id"`
+	Owner struct {
+		DeviceID string `json:"rid"`
+	} `json:"owner"`
+	Status string `json:"status"`
 }
 
 type HueDeviceGroup struct {
@@ -46,7 +74,10 @@ type HueSceneAction struct {
 		} `json:"dimming"`
 		ColorTemperature *struct {
 			Mirek int `json:"mirek"`
-		} `json:"color_temperature"`
+		} `json:"color_temperature,omitempty"`
+		Color *struct {
+			XY HueXY `json:"xy"`
+		} `json:"color,omitempty"`
 	} `json:"action"`
 }
 
@@ -55,22 +86,16 @@ type HueScene struct {
 	Actions []HueSceneAction `json:"actions"`
 }
 
-type DevicesResponse struct {
-	Errors []any       `json:"errors"`
-	Data   []HueDevice `json:"data"`
+type HueError struct {
+	Description string `json:"description"`
 }
 
 type LightResponse struct {
-	Errors []any      `json:"errors"`
+	Errors []HueError `json:"errors"`
 	Data   []HueLight `json:"data"`
 }
 
-type GroupResponse struct {
-	Errors []any            `json:"errors"`
-	Data   []HueDeviceGroup `json:"data"`
-}
-
-type SceneResponse struct {
-	Errors []any      `json:"errors"`
-	Data   []HueScene `json:"data"`
+type ZigbeeConnectivityResponse struct {
+	Errors []HueError              `json:"errors"`
+	Data   []HueZigbeeConnectivity `json:"data"`
 }
@@ -0,0 +1,154 @@
+package hue
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+// the root certificate Signify signs every current bridge's certificate with
+//
+//go:embed certs/hue_root_ca.pem
+var hueRootCA []byte
+
+const (
+	// the bridge certificate must chain to the hue root CA
+	CertificateModeCA = "ca"
+	// trust the certificate presented on first connection and require the same one afterwards,
+	// for older bridges with self-signed certificates
+	CertificateModePin = "pin"
+	// don't verify the bridge at all
+	CertificateModeInsecure = "insecure"
+)
+
+type TLSOptions struct {
+	Mode string
+	// the bridge's name in the config, pinned certificates are kept against it rather than anything the bridge presents
+	Bridge string
+	// the id the bridge reported when pairing, its certificate is issued to this id rather than an address
+	BridgeID string
+	// where trust-on-first-use fingerprints are kept
+	PinFile string
+	// overrides the bundled hue root CA
+	RootCAs *x509.CertPool
+}
+
+// NewBridgeTransport builds the transport shared by every request and event stream to a bridge
+func NewBridgeTransport(opts TLSOptions) (*http.Transport, error) {
+	tlsConfig := &tls.Config{}
+
+	if opts.Mode == CertificateModePin {
+		if opts.BridgeID == "" {
+			return nil, errors.New("the bridge id is needed to pin the bridge certificate, run `hugh pair` to set it")
+		}
+		if opts.Bridge == "" {
+			return nil, errors.New("the bridge name is needed to pin the bridge certificate")
+		}
+	}
+
+	switch opts.Mode {
+	case CertificateModeInsecure:
+		tlsConfig.InsecureSkipVerify = true
+
+	case CertificateModeCA, CertificateModePin, "":
+		if opts.RootCAs == nil {
+			opts.RootCAs = x509.NewCertPool()
+			if !opts.RootCAs.AppendCertsFromPEM(hueRootCA) {
+				return nil, errors.New("unable to load the bundled hue root certificate")
+			}
+		}
+		verifier := &bridgeVerifier{opts: opts}
+		// the bridge is addressed by ip but its certificate names the bridge id, so the standard
+		// hostname verification is replaced by VerifyConnection
+		tlsConfig.InsecureSkipVerify = true
+		tlsConfig.VerifyConnection = verifier.verify
+
+	default:
+		return nil, fmt.Errorf("unknown bridge certificate mode: %s", opts.Mode)
+	}
+
+	return &http.Transport{TLSClientConfig: tlsConfig}, nil
+}
+
+type bridgeVerifier struct {
+	opts TLSOptions
+	mu   sync.Mutex
+}
+
+func (v *bridgeVerifier) verify(cs tls.ConnectionState) error {
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("hue bridge presented no certificate")
+	}
+	leaf := cs.PeerCertificates[0]
+
+	if v.opts.BridgeID != "" && !strings.EqualFold(leaf.Subject.CommonName, v.opts.BridgeID) {
+		return fmt.Errorf("hue bridge certificate is for %q, expected bridge id %q", leaf.Subject.CommonName, v.opts.BridgeID)
+	}
+
+	if v.opts.Mode == CertificateModePin {
+		return v.verifyPin(leaf)
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, c := range cs.PeerCertificates[1:] {
+		intermediates.AddCert(c)
+	}
+	_, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         v.opts.RootCAs,
+		Intermediates: intermediates,
+	})
+	if err != nil {
+		return fmt.Errorf("hue bridge certificate is not signed by the hue root CA (set bridgeCertificate: pin for older bridges): %w", err)
+	}
+
+	return nil
+}
+
+// compares the certificate's fingerprint with the one seen on first use, saving it if this is the first time
+func (v *bridgeVerifier) verifyPin(leaf *x509.Certificate) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	sum := sha256.Sum256(leaf.Raw)
+	fingerprint := hex.EncodeToString(sum[:])
+	key := v.opts.Bridge
+
+	pins := map[string]string{}
+	data, err := os.ReadFile(v.opts.PinFile)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("error reading certificate pins: %w", err)
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &pins); err != nil {
+			return fmt.Errorf("error parsing certificate pins (%s): %w", v.opts.PinFile, err)
+		}
+	}
+
+	pinned, found := pins[key]
+	if found {
+		if pinned != fingerprint {
+			return fmt.Errorf("hue bridge (%s) certificate has changed since it was pinned, remove it from %s if this is expected", key, v.opts.PinFile)
+		}
+		return nil
+	}
+
+	pins[key] = fingerprint
+	data, err = json.MarshalIndent(pins, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(v.opts.PinFile, data, 0o600); err != nil {
+		return fmt.Errorf("error saving certificate pin: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,136 @@
+package hue
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testBridgeID = "001788fffe123456"
+
+// issues a certificate for the bridge id, signed by parent (or self-signed when parent is nil)
+func issueCert(t *testing.T, cn string, isCA bool, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey, tls.Certificate) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	assert.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+
+	return cert, key, tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func startBridge(t *testing.T, cert tls.Certificate) *httptest.Server {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.TLS = &tls.Config{Certificates: []tls.Certificate{cert}}
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func get(transport *http.Transport, url string) error {
+	resp, err := (&http.Client{Transport: transport}).Get(url)
+	if err == nil {
+		resp.Body.Close()
+	}
+	return err
+}
+
+func Test_NewBridgeTransport(t *testing.T) {
+	ca, caKey, _ := issueCert(t, "root-bridge", true, nil, nil)
+	_, _, bridgeCert := issueCert(t, testBridgeID, false, ca, caKey)
+	_, _, selfSignedCert := issueCert(t, testBridgeID, false, nil, nil)
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+
+	t.Run("ca: should accept a certificate for the bridge id signed by the root", func(t *testing.T) {
+		srv := startBridge(t, bridgeCert)
+		transport, err := NewBridgeTransport(TLSOptions{Mode: CertificateModeCA, BridgeID: "001788FFFE123456", RootCAs: roots})
+		assert.NoError(t, err)
+		assert.NoError(t, get(transport, srv.URL))
+	})
+
+	t.Run("ca: should reject a certificate for a different bridge", func(t *testing.T) {
+		srv := startBridge(t, bridgeCert)
+		transport, _ := NewBridgeTransport(TLSOptions{Mode: CertificateModeCA, BridgeID: "001788fffe999999", RootCAs: roots})
+		assert.ErrorContains(t, get(transport, srv.URL), "expected bridge id")
+	})
+
+	t.Run("ca: should reject a self-signed certificate", func(t *testing.T) {
+		srv := startBridge(t, selfSignedCert)
+		transport, _ := NewBridgeTransport(TLSOptions{Mode: CertificateModeCA, BridgeID: testBridgeID, RootCAs: roots})
+		assert.ErrorContains(t, get(transport, srv.URL), "not signed by the hue root CA")
+	})
+
+	t.Run("pin: should trust the first certificate seen and reject a different one later", func(t *testing.T) {
+		pinFile := filepath.Join(t.TempDir(), "bridge-pins.json")
+		transport, _ := NewBridgeTransport(TLSOptions{Mode: CertificateModePin, Bridge: "home", BridgeID: testBridgeID, PinFile: pinFile})
+
+		first := startBridge(t, selfSignedCert)
+		assert.NoError(t, get(transport, first.URL))
+		assert.NoError(t, get(transport, first.URL))
+
+		_, _, replacedCert := issueCert(t, testBridgeID, false, nil, nil)
+		replaced := startBridge(t, replacedCert)
+		transport, _ = NewBridgeTransport(TLSOptions{Mode: CertificateModePin, Bridge: "home", BridgeID: testBridgeID, PinFile: pinFile})
+		assert.ErrorContains(t, get(transport, replaced.URL), "has changed")
+	})
+
+	t.Run("pin: should reject a certificate for a different bridge once one is pinned", func(t *testing.T) {
+		pinFile := filepath.Join(t.TempDir(), "bridge-pins.json")
+		transport, _ := NewBridgeTransport(TLSOptions{Mode: CertificateModePin, Bridge: "home", BridgeID: testBridgeID, PinFile: pinFile})
+
+		first := startBridge(t, selfSignedCert)
+		assert.NoError(t, get(transport, first.URL))
+
+		_, _, otherCert := issueCert(t, "001788fffe999999", false, nil, nil)
+		other := startBridge(t, otherCert)
+		assert.ErrorContains(t, get(transport, other.URL), "expected bridge id")
+
+		// the pin belongs to the configured bridge, so it holds even if the id were to match the impostor
+		transport, _ = NewBridgeTransport(TLSOptions{Mode: CertificateModePin, Bridge: "home", BridgeID: "001788fffe999999", PinFile: pinFile})
+		assert.ErrorContains(t, get(transport, other.URL), "has changed")
+	})
+
+	t.Run("pin: should refuse to pin without the bridge id", func(t *testing.T) {
+		_, err := NewBridgeTransport(TLSOptions{Mode: CertificateModePin, Bridge: "home", PinFile: filepath.Join(t.TempDir(), "bridge-pins.json")})
+		assert.ErrorContains(t, err, "bridge id is needed")
+	})
+
+	t.Run("insecure: should accept anything", func(t *testing.T) {
+		srv := startBridge(t, selfSignedCert)
+		transport, _ := NewBridgeTransport(TLSOptions{Mode: CertificateModeInsecure})
+		assert.NoError(t, get(transport, srv.URL))
+	})
+
+	t.Run("should load the bundled hue root certificate", func(t *testing.T) {
+		transport, err := NewBridgeTransport(TLSOptions{})
+		assert.NoError(t, err)
+		assert.NotNil(t, transport.TLSClientConfig.VerifyConnection)
+	})
+}
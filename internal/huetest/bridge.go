@@ -0,0 +1,913 @@
+// Package huetest provides an in-process fake of a hue bridge's CLIP v2 API for integration tests.
+package huetest
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/wheelibin/hugh/internal/models"
+)
+
+// the application key the fake bridge accepts
+const ApplicationKey = "huetest-application-key"
+
+// a light added to the fake bridge, along with the device and zigbee_connectivity services that come with it
+type Light struct {
+	ID       string
+	DeviceID string
+	ZigbeeID string
+}
+
+// a motion or contact sensor added to the fake bridge
+type Sensor struct {
+	// the id of the motion or contact service
+	ID       string
+	DeviceID string
+	// the id of a motion sensor's light_level service
+	LightLevelID string
+}
+
+// a dimmer switch or tap dial added to the fake bridge
+type Switch struct {
+	DeviceID string
+	// the ids of the button services, in the order the bridge lists them
+	ButtonIDs []string
+	// the id of a tap dial's relative_rotary service
+	RotaryID string
+}
+
+// a room or zone added to the fake bridge
+type Group struct {
+	ID             string
+	Type           string
+	GroupedLightID string
+}
+
+type LightState struct {
+	On         bool
+	Brightness float64
+	Mirek      int
+	// the colour of colour lights, 0 for colour temperature lights
+	X float64
+	Y float64
+}
+
+// a request received by the fake bridge, event stream connections aren't recorded
+type Request struct {
+	Method string
+	Path   string
+	Body   []byte
+}
+
+// Fault makes the bridge answer matching requests with an error instead of handling them
+type Fault struct {
+	// the request method to match, any if empty
+	Method string
+	// the request path prefix to match, any if empty
+	Path string
+
+	StatusCode int
+	// descriptions for the errors array of the response
+	Errors []string
+	// how many requests to fail, every matching request until the faults are cleared if 0
+	Times int
+}
+
+type resource = map[string]any
+
+// Bridge is a fake hue bridge served over TLS. It keeps its resources in memory, applies PUTs to them
+// and sends the resulting update events to every connected event stream, like a real bridge.
+type Bridge struct {
+	t       testing.TB
+	id      string
+	server  *httptest.Server
+	rootCAs *x509.CertPool
+
+	mu          sync.Mutex
+	resources   map[string][]resource
+	unreachable map[string]bool
+	faults      []*Fault
+	requests    []Request
+	streams     map[*eventStream]struct{}
+	connections int
+	closed      chan struct{}
+}
+
+type eventStream struct {
+	events     chan []byte
+	disconnect chan struct{}
+}
+
+var bridgeCount atomic.Int64
+
+// NewBridge starts a fake bridge that is closed when the test finishes
+func NewBridge(t testing.TB) *Bridge {
+	t.Helper()
+
+	b := &Bridge{
+		t:           t,
+		id:          fmt.Sprintf("001788fffe%06x", bridgeCount.Add(1)),
+		resources:   map[string][]resource{},
+		unreachable: map[string]bool{},
+		streams:     map[*eventStream]struct{}{},
+		closed:      make(chan struct{}),
+	}
+
+	cert, rootCAs, err := bridgeCertificate(b.id)
+	if err != nil {
+		t.Fatalf("huetest: error creating bridge certificate: %v", err)
+	}
+	b.rootCAs = rootCAs
+
+	b.server = httptest.NewUnstartedServer(http.HandlerFunc(b.serveHTTP))
+	b.server.TLS = &tls.Config{Certificates: []tls.Certificate{cert}}
+	b.server.StartTLS()
+	t.Cleanup(b.Close)
+
+	return b
+}
+
+// Config returns the connection details of the bridge in the form hugh reads them from its config
+func (b *Bridge) Config(name string) models.Bridge {
+	return models.Bridge{
+		Name:           name,
+		IP:             b.server.Listener.Addr().String(),
+		ApplicationKey: ApplicationKey,
+		ID:             b.id,
+	}
+}
+
+// RootCAs returns the pool holding the root certificate the bridge certificate was issued by
+func (b *Bridge) RootCAs() *x509.CertPool {
+	return b.rootCAs
+}
+
+func (b *Bridge) Close() {
+	select {
+	case <-b.closed:
+		return
+	default:
+	}
+	// event streams never finish by themselves, so they have to be ended before the server will close
+	close(b.closed)
+	b.server.CloseClientConnections()
+	b.server.Close()
+}
+
+// AddLight adds a colour temperature light, switched on at full brightness
+func (b *Bridge) AddLight(name string) Light {
+	return b.addLight(name, resource{})
+}
+
+// AddColourLight adds a colour light with the gamut type (A, B or C), switched on at full brightness
+func (b *Bridge) AddColourLight(name string, gamutType string) Light {
+	return b.addLight(name, resource{
+		"color": resource{
+			"xy":         resource{"x": 0.4573, "y": 0.41},
+			"gamut_type": gamutType,
+		},
+	})
+}
+
+func (b *Bridge) addLight(name string, extra resource) Light {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	l := Light{ID: newID(), DeviceID: newID(), ZigbeeID: newID()}
+	owner := reference(l.DeviceID, "device")
+
+	b.add("device", resource{
+		"id":       l.DeviceID,
+		"type":     "device",
+		"metadata": resource{"name": name, "archetype": "sultan_bulb"},
+		"services": []any{reference(l.ID, "light"), reference(l.ZigbeeID, "zigbee_connectivity")},
+	})
+	light := resource{
+		"id":       l.ID,
+		"type":     "light",
+		"owner":    owner,
+		"metadata": resource{"name": name, "archetype": "sultan_bulb"},
+		"on":       resource{"on": true},
+		"dimming":  resource{"brightness": 100.0},
+		"color_temperature": resource{
+			"mirek":        366,
+			"mirek_valid":  true,
+			"mirek_schema": resource{"mirek_minimum": 153, "mirek_maximum": 454},
+		},
+	}
+	merge(light, extra)
+	b.add("light", light)
+	b.add("zigbee_connectivity", resource{
+		"id":     l.ZigbeeID,
+		"type":   "zigbee_connectivity",
+		"owner":  owner,
+		"status": "connected",
+	})
+
+	return l
+}
+
+// AddMotionSensor adds a motion sensor, reporting no motion, along with the light_level service that comes with it
+func (b *Bridge) AddMotionSensor(name string) Sensor {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	s := b.addSensor(name, "motion", resource{"motion": resource{"motion": false, "motion_valid": true}})
+	s.LightLevelID = newID()
+	device, _ := b.find("device", s.DeviceID)
+	device["services"] = append(device["services"].([]any), reference(s.LightLevelID, "light_level"))
+	b.add("light_level", resource{
+		"id":    s.LightLevelID,
+		"type":  "light_level",
+		"owner": reference(s.DeviceID, "device"),
+		"light": resource{"light_level": lightLevel(0), "light_level_valid": true},
+	})
+	return s
+}
+
+// AddContactSensor adds a door/window contact sensor, closed
+func (b *Bridge) AddContactSensor(name string) Sensor {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.addSensor(name, "contact", resource{"contact_report": resource{"state": "contact"}})
+}
+
+// must be called with the lock held
+func (b *Bridge) addSensor(name string, rtype string, state resource) Sensor {
+	s := Sensor{ID: newID(), DeviceID: newID()}
+	b.add("device", resource{
+		"id":       s.DeviceID,
+		"type":     "device",
+		"metadata": resource{"name": name, "archetype": "unknown_archetype"},
+		"services": []any{reference(s.ID, rtype)},
+	})
+	sensor := resource{
+		"id":    s.ID,
+		"type":  rtype,
+		"owner": reference(s.DeviceID, "device"),
+	}
+	merge(sensor, state)
+	b.add(rtype, sensor)
+
+	return s
+}
+
+// SetLightLevel changes the ambient light the motion sensor reports, sending the update event
+func (b *Bridge) SetLightLevel(s Sensor, lux float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.update("light_level", s.LightLevelID, resource{"light": resource{"light_level": lightLevel(lux), "light_level_valid": true}})
+}
+
+// the light_level the bridge reports for the lux, 10000*log10(lux)+1
+func lightLevel(lux float64) int {
+	if lux <= 0 {
+		return 0
+	}
+	return int(math.Round(10000*math.Log10(lux))) + 1
+}
+
+// SetMotion changes what the motion sensor reports, sending the update event
+func (b *Bridge) SetMotion(s Sensor, motion bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.update("motion", s.ID, resource{"motion": resource{"motion": motion, "motion_valid": true}})
+}
+
+// SetContactOpen opens or closes the contact sensor, sending the update event
+func (b *Bridge) SetContactOpen(s Sensor, open bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	state := "contact"
+	if open {
+		state = "no_contact"
+	}
+	b.update("contact", s.ID, resource{"contact_report": resource{"changed": time.Now().UTC().Format(time.RFC3339), "state": state}})
+}
+
+// AddDimmerSwitch adds a switch with four buttons
+func (b *Bridge) AddDimmerSwitch(name string) Switch {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.addSwitch(name, false)
+}
+
+// AddTapDial adds a tap dial, four buttons and a dial
+func (b *Bridge) AddTapDial(name string) Switch {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.addSwitch(name, true)
+}
+
+// must be called with the lock held
+func (b *Bridge) addSwitch(name string, dial bool) Switch {
+	s := Switch{DeviceID: newID()}
+	services := []any{}
+	for i := 1; i <= 4; i++ {
+		id := newID()
+		s.ButtonIDs = append(s.ButtonIDs, id)
+		services = append(services, reference(id, "button"))
+		b.add("button", resource{
+			"id":       id,
+			"type":     "button",
+			"owner":    reference(s.DeviceID, "device"),
+			"metadata": resource{"control_id": i},
+		})
+	}
+	if dial {
+		s.RotaryID = newID()
+		services = append(services, reference(s.RotaryID, "relative_rotary"))
+		b.add("relative_rotary", resource{
+			"id":    s.RotaryID,
+			"type":  "relative_rotary",
+			"owner": reference(s.DeviceID, "device"),
+		})
+	}
+	b.add("device", resource{
+		"id":       s.DeviceID,
+		"type":     "device",
+		"metadata": resource{"name": name, "archetype": "unknown_archetype"},
+		"services": services,
+	})
+
+	return s
+}
+
+// PressButton sends the event for a button of the switch (numbered from 1), e.g. short_release
+func (b *Bridge) PressButton(s Switch, button int, event string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.update("button", s.ButtonIDs[button-1], resource{"button": resource{
+		"button_report": resource{"event": event, "updated": time.Now().UTC().Format(time.RFC3339)},
+		"last_event":    event,
+	}})
+}
+
+// TurnDial sends the event for the dial being turned by the steps, clockwise if positive
+func (b *Bridge) TurnDial(s Switch, steps int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	direction := "clock_wise"
+	if steps < 0 {
+		direction = "counter_clock_wise"
+		steps = -steps
+	}
+	b.update("relative_rotary", s.RotaryID, resource{"relative_rotary": resource{
+		"rotary_report": resource{
+			"action":   "start",
+			"rotation": resource{"direction": direction, "steps": steps, "duration": 400},
+			"updated":  time.Now().UTC().Format(time.RFC3339),
+		},
+	}})
+}
+
+// AddRoom adds a room, rooms list the devices of their lights as children
+func (b *Bridge) AddRoom(name string, lights ...Light) Group {
+	children := []any{}
+	for _, l := range lights {
+		children = append(children, reference(l.DeviceID, "device"))
+	}
+	return b.addGroup("room", name, children)
+}
+
+// AddZone adds a zone, zones list their lights as children
+func (b *Bridge) AddZone(name string, lights ...Light) Group {
+	children := []any{}
+	for _, l := range lights {
+		children = append(children, reference(l.ID, "light"))
+	}
+	return b.addGroup("zone", name, children)
+}
+
+func (b *Bridge) addGroup(rtype string, name string, children []any) Group {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	g := Group{ID: newID(), Type: rtype, GroupedLightID: newID()}
+	b.add(rtype, resource{
+		"id":       g.ID,
+		"type":     rtype,
+		"metadata": resource{"name": name, "archetype": "other"},
+		"children": children,
+		"services": []any{reference(g.GroupedLightID, "grouped_light")},
+	})
+	b.add("grouped_light", resource{
+		"id":    g.GroupedLightID,
+		"type":  "grouped_light",
+		"owner": reference(g.ID, rtype),
+		"on":    resource{"on": true},
+	})
+
+	return g
+}
+
+// AddToGroup adds lights to an existing room or zone, sending the update event for its children
+func (b *Bridge) AddToGroup(g Group, lights ...Light) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	group, found := b.find(g.Type, g.ID)
+	if !found {
+		b.t.Fatalf("huetest: %s %s not found", g.Type, g.ID)
+	}
+	children, _ := group["children"].([]any)
+	children = slices.Clone(children)
+	for _, l := range lights {
+		if g.Type == "room" {
+			children = append(children, reference(l.DeviceID, "device"))
+		} else {
+			children = append(children, reference(l.ID, "light"))
+		}
+	}
+	b.update(g.Type, g.ID, resource{"children": children})
+}
+
+// RemoveFromGroup takes lights out of an existing room or zone, sending the update event for its children
+func (b *Bridge) RemoveFromGroup(g Group, lights ...Light) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	group, found := b.find(g.Type, g.ID)
+	if !found {
+		b.t.Fatalf("huetest: %s %s not found", g.Type, g.ID)
+	}
+	children, _ := group["children"].([]any)
+	children = slices.DeleteFunc(slices.Clone(children), func(c any) bool {
+		child, _ := c.(resource)
+		for _, l := range lights {
+			if child["rid"] == l.DeviceID || child["rid"] == l.ID {
+				return true
+			}
+		}
+		return false
+	})
+	b.update(g.Type, g.ID, resource{"children": children})
+}
+
+// AddScene adds a scene for the group with an action for each of its lights, returning the scene id
+func (b *Bridge) AddScene(name string, group Group) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	actions := []any{}
+	for _, lsID := range b.groupLightIDs(group.ID) {
+		actions = append(actions, resource{
+			"target": reference(lsID, "light"),
+			"action": resource{
+				"on":                resource{"on": true},
+				"dimming":           resource{"brightness": 100.0},
+				"color_temperature": resource{"mirek": 366},
+			},
+		})
+	}
+
+	id := newID()
+	b.add("scene", resource{
+		"id":       id,
+		"type":     "scene",
+		"metadata": resource{"name": name},
+		"group":    reference(group.ID, group.Type),
+		"actions":  actions,
+	})
+
+	return id
+}
+
+// Light returns the current state of the light
+func (b *Bridge) Light(l Light) LightState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	r, found := b.find("light", l.ID)
+	if !found {
+		b.t.Fatalf("huetest: light %s not found", l.ID)
+	}
+
+	state := LightState{}
+	state.On, _ = lookup(r, "on", "on").(bool)
+	state.Brightness, _ = lookup(r, "dimming", "brightness").(float64)
+	switch mirek := lookup(r, "color_temperature", "mirek").(type) {
+	case int:
+		state.Mirek = mirek
+	case float64:
+		state.Mirek = int(mirek)
+	}
+	state.X, _ = lookup(r, "color", "xy", "x").(float64)
+	state.Y, _ = lookup(r, "color", "xy", "y").(float64)
+	return state
+}
+
+// Powerup returns the state the light comes on in when it is powered on, as set by a custom powerup preset
+func (b *Bridge) Powerup(l Light) LightState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	r, found := b.find("light", l.ID)
+	if !found {
+		b.t.Fatalf("huetest: light %s not found", l.ID)
+	}
+
+	state := LightState{}
+	state.On, _ = lookup(r, "powerup", "on", "on", "on").(bool)
+	state.Brightness, _ = lookup(r, "powerup", "dimming", "dimming", "brightness").(float64)
+	if mirek, ok := lookup(r, "powerup", "color", "color_temperature", "mirek").(float64); ok {
+		state.Mirek = int(mirek)
+	}
+	state.X, _ = lookup(r, "powerup", "color", "color", "xy", "x").(float64)
+	state.Y, _ = lookup(r, "powerup", "color", "color", "xy", "y").(float64)
+	return state
+}
+
+// SetLightState changes the light as if someone had used the hue app or a switch, sending the update event
+func (b *Bridge) SetLightState(l Light, state LightState) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.update("light", l.ID, resource{
+		"on":                resource{"on": state.On},
+		"dimming":           resource{"brightness": state.Brightness},
+		"color_temperature": resource{"mirek": state.Mirek},
+	})
+}
+
+// SetLightStateWithoutEvent changes the light without sending the update event, as if the change was made while
+// nothing was connected to the event stream
+func (b *Bridge) SetLightStateWithoutEvent(l Light, state LightState) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	r, found := b.find("light", l.ID)
+	if !found {
+		b.t.Fatalf("huetest: light %s not found", l.ID)
+	}
+	merge(r, resource{
+		"on":                resource{"on": state.On},
+		"dimming":           resource{"brightness": state.Brightness},
+		"color_temperature": resource{"mirek": state.Mirek},
+	})
+}
+
+// SetReachable changes the light's zigbee connectivity, commands sent to an unreachable light are answered
+// with a 207 and have no effect
+func (b *Bridge) SetReachable(l Light, reachable bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.unreachable[l.ID] = !reachable
+	status := "connected"
+	if !reachable {
+		status = "connectivity_issue"
+	}
+	b.update("zigbee_connectivity", l.ZigbeeID, resource{"status": status})
+}
+
+func (b *Bridge) InjectFault(f Fault) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.faults = append(b.faults, &f)
+}
+
+func (b *Bridge) ClearFaults() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.faults = nil
+}
+
+// Requests returns every request received so far
+func (b *Bridge) Requests() []Request {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]Request{}, b.requests...)
+}
+
+// Disconnect drops the connection of every connected event stream, clients are expected to reconnect
+func (b *Bridge) Disconnect() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for s := range b.streams {
+		close(s.disconnect)
+		delete(b.streams, s)
+	}
+}
+
+// EventStreamConnections returns how many times a client has connected to the event stream
+func (b *Bridge) EventStreamConnections() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.connections
+}
+
+func (b *Bridge) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("hue-application-key") != ApplicationKey {
+		writeErrors(w, http.StatusForbidden, "unauthorized user")
+		return
+	}
+
+	if r.URL.Path == "/eventstream/clip/v2" {
+		b.serveEventStream(w, r)
+		return
+	}
+
+	body, _ := io.ReadAll(r.Body)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.requests = append(b.requests, Request{Method: r.Method, Path: r.URL.Path, Body: body})
+
+	if f := b.fault(r); f != nil {
+		writeErrors(w, f.StatusCode, f.Errors...)
+		return
+	}
+
+	path, found := strings.CutPrefix(r.URL.Path, "/clip/v2/resource/")
+	if !found {
+		writeErrors(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	rtype, id, _ := strings.Cut(path, "/")
+
+	switch {
+	case r.Method == http.MethodGet && id == "":
+		writeData(w, http.StatusOK, append([]resource{}, b.resources[rtype]...))
+
+	case r.Method == http.MethodGet:
+		res, found := b.find(rtype, id)
+		if !found {
+			writeErrors(w, http.StatusNotFound, "Not Found")
+			return
+		}
+		writeData(w, http.StatusOK, []resource{res})
+
+	case r.Method == http.MethodPut && id != "":
+		b.put(w, rtype, id, body)
+
+	default:
+		writeErrors(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (b *Bridge) put(w http.ResponseWriter, rtype string, id string, body []byte) {
+	if _, found := b.find(rtype, id); !found {
+		writeErrors(w, http.StatusNotFound, "Not Found")
+		return
+	}
+
+	changes := resource{}
+	if err := json.Unmarshal(body, &changes); err != nil {
+		writeErrors(w, http.StatusBadRequest, fmt.Sprintf("invalid json: %v", err))
+		return
+	}
+
+	result := []resource{reference(id, rtype)}
+
+	switch {
+	case rtype == "light" && b.unreachable[id]:
+		// the bridge accepts the command but the light never receives it
+		writeResponse(w, http.StatusMultiStatus, result, []string{"device (light) has communication issues, command (.on.on) may not have effect"})
+		return
+
+	case rtype == "grouped_light":
+		// broadcast to the lights of the room/zone, no feedback is given for lights that are unreachable
+		grouped, _ := b.find(rtype, id)
+		groupID, _ := lookup(grouped, "owner", "rid").(string)
+		for _, lsID := range b.groupLightIDs(groupID) {
+			if !b.unreachable[lsID] {
+				b.update("light", lsID, changes)
+			}
+		}
+	}
+
+	b.update(rtype, id, changes)
+	writeData(w, http.StatusOK, result)
+}
+
+func (b *Bridge) serveEventStream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeErrors(w, http.StatusInternalServerError, "streaming unsupported")
+		return
+	}
+
+	stream := &eventStream{events: make(chan []byte, 256), disconnect: make(chan struct{})}
+	b.mu.Lock()
+	b.streams[stream] = struct{}{}
+	b.connections++
+	b.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	// the bridge greets new connections with a comment
+	fmt.Fprint(w, ": hi\n\n")
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			b.mu.Lock()
+			delete(b.streams, stream)
+			b.mu.Unlock()
+			return
+		case <-stream.disconnect:
+			// drop the connection mid-stream, like a bridge restarting or a network failure
+			panic(http.ErrAbortHandler)
+		case <-b.closed:
+			return
+		case data := <-stream.events:
+			fmt.Fprintf(w, "id: %d:0\ndata: %s\n\n", time.Now().Unix(), data)
+			flusher.Flush()
+		}
+	}
+}
+
+// applies the changes to the resource and sends an update event for it, must be called with the lock held
+func (b *Bridge) update(rtype string, id string, changes resource) {
+	res, found := b.find(rtype, id)
+	if !found {
+		b.t.Errorf("huetest: %s %s not found", rtype, id)
+		return
+	}
+	merge(res, changes)
+
+	eventData := resource{"id": id, "type": rtype}
+	if owner, hasOwner := res["owner"]; hasOwner {
+		eventData["owner"] = owner
+	}
+	merge(eventData, changes)
+
+	b.publish([]resource{{
+		"creationtime": time.Now().UTC().Format(time.RFC3339),
+		"id":           newID(),
+		"type":         "update",
+		"data":         []any{eventData},
+	}})
+}
+
+func (b *Bridge) publish(batch []resource) {
+	data, err := json.Marshal(batch)
+	if err != nil {
+		b.t.Errorf("huetest: error encoding event: %v", err)
+		return
+	}
+	for s := range b.streams {
+		select {
+		case s.events <- data:
+		default:
+			b.t.Logf("huetest: event stream is full, dropping event")
+		}
+	}
+}
+
+// returns the first fault matching the request, using up one of its times, must be called with the lock held
+func (b *Bridge) fault(r *http.Request) *Fault {
+	for i, f := range b.faults {
+		if (f.Method == "" || f.Method == r.Method) && strings.HasPrefix(r.URL.Path, f.Path) {
+			if f.Times > 0 {
+				f.Times--
+				if f.Times == 0 {
+					b.faults = append(b.faults[:i], b.faults[i+1:]...)
+				}
+			}
+			return f
+		}
+	}
+	return nil
+}
+
+// the light service ids of a room (via its devices) or zone, must be called with the lock held
+func (b *Bridge) groupLightIDs(groupID string) []string {
+	group, found := b.find("room", groupID)
+	if !found {
+		group, found = b.find("zone", groupID)
+	}
+	if !found {
+		return nil
+	}
+
+	ids := []string{}
+	children, _ := group["children"].([]any)
+	for _, c := range children {
+		child, _ := c.(resource)
+		rid, _ := child["rid"].(string)
+		if child["rtype"] == "light" {
+			ids = append(ids, rid)
+			continue
+		}
+		device, _ := b.find("device", rid)
+		services, _ := device["services"].([]any)
+		for _, s := range services {
+			service, _ := s.(resource)
+			if service["rtype"] == "light" {
+				ids = append(ids, service["rid"].(string))
+			}
+		}
+	}
+	return ids
+}
+
+// adds the resource and sends an add event for it, must be called with the lock held
+func (b *Bridge) add(rtype string, r resource) {
+	b.resources[rtype] = append(b.resources[rtype], r)
+	b.publish([]resource{{
+		"creationtime": time.Now().UTC().Format(time.RFC3339),
+		"id":           newID(),
+		"type":         "add",
+		"data":         []any{r},
+	}})
+}
+
+func (b *Bridge) find(rtype string, id string) (resource, bool) {
+	for _, r := range b.resources[rtype] {
+		if r["id"] == id {
+			return r, true
+		}
+	}
+	return nil, false
+}
+
+// deep merges src into dst, src values that aren't objects replace those in dst
+func merge(dst resource, src resource) {
+	for k, v := range src {
+		srcObject, srcIsObject := v.(resource)
+		dstObject, dstIsObject := dst[k].(resource)
+		if srcIsObject && dstIsObject {
+			merge(dstObject, srcObject)
+			continue
+		}
+		if srcIsObject {
+			copied := resource{}
+			merge(copied, srcObject)
+			v = copied
+		}
+		dst[k] = v
+	}
+}
+
+func lookup(r resource, path ...string) any {
+	var v any = r
+	for _, key := range path {
+		object, ok := v.(resource)
+		if !ok {
+			return nil
+		}
+		v = object[key]
+	}
+	return v
+}
+
+func reference(id string, rtype string) resource {
+	return resource{"rid": id, "rtype": rtype}
+}
+
+var idCount atomic.Int64
+
+// returns a unique id in the uuid format the bridge uses
+func newID() string {
+	n := idCount.Add(1)
+	return fmt.Sprintf("%08x-0000-4000-8000-%012x", n, n)
+}
+
+func writeData(w http.ResponseWriter, statusCode int, data []resource) {
+	writeResponse(w, statusCode, data, nil)
+}
+
+func writeErrors(w http.ResponseWriter, statusCode int, descriptions ...string) {
+	writeResponse(w, statusCode, []resource{}, descriptions)
+}
+
+func writeResponse(w http.ResponseWriter, statusCode int, data []resource, descriptions []string) {
+	errs := []resource{}
+	for _, d := range descriptions {
+		errs = append(errs, resource{"description": d})
+	}
+
+	body := bytes.Buffer{}
+	_ = json.NewEncoder(&body).Encode(resource{"errors": errs, "data": data})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_, _ = w.Write(body.Bytes())
+}
@@ -2,10 +2,13 @@ package hugh
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"time"
 
 	"github.com/charmbracelet/log"
 	sse "github.com/r3labs/sse/v2"
+	"github.com/samber/lo"
 	"github.com/wheelibin/hugh/internal/constants"
 	"github.com/wheelibin/hugh/internal/models"
 )
@@ -13,32 +16,63 @@ import (
 type LogicalStateManager interface {
 	AddLights(lights []models.HughLight) error
 	AddScenes(scenes []models.HughScene) error
+	AddGroups(groups []models.HughGroup) error
+	// updates the lights, scenes and groups after a rediscovery, returning the ids of any new lights
+	SyncLights(lights []models.HughLight) ([]string, error)
+	SyncScenes(scenes []models.HughScene) error
+	SyncGroups(groups []models.HughGroup) error
+	AddSensors(sensors []models.HughSensor) error
+	SyncSensors(sensors []models.HughSensor) error
+	AddBindings(bindings []models.HughBinding) error
+	SyncBindings(bindings []models.HughBinding) error
 	UpdateAllTargetStates(schedules []models.Schedule, currentTime time.Time)
-	HandleBridgeEvent(event *sse.Event)
+	HandleBridgeEvent(ctx context.Context, event *sse.Event)
+	// handles the readings of the schedules' occupancy and daylight sensors, and their switches and dials
+	HandleSensorEvent(ctx context.Context, event *sse.Event, schedules []models.Schedule)
 }
 
 type PhysicalStateManager interface {
 	// discovers lights connected to the hue bridge
 	DiscoverLights(schedules []models.Schedule) ([]models.HughLight, error)
-	SetAllLightAndSceneStatesToTarget(currentTime time.Time) error
+	SetAllLightAndSceneStatesToTarget(ctx context.Context, currentTime time.Time) error
+	SetLightStateToTarget(ctx context.Context, lsID string, currentTime time.Time) error
 	DiscoverScenes(schedules []models.Schedule) ([]models.HughScene, error)
+	// discovers the rooms/zones that can be controlled with a single grouped_light command
+	DiscoverGroups(schedules []models.Schedule) ([]models.HughGroup, error)
+	// discovers the motion/contact sensors the schedules use for occupancy
+	DiscoverSensors(schedules []models.Schedule) ([]models.HughSensor, error)
+	// discovers the switch buttons and dials the bindings use
+	DiscoverBindings(bindings []models.Binding) ([]models.HughBinding, error)
 
 	SubscribeToLightUpdateEvents(chan *sse.Event)
 	UnsubscribeFromBrideEvents()
 }
 
+// a hue bridge and the state managers looking after its lights
+type Bridge struct {
+	Name                 string
+	LogicalStateManager  LogicalStateManager
+	PhysicalStateManager PhysicalStateManager
+}
+
 type Hugh struct {
-	logicalStateManager  LogicalStateManager
-	physicalStateManager PhysicalStateManager
-	logger               *log.Logger
-	schedules            []models.Schedule
+	logger    *log.Logger
+	schedules []models.Schedule
+	bindings  []models.Binding
+	bridges   []Bridge
+}
+
+// an event from the event stream of one of the bridges
+type bridgeEvent struct {
+	bridge *Bridge
+	event  *sse.Event
 }
 
 func NewHugh(
 	logger *log.Logger,
 	schedules []models.Schedule,
-	logicalStateManager LogicalStateManager,
-	physicalStateManager PhysicalStateManager,
+	bindings []models.Binding,
+	bridges []Bridge,
 ) *Hugh {
 
 	// filter out any disabled schedules
@@ -49,54 +83,236 @@ func NewHugh(
 		}
 	}
 
+	for _, s := range enabledSchedules {
+		if s.Bridge != "" && !lo.ContainsBy(bridges, func(b Bridge) bool { return b.Name == s.Bridge }) {
+			logger.Warn("Schedule is for a bridge that isn't configured, it will be ignored", "schedule", s.Name, "bridge", s.Bridge)
+		}
+	}
+
+	// and the bindings for them
+	var enabledBindings []models.Binding
+	for _, b := range bindings {
+		if !lo.ContainsBy(enabledSchedules, func(s models.Schedule) bool { return s.Name == b.Schedule }) {
+			logger.Warn("Binding is for a schedule that isn't configured or is disabled, it will be ignored", "device", b.Device, "schedule", b.Schedule)
+			continue
+		}
+		enabledBindings = append(enabledBindings, b)
+	}
+
 	return &Hugh{
-		logger:               logger,
-		schedules:            enabledSchedules,
-		logicalStateManager:  logicalStateManager,
-		physicalStateManager: physicalStateManager,
+		logger:    logger,
+		schedules: enabledSchedules,
+		bindings:  enabledBindings,
+		bridges:   bridges,
 	}
 }
 
+// the schedules that apply to the bridge, those naming it and those not naming any bridge
+func (h *Hugh) schedulesForBridge(b *Bridge) []models.Schedule {
+	return lo.Filter(h.schedules, func(s models.Schedule, _ int) bool {
+		return s.Bridge == "" || s.Bridge == b.Name
+	})
+}
+
+// the bindings for the schedules that apply to the bridge
+func (h *Hugh) bindingsForBridge(b *Bridge) []models.Binding {
+	schedules := h.schedulesForBridge(b)
+	return lo.Filter(h.bindings, func(binding models.Binding, _ int) bool {
+		return lo.ContainsBy(schedules, func(s models.Schedule) bool { return s.Name == binding.Schedule })
+	})
+}
+
 func (h *Hugh) Initialise() error {
 	h.logger.Debug("Hugh.Initialise")
 
-	lights, err := h.physicalStateManager.DiscoverLights(h.schedules)
+	for i := range h.bridges {
+		b := &h.bridges[i]
+		if err := h.initialiseBridge(b); err != nil {
+			return fmt.Errorf("error initialising bridge %s: %w", b.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func (h *Hugh) initialiseBridge(b *Bridge) error {
+	schedules := h.schedulesForBridge(b)
+
+	lights, err := b.PhysicalStateManager.DiscoverLights(schedules)
 	if err != nil {
 		return err
 	}
-	err = h.logicalStateManager.AddLights(lights)
+	err = b.LogicalStateManager.AddLights(lights)
 	if err != nil {
 		return err
 	}
 
-	scenes, err := h.physicalStateManager.DiscoverScenes(h.schedules)
+	groups, err := b.PhysicalStateManager.DiscoverGroups(schedules)
 	if err != nil {
 		return err
 	}
-	err = h.logicalStateManager.AddScenes(scenes)
+	err = b.LogicalStateManager.AddGroups(groups)
 	if err != nil {
 		return err
 	}
 
-	h.logicalStateManager.UpdateAllTargetStates(h.schedules, time.Now())
+	scenes, err := b.PhysicalStateManager.DiscoverScenes(schedules)
+	if err != nil {
+		return err
+	}
+	err = b.LogicalStateManager.AddScenes(scenes)
+	if err != nil {
+		return err
+	}
+
+	sensors, err := b.PhysicalStateManager.DiscoverSensors(schedules)
+	if err != nil {
+		return err
+	}
+	err = b.LogicalStateManager.AddSensors(sensors)
+	if err != nil {
+		return err
+	}
+
+	bindings, err := b.PhysicalStateManager.DiscoverBindings(h.bindingsForBridge(b))
+	if err != nil {
+		return err
+	}
+	err = b.LogicalStateManager.AddBindings(bindings)
+	if err != nil {
+		return err
+	}
+
+	b.LogicalStateManager.UpdateAllTargetStates(schedules, time.Now())
 
 	return nil
 }
 
+// brings the bridge's lights, groups and scenes up to date after they changed on the bridge, new lights are set to
+// their target straight away rather than at the next update
+func (h *Hugh) rediscoverBridge(ctx context.Context, b *Bridge) error {
+	schedules := h.schedulesForBridge(b)
+
+	lights, err := b.PhysicalStateManager.DiscoverLights(schedules)
+	if err != nil {
+		return err
+	}
+	added, err := b.LogicalStateManager.SyncLights(lights)
+	if err != nil {
+		return err
+	}
+
+	groups, err := b.PhysicalStateManager.DiscoverGroups(schedules)
+	if err != nil {
+		return err
+	}
+	err = b.LogicalStateManager.SyncGroups(groups)
+	if err != nil {
+		return err
+	}
+
+	scenes, err := b.PhysicalStateManager.DiscoverScenes(schedules)
+	if err != nil {
+		return err
+	}
+	err = b.LogicalStateManager.SyncScenes(scenes)
+	if err != nil {
+		return err
+	}
+
+	sensors, err := b.PhysicalStateManager.DiscoverSensors(schedules)
+	if err != nil {
+		return err
+	}
+	err = b.LogicalStateManager.SyncSensors(sensors)
+	if err != nil {
+		return err
+	}
+
+	bindings, err := b.PhysicalStateManager.DiscoverBindings(h.bindingsForBridge(b))
+	if err != nil {
+		return err
+	}
+	err = b.LogicalStateManager.SyncBindings(bindings)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	b.LogicalStateManager.UpdateAllTargetStates(schedules, now)
+
+	go func() {
+		for _, lsID := range added {
+			if ctx.Err() != nil {
+				return
+			}
+			err := b.PhysicalStateManager.SetLightStateToTarget(ctx, lsID, now)
+			if err != nil {
+				h.logger.Error(err, "bridge", b.Name)
+			}
+		}
+	}()
+
+	return nil
+}
+
+// the resource types whose changes can change which lights, groups and scenes hugh controls
+var discoveredTypes = []string{
+	constants.EventTypeLight,
+	constants.EventTypeDevice,
+	constants.EventTypeRoom,
+	constants.EventTypeZone,
+	constants.EventTypeScene,
+}
+
+// whether the event adds, removes or renames anything hugh discovers, or changes the lights in a room/zone
+func rediscoveryNeeded(event *sse.Event) bool {
+	events := []models.Event{}
+	if err := json.Unmarshal(event.Data, &events); err != nil {
+		return false
+	}
+
+	for _, evt := range events {
+		for _, eventData := range evt.Data {
+			if !lo.Contains(discoveredTypes, eventData.Type) {
+				continue
+			}
+			switch evt.Type {
+			case constants.EventBatchTypeAdd, constants.EventBatchTypeDelete:
+				return true
+			case constants.EventBatchTypeUpdate:
+				if eventData.Metadata != nil || eventData.Children != nil {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
 func (h *Hugh) Run(ctx context.Context) {
 	h.logger.Debug("Hugh.Run")
 
-	// start listening to hue bridge events
-	eventChannel := make(chan *sse.Event)
-	h.physicalStateManager.SubscribeToLightUpdateEvents(eventChannel)
-	defer h.physicalStateManager.UnsubscribeFromBrideEvents()
+	// start listening to the event stream of every bridge, merging them into one channel
+	events := make(chan bridgeEvent)
+	for i := range h.bridges {
+		b := &h.bridges[i]
+		eventChannel := make(chan *sse.Event)
+		b.PhysicalStateManager.SubscribeToLightUpdateEvents(eventChannel)
+		defer b.PhysicalStateManager.UnsubscribeFromBrideEvents()
+		go forwardEvents(ctx, b, eventChannel, events)
+	}
 
 	// start the update timers
 	lightUpdateTimer := time.NewTicker(constants.MainUpdateInterval)
 	defer lightUpdateTimer.Stop()
 
+	// rediscoveries waiting for their bridge's changes to settle
+	rediscover := make(chan *Bridge)
+	pendingRediscovery := map[*Bridge]bool{}
+
 	// update all lights straight away
-	go h.updateAll()
+	h.updateAll(ctx)
 
 	// start the main application loop
 	for {
@@ -105,23 +321,66 @@ func (h *Hugh) Run(ctx context.Context) {
 			h.logger.Info("Hugh.Run: stop signal received")
 			return
 
-		case event := <-eventChannel:
-			h.logger.Debug("Hugh.Run: Received hue bridge event")
-			h.logicalStateManager.HandleBridgeEvent(event)
+		case e := <-events:
+			h.logger.Debug("Hugh.Run: Received hue bridge event", "bridge", e.bridge.Name)
+			e.bridge.LogicalStateManager.HandleBridgeEvent(ctx, e.event)
+			e.bridge.LogicalStateManager.HandleSensorEvent(ctx, e.event, h.schedulesForBridge(e.bridge))
+
+			if rediscoveryNeeded(e.event) && !pendingRediscovery[e.bridge] {
+				pendingRediscovery[e.bridge] = true
+				b := e.bridge
+				time.AfterFunc(constants.RediscoveryDelay, func() {
+					select {
+					case rediscover <- b:
+					case <-ctx.Done():
+					}
+				})
+			}
+
+		case b := <-rediscover:
+			delete(pendingRediscovery, b)
+			h.logger.Info("Lights, rooms, zones or scenes changed on the bridge, rediscovering...", "bridge", b.Name)
+			if err := h.rediscoverBridge(ctx, b); err != nil {
+				h.logger.Error(fmt.Errorf("error rediscovering bridge %s: %w", b.Name, err))
+			}
 
 		case t := <-lightUpdateTimer.C:
 			h.logger.Debug("Hugh.Run: calculating new target states...", "t", t)
-			h.logicalStateManager.UpdateAllTargetStates(h.schedules, t)
+			for i := range h.bridges {
+				b := &h.bridges[i]
+				b.LogicalStateManager.UpdateAllTargetStates(h.schedulesForBridge(b), t)
+			}
 
 			h.logger.Debug("Hugh.Run: Setting lights to target states...")
-			go h.updateAll()
+			h.updateAll(ctx)
 		}
 	}
 }
 
-func (h *Hugh) updateAll() {
-	err := h.physicalStateManager.SetAllLightAndSceneStatesToTarget(time.Now())
-	if err != nil {
-		h.logger.Error(err)
+func forwardEvents(ctx context.Context, b *Bridge, from <-chan *sse.Event, to chan<- bridgeEvent) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-from:
+			select {
+			case to <- bridgeEvent{bridge: b, event: event}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// sets every bridge's lights to their targets, the bridges are updated concurrently as each has its own rate limits
+func (h *Hugh) updateAll(ctx context.Context) {
+	for i := range h.bridges {
+		b := &h.bridges[i]
+		go func() {
+			err := b.PhysicalStateManager.SetAllLightAndSceneStatesToTarget(ctx, time.Now())
+			if err != nil {
+				h.logger.Error(err, "bridge", b.Name)
+			}
+		}()
 	}
 }
@@ -0,0 +1,351 @@
+package hugh_test
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/log"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/samber/lo"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wheelibin/hugh/internal/concurrency"
+	"github.com/wheelibin/hugh/internal/constants"
+	"github.com/wheelibin/hugh/internal/hue"
+	"github.com/wheelibin/hugh/internal/huetest"
+	"github.com/wheelibin/hugh/internal/hugh"
+	"github.com/wheelibin/hugh/internal/logicalStateManager"
+	"github.com/wheelibin/hugh/internal/models"
+	"github.com/wheelibin/hugh/internal/physicalStateManager"
+	"github.com/wheelibin/hugh/internal/repos"
+	"github.com/wheelibin/hugh/internal/schedule"
+)
+
+// the state every light is set to by the "constant" day pattern
+var constantTarget = huetest.LightState{On: true, Brightness: 50, Mirek: 400}
+
+func setConstantDayPattern(t *testing.T) {
+	viper.Set("dayPatterns", map[string]any{
+		"constant": map[string]any{
+			"type":    "static",
+			"default": map[string]any{"time": "00:00", "temperature": 2500, "brightness": 50},
+			"pattern": []any{map[string]any{"time": "12:00", "temperature": 2500, "brightness": 50}},
+		},
+	})
+	t.Cleanup(func() { viper.Set("dayPatterns", nil) })
+}
+
+// wires up hugh against the fake bridges the way main does, running it until the test finishes
+func startHugh(t *testing.T, schedules []models.Schedule, bridges map[string]*huetest.Bridge) {
+	startHughWithBindings(t, schedules, nil, bridges)
+}
+
+func startHughWithBindings(t *testing.T, schedules []models.Schedule, bindings []models.Binding, bridges map[string]*huetest.Bridge) {
+	setConstantDayPattern(t)
+	logger := log.New(io.Discard)
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	// each connection to an in-memory database gets its own database
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	lrepo, err := repos.NewLightRepo(logger, db)
+	require.NoError(t, err)
+	scheduleService := schedule.NewScheduleService(logger, lrepo)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	names := lo.Keys(bridges)
+	slices.Sort(names)
+
+	hughBridges := []hugh.Bridge{}
+	for _, name := range names {
+		fake := bridges[name]
+		config := fake.Config(name)
+
+		transport, err := hue.NewBridgeTransport(hue.TLSOptions{BridgeID: config.ID, RootCAs: fake.RootCAs()})
+		require.NoError(t, err)
+
+		bridgeRepo := lrepo.ForBridge(name)
+		hueService := hue.NewHueAPIService(logger, config, transport)
+		commandScheduler := concurrency.NewCommandScheduler(concurrency.RateLimits{
+			LightCommandsPerSecond: 1000,
+			GroupCommandsPerSecond: 1000,
+			InitialBackoff:         10 * time.Millisecond,
+			MaxBackoff:             50 * time.Millisecond,
+		}, hue.ShouldBackOff)
+		psm := physicalstatemanager.NewPhysicalStateManager(logger, hueService, bridgeRepo, commandScheduler)
+		lsm := logicalstatemanager.NewLogicalStateManager(logger, bridgeRepo, scheduleService, psm)
+		go commandScheduler.Run(ctx)
+
+		hughBridges = append(hughBridges, hugh.Bridge{Name: name, LogicalStateManager: lsm, PhysicalStateManager: psm})
+	}
+
+	h := hugh.NewHugh(logger, schedules, bindings, hughBridges)
+	require.NoError(t, h.Initialise())
+
+	stopped := make(chan struct{})
+	go func() {
+		h.Run(ctx)
+		close(stopped)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-stopped
+	})
+}
+
+func requested(bridge *huetest.Bridge, method string, path string) bool {
+	return lo.ContainsBy(bridge.Requests(), func(r huetest.Request) bool { return r.Method == method && r.Path == path })
+}
+
+// whether the light was sent a state, rather than just its power-on behaviour
+func lightStateRequested(bridge *huetest.Bridge, l huetest.Light) bool {
+	return lo.ContainsBy(bridge.Requests(), func(r huetest.Request) bool {
+		return r.Method == http.MethodPut && r.Path == "/clip/v2/resource/light/"+l.ID && !strings.Contains(string(r.Body), "powerup")
+	})
+}
+
+func Test_Hugh(t *testing.T) {
+
+	t.Run("should set every scheduled light to its target, using one command for a room", func(t *testing.T) {
+		bridge := huetest.NewBridge(t)
+		kitchen1 := bridge.AddLight("Kitchen 1")
+		kitchen2 := bridge.AddLight("Kitchen 2")
+		bedroom := bridge.AddLight("Bedroom")
+		room := bridge.AddRoom("Kitchen", kitchen1, kitchen2)
+		bridge.AddRoom("Bedroom", bedroom)
+		sceneID := bridge.AddScene("Hugh_Kitchen", room)
+
+		startHugh(t, []models.Schedule{{Name: "Kitchen", Rooms: []string{"Kitchen"}, DayPattern: models.DayPatternSelector{"default": "constant"}}}, map[string]*huetest.Bridge{"house": bridge})
+
+		assert.Eventually(t, func() bool {
+			return bridge.Light(kitchen1) == constantTarget && bridge.Light(kitchen2) == constantTarget
+		}, 5*time.Second, 10*time.Millisecond)
+		assert.True(t, requested(bridge, http.MethodPut, "/clip/v2/resource/grouped_light/"+room.GroupedLightID))
+		assert.False(t, lightStateRequested(bridge, kitchen1))
+		assert.Eventually(t, func() bool {
+			return requested(bridge, http.MethodPut, "/clip/v2/resource/scene/"+sceneID)
+		}, 5*time.Second, 10*time.Millisecond)
+		assert.NotEqual(t, constantTarget, bridge.Light(bedroom), "unscheduled lights should be left alone")
+	})
+
+	t.Run("should fall back to light commands when the room command fails, skipping unreachable lights", func(t *testing.T) {
+		bridge := huetest.NewBridge(t)
+		kitchen1 := bridge.AddLight("Kitchen 1")
+		kitchen2 := bridge.AddLight("Kitchen 2")
+		bridge.AddRoom("Kitchen", kitchen1, kitchen2)
+		bridge.SetReachable(kitchen1, false)
+		bridge.InjectFault(huetest.Fault{Method: http.MethodPut, Path: "/clip/v2/resource/grouped_light/", StatusCode: http.StatusBadRequest, Errors: []string{"invalid body"}})
+
+		startHugh(t, []models.Schedule{{Name: "Kitchen", Rooms: []string{"Kitchen"}, DayPattern: models.DayPatternSelector{"default": "constant"}}}, map[string]*huetest.Bridge{"house": bridge})
+
+		assert.Eventually(t, func() bool {
+			return bridge.Light(kitchen2) == constantTarget
+		}, 5*time.Second, 10*time.Millisecond)
+		assert.True(t, lightStateRequested(bridge, kitchen1))
+		assert.NotEqual(t, constantTarget, bridge.Light(kitchen1))
+	})
+
+	t.Run("should back off and retry when the bridge is rate limiting", func(t *testing.T) {
+		bridge := huetest.NewBridge(t)
+		kitchen1 := bridge.AddLight("Kitchen 1")
+		kitchen2 := bridge.AddLight("Kitchen 2")
+		bridge.AddRoom("Kitchen", kitchen1, kitchen2)
+		bridge.InjectFault(huetest.Fault{Method: http.MethodPut, StatusCode: http.StatusTooManyRequests, Times: 3})
+
+		startHugh(t, []models.Schedule{{Name: "Kitchen", Rooms: []string{"Kitchen"}, DayPattern: models.DayPatternSelector{"default": "constant"}}}, map[string]*huetest.Bridge{"house": bridge})
+
+		assert.Eventually(t, func() bool {
+			return bridge.Light(kitchen1) == constantTarget && bridge.Light(kitchen2) == constantTarget
+		}, 5*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("should control the rooms of each bridge, only on the bridge a schedule names", func(t *testing.T) {
+		house := huetest.NewBridge(t)
+		houseKitchen := house.AddLight("Kitchen")
+		house.AddRoom("Kitchen", houseKitchen)
+		garage := huetest.NewBridge(t)
+		garageKitchen := garage.AddLight("Kitchen")
+		garage.AddRoom("Kitchen", garageKitchen)
+		garageWorkshop := garage.AddLight("Workshop")
+		garage.AddRoom("Workshop", garageWorkshop)
+
+		startHugh(t, []models.Schedule{
+			{Name: "Kitchen", Bridge: "house", Rooms: []string{"Kitchen"}, DayPattern: models.DayPatternSelector{"default": "constant"}},
+			{Name: "Workshop", Rooms: []string{"Workshop"}, DayPattern: models.DayPatternSelector{"default": "constant"}},
+		}, map[string]*huetest.Bridge{"house": house, "garage": garage})
+
+		assert.Eventually(t, func() bool {
+			return house.Light(houseKitchen) == constantTarget && garage.Light(garageWorkshop) == constantTarget
+		}, 5*time.Second, 10*time.Millisecond)
+		assert.NotEqual(t, constantTarget, garage.Light(garageKitchen))
+	})
+
+	t.Run("should reconnect to the event stream when the connection drops", func(t *testing.T) {
+		bridge := huetest.NewBridge(t)
+		kitchen := bridge.AddLight("Kitchen")
+		bridge.AddRoom("Kitchen", kitchen)
+
+		startHugh(t, []models.Schedule{{Name: "Kitchen", Rooms: []string{"Kitchen"}, DayPattern: models.DayPatternSelector{"default": "constant"}}}, map[string]*huetest.Bridge{"house": bridge})
+
+		require.Eventually(t, func() bool { return bridge.EventStreamConnections() == 1 }, 5*time.Second, 10*time.Millisecond)
+		bridge.Disconnect()
+		assert.Eventually(t, func() bool { return bridge.EventStreamConnections() == 2 }, 5*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("should catch up on changes missed while the event stream was disconnected", func(t *testing.T) {
+		bridge := huetest.NewBridge(t)
+		kitchen := bridge.AddLight("Kitchen")
+		bridge.AddRoom("Kitchen", kitchen)
+
+		startHugh(t, []models.Schedule{{Name: "Kitchen", Rooms: []string{"Kitchen"}, DayPattern: models.DayPatternSelector{"default": "constant"}}}, map[string]*huetest.Bridge{"house": bridge})
+		require.Eventually(t, func() bool { return bridge.Light(kitchen) == constantTarget }, 5*time.Second, 10*time.Millisecond)
+
+		// switched off by hand, hugh leaves it off
+		bridge.SetLightState(kitchen, huetest.LightState{On: false, Brightness: 50, Mirek: 400})
+		// events straight after an update from hugh are taken to be caused by it
+		time.Sleep(constants.HughUpdateWindow)
+
+		// switched back on at the wall while the stream is down, so hugh never gets the event
+		bridge.Disconnect()
+		bridge.SetLightStateWithoutEvent(kitchen, huetest.LightState{On: true, Brightness: 100, Mirek: 366})
+
+		assert.Eventually(t, func() bool { return bridge.Light(kitchen) == constantTarget }, 5*time.Second, 10*time.Millisecond)
+		assert.Equal(t, 2, bridge.EventStreamConnections())
+	})
+
+	t.Run("should pick up lights added to a room after starting", func(t *testing.T) {
+		bridge := huetest.NewBridge(t)
+		kitchen1 := bridge.AddLight("Kitchen 1")
+		room := bridge.AddRoom("Kitchen", kitchen1)
+
+		startHugh(t, []models.Schedule{{Name: "Kitchen", Rooms: []string{"Kitchen"}, DayPattern: models.DayPatternSelector{"default": "constant"}}}, map[string]*huetest.Bridge{"house": bridge})
+		require.Eventually(t, func() bool { return bridge.Light(kitchen1) == constantTarget }, 5*time.Second, 10*time.Millisecond)
+
+		kitchen2 := bridge.AddLight("Kitchen 2")
+		bridge.AddToGroup(room, kitchen2)
+
+		// well before the next scheduled update
+		assert.Eventually(t, func() bool { return bridge.Light(kitchen2) == constantTarget }, 5*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("should stop controlling lights removed from a room after starting", func(t *testing.T) {
+		bridge := huetest.NewBridge(t)
+		kitchen1 := bridge.AddLight("Kitchen 1")
+		kitchen2 := bridge.AddLight("Kitchen 2")
+		room := bridge.AddRoom("Kitchen", kitchen1, kitchen2)
+
+		startHugh(t, []models.Schedule{{Name: "Kitchen", Rooms: []string{"Kitchen"}, DayPattern: models.DayPatternSelector{"default": "constant"}}}, map[string]*huetest.Bridge{"house": bridge})
+		require.Eventually(t, func() bool {
+			return bridge.Light(kitchen1) == constantTarget && bridge.Light(kitchen2) == constantTarget
+		}, 5*time.Second, 10*time.Millisecond)
+
+		bridge.RemoveFromGroup(room, kitchen2)
+		// long enough for the rediscovery, and for the switching below not to be taken as caused by the update
+		// hugh makes after it (the bridge's event times are to the second)
+		time.Sleep(constants.RediscoveryDelay + constants.HughUpdateWindow + 1500*time.Millisecond)
+		sent := len(bridge.Requests())
+
+		// both switched off and on again, hugh only sets the light still in the room to its target
+		off := huetest.LightState{On: false, Brightness: 100, Mirek: 366}
+		on := huetest.LightState{On: true, Brightness: 100, Mirek: 366}
+		bridge.SetLightState(kitchen1, off)
+		bridge.SetLightState(kitchen2, off)
+		bridge.SetLightState(kitchen1, on)
+		bridge.SetLightState(kitchen2, on)
+
+		require.Eventually(t, func() bool { return bridge.Light(kitchen1) == constantTarget }, 5*time.Second, 10*time.Millisecond)
+		time.Sleep(100 * time.Millisecond)
+		assert.Equal(t, on, bridge.Light(kitchen2))
+		assert.False(t, lo.ContainsBy(bridge.Requests()[sent:], func(r huetest.Request) bool {
+			return r.Method == http.MethodPut && strings.Contains(r.Path, kitchen2.ID)
+		}))
+	})
+
+	t.Run("should turn a room on when motion is detected and off again once it stops", func(t *testing.T) {
+		bridge := huetest.NewBridge(t)
+		hall := bridge.AddLight("Hall")
+		bridge.AddRoom("Hall", hall)
+		sensor := bridge.AddMotionSensor("Hall sensor")
+
+		startHugh(t, []models.Schedule{{
+			Name:       "Hall",
+			Rooms:      []string{"Hall"},
+			DayPattern: models.DayPatternSelector{"default": "constant"},
+			Occupancy:  &models.ScheduleOccupancy{Sensors: []string{"Hall sensor"}, Timeout: time.Second},
+		}}, map[string]*huetest.Bridge{"house": bridge})
+
+		// nobody has been seen since starting, so hugh can't tell the hall is empty and leaves it on
+		require.Eventually(t, func() bool { return bridge.Light(hall) == constantTarget }, 5*time.Second, 10*time.Millisecond)
+		time.Sleep(constants.HughUpdateWindow)
+
+		bridge.SetMotion(sensor, true)
+		require.Eventually(t, func() bool { return bridge.Light(hall) == constantTarget }, 5*time.Second, 10*time.Millisecond)
+
+		bridge.SetMotion(sensor, false)
+		assert.Eventually(t, func() bool { return !bridge.Light(hall).On }, 5*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("should return a room to its schedule when a switch button bound to resume is pressed", func(t *testing.T) {
+		bridge := huetest.NewBridge(t)
+		kitchen := bridge.AddLight("Kitchen")
+		bridge.AddRoom("Kitchen", kitchen)
+		dimmer := bridge.AddDimmerSwitch("Kitchen switch")
+
+		startHughWithBindings(t,
+			[]models.Schedule{{Name: "Kitchen", Rooms: []string{"Kitchen"}, DayPattern: models.DayPatternSelector{"default": "constant"}}},
+			[]models.Binding{{Device: "Kitchen switch", Button: 1, Action: constants.ActionResume, Schedule: "Kitchen"}},
+			map[string]*huetest.Bridge{"house": bridge})
+		require.Eventually(t, func() bool { return bridge.Light(kitchen) == constantTarget }, 5*time.Second, 10*time.Millisecond)
+		time.Sleep(constants.HughUpdateWindow)
+
+		// dimmed by hand, hugh leaves it
+		bridge.SetLightState(kitchen, huetest.LightState{On: true, Brightness: 10, Mirek: 400})
+		time.Sleep(100 * time.Millisecond)
+		require.Equal(t, float64(10), bridge.Light(kitchen).Brightness)
+
+		// other presses of the button do nothing
+		bridge.PressButton(dimmer, 1, "initial_press")
+		bridge.PressButton(dimmer, 2, constants.ButtonEventShortRelease)
+		time.Sleep(100 * time.Millisecond)
+		require.Equal(t, float64(10), bridge.Light(kitchen).Brightness)
+
+		bridge.PressButton(dimmer, 1, constants.ButtonEventShortRelease)
+		assert.Eventually(t, func() bool { return bridge.Light(kitchen) == constantTarget }, 5*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("should raise the brightness of a room as its dial is turned", func(t *testing.T) {
+		bridge := huetest.NewBridge(t)
+		kitchen := bridge.AddLight("Kitchen")
+		bridge.AddRoom("Kitchen", kitchen)
+		dial := bridge.AddTapDial("Kitchen dial")
+
+		startHughWithBindings(t,
+			[]models.Schedule{{Name: "Kitchen", Rooms: []string{"Kitchen"}, DayPattern: models.DayPatternSelector{"default": "constant"}}},
+			[]models.Binding{{Device: "Kitchen dial", Action: constants.ActionBrightness, Schedule: "Kitchen"}},
+			map[string]*huetest.Bridge{"house": bridge})
+		require.Eventually(t, func() bool { return bridge.Light(kitchen) == constantTarget }, 5*time.Second, 10*time.Millisecond)
+
+		bridge.TurnDial(dial, 100)
+		assert.Eventually(t, func() bool { return bridge.Light(kitchen).Brightness == 60 }, 5*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("should set lights to come on at their target when powered on at the wall", func(t *testing.T) {
+		bridge := huetest.NewBridge(t)
+		kitchen := bridge.AddLight("Kitchen")
+		bridge.AddRoom("Kitchen", kitchen)
+
+		startHugh(t, []models.Schedule{{Name: "Kitchen", Rooms: []string{"Kitchen"}, DayPattern: models.DayPatternSelector{"default": "constant"}}}, map[string]*huetest.Bridge{"house": bridge})
+
+		assert.Eventually(t, func() bool { return bridge.Powerup(kitchen) == constantTarget }, 5*time.Second, 10*time.Millisecond)
+	})
+}
@@ -0,0 +1,31 @@
+package hugh
+
+import (
+	"testing"
+
+	sse "github.com/r3labs/sse/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_rediscoveryNeeded(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want bool
+	}{
+		{"light added", `[{"type":"add","data":[{"id":"l1","type":"light","metadata":{"name":"Kitchen 3"}}]}]`, true},
+		{"device deleted", `[{"type":"delete","data":[{"id":"d1","type":"device"}]}]`, true},
+		{"scene deleted", `[{"type":"delete","data":[{"id":"s1","type":"scene"}]}]`, true},
+		{"light taken out of a room", `[{"type":"update","data":[{"id":"r1","type":"room","children":[{"rid":"d1","rtype":"device"}]}]}]`, true},
+		{"zone renamed", `[{"type":"update","data":[{"id":"z1","type":"zone","metadata":{"name":"Downstairs"}}]}]`, true},
+		{"light switched and dimmed", `[{"type":"update","data":[{"id":"l1","type":"light","on":{"on":true},"dimming":{"brightness":40}}]}]`, false},
+		{"motion sensor added", `[{"type":"add","data":[{"id":"m1","type":"motion"}]}]`, false},
+		{"unrelated update alongside a room change", `[{"type":"update","data":[{"id":"l1","type":"light","on":{"on":false}}]},{"type":"update","data":[{"id":"r1","type":"room","children":[]}]}]`, true},
+		{"not json", `keep-alive`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, rediscoveryNeeded(&sse.Event{Data: []byte(tt.data)}))
+		})
+	}
+}
@@ -0,0 +1,212 @@
+package logicalstatemanager
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/samber/lo"
+	"github.com/wheelibin/hugh/internal/constants"
+	"github.com/wheelibin/hugh/internal/models"
+)
+
+// the changes made to a schedule from its switches and dials, each lasting until its time
+type adjustment struct {
+	// the schedule's targets are left as they are until then
+	pausedUntil time.Time
+	// the step skipped to, the targets are those of the step until it is reached
+	skippedTo time.Time
+	// the lights fade off from windDownFrom to windDownUntil, then stay off until windDownOffUntil
+	windDownFrom     time.Time
+	windDownUntil    time.Time
+	windDownOffUntil time.Time
+	// added to the brightness until the end of the interval the dial was turned in
+	brightnessOffset int
+	offsetUntil      time.Time
+}
+
+func (a adjustment) paused(t time.Time) bool {
+	return t.Before(a.pausedUntil)
+}
+
+// the time the targets are calculated for
+func (a adjustment) targetTime(t time.Time) time.Time {
+	if t.Before(a.skippedTo) {
+		return a.skippedTo
+	}
+	return t
+}
+
+func (a adjustment) apply(target models.LightState, t time.Time) models.LightState {
+	if t.Before(a.offsetUntil) {
+		target.Brightness = min(max(target.Brightness+a.brightnessOffset, 1), 100)
+	}
+
+	switch {
+	case !t.Before(a.windDownFrom) && t.Before(a.windDownUntil):
+		remaining := float64(a.windDownUntil.Sub(t)) / float64(a.windDownUntil.Sub(a.windDownFrom))
+		target.Brightness = int(math.Round(float64(target.Brightness) * remaining))
+		if target.Brightness < 1 {
+			target.Brightness = 1
+			target.On = false
+		}
+	case !t.Before(a.windDownUntil) && t.Before(a.windDownOffUntil):
+		target.On = false
+	}
+
+	return target
+}
+
+func (m *LogicalStateManager) adjustment(scheduleName string) adjustment {
+	m.adjustmentsMu.Lock()
+	defer m.adjustmentsMu.Unlock()
+	return m.adjustments[scheduleName]
+}
+
+func (m *LogicalStateManager) adjust(scheduleName string, change func(a *adjustment)) {
+	m.adjustmentsMu.Lock()
+	defer m.adjustmentsMu.Unlock()
+	a := m.adjustments[scheduleName]
+	change(&a)
+	m.adjustments[scheduleName] = a
+}
+
+func (m *LogicalStateManager) AddBindings(bindings []models.HughBinding) error {
+	return m.dbAccess.AddBindings(bindings)
+}
+
+func (m *LogicalStateManager) SyncBindings(bindings []models.HughBinding) error {
+	return m.dbAccess.SyncBindings(bindings)
+}
+
+// performs the actions bound to the button press or dial turn
+func (m *LogicalStateManager) handleSwitchEvent(ctx context.Context, eventData models.EventData, schedules []models.Schedule) {
+	bindings, err := m.dbAccess.GetBindings(eventData.Id)
+	if err != nil {
+		m.logger.Error(err)
+		return
+	}
+
+	now := time.Now()
+	for _, b := range bindings {
+		sch, found := lo.Find(schedules, func(s models.Schedule) bool { return s.Name == b.ScheduleName })
+		if !found {
+			continue
+		}
+
+		switch {
+		case eventData.Button != nil && b.Event == buttonEvent(eventData):
+			m.performAction(ctx, sch, b, now)
+
+		case eventData.RelativeRotary != nil && eventData.RelativeRotary.RotaryReport != nil && b.Action == constants.ActionBrightness:
+			rotation := eventData.RelativeRotary.RotaryReport.Rotation
+			steps := rotation.Steps
+			if rotation.Direction != constants.RotaryDirectionClockwise {
+				steps = -steps
+			}
+			m.adjustBrightness(ctx, sch, steps, now)
+		}
+	}
+}
+
+func buttonEvent(eventData models.EventData) string {
+	if eventData.Button.ButtonReport != nil {
+		return eventData.Button.ButtonReport.Event
+	}
+	return eventData.Button.LastEvent
+}
+
+func (m *LogicalStateManager) performAction(ctx context.Context, sch models.Schedule, b models.HughBinding, t time.Time) {
+	switch b.Action {
+
+	case constants.ActionResume:
+		m.logger.Info("Resuming schedule", "schedule", sch.Name)
+		m.adjustmentsMu.Lock()
+		delete(m.adjustments, sch.Name)
+		m.adjustmentsMu.Unlock()
+		if err := m.dbAccess.ClearScheduleOverrides(sch.Name); err != nil {
+			m.logger.Error(err)
+		}
+
+	case constants.ActionPause:
+		duration := b.Duration
+		if duration <= 0 {
+			duration = constants.DefaultPauseDuration
+		}
+		m.logger.Info("Pausing schedule", "schedule", sch.Name, "for", duration)
+		m.adjust(sch.Name, func(a *adjustment) { a.pausedUntil = t.Add(duration) })
+		return
+
+	case constants.ActionNextStep:
+		// pressed again before the step is reached skips to the one after
+		interval, err := m.intervalGetter.GetScheduleIntervalForTime(sch, m.adjustment(sch.Name).targetTime(t))
+		if err != nil {
+			m.logger.Error(err)
+			return
+		}
+		m.logger.Info("Skipping to the next step", "schedule", sch.Name, "step", interval.End.Time)
+		m.adjust(sch.Name, func(a *adjustment) { a.skippedTo = interval.End.Time })
+
+	case constants.ActionWindDown:
+		duration := b.Duration
+		if duration <= 0 {
+			duration = constants.DefaultWindDownDuration
+		}
+		until := t.Add(duration)
+		interval, err := m.intervalGetter.GetScheduleIntervalForTime(sch, until)
+		if err != nil {
+			m.logger.Error(err)
+			return
+		}
+		m.logger.Info("Winding down", "schedule", sch.Name, "for", duration)
+		m.adjust(sch.Name, func(a *adjustment) {
+			a.windDownFrom = t
+			a.windDownUntil = until
+			a.windDownOffUntil = interval.End.Time
+		})
+		// the lights follow the fade with each update
+		m.updateLightTargetsForSchedule(sch, t)
+		return
+
+	default:
+		m.logger.Warn("Unknown binding action, ignoring", "action", b.Action, "schedule", sch.Name)
+		return
+	}
+
+	m.updateLightTargetsForSchedule(sch, t)
+	m.setScheduleLightsToTarget(sch, func(lsID string) error {
+		return m.lightStateSetter.SetLightStateToTarget(ctx, lsID, t)
+	})
+}
+
+// raises or lowers the brightness of the schedule's lights for the rest of the current interval
+func (m *LogicalStateManager) adjustBrightness(ctx context.Context, sch models.Schedule, steps int, t time.Time) {
+	interval, err := m.intervalGetter.GetScheduleIntervalForTime(sch, t)
+	if err != nil {
+		m.logger.Error(err)
+		return
+	}
+
+	change := int(math.Round(float64(steps) * constants.BrightnessPerRotaryStep))
+	if change == 0 && steps != 0 {
+		// always move a little, however slowly the dial is turned
+		change = steps / int(math.Abs(float64(steps)))
+	}
+
+	var offset int
+	m.adjust(sch.Name, func(a *adjustment) {
+		if !t.Before(a.offsetUntil) {
+			// the last offset was for an earlier interval
+			a.brightnessOffset = 0
+		}
+		a.brightnessOffset = min(max(a.brightnessOffset+change, -100), 100)
+		a.offsetUntil = interval.End.Time
+		offset = a.brightnessOffset
+	})
+	m.logger.Debug("Brightness offset changed", "schedule", sch.Name, "offset", offset)
+
+	m.updateLightTargetsForSchedule(sch, t)
+	m.setScheduleLightsToTarget(sch, func(lsID string) error {
+		return m.lightStateSetter.SetLightStateToTarget(ctx, lsID, t)
+	})
+}
@@ -0,0 +1,162 @@
+package logicalstatemanager_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/r3labs/sse/v2"
+	"github.com/stretchr/testify/mock"
+	"github.com/wheelibin/hugh/internal/constants"
+	"github.com/wheelibin/hugh/internal/logicalStateManager"
+	"github.com/wheelibin/hugh/internal/models"
+	"github.com/wheelibin/hugh/internal/schedule"
+	"github.com/wheelibin/hugh/mocks"
+)
+
+func buttonEvent(t *testing.T, buttonID string, event string) *sse.Event {
+	eventData := models.EventData{Id: buttonID, Type: constants.EventTypeButton}
+	eventData.Button = &struct {
+		ButtonReport *struct {
+			Event string `json:"event"`
+		} `json:"button_report"`
+		LastEvent string `json:"last_event"`
+	}{LastEvent: event}
+	return sensorEvent(t, eventData)
+}
+
+func dialEvent(t *testing.T, rotaryID string, direction string, steps int) *sse.Event {
+	eventData := models.EventData{Id: rotaryID, Type: constants.EventTypeRelativeRotary}
+	eventData.RelativeRotary = &struct {
+		RotaryReport *struct {
+			Rotation struct {
+				Direction string `json:"direction"`
+				Steps     int    `json:"steps"`
+			} `json:"rotation"`
+		} `json:"rotary_report"`
+	}{}
+	eventData.RelativeRotary.RotaryReport = &struct {
+		Rotation struct {
+			Direction string `json:"direction"`
+			Steps     int    `json:"steps"`
+		} `json:"rotation"`
+	}{}
+	eventData.RelativeRotary.RotaryReport.Rotation.Direction = direction
+	eventData.RelativeRotary.RotaryReport.Rotation.Steps = steps
+	return sensorEvent(t, eventData)
+}
+
+func Test_HandleSensorEvent_Bindings(t *testing.T) {
+
+	sch := models.Schedule{Name: "Kitchen"}
+	start := schedule.IntervalStep{Time: time.Now().Add(-time.Hour), Brightness: 50, TemperatureKelvin: 2500}
+	end := schedule.IntervalStep{Time: time.Now().Add(time.Hour), Brightness: 80, TemperatureKelvin: 2500}
+	interval := schedule.Interval{Start: start, End: end}
+
+	tests := []struct {
+		name    string
+		binding models.HughBinding
+		event   *sse.Event
+		// what the lights are set to straight away, nil if they are left alone
+		immediate func(s models.LightState) bool
+		// when the next update happens and what it sets the target to, nil if the target is left alone
+		updateAfter time.Duration
+		updated     func(s models.LightState) bool
+	}{
+		{
+			name:      "resume: should clear the overrides and set the lights to target",
+			binding:   models.HughBinding{ID: "button1", Event: constants.ButtonEventShortRelease, Action: constants.ActionResume, ScheduleName: "Kitchen"},
+			event:     buttonEvent(t, "button1", constants.ButtonEventShortRelease),
+			immediate: func(s models.LightState) bool { return s.On && s.Brightness > 50 && s.Brightness < 80 },
+			updated:   func(s models.LightState) bool { return s.On && s.Brightness > 50 && s.Brightness < 80 },
+		},
+		{
+			name:    "pause: should leave the targets alone for the duration",
+			binding: models.HughBinding{ID: "button1", Event: constants.ButtonEventShortRelease, Action: constants.ActionPause, ScheduleName: "Kitchen", Duration: time.Hour},
+			event:   buttonEvent(t, "button1", constants.ButtonEventShortRelease),
+			// still paused
+			updateAfter: 30 * time.Minute,
+		},
+		{
+			name:        "next: should skip to the next step",
+			binding:     models.HughBinding{ID: "button1", Event: constants.ButtonEventShortRelease, Action: constants.ActionNextStep, ScheduleName: "Kitchen"},
+			event:       buttonEvent(t, "button1", constants.ButtonEventShortRelease),
+			immediate:   func(s models.LightState) bool { return s.On && s.Brightness == 80 },
+			updateAfter: 30 * time.Minute,
+			updated:     func(s models.LightState) bool { return s.On && s.Brightness == 80 },
+		},
+		{
+			name:    "wind down: should fade the lights off over the duration",
+			binding: models.HughBinding{ID: "button1", Event: constants.ButtonEventShortRelease, Action: constants.ActionWindDown, ScheduleName: "Kitchen", Duration: 20 * time.Minute},
+			event:   buttonEvent(t, "button1", constants.ButtonEventShortRelease),
+			// three quarters of the way through the fade
+			updateAfter: 15 * time.Minute,
+			updated:     func(s models.LightState) bool { return s.On && s.Brightness > 15 && s.Brightness < 20 },
+		},
+		{
+			name:        "wind down: should keep the lights off until the next step",
+			binding:     models.HughBinding{ID: "button1", Event: constants.ButtonEventShortRelease, Action: constants.ActionWindDown, ScheduleName: "Kitchen", Duration: 20 * time.Minute},
+			event:       buttonEvent(t, "button1", constants.ButtonEventShortRelease),
+			updateAfter: 30 * time.Minute,
+			updated:     func(s models.LightState) bool { return !s.On },
+		},
+		{
+			name:        "dial: should raise the brightness for the rest of the interval",
+			binding:     models.HughBinding{ID: "rotary1", Action: constants.ActionBrightness, ScheduleName: "Kitchen"},
+			event:       dialEvent(t, "rotary1", constants.RotaryDirectionClockwise, 100),
+			immediate:   func(s models.LightState) bool { return s.Brightness > 60 && s.Brightness < 90 },
+			updateAfter: 30 * time.Minute,
+			updated:     func(s models.LightState) bool { return s.Brightness > 60 && s.Brightness < 90 },
+		},
+		{
+			name:    "another button event: should ignore",
+			binding: models.HughBinding{ID: "button1", Event: constants.ButtonEventShortRelease, Action: constants.ActionNextStep, ScheduleName: "Kitchen"},
+			event:   buttonEvent(t, "button1", "initial_press"),
+			updated: func(s models.LightState) bool { return s.On && s.Brightness > 50 && s.Brightness < 80 },
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			// arrange
+			logger := log.NewWithOptions(os.Stderr, log.Options{Level: log.FatalLevel})
+			mockDBAccess := mocks.NewMockLogicalstatemanagerDbAccess(t)
+			mockIntervalGetter := mocks.NewMockLogicalstatemanagerIntervalGetter(t)
+			mockLightStateSetter := mocks.NewMockLogicalstatemanagerLightStateSetter(t)
+
+			mockDBAccess.On("GetBindings", test.binding.ID).Return([]models.HughBinding{test.binding}, nil)
+			mockIntervalGetter.On("GetScheduleIntervalForTime", sch, mock.Anything).Return(interval, nil).Maybe()
+			if test.binding.Action == constants.ActionResume {
+				mockDBAccess.On("ClearScheduleOverrides", "Kitchen").Return(nil)
+			}
+			if test.immediate != nil {
+				mockDBAccess.On("UpdateTargetState", "Kitchen", mock.MatchedBy(test.immediate)).Return(nil).Once()
+				mockDBAccess.On("GetControllingLightIDsForSchedule", "Kitchen").Return([]string{"ls1"}, nil)
+				mockLightStateSetter.On("SetLightStateToTarget", mock.Anything, "ls1", mock.Anything).Return(nil).Once()
+			}
+			if test.binding.Action == constants.ActionWindDown {
+				// the fade starts from the current target
+				mockDBAccess.On("UpdateTargetState", "Kitchen", mock.MatchedBy(func(s models.LightState) bool { return s.On && s.Brightness > 50 })).Return(nil).Once()
+			}
+			if test.updated != nil {
+				mockDBAccess.On("UpdateTargetState", "Kitchen", mock.MatchedBy(test.updated)).Return(nil).Once()
+			}
+
+			// act
+			lsm := logicalstatemanager.NewLogicalStateManager(logger, mockDBAccess, mockIntervalGetter, mockLightStateSetter)
+			lsm.HandleSensorEvent(context.Background(), test.event, []models.Schedule{sch})
+			lsm.UpdateAllTargetStates([]models.Schedule{sch}, time.Now().Add(test.updateAfter))
+
+			// assert
+			if test.immediate == nil {
+				mockLightStateSetter.AssertNotCalled(t, "SetLightStateToTarget", mock.Anything, mock.Anything, mock.Anything)
+			}
+			if test.updated == nil && test.immediate == nil {
+				mockDBAccess.AssertNotCalled(t, "UpdateTargetState", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
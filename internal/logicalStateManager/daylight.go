@@ -0,0 +1,50 @@
+package logicalstatemanager
+
+import (
+	"math"
+
+	"github.com/wheelibin/hugh/internal/models"
+	"github.com/wheelibin/hugh/internal/schedule"
+)
+
+// stores the ambient light for the schedule's daylight dimming, unless it is within the hysteresis of the last
+// reading so the lights don't keep adjusting as the light level wavers
+func (m *LogicalStateManager) updateAmbientLight(sch models.Schedule, lightLevel int) {
+	lux := schedule.LuxFromLightLevel(lightLevel)
+
+	previous, err := m.dbAccess.GetAmbientLux(sch.Name)
+	if err != nil {
+		m.logger.Error(err)
+		return
+	}
+	if previous != nil && math.Abs(lux-*previous) < schedule.DaylightHysteresis(*sch.Daylight) {
+		m.logger.Debugf("ambient light (%.0f lux) within hysteresis of %.0f lux, ignoring", lux, *previous)
+		return
+	}
+
+	m.logger.Debug("Ambient light changed", "schedule", sch.Name, "lux", math.Round(lux))
+	err = m.dbAccess.SetAmbientLux(sch.Name, lux)
+	if err != nil {
+		m.logger.Error(err)
+	}
+}
+
+// dims the target by the daylight, using the latest ambient light reading
+func (m *LogicalStateManager) applyDaylight(sch models.Schedule, target models.LightState) models.LightState {
+	if sch.Daylight == nil || !target.On {
+		return target
+	}
+
+	lux, err := m.dbAccess.GetAmbientLux(sch.Name)
+	if err != nil {
+		m.logger.Error(err)
+		return target
+	}
+	if lux == nil {
+		// no reading yet
+		return target
+	}
+
+	target.Brightness = schedule.DaylightBrightness(target.Brightness, *lux, *sch.Daylight)
+	return target
+}
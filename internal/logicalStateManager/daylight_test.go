@@ -0,0 +1,94 @@
+package logicalstatemanager_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/r3labs/sse/v2"
+	"github.com/stretchr/testify/mock"
+	"github.com/wheelibin/hugh/internal/constants"
+	"github.com/wheelibin/hugh/internal/logicalStateManager"
+	"github.com/wheelibin/hugh/internal/models"
+	"github.com/wheelibin/hugh/internal/schedule"
+	"github.com/wheelibin/hugh/mocks"
+)
+
+func lightLevelEvent(t *testing.T, sensorID string, lightLevel int) *sse.Event {
+	eventData := models.EventData{Id: sensorID, Type: constants.EventTypeLightLevel}
+	eventData.Light = &struct {
+		LightLevel      int  `json:"light_level"`
+		LightLevelValid bool `json:"light_level_valid"`
+	}{LightLevel: lightLevel, LightLevelValid: true}
+	return sensorEvent(t, eventData)
+}
+
+func Test_HandleSensorEvent_Daylight(t *testing.T) {
+
+	sch := models.Schedule{Name: "Study", Daylight: &models.ScheduleDaylight{Sensor: "Study sensor", TargetLux: 400}}
+
+	tests := []struct {
+		name     string
+		previous *float64
+		// light_level 20001 is 100 lux
+		lightLevel int
+		stored     bool
+	}{
+		{name: "first reading: should store it", previous: nil, lightLevel: 20001, stored: true},
+		{name: "within the hysteresis of the last reading: should ignore", previous: luxReading(130), lightLevel: 20001, stored: false},
+		{name: "beyond the hysteresis of the last reading: should store it", previous: luxReading(150), lightLevel: 20001, stored: true},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			// arrange
+			logger := log.NewWithOptions(os.Stderr, log.Options{Level: log.FatalLevel})
+			mockDBAccess := mocks.NewMockLogicalstatemanagerDbAccess(t)
+			mockIntervalGetter := mocks.NewMockLogicalstatemanagerIntervalGetter(t)
+			mockLightStateSetter := mocks.NewMockLogicalstatemanagerLightStateSetter(t)
+
+			mockDBAccess.On("GetSensorSchedules", "lightlevel1").Return([]string{"Study"}, nil)
+			mockDBAccess.On("GetAmbientLux", "Study").Return(test.previous, nil)
+			if test.stored {
+				mockDBAccess.On("SetAmbientLux", "Study", mock.MatchedBy(func(lux float64) bool { return lux > 99.9 && lux < 100.1 })).Return(nil)
+			}
+
+			// act
+			lsm := logicalstatemanager.NewLogicalStateManager(logger, mockDBAccess, mockIntervalGetter, mockLightStateSetter)
+			lsm.HandleSensorEvent(context.Background(), lightLevelEvent(t, "lightlevel1", test.lightLevel), []models.Schedule{sch})
+
+			// assert
+			if !test.stored {
+				mockDBAccess.AssertNotCalled(t, "SetAmbientLux", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func Test_UpdateAllTargetStates_Daylight(t *testing.T) {
+	// arrange
+	logger := log.NewWithOptions(os.Stderr, log.Options{Level: log.FatalLevel})
+	mockDBAccess := mocks.NewMockLogicalstatemanagerDbAccess(t)
+	mockIntervalGetter := mocks.NewMockLogicalstatemanagerIntervalGetter(t)
+	mockLightStateSetter := mocks.NewMockLogicalstatemanagerLightStateSetter(t)
+	sch := models.Schedule{Name: "Study", Daylight: &models.ScheduleDaylight{Sensor: "Study sensor", TargetLux: 400, MinBrightness: 10}}
+
+	step := schedule.IntervalStep{Time: time.Now().Add(-time.Hour), Brightness: 80, TemperatureKelvin: 4000}
+	end := schedule.IntervalStep{Time: time.Now().Add(time.Hour), Brightness: 80, TemperatureKelvin: 4000}
+	mockIntervalGetter.On("GetScheduleIntervalForTime", sch, mock.Anything).Return(schedule.Interval{Start: step, End: end}, nil)
+
+	// daylight provides half the target, so the lights make up the other half
+	mockDBAccess.On("GetAmbientLux", "Study").Return(luxReading(200), nil)
+	mockDBAccess.On("UpdateTargetState", "Study", mock.MatchedBy(func(s models.LightState) bool { return s.On && s.Brightness == 40 })).Return(nil)
+
+	// act
+	lsm := logicalstatemanager.NewLogicalStateManager(logger, mockDBAccess, mockIntervalGetter, mockLightStateSetter)
+	lsm.UpdateAllTargetStates([]models.Schedule{sch}, time.Now())
+}
+
+func luxReading(lux float64) *float64 {
+	return &lux
+}
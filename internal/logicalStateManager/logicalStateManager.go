@@ -1,8 +1,11 @@
 package logicalstatemanager
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"math"
+	"sync"
 	"time"
 
 	"github.com/charmbracelet/log"
@@ -13,12 +16,27 @@ import (
 )
 
 type lightStateSetter interface {
-	SetLightStateToTarget(lsID string, currentTime time.Time) error
+	SetLightStateToTarget(ctx context.Context, lsID string, currentTime time.Time) error
+	FadeLightToTarget(ctx context.Context, lsID string, currentTime time.Time, transition time.Duration) error
 }
 
 type dbAccess interface {
 	Add(lights []models.HughLight) error
 	AddScenes(scenes []models.HughScene) error
+	AddGroups(groups []models.HughGroup) error
+	SyncLights(lights []models.HughLight) ([]string, []string, error)
+	SyncScenes(scenes []models.HughScene) error
+	SyncGroups(groups []models.HughGroup) error
+	AddSensors(sensors []models.HughSensor) error
+	SyncSensors(sensors []models.HughSensor) error
+	GetSensorSchedules(id string) ([]string, error)
+	AddBindings(bindings []models.HughBinding) error
+	SyncBindings(bindings []models.HughBinding) error
+	GetBindings(id string) ([]models.HughBinding, error)
+	SetAmbientLux(scheduleName string, lux float64) error
+	GetAmbientLux(scheduleName string) (*float64, error)
+	SetScheduleDisabled(scheduleName string, disabled bool) error
+	GetControllingLightIDsForSchedule(scheduleName string) ([]string, error)
 	SetLightOnState(lsID string, on bool) error
 	SetLightBrightnessOverride(lsID string, brightness int, targetBrightness int) error
 	SetLightColourTempOverride(lsID string, colourTemp int, targetColourTemp int) error
@@ -30,6 +48,7 @@ type dbAccess interface {
 	GetLightServiceIDForZigbeeID(zigbeeID string) (string, error)
 	GetLightLastUpdate(lsID string) (*time.Time, error)
 	ClearLightOverrides(lsID string) error
+	ClearScheduleOverrides(scheduleName string) error
 }
 
 type intervalGetter interface {
@@ -41,10 +60,34 @@ type LogicalStateManager struct {
 	intervalGetter   intervalGetter
 	lightStateSetter lightStateSetter
 	logger           *log.Logger
+
+	// the schedules with occupancy sensors that are occupied, each vacated once nobody has been seen for its timeout
+	occupancyMu sync.Mutex
+	vacancies   map[string]*vacancy
+	// the schedules whose sensors have seen someone since starting, until then hugh doesn't know if they are
+	// occupied so leaves their lights on rather than switching them off at every restart
+	sensed map[string]bool
+
+	// the schedules a calendar event currently disables, as last recorded in the db
+	calendarDisabledMu sync.Mutex
+	calendarDisabled   map[string]bool
+
+	// the changes made to schedules from their switches and dials
+	adjustmentsMu sync.Mutex
+	adjustments   map[string]adjustment
 }
 
 func NewLogicalStateManager(logger *log.Logger, dbUpdater dbAccess, intervalGetter intervalGetter, lightStateSetter lightStateSetter) *LogicalStateManager {
-	return &LogicalStateManager{logger: logger, dbAccess: dbUpdater, intervalGetter: intervalGetter, lightStateSetter: lightStateSetter}
+	return &LogicalStateManager{
+		logger:           logger,
+		dbAccess:         dbUpdater,
+		intervalGetter:   intervalGetter,
+		lightStateSetter: lightStateSetter,
+		vacancies:        map[string]*vacancy{},
+		sensed:           map[string]bool{},
+		calendarDisabled: map[string]bool{},
+		adjustments:      map[string]adjustment{},
+	}
 }
 
 func (m *LogicalStateManager) AddLights(lights []models.HughLight) error {
@@ -55,7 +98,31 @@ func (m *LogicalStateManager) AddScenes(scenes []models.HughScene) error {
 	return m.dbAccess.AddScenes(scenes)
 }
 
-func (m *LogicalStateManager) HandleBridgeEvent(event *sse.Event) {
+func (m *LogicalStateManager) AddGroups(groups []models.HughGroup) error {
+	return m.dbAccess.AddGroups(groups)
+}
+
+// SyncLights updates the lights after a rediscovery, returning the ids of any new lights
+func (m *LogicalStateManager) SyncLights(lights []models.HughLight) ([]string, error) {
+	added, removed, err := m.dbAccess.SyncLights(lights)
+	if err != nil {
+		return nil, err
+	}
+	if len(added) > 0 || len(removed) > 0 {
+		m.logger.Info("Lights changed on the bridge", "added", added, "removed", removed)
+	}
+	return added, nil
+}
+
+func (m *LogicalStateManager) SyncScenes(scenes []models.HughScene) error {
+	return m.dbAccess.SyncScenes(scenes)
+}
+
+func (m *LogicalStateManager) SyncGroups(groups []models.HughGroup) error {
+	return m.dbAccess.SyncGroups(groups)
+}
+
+func (m *LogicalStateManager) HandleBridgeEvent(ctx context.Context, event *sse.Event) {
 	events := []models.Event{}
 	if err := json.Unmarshal(event.Data, &events); err != nil {
 		m.logger.Error(err)
@@ -88,7 +155,7 @@ func (m *LogicalStateManager) HandleBridgeEvent(event *sse.Event) {
 						if err != nil {
 							m.logger.Error(err)
 						}
-						m.HandleLightOnOffEvent(evt.CreationTime, lsID, true, currentLightTargetState.On)
+						m.HandleLightOnOffEvent(ctx, evt.CreationTime, lsID, true, currentLightTargetState.On)
 						continue
 					}
 
@@ -111,7 +178,7 @@ func (m *LogicalStateManager) HandleBridgeEvent(event *sse.Event) {
 
 					// light has been switched on/off
 					if eventData.On != nil {
-						m.HandleLightOnOffEvent(evt.CreationTime, eventData.Id, eventData.On.On, currentLightTargetState.On)
+						m.HandleLightOnOffEvent(ctx, evt.CreationTime, eventData.Id, eventData.On.On, currentLightTargetState.On)
 						continue
 					}
 
@@ -133,7 +200,7 @@ func (m *LogicalStateManager) HandleBridgeEvent(event *sse.Event) {
 	}
 }
 
-func (m *LogicalStateManager) HandleLightOnOffEvent(eventTime time.Time, lightId string, eventOn bool, targetOn bool) {
+func (m *LogicalStateManager) HandleLightOnOffEvent(ctx context.Context, eventTime time.Time, lightId string, eventOn bool, targetOn bool) {
 	m.logger.Debugf("(%s): event on: %t, target: %t", lightId, eventOn, targetOn)
 
 	if eventOn == targetOn && m.eventInsideHughUpdateWindow(eventTime, lightId) {
@@ -151,7 +218,7 @@ func (m *LogicalStateManager) HandleLightOnOffEvent(eventTime time.Time, lightId
 
 	if eventOn && targetOn {
 		// light has just been switched on and should be on, set to target
-		err := m.lightStateSetter.SetLightStateToTarget(lightId, eventTime)
+		err := m.lightStateSetter.SetLightStateToTarget(ctx, lightId, eventTime)
 		if err != nil {
 			m.logger.Error(err)
 		}
@@ -205,14 +272,34 @@ func (m *LogicalStateManager) UpdateAllTargetStates(schedules []models.Schedule,
 func (m *LogicalStateManager) updateLightTargetsForSchedule(sch models.Schedule, t time.Time) {
 	m.logger.Infof("Calculating next target states for lights (%s)...", sch.Name)
 
-	currentInterval, err := m.intervalGetter.GetScheduleIntervalForTime(sch, t)
+	adj := m.adjustment(sch.Name)
+	if adj.paused(t) {
+		m.logger.Debug("schedule paused, leaving the targets as they are", "schedule", sch.Name, "until", adj.pausedUntil)
+		return
+	}
+
+	// after skipping to the next step the targets are those of the step until it is reached
+	targetTime := adj.targetTime(t)
+	currentInterval, err := m.intervalGetter.GetScheduleIntervalForTime(sch, targetTime)
 
+	if errors.Is(err, schedule.ErrScheduleDisabled) {
+		m.logger.Debug("schedule disabled by a calendar event, leaving the targets as they are", "schedule", sch.Name)
+		m.setCalendarDisabled(sch, true)
+		return
+	}
 	if err != nil {
 		m.logger.Error(err)
 		return
 	}
+	m.setCalendarDisabled(sch, false)
 
-	targetState := currentInterval.CalculateTargetLightState(t)
+	targetState := currentInterval.CalculateTargetLightState(targetTime)
+	if !m.occupied(sch) {
+		// nobody there, the lights stay off until a sensor detects someone
+		targetState.On = false
+	}
+	targetState = m.applyDaylight(sch, targetState)
+	targetState = adj.apply(targetState, t)
 	err = m.dbAccess.UpdateTargetState(sch.Name, targetState)
 	if err != nil {
 		m.logger.Error(err)
@@ -220,6 +307,21 @@ func (m *LogicalStateManager) updateLightTargetsForSchedule(sch models.Schedule,
 
 }
 
+// records whether a calendar event disables the schedule, so its lights and scenes aren't sent their last targets
+// while it does
+func (m *LogicalStateManager) setCalendarDisabled(sch models.Schedule, disabled bool) {
+	m.calendarDisabledMu.Lock()
+	defer m.calendarDisabledMu.Unlock()
+	if m.calendarDisabled[sch.Name] == disabled {
+		return
+	}
+	if err := m.dbAccess.SetScheduleDisabled(sch.Name, disabled); err != nil {
+		m.logger.Error(err)
+		return
+	}
+	m.calendarDisabled[sch.Name] = disabled
+}
+
 func (m *LogicalStateManager) eventInsideHughUpdateWindow(eventTime time.Time, lightId string) bool {
 	lightLastUpdated, err := m.dbAccess.GetLightLastUpdate(lightId)
 	if err != nil {
@@ -1,6 +1,7 @@
 package logicalstatemanager_test
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -13,6 +14,7 @@ import (
 	"github.com/wheelibin/hugh/internal/constants"
 	"github.com/wheelibin/hugh/internal/logicalStateManager"
 	"github.com/wheelibin/hugh/internal/models"
+	"github.com/wheelibin/hugh/internal/schedule"
 	"github.com/wheelibin/hugh/mocks"
 )
 
@@ -43,7 +45,7 @@ func Test_HandleBridgeEvent_ZigbeeConnectivity(t *testing.T) {
 			events := []models.Event{event}
 			data, _ := json.Marshal(events)
 			lsm := logicalstatemanager.NewLogicalStateManager(logger, mockDBAccess, mockIntervalGetter, mockLightStateSetter)
-			lsm.HandleBridgeEvent(&sse.Event{Data: data})
+			lsm.HandleBridgeEvent(context.Background(), &sse.Event{Data: data})
 
 			// assert
 
@@ -79,7 +81,7 @@ func Test_HandleBridgeEvent_ZigbeeConnectivity(t *testing.T) {
 			events := []models.Event{event}
 			data, _ := json.Marshal(events)
 			lsm := logicalstatemanager.NewLogicalStateManager(logger, mockDBAccess, mockIntervalGetter, mockLightStateSetter)
-			lsm.HandleBridgeEvent(&sse.Event{Data: data})
+			lsm.HandleBridgeEvent(context.Background(), &sse.Event{Data: data})
 
 			// assert
 
@@ -114,13 +116,13 @@ func Test_HandleBridgeEvent_ZigbeeConnectivity(t *testing.T) {
 			mockDBAccess.On("GetLightTargetState", "ls123").Return(models.LightState{On: true}, nil)
 
 			// and set to target
-			mockLightStateSetter.On("SetLightStateToTarget", "ls123", mock.Anything).Return(nil)
+			mockLightStateSetter.On("SetLightStateToTarget", mock.Anything, "ls123", mock.Anything).Return(nil)
 
 			// act
 			events := []models.Event{event}
 			data, _ := json.Marshal(events)
 			lsm := logicalstatemanager.NewLogicalStateManager(logger, mockDBAccess, mockIntervalGetter, mockLightStateSetter)
-			lsm.HandleBridgeEvent(&sse.Event{Data: data})
+			lsm.HandleBridgeEvent(context.Background(), &sse.Event{Data: data})
 
 			// assert
 
@@ -146,11 +148,11 @@ func Test_HandleLightOnOffEvent(t *testing.T) {
 
 			// should ignore so shouldn't call these
 			mockDBAccess.AssertNotCalled(t, "SetLightOnStateOverride", lsID, true, true)
-			mockLightStateSetter.AssertNotCalled(t, "SetLightStateToTarget", lsID, mock.Anything)
+			mockLightStateSetter.AssertNotCalled(t, "SetLightStateToTarget", mock.Anything, lsID, mock.Anything)
 
 			// act
 			lsm := logicalstatemanager.NewLogicalStateManager(logger, mockDBAccess, mockIntervalGetter, mockLightStateSetter)
-			lsm.HandleLightOnOffEvent(currentTime, lsID, true, true)
+			lsm.HandleLightOnOffEvent(context.Background(), currentTime, lsID, true, true)
 
 			// assert
 
@@ -179,7 +181,7 @@ func Test_HandleLightOnOffEvent(t *testing.T) {
 
 				// act
 				lsm := logicalstatemanager.NewLogicalStateManager(logger, mockDBAccess, mockIntervalGetter, mockLightStateSetter)
-				lsm.HandleLightOnOffEvent(time.Now(), lsID, c.EventOn, c.TargetOn)
+				lsm.HandleLightOnOffEvent(context.Background(), time.Now(), lsID, c.EventOn, c.TargetOn)
 
 				// assert
 
@@ -202,14 +204,45 @@ func Test_HandleLightOnOffEvent(t *testing.T) {
 			mockDBAccess.On("GetLightLastUpdate", lsID).Return(&lastUpdateTime, nil)
 
 			// set to target
-			mockLightStateSetter.On("SetLightStateToTarget", "ls123", mock.Anything).Return(nil)
+			mockLightStateSetter.On("SetLightStateToTarget", mock.Anything, "ls123", mock.Anything).Return(nil)
 
 			// act
 			lsm := logicalstatemanager.NewLogicalStateManager(logger, mockDBAccess, mockIntervalGetter, mockLightStateSetter)
-			lsm.HandleLightOnOffEvent(eventTime, lsID, true, true)
+			lsm.HandleLightOnOffEvent(context.Background(), eventTime, lsID, true, true)
 
 			// assert
 
 		})
 
 }
+
+func Test_UpdateAllTargetStates_CalendarDisabled(t *testing.T) {
+	// arrange
+	logger := log.NewWithOptions(os.Stderr, log.Options{Level: log.FatalLevel})
+	mockDBAccess := mocks.NewMockLogicalstatemanagerDbAccess(t)
+	mockIntervalGetter := mocks.NewMockLogicalstatemanagerIntervalGetter(t)
+	mockLightStateSetter := mocks.NewMockLogicalstatemanagerLightStateSetter(t)
+	sch := models.Schedule{Name: "Kitchen"}
+
+	step := schedule.IntervalStep{Time: time.Now().Add(-time.Hour), Brightness: 50, TemperatureKelvin: 2500}
+	end := schedule.IntervalStep{Time: time.Now().Add(time.Hour), Brightness: 50, TemperatureKelvin: 2500}
+	disabledAt := time.Now()
+	enabledAt := disabledAt.Add(time.Hour)
+	mockIntervalGetter.On("GetScheduleIntervalForTime", sch, disabledAt).Return(schedule.Interval{}, fmt.Errorf("Kitchen: %w (holiday)", schedule.ErrScheduleDisabled))
+	mockIntervalGetter.On("GetScheduleIntervalForTime", sch, enabledAt).Return(schedule.Interval{Start: step, End: end}, nil)
+
+	// while the calendar disables the schedule its targets are left alone and its lights aren't updated, only
+	// recorded once however many updates there are
+	mockDBAccess.On("SetScheduleDisabled", "Kitchen", true).Return(nil).Once()
+	// until the event ends
+	mockDBAccess.On("SetScheduleDisabled", "Kitchen", false).Return(nil).Once()
+	mockDBAccess.On("UpdateTargetState", "Kitchen", mock.Anything).Return(nil).Twice()
+
+	// act
+	lsm := logicalstatemanager.NewLogicalStateManager(logger, mockDBAccess, mockIntervalGetter, mockLightStateSetter)
+	lsm.UpdateAllTargetStates([]models.Schedule{sch}, disabledAt)
+	lsm.UpdateAllTargetStates([]models.Schedule{sch}, disabledAt)
+	mockDBAccess.AssertNotCalled(t, "UpdateTargetState", mock.Anything, mock.Anything)
+	lsm.UpdateAllTargetStates([]models.Schedule{sch}, enabledAt)
+	lsm.UpdateAllTargetStates([]models.Schedule{sch}, enabledAt)
+}
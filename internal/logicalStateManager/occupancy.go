@@ -0,0 +1,108 @@
+package logicalstatemanager
+
+import (
+	"context"
+	"time"
+
+	"github.com/wheelibin/hugh/internal/constants"
+	"github.com/wheelibin/hugh/internal/models"
+	"github.com/wheelibin/hugh/internal/schedule"
+)
+
+// the pending vacancy of an occupied schedule
+type vacancy struct {
+	timer *time.Timer
+}
+
+// marks the schedule as occupied, turning its lights on if it wasn't already
+func (m *LogicalStateManager) occupy(ctx context.Context, sch models.Schedule, t time.Time) {
+	timeout := sch.Occupancy.Timeout
+	if timeout <= 0 {
+		timeout = constants.DefaultOccupancyTimeout
+	}
+
+	m.occupancyMu.Lock()
+	if v, occupied := m.vacancies[sch.Name]; occupied && v.timer.Stop() {
+		// still occupied, start the timeout again
+		v.timer.Reset(timeout)
+		m.occupancyMu.Unlock()
+		return
+	}
+	if !insideAutoOnWindow(sch, t) {
+		m.occupancyMu.Unlock()
+		m.logger.Debug("motion detected outside the auto on window, ignoring", "schedule", sch.Name)
+		return
+	}
+	v := &vacancy{}
+	v.timer = time.AfterFunc(timeout, func() { m.vacate(ctx, sch, v) })
+	m.vacancies[sch.Name] = v
+	m.sensed[sch.Name] = true
+	m.occupancyMu.Unlock()
+
+	m.logger.Info("Motion detected, turning lights on", "schedule", sch.Name)
+	m.updateLightTargetsForSchedule(sch, t)
+	m.setScheduleLightsToTarget(sch, func(lsID string) error {
+		return m.lightStateSetter.SetLightStateToTarget(ctx, lsID, t)
+	})
+}
+
+// fades the lights of the schedule off once nobody has been detected for the timeout
+func (m *LogicalStateManager) vacate(ctx context.Context, sch models.Schedule, v *vacancy) {
+	m.occupancyMu.Lock()
+	if m.vacancies[sch.Name] != v {
+		// occupied again since the timer fired
+		m.occupancyMu.Unlock()
+		return
+	}
+	delete(m.vacancies, sch.Name)
+	m.occupancyMu.Unlock()
+
+	m.logger.Info("No motion detected, turning lights off", "schedule", sch.Name)
+	now := time.Now()
+	m.updateLightTargetsForSchedule(sch, now)
+	m.setScheduleLightsToTarget(sch, func(lsID string) error {
+		return m.lightStateSetter.FadeLightToTarget(ctx, lsID, now, constants.VacancyTransition)
+	})
+}
+
+// whether the lights of the schedule should be on as far as occupancy is concerned, always for schedules without it
+// and for those whose sensors haven't seen anyone yet
+func (m *LogicalStateManager) occupied(sch models.Schedule) bool {
+	if sch.Occupancy == nil {
+		return true
+	}
+	m.occupancyMu.Lock()
+	defer m.occupancyMu.Unlock()
+	if !m.sensed[sch.Name] {
+		return true
+	}
+	_, occupied := m.vacancies[sch.Name]
+	return occupied
+}
+
+// sets the lights of the schedule to their target, leaving any that have been changed by hand
+func (m *LogicalStateManager) setScheduleLightsToTarget(sch models.Schedule, set func(lsID string) error) {
+	if m.adjustment(sch.Name).paused(time.Now()) {
+		return
+	}
+	lightIDs, err := m.dbAccess.GetControllingLightIDsForSchedule(sch.Name)
+	if err != nil {
+		m.logger.Error(err)
+		return
+	}
+	for _, lsID := range lightIDs {
+		if err := set(lsID); err != nil {
+			m.logger.Error(err)
+		}
+	}
+}
+
+func insideAutoOnWindow(sch models.Schedule, t time.Time) bool {
+	if sch.AutoOn == nil || sch.AutoOn.From == "" || sch.AutoOn.To == "" {
+		return true
+	}
+	t = t.Local()
+	from := schedule.TimeFromConfigTimeString(sch.AutoOn.From, t)
+	to := schedule.TimeFromConfigTimeString(sch.AutoOn.To, t)
+	return !t.Before(from) && !t.After(to)
+}
@@ -0,0 +1,175 @@
+package logicalstatemanager_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/r3labs/sse/v2"
+	"github.com/stretchr/testify/mock"
+	"github.com/wheelibin/hugh/internal/constants"
+	"github.com/wheelibin/hugh/internal/logicalStateManager"
+	"github.com/wheelibin/hugh/internal/models"
+	"github.com/wheelibin/hugh/internal/schedule"
+	"github.com/wheelibin/hugh/mocks"
+)
+
+func sensorEvent(t *testing.T, eventData models.EventData) *sse.Event {
+	data, err := json.Marshal([]models.Event{{CreationTime: time.Now(), Type: constants.EventBatchTypeUpdate, Data: []models.EventData{eventData}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &sse.Event{Data: data}
+}
+
+func motionEvent(t *testing.T, sensorID string, motion bool) *sse.Event {
+	eventData := models.EventData{Id: sensorID, Type: constants.EventTypeMotion}
+	eventData.Motion = &struct {
+		Motion bool `json:"motion"`
+	}{Motion: motion}
+	return sensorEvent(t, eventData)
+}
+
+func Test_HandleSensorEvent_Occupancy(t *testing.T) {
+
+	step := schedule.IntervalStep{Time: time.Now().Add(-time.Hour), Brightness: 50, TemperatureKelvin: 2500}
+	interval := schedule.Interval{Start: step, End: schedule.IntervalStep{Time: time.Now().Add(time.Hour), Brightness: 50, TemperatureKelvin: 2500}}
+
+	t.Run("motion: should turn the lights on, then fade them off after the timeout", func(t *testing.T) {
+		// arrange
+		logger := log.NewWithOptions(os.Stderr, log.Options{Level: log.FatalLevel})
+		mockDBAccess := mocks.NewMockLogicalstatemanagerDbAccess(t)
+		mockIntervalGetter := mocks.NewMockLogicalstatemanagerIntervalGetter(t)
+		mockLightStateSetter := mocks.NewMockLogicalstatemanagerLightStateSetter(t)
+		sch := models.Schedule{Name: "Hall", Occupancy: &models.ScheduleOccupancy{Sensors: []string{"Hall sensor"}, Timeout: 50 * time.Millisecond}}
+
+		mockDBAccess.On("GetSensorSchedules", "motion1").Return([]string{"Hall"}, nil)
+		mockIntervalGetter.On("GetScheduleIntervalForTime", sch, mock.Anything).Return(interval, nil)
+		// lights changed by hand aren't returned, so are left alone
+		mockDBAccess.On("GetControllingLightIDsForSchedule", "Hall").Return([]string{"ls1"}, nil)
+
+		// it should turn the lights on
+		mockDBAccess.On("UpdateTargetState", "Hall", mock.MatchedBy(func(s models.LightState) bool { return s.On })).Return(nil).Once()
+		mockLightStateSetter.On("SetLightStateToTarget", mock.Anything, "ls1", mock.Anything).Return(nil).Once()
+
+		// and off once nobody has been seen for the timeout
+		faded := make(chan struct{})
+		mockDBAccess.On("UpdateTargetState", "Hall", mock.MatchedBy(func(s models.LightState) bool { return !s.On })).Return(nil).Once()
+		mockLightStateSetter.On("FadeLightToTarget", mock.Anything, "ls1", mock.Anything, constants.VacancyTransition).Return(nil).Once().
+			Run(func(mock.Arguments) { close(faded) })
+
+		// act
+		lsm := logicalstatemanager.NewLogicalStateManager(logger, mockDBAccess, mockIntervalGetter, mockLightStateSetter)
+		lsm.HandleSensorEvent(context.Background(), motionEvent(t, "motion1", true), []models.Schedule{sch})
+
+		// assert
+		select {
+		case <-faded:
+		case <-time.After(5 * time.Second):
+			t.Fatal("lights weren't faded off")
+		}
+	})
+
+	t.Run("motion outside the auto on window: should ignore", func(t *testing.T) {
+		// arrange
+		logger := log.NewWithOptions(os.Stderr, log.Options{Level: log.FatalLevel})
+		mockDBAccess := mocks.NewMockLogicalstatemanagerDbAccess(t)
+		mockIntervalGetter := mocks.NewMockLogicalstatemanagerIntervalGetter(t)
+		mockLightStateSetter := mocks.NewMockLogicalstatemanagerLightStateSetter(t)
+		sch := models.Schedule{Name: "Hall", Occupancy: &models.ScheduleOccupancy{Sensors: []string{"Hall sensor"}}}
+		sch.AutoOn = &struct {
+			From string `json:"from"`
+			To   string `json:"to"`
+		}{From: "00:00", To: "00:00"}
+
+		mockDBAccess.On("GetSensorSchedules", "motion1").Return([]string{"Hall"}, nil)
+
+		// act
+		lsm := logicalstatemanager.NewLogicalStateManager(logger, mockDBAccess, mockIntervalGetter, mockLightStateSetter)
+		lsm.HandleSensorEvent(context.Background(), motionEvent(t, "motion1", true), []models.Schedule{sch})
+
+		// assert
+		mockLightStateSetter.AssertNotCalled(t, "SetLightStateToTarget", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("motion stopping or a door closing: should ignore", func(t *testing.T) {
+		// arrange
+		logger := log.NewWithOptions(os.Stderr, log.Options{Level: log.FatalLevel})
+		mockDBAccess := mocks.NewMockLogicalstatemanagerDbAccess(t)
+		mockIntervalGetter := mocks.NewMockLogicalstatemanagerIntervalGetter(t)
+		mockLightStateSetter := mocks.NewMockLogicalstatemanagerLightStateSetter(t)
+		sch := models.Schedule{Name: "Hall", Occupancy: &models.ScheduleOccupancy{Sensors: []string{"Hall sensor"}}}
+
+		closed := models.EventData{Id: "contact1", Type: constants.EventTypeContact}
+		closed.ContactReport = &struct {
+			State string `json:"state"`
+		}{State: "contact"}
+
+		// act
+		lsm := logicalstatemanager.NewLogicalStateManager(logger, mockDBAccess, mockIntervalGetter, mockLightStateSetter)
+		lsm.HandleSensorEvent(context.Background(), motionEvent(t, "motion1", false), []models.Schedule{sch})
+		lsm.HandleSensorEvent(context.Background(), sensorEvent(t, closed), []models.Schedule{sch})
+
+		// assert
+		mockDBAccess.AssertNotCalled(t, "GetSensorSchedules", mock.Anything)
+	})
+}
+
+func Test_UpdateAllTargetStates_Occupancy(t *testing.T) {
+
+	step := schedule.IntervalStep{Time: time.Now().Add(-time.Hour), Brightness: 50, TemperatureKelvin: 2500}
+	end := schedule.IntervalStep{Time: time.Now().Add(time.Hour), Brightness: 50, TemperatureKelvin: 2500}
+	interval := schedule.Interval{Start: step, End: end}
+
+	t.Run("on starting: should follow the pattern until the sensors have seen someone", func(t *testing.T) {
+		// arrange
+		logger := log.NewWithOptions(os.Stderr, log.Options{Level: log.FatalLevel})
+		mockDBAccess := mocks.NewMockLogicalstatemanagerDbAccess(t)
+		mockIntervalGetter := mocks.NewMockLogicalstatemanagerIntervalGetter(t)
+		mockLightStateSetter := mocks.NewMockLogicalstatemanagerLightStateSetter(t)
+		sch := models.Schedule{Name: "Hall", Occupancy: &models.ScheduleOccupancy{Sensors: []string{"Hall sensor"}}}
+
+		mockIntervalGetter.On("GetScheduleIntervalForTime", sch, mock.Anything).Return(interval, nil)
+
+		// hugh has only just started so doesn't know if anyone is there, the lights shouldn't be switched off
+		mockDBAccess.On("UpdateTargetState", "Hall", mock.MatchedBy(func(s models.LightState) bool { return s.On })).Return(nil).Once()
+
+		// act
+		lsm := logicalstatemanager.NewLogicalStateManager(logger, mockDBAccess, mockIntervalGetter, mockLightStateSetter)
+		lsm.UpdateAllTargetStates([]models.Schedule{sch}, time.Now())
+	})
+
+	t.Run("once vacated: should turn the lights off whatever the pattern says", func(t *testing.T) {
+		// arrange
+		logger := log.NewWithOptions(os.Stderr, log.Options{Level: log.FatalLevel})
+		mockDBAccess := mocks.NewMockLogicalstatemanagerDbAccess(t)
+		mockIntervalGetter := mocks.NewMockLogicalstatemanagerIntervalGetter(t)
+		mockLightStateSetter := mocks.NewMockLogicalstatemanagerLightStateSetter(t)
+		sch := models.Schedule{Name: "Hall", Occupancy: &models.ScheduleOccupancy{Sensors: []string{"Hall sensor"}, Timeout: 10 * time.Millisecond}}
+
+		mockDBAccess.On("GetSensorSchedules", "motion1").Return([]string{"Hall"}, nil)
+		mockIntervalGetter.On("GetScheduleIntervalForTime", sch, mock.Anything).Return(interval, nil)
+		mockDBAccess.On("GetControllingLightIDsForSchedule", "Hall").Return([]string{"ls1"}, nil)
+		mockDBAccess.On("UpdateTargetState", "Hall", mock.MatchedBy(func(s models.LightState) bool { return s.On })).Return(nil).Once()
+		mockLightStateSetter.On("SetLightStateToTarget", mock.Anything, "ls1", mock.Anything).Return(nil).Once()
+		faded := make(chan struct{})
+		mockLightStateSetter.On("FadeLightToTarget", mock.Anything, "ls1", mock.Anything, constants.VacancyTransition).Return(nil).Once().
+			Run(func(mock.Arguments) { close(faded) })
+
+		// nobody has been seen for the timeout, so the lights should be off from then on
+		mockDBAccess.On("UpdateTargetState", "Hall", mock.MatchedBy(func(s models.LightState) bool { return !s.On })).Return(nil).Twice()
+
+		// act
+		lsm := logicalstatemanager.NewLogicalStateManager(logger, mockDBAccess, mockIntervalGetter, mockLightStateSetter)
+		lsm.HandleSensorEvent(context.Background(), motionEvent(t, "motion1", true), []models.Schedule{sch})
+		select {
+		case <-faded:
+		case <-time.After(5 * time.Second):
+			t.Fatal("lights weren't faded off")
+		}
+		lsm.UpdateAllTargetStates([]models.Schedule{sch}, time.Now())
+	})
+}
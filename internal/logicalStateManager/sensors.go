@@ -0,0 +1,76 @@
+package logicalstatemanager
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	sse "github.com/r3labs/sse/v2"
+	"github.com/samber/lo"
+	"github.com/wheelibin/hugh/internal/constants"
+	"github.com/wheelibin/hugh/internal/models"
+)
+
+func (m *LogicalStateManager) AddSensors(sensors []models.HughSensor) error {
+	return m.dbAccess.AddSensors(sensors)
+}
+
+func (m *LogicalStateManager) SyncSensors(sensors []models.HughSensor) error {
+	return m.dbAccess.SyncSensors(sensors)
+}
+
+// HandleSensorEvent passes the readings of the schedules' sensors on to occupancy and daylight dimming, and performs
+// the actions bound to switch buttons and dials
+func (m *LogicalStateManager) HandleSensorEvent(ctx context.Context, event *sse.Event, schedules []models.Schedule) {
+	events := []models.Event{}
+	if err := json.Unmarshal(event.Data, &events); err != nil {
+		m.logger.Error(err)
+	}
+
+	for _, evt := range events {
+		if evt.Type != constants.EventBatchTypeUpdate {
+			continue
+		}
+
+		for _, eventData := range evt.Data {
+			if eventData.Type == constants.EventTypeButton || eventData.Type == constants.EventTypeRelativeRotary {
+				m.handleSwitchEvent(ctx, eventData, schedules)
+				continue
+			}
+
+			var handle func(sch models.Schedule)
+
+			switch {
+			case eventData.Type == constants.EventTypeMotion && eventData.Motion != nil && eventData.Motion.Motion,
+				eventData.Type == constants.EventTypeContact && eventData.ContactReport != nil && eventData.ContactReport.State == constants.ContactStateOpen:
+				handle = func(sch models.Schedule) {
+					if sch.Occupancy != nil {
+						m.occupy(ctx, sch, time.Now())
+					}
+				}
+
+			case eventData.Type == constants.EventTypeLightLevel && eventData.Light != nil && eventData.Light.LightLevelValid:
+				lightLevel := eventData.Light.LightLevel
+				handle = func(sch models.Schedule) {
+					if sch.Daylight != nil {
+						m.updateAmbientLight(sch, lightLevel)
+					}
+				}
+
+			default:
+				continue
+			}
+
+			scheduleNames, err := m.dbAccess.GetSensorSchedules(eventData.Id)
+			if err != nil {
+				m.logger.Error(err)
+				continue
+			}
+			for _, name := range scheduleNames {
+				if sch, found := lo.Find(schedules, func(s models.Schedule) bool { return s.Name == name }); found {
+					handle(sch)
+				}
+			}
+		}
+	}
+}
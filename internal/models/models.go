@@ -1,6 +1,12 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/wheelibin/hugh/internal/colour"
+)
 
 type HughLight struct {
 	Id              string
@@ -14,6 +20,9 @@ type HughLight struct {
 	MinColorTemperatuerMirek int
 	MaxColorTemperatuerMirek int
 
+	// the colours the light can produce, nil for lights without colour support
+	Gamut *colour.Gamut
+
 	// whether the light was reachable during the last attempted update
 	Reachable bool
 
@@ -34,18 +43,53 @@ type HughGroup struct {
 	DeviceIds []string
 	// light service ids in the zone
 	LightServiceIds []string
+	// the grouped_light service that controls every light in the group with a single command
+	GroupedLightServiceId string
 }
 
 type LightState struct {
 	Brightness       int
 	TemperatureMirek int
-	On               bool
+	// the colour for lights that support it, nil to use TemperatureMirek
+	// (TemperatureMirek is still set to the nearest colour temperature for lights that don't)
+	Colour *colour.XY
+	On     bool
+	// how long the light takes to fade to this state, the bridge default if 0
+	Transition time.Duration
 
 	AutoOnFrom     string
 	AutoOnTo       string
 	CurrentOnState bool
 }
 
+// the state of a light, as read from the bridge or as hugh last knew it to be
+type LightStatus struct {
+	LightServiceId   string
+	ZigbeeServiceID  string
+	On               bool
+	Brightness       int
+	TemperatureMirek int
+	Reachable        bool
+}
+
+// a motion, contact or light_level sensor service used by a schedule
+type HughSensor struct {
+	ID           string
+	Type         string
+	ScheduleName string
+}
+
+// a switch button or dial service bound to an action on a schedule
+type HughBinding struct {
+	// the button or relative_rotary service id
+	ID string
+	// the button event that triggers the action, empty for dials
+	Event        string
+	Action       string
+	ScheduleName string
+	Duration     time.Duration
+}
+
 type HughScene struct {
 	ID           string
 	ScheduleName string
@@ -68,20 +112,152 @@ type EventData struct {
 	ColorTemperature *struct {
 		Mirek int `json:"mirek"`
 	} `json:"color_temperature"`
+	Motion *struct {
+		Motion bool `json:"motion"`
+	} `json:"motion"`
+	ContactReport *struct {
+		State string `json:"state"`
+	} `json:"contact_report"`
+	// the ambient light reported by a light_level sensor
+	Light *struct {
+		LightLevel      int  `json:"light_level"`
+		LightLevelValid bool `json:"light_level_valid"`
+	} `json:"light"`
+	// a press of a switch button
+	Button *struct {
+		ButtonReport *struct {
+			Event string `json:"event"`
+		} `json:"button_report"`
+		// reported by older bridge firmware instead of button_report
+		LastEvent string `json:"last_event"`
+	} `json:"button"`
+	// a turn of a dial
+	RelativeRotary *struct {
+		RotaryReport *struct {
+			Rotation struct {
+				Direction string `json:"direction"`
+				Steps     int    `json:"steps"`
+			} `json:"rotation"`
+		} `json:"rotary_report"`
+	} `json:"relative_rotary"`
+	// set when something is renamed
+	Metadata *struct {
+		Name string `json:"name"`
+	} `json:"metadata"`
+	// set when the lights in a room or zone change
+	Children []struct {
+		Rid   string `json:"rid"`
+		Rtype string `json:"rtype"`
+	} `json:"children"`
 	Type   string `json:"type"`
 	Status string `json:"status"`
 }
 
+// a hue bridge hugh is paired with
+type Bridge struct {
+	Name           string `json:"name"`
+	IP             string `json:"ip"`
+	ApplicationKey string `json:"applicationKey"`
+	// the id reported by the bridge, its certificate is issued to this
+	ID string `json:"id"`
+	// how the bridge certificate is verified (ca, pin or insecure)
+	Certificate string `json:"certificate"`
+}
+
 type Schedule struct {
-	Name       string   `json:"name"`
-	Disabled   bool     `json:"disabled"`
-	Rooms      []string `json:"rooms"`
-	Zones      []string `json:"zones"`
-	DayPattern string   `json:"dayPattern"`
+	Name     string `json:"name"`
+	Disabled bool   `json:"disabled"`
+	// the bridge the rooms/zones are on, all bridges if empty
+	Bridge string   `json:"bridge"`
+	Rooms  []string `json:"rooms"`
+	Zones  []string `json:"zones"`
+	// the name of the day pattern, or the patterns by weekday/date
+	DayPattern DayPatternSelector `json:"dayPattern"`
 	AutoOn     *struct {
 		From string `json:"from"`
 		To   string `json:"to"`
 	} `json:"autoOn"`
+	// turns the lights on when someone is there, inside the autoOn window if there is one
+	Occupancy *ScheduleOccupancy `json:"occupancy"`
+	// dims the lights as daylight brightens the room
+	Daylight *ScheduleDaylight `json:"daylight"`
+}
+
+// maps a button of a switch, or the turning of a dial, to an action on a schedule
+type Binding struct {
+	// the name of the dimmer switch, smart button or tap dial (as shown in the hue app)
+	Device string `json:"device"`
+	// the button, numbered from 1 in the order the bridge lists them (1 is the top button of a dimmer switch),
+	// not used by the brightness action which binds the dial
+	Button int `json:"button"`
+	// the button event that triggers the action, defaults to short_release
+	Event string `json:"event"`
+	// resume, pause, next, windDown or, for the dial, brightness
+	Action   string `json:"action"`
+	Schedule string `json:"schedule"`
+	// how long a pause or wind down lasts, defaults to constants.DefaultPauseDuration/DefaultWindDownDuration
+	Duration time.Duration `json:"duration"`
+}
+
+// the day patterns a schedule uses keyed by date (2006-01-02), weekday (mon..sun) or DayPatternDefault,
+// a date taking precedence over its weekday. Configured either as a map or as the name of a single pattern.
+type DayPatternSelector map[string]string
+
+// the key of the pattern used on days without their own
+const DayPatternDefault = "default"
+
+func (d *DayPatternSelector) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		*d = DayPatternSelector{DayPatternDefault: name}
+		return nil
+	}
+	var patterns map[string]string
+	if err := json.Unmarshal(data, &patterns); err != nil {
+		return fmt.Errorf("dayPattern should be a pattern name or a map of patterns: %w", err)
+	}
+	*d = DayPatternSelector(patterns)
+	return nil
+}
+
+// a calendar whose events change the day pattern of schedules, or disable them, while they are on
+type Calendar struct {
+	// a local .ics file, or a url the calendar is downloaded from (cached to disk for when it can't be reached)
+	File string `json:"file"`
+	URL  string `json:"url"`
+	// how often the calendar is read again, defaults to constants.DefaultCalendarRefresh
+	Refresh time.Duration  `json:"refresh"`
+	Modes   []CalendarMode `json:"modes"`
+}
+
+type CalendarMode struct {
+	// matched against the names (contained in, ignoring case) and categories (ignoring case) of the events
+	Match string `json:"match"`
+	// the day pattern used while the event is on
+	DayPattern string `json:"dayPattern"`
+	// leaves the lights alone while the event is on
+	Disable bool `json:"disable"`
+	// the schedules the mode applies to, every schedule if empty
+	Schedules []string `json:"schedules"`
+}
+
+type ScheduleOccupancy struct {
+	// the names of the motion and contact sensors (as shown in the hue app)
+	Sensors []string `json:"sensors"`
+	// how long after the last motion the lights fade off, defaults to constants.DefaultOccupancyTimeout
+	Timeout time.Duration `json:"timeout"`
+}
+
+type ScheduleDaylight struct {
+	// the name of the motion sensor whose light level is used (as shown in the hue app)
+	Sensor string `json:"sensor"`
+	// the ambient light level the lights make up to, once daylight reaches it the lights are at minBrightness
+	TargetLux float64 `json:"targetLux"`
+	// the range the dimmed brightness is kept within, maxBrightness defaults to 100
+	MinBrightness int `json:"minBrightness"`
+	MaxBrightness int `json:"maxBrightness"`
+	// how far the light level has to move before the brightness follows, defaults to 10% of targetLux
+	Hysteresis float64 `json:"hysteresis"`
 }
 
 type ScheduleDayPatternStep struct {
@@ -90,15 +266,44 @@ type ScheduleDayPatternStep struct {
 	Brightness   int    `json:"brightness"`
 	TransitionAt int    `json:"transitionAt"`
 	Off          bool   `json:"off"`
+	// how long each update during this step takes to fade in, defaults to the time until the next update
+	Transition time.Duration `json:"transition"`
+	// a colour for colour capable lights, either xy coordinates or a hex/named colour (replaces temperature)
+	XY     []float64 `json:"xy"`
+	Colour string    `json:"colour"`
+	// how the lights move from this step to the next: linear (the default), easeIn, easeOut, easeInOut, sigmoid or step
+	Easing string `json:"easing"`
+	// moves the brightness evenly to the eye (in CIE L*) and the temperature in mired, rather than in kelvin
+	PerceptualBrightness bool `json:"perceptualBrightness"`
+	MiredTemperature     bool `json:"miredTemperature"`
+	// in place of a step, the steps of the named pattern fragment
+	Include string `json:"include"`
+	// in a pattern extending another, removes the extended pattern's step with the same time
+	Remove bool `json:"remove"`
 }
 
 type DayPattern struct {
-	Name       string `json:"name"`
+	Name string `json:"name"`
+	// the day pattern this one is based on, it only needs the fields and steps (by time) that differ
+	Extends    string `json:"extends"`
 	Type       string `json:"type"`
 	SunriseMin string `json:"sunriseMin"`
 	SunriseMax string `json:"sunriseMax"`
 	SunsetMin  string `json:"sunsetMin"`
 	SunsetMax  string `json:"sunsetMax"`
+	// the clamps for the other sun events, each optional
+	CivilDawnMin    string `json:"civilDawnMin"`
+	CivilDawnMax    string `json:"civilDawnMax"`
+	CivilDuskMin    string `json:"civilDuskMin"`
+	CivilDuskMax    string `json:"civilDuskMax"`
+	NauticalDawnMin string `json:"nauticalDawnMin"`
+	NauticalDawnMax string `json:"nauticalDawnMax"`
+	NauticalDuskMin string `json:"nauticalDuskMin"`
+	NauticalDuskMax string `json:"nauticalDuskMax"`
+	SolarNoonMin    string `json:"solarNoonMin"`
+	SolarNoonMax    string `json:"solarNoonMax"`
+	GoldenHourMin   string `json:"goldenHourMin"`
+	GoldenHourMax   string `json:"goldenHourMax"`
 
 	Default struct {
 		Time        string `json:"time"`
@@ -106,4 +311,13 @@ type DayPattern struct {
 		Brightness  int    `json:"brightness"`
 	} `json:"default"`
 	Pattern []ScheduleDayPatternStep `json:"pattern"`
+	// for solar day patterns, the lights follow the sun's elevation through these points instead of the steps
+	Elevations []SolarPatternPoint `json:"elevations"`
+}
+
+// the temperature and brightness of the lights when the sun is at the elevation (in degrees, negative below the horizon)
+type SolarPatternPoint struct {
+	Elevation   float64 `json:"elevation"`
+	Temperature int     `json:"temperature"`
+	Brightness  int     `json:"brightness"`
 }
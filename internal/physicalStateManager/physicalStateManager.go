@@ -1,15 +1,20 @@
 package physicalstatemanager
 
 import (
-	"crypto/tls"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"net/http"
+	"reflect"
+	"sync"
 	"time"
 
 	"github.com/charmbracelet/log"
 	sse "github.com/r3labs/sse/v2"
+	"github.com/samber/lo"
 	"github.com/spf13/viper"
 	"github.com/wheelibin/hugh/internal/concurrency"
+	"github.com/wheelibin/hugh/internal/constants"
 	"github.com/wheelibin/hugh/internal/hue"
 	"github.com/wheelibin/hugh/internal/models"
 	"github.com/wheelibin/hugh/internal/schedule"
@@ -17,45 +22,101 @@ import (
 
 type hueApiService interface {
 	DiscoverLights(schedules []models.Schedule) ([]models.HughLight, error)
+	DiscoverGroups(schedules []models.Schedule) ([]models.HughGroup, error)
+	DiscoverSensors(schedules []models.Schedule) ([]models.HughSensor, error)
+	DiscoverBindings(bindings []models.Binding) ([]models.HughBinding, error)
 	GetScenes() ([]hue.HueScene, error)
-	UpdateLightState(lsID string, targetState models.LightState) error
-	UpdateSceneState(ID string, targetState models.LightState) error
+	UpdateLightState(ctx context.Context, lsID string, targetState models.LightState) error
+	UpdateLightPowerup(ctx context.Context, lsID string, targetState models.LightState) error
+	UpdateGroupedLightState(ctx context.Context, groupedLightID string, targetState models.LightState) error
+	UpdateSceneState(ctx context.Context, ID string, targetState models.LightState) error
+	GetLightStatuses() ([]models.LightStatus, error)
+	LoadResources() error
+	ApplyEvent(event *sse.Event)
+	NewEventStreamClient() *sse.Client
 }
 
 type dbAccess interface {
 	GetLightTargetState(lsID string) (models.LightState, error)
 	GetSceneTargetState(id string) (models.LightState, error)
 	GetAllControllingLightIDs() ([]string, error)
+	GetAllLightIDs() ([]string, error)
 	GetAllSceneIDs() ([]string, error)
+	GetGroupedLights() ([]models.HughGroup, error)
 	MarkLightAsUpdated(lsID string) error
 	SetLightUnreachable(lsID string) error
+	RemoveLight(lsID string) error
+	GetLightStatuses() ([]models.LightStatus, error)
+}
+
+type commandScheduler interface {
+	Submit(ctx context.Context, kind concurrency.CommandKind, key string, run func(ctx context.Context) error) <-chan error
+	Do(ctx context.Context, kind concurrency.CommandKind, key string, run func(ctx context.Context) error) error
 }
 
 type PhysicalStateManager struct {
 	logger        *log.Logger
 	hueApiService hueApiService
 	dbAccess      dbAccess
+	scheduler     commandScheduler
+
+	// how long lights take to fade to their target when hugh reacts to an event, e.g. a light being switched on
+	eventTransition time.Duration
+
+	// the least time between updates to a light's power-on behaviour, 0 leaves it alone
+	powerupInterval time.Duration
+	powerupsMu      sync.Mutex
+	powerups        map[string]sentPowerup
 
 	client       *sse.Client
 	eventChannel chan *sse.Event
+	// events from the stream, applied to the resource cache before being passed on to eventChannel
+	streamEvents chan *sse.Event
+	// closed when unsubscribing, so a resync in progress stops waiting to send its events
+	unsubscribed chan struct{}
 }
 
 func NewPhysicalStateManager(
 	logger *log.Logger,
 	lightManager hueApiService,
 	dbAccess dbAccess,
+	scheduler commandScheduler,
 ) *PhysicalStateManager {
 	return &PhysicalStateManager{
 		logger:        logger,
 		hueApiService: lightManager,
 		dbAccess:      dbAccess,
+		scheduler:     scheduler,
+
+		eventTransition: viper.GetDuration("eventTransition"),
+		powerupInterval: powerupInterval(),
+		powerups:        map[string]sentPowerup{},
+	}
+}
+
+func powerupInterval() time.Duration {
+	if !viper.IsSet("powerupInterval") {
+		return constants.DefaultPowerupInterval
 	}
+	return viper.GetDuration("powerupInterval")
 }
 
 func (m *PhysicalStateManager) DiscoverLights(schedules []models.Schedule) ([]models.HughLight, error) {
 	return m.hueApiService.DiscoverLights(schedules)
 }
 
+func (m *PhysicalStateManager) DiscoverGroups(schedules []models.Schedule) ([]models.HughGroup, error) {
+	return m.hueApiService.DiscoverGroups(schedules)
+}
+
+func (m *PhysicalStateManager) DiscoverSensors(schedules []models.Schedule) ([]models.HughSensor, error) {
+	return m.hueApiService.DiscoverSensors(schedules)
+}
+
+func (m *PhysicalStateManager) DiscoverBindings(bindings []models.Binding) ([]models.HughBinding, error) {
+	return m.hueApiService.DiscoverBindings(bindings)
+}
+
 func (m *PhysicalStateManager) DiscoverScenes(schedules []models.Schedule) ([]models.HughScene, error) {
 	scenes, err := m.hueApiService.GetScenes()
 	if err != nil {
@@ -83,61 +144,218 @@ func (m *PhysicalStateManager) DiscoverScenes(schedules []models.Schedule) ([]mo
 
 func (m *PhysicalStateManager) SubscribeToLightUpdateEvents(eventChannel chan *sse.Event) {
 	m.eventChannel = eventChannel
-	m.client = sse.NewClient(fmt.Sprintf("https://%s/eventstream/clip/v2", viper.GetString("bridgeIp")))
+	m.streamEvents = make(chan *sse.Event)
+	m.unsubscribed = make(chan struct{})
+	m.client = m.hueApiService.NewEventStreamClient()
+	go m.forwardEvents()
 
-	m.client.Connection.Transport = &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-	}
-	m.client.Headers["hue-application-key"] = viper.GetString("hueApplicationKey")
+	// the callbacks are called in turn by the client as the stream drops and reconnects
+	var disconnectedAt time.Time
 
 	m.client.OnConnect(func(_ *sse.Client) {
 		m.logger.Info("Connected to HUE bridge, listening for events...")
+		if !disconnectedAt.IsZero() {
+			// the client is waiting on this callback to start reading events
+			go m.resync(time.Since(disconnectedAt))
+		}
 	})
 	m.client.OnDisconnect(func(c *sse.Client) {
 		m.logger.Info("Disconnected from HUE bridge")
+		disconnectedAt = time.Now()
 	})
 
-	if err := m.client.SubscribeChan("", m.eventChannel); err != nil {
+	if err := m.client.SubscribeChan("", m.streamEvents); err != nil {
 		m.logger.Errorf("error subscribing to light updates: %s", err)
 	}
 }
 
 func (m *PhysicalStateManager) UnsubscribeFromBrideEvents() {
 	m.logger.Debug("Unsubscribe events")
-	m.client.Unsubscribe(m.eventChannel)
+	close(m.unsubscribed)
+	m.client.Unsubscribe(m.streamEvents)
+}
+
+// keeps the resource cache up to date before passing each event on to be handled
+func (m *PhysicalStateManager) forwardEvents() {
+	for {
+		select {
+		case <-m.unsubscribed:
+			return
+		case event := <-m.streamEvents:
+			m.hueApiService.ApplyEvent(event)
+			select {
+			case m.eventChannel <- event:
+			case <-m.unsubscribed:
+				return
+			}
+		}
+	}
+}
+
+// sends the events missed while the event stream was disconnected, so they are handled as if they had been received
+func (m *PhysicalStateManager) resync(disconnectedFor time.Duration) {
+	m.logger.Info("Checking for changes missed while disconnected from the HUE bridge", "disconnectedFor", disconnectedFor.Round(time.Second))
+
+	// resources may have been added, changed or removed too
+	if err := m.hueApiService.LoadResources(); err != nil {
+		m.logger.Error("unable to reload resources from the bridge", "err", err)
+	}
+
+	event, err := m.ReconcileLightStates(time.Now())
+	if err != nil {
+		m.logger.Error("unable to check for missed changes, overrides may be out of date", "err", err)
+		return
+	}
+	if event == nil {
+		return
+	}
+
+	select {
+	case m.eventChannel <- event:
+	case <-m.unsubscribed:
+	}
 }
 
-func (m *PhysicalStateManager) SetLightStateToTarget(lsID string, currentTime time.Time) error {
+// ReconcileLightStates compares the lights on the bridge with the state hugh last knew them to be in, returning
+// the events that would have been received for the differences (nil if there are none)
+func (m *PhysicalStateManager) ReconcileLightStates(eventTime time.Time) (*sse.Event, error) {
+	known, err := m.dbAccess.GetLightStatuses()
+	if err != nil {
+		return nil, err
+	}
+	current, err := m.hueApiService.GetLightStatuses()
+	if err != nil {
+		return nil, err
+	}
+	currentByID := lo.KeyBy(current, func(s models.LightStatus) string { return s.LightServiceId })
+
+	var (
+		missed  []models.EventData
+		summary struct{ switchedOn, switchedOff, adjusted, unreachable, reachable int }
+	)
+
+	for _, k := range known {
+		c, found := currentByID[k.LightServiceId]
+		if !found {
+			continue
+		}
+
+		if c.Reachable != k.Reachable {
+			status := constants.EventStatusConnectivityIssue
+			if c.Reachable {
+				status = constants.EventStatusConnected
+				summary.reachable++
+			} else {
+				summary.unreachable++
+			}
+			missed = append(missed, models.EventData{Id: k.ZigbeeServiceID, Type: constants.EventTypeZigbeeConnectivity, Status: status})
+			// a light coming back is handled as being powered on, which sets it to its target
+			continue
+		}
+		if !c.Reachable {
+			continue
+		}
+
+		if c.On != k.On {
+			missed = append(missed, models.EventData{Id: k.LightServiceId, Type: constants.EventTypeLight, On: &struct {
+				On bool `json:"on"`
+			}{On: c.On}})
+			if c.On {
+				summary.switchedOn++
+			} else {
+				summary.switchedOff++
+			}
+			continue
+		}
+		if !c.On {
+			continue
+		}
+
+		changed := models.EventData{Id: k.LightServiceId, Type: constants.EventTypeLight}
+		if k.Brightness > 0 && abs(c.Brightness-k.Brightness) > constants.OverrideToleranceBrightness {
+			changed.Dimming = &struct {
+				Brightness float64 `json:"brightness"`
+			}{Brightness: float64(c.Brightness)}
+		}
+		if k.TemperatureMirek > 0 && c.TemperatureMirek > 0 && abs(c.TemperatureMirek-k.TemperatureMirek) > constants.OverrideToleranceColourTemp {
+			changed.ColorTemperature = &struct {
+				Mirek int `json:"mirek"`
+			}{Mirek: c.TemperatureMirek}
+		}
+		if changed.Dimming != nil || changed.ColorTemperature != nil {
+			missed = append(missed, changed)
+			summary.adjusted++
+		}
+	}
+
+	m.logger.Info("Changes missed while disconnected from the HUE bridge",
+		"switchedOn", summary.switchedOn,
+		"switchedOff", summary.switchedOff,
+		"adjusted", summary.adjusted,
+		"becameUnreachable", summary.unreachable,
+		"becameReachable", summary.reachable,
+	)
+
+	if len(missed) == 0 {
+		return nil, nil
+	}
+
+	data, err := json.Marshal([]models.Event{{CreationTime: eventTime, Type: constants.EventBatchTypeUpdate, Data: missed}})
+	if err != nil {
+		return nil, err
+	}
+	return &sse.Event{Data: data}, nil
+}
+
+func abs(i int) int {
+	if i < 0 {
+		return -i
+	}
+	return i
+}
+
+// SetLightStateToTarget queues an update of the light to its current target and waits for it to be sent
+func (m *PhysicalStateManager) SetLightStateToTarget(ctx context.Context, lsID string, currentTime time.Time) error {
+	return m.scheduler.Do(ctx, concurrency.LightCommand, lightCommandKey(lsID), func(ctx context.Context) error {
+		return m.setLightStateToTarget(ctx, lsID, currentTime, func(models.LightState) time.Duration { return m.eventTransition })
+	})
+}
+
+// FadeLightToTarget sets the light to its target over the transition, e.g. to fade off a room that is now empty
+func (m *PhysicalStateManager) FadeLightToTarget(ctx context.Context, lsID string, currentTime time.Time, transition time.Duration) error {
+	return m.scheduler.Do(ctx, concurrency.LightCommand, lightCommandKey(lsID), func(ctx context.Context) error {
+		return m.setLightStateToTarget(ctx, lsID, currentTime, func(models.LightState) time.Duration { return transition })
+	})
+}
+
+func (m *PhysicalStateManager) setLightStateToTarget(ctx context.Context, lsID string, currentTime time.Time, transition func(target models.LightState) time.Duration) error {
 	target, err := m.dbAccess.GetLightTargetState(lsID)
 	if err != nil {
 		return err
 	}
+	target.Transition = transition(target)
 
 	m.logger.Debugf("setting light (%s) to target: %v", lsID, target)
 
-	var skipUpdate bool
-	if !target.CurrentOnState && target.On && target.AutoOnFrom != "" && target.AutoOnTo != "" {
-		// if we're outside the auto on window then don't turn the light on
-		from := schedule.TimeFromConfigTimeString(target.AutoOnFrom, currentTime)
-		to := schedule.TimeFromConfigTimeString(target.AutoOnTo, currentTime)
-		skipUpdate = currentTime.Before(from) || currentTime.After(to)
-	}
-
-	if skipUpdate {
+	if skipUpdate(target, currentTime) {
 		m.logger.Debugf("not turning light (%s) on, outside window", lsID)
 		return nil
 	}
 
-	err = m.hueApiService.UpdateLightState(lsID, target)
-	if err != nil {
-		if err.Error() == "unreachable" {
-			err := m.dbAccess.SetLightUnreachable(lsID)
-			if err != nil {
-				return err
-			}
-		} else {
+	err = m.hueApiService.UpdateLightState(ctx, lsID, target)
+	switch {
+	case err == nil:
+	case errors.Is(err, hue.ErrUnreachable):
+		err := m.dbAccess.SetLightUnreachable(lsID)
+		if err != nil {
 			return err
 		}
+	case errors.Is(err, hue.ErrNotFound):
+		// deleted from the bridge without hugh seeing the event, stop controlling it
+		m.logger.Warn("Light no longer exists on the bridge, it will no longer be controlled", "light", lsID)
+		return m.dbAccess.RemoveLight(lsID)
+	default:
+		return err
 	}
 
 	// mark the light as updated in the db (clearing unreachable/manual overrides)
@@ -148,13 +366,143 @@ func (m *PhysicalStateManager) SetLightStateToTarget(lsID string, currentTime ti
 	return nil
 }
 
-func (m *PhysicalStateManager) SetSceneStateToTarget(ID string) error {
+// how long a scheduled update takes to fade in, unless the pattern step sets its own transition the light
+// fades continuously until the next update
+func scheduledTransition(target models.LightState) time.Duration {
+	if target.Transition > 0 {
+		return target.Transition
+	}
+	return constants.MainUpdateInterval
+}
+
+// whether the light is off and the target would turn it on outside of its auto on window
+func skipUpdate(target models.LightState, currentTime time.Time) bool {
+	if !target.CurrentOnState && target.On && target.AutoOnFrom != "" && target.AutoOnTo != "" {
+		// if we're outside the auto on window then don't turn the light on
+		from := schedule.TimeFromConfigTimeString(target.AutoOnFrom, currentTime)
+		to := schedule.TimeFromConfigTimeString(target.AutoOnTo, currentTime)
+		return currentTime.Before(from) || currentTime.After(to)
+	}
+	return false
+}
+
+// sends a single grouped_light command for each room/zone whose lights all need the same update,
+// returning the ids of the lights that still need updating individually
+func (m *PhysicalStateManager) SetGroupedLightStatesToTarget(ctx context.Context, lightIDs []string, currentTime time.Time) ([]string, error) {
+	groups, err := m.dbAccess.GetGroupedLights()
+	if err != nil {
+		return lightIDs, err
+	}
+
+	pending := lo.SliceToMap(lightIDs, func(lsID string) (string, bool) { return lsID, true })
+
+	type groupUpdate struct {
+		group  models.HughGroup
+		result <-chan error
+	}
+	updates := []groupUpdate{}
+
+	for _, group := range groups {
+		target, ok := m.commonGroupTarget(group, pending, currentTime)
+		if !ok {
+			continue
+		}
+
+		// claim the lights so they can't also be sent as part of an overlapping group
+		for _, lsID := range group.LightServiceIds {
+			delete(pending, lsID)
+		}
+
+		group := group
+		target.Transition = scheduledTransition(target)
+		result := m.scheduler.Submit(ctx, concurrency.GroupCommand, groupCommandKey(group.GroupedLightServiceId), func(ctx context.Context) error {
+			return m.hueApiService.UpdateGroupedLightState(ctx, group.GroupedLightServiceId, target)
+		})
+		updates = append(updates, groupUpdate{group: group, result: result})
+	}
+
+	for _, update := range updates {
+		var err error
+		select {
+		case err = <-update.result:
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+
+		for _, lsID := range update.group.LightServiceIds {
+			if err != nil {
+				// leave the lights to be updated one by one
+				pending[lsID] = true
+				continue
+			}
+			err := m.dbAccess.MarkLightAsUpdated(lsID)
+			if err != nil {
+				m.logger.Error(err)
+			}
+		}
+
+		if err != nil {
+			m.logger.Debugf("grouped light (%s) update failed, falling back to individual updates: %v", update.group.GroupedLightServiceId, err)
+		}
+	}
+
+	return lo.Filter(lightIDs, func(lsID string, _ int) bool { return pending[lsID] }), nil
+}
+
+// returns the target shared by every light in the group, if every one of them needs updating to the same state
+func (m *PhysicalStateManager) commonGroupTarget(group models.HughGroup, pending map[string]bool, currentTime time.Time) (models.LightState, bool) {
+	// lights with overrides (or not controlled by hugh at all) won't be pending
+	allPending := lo.EveryBy(group.LightServiceIds, func(lsID string) bool { return pending[lsID] })
+	if !allPending || len(group.LightServiceIds) == 0 {
+		return models.LightState{}, false
+	}
+
+	var common models.LightState
+
+	for i, lsID := range group.LightServiceIds {
+		target, err := m.dbAccess.GetLightTargetState(lsID)
+		if err != nil {
+			m.logger.Error(err)
+			return models.LightState{}, false
+		}
+		if skipUpdate(target, currentTime) {
+			return models.LightState{}, false
+		}
+		// colours are mapped into each light's own gamut, so have to be sent light by light
+		if target.On && target.Colour != nil {
+			return models.LightState{}, false
+		}
+
+		if i == 0 {
+			common = target
+			continue
+		}
+		if target.On != common.On || target.Transition != common.Transition {
+			return models.LightState{}, false
+		}
+		// brightness/temperature are ignored when turning off, but may differ where a light's temperature was clamped
+		if target.On && (target.Brightness != common.Brightness || target.TemperatureMirek != common.TemperatureMirek) {
+			return models.LightState{}, false
+		}
+	}
+
+	return common, true
+}
+
+// SetSceneStateToTarget queues an update of the scene to its current target and waits for it to be sent
+func (m *PhysicalStateManager) SetSceneStateToTarget(ctx context.Context, ID string) error {
+	return m.scheduler.Do(ctx, concurrency.LightCommand, sceneCommandKey(ID), func(ctx context.Context) error {
+		return m.setSceneStateToTarget(ctx, ID)
+	})
+}
+
+func (m *PhysicalStateManager) setSceneStateToTarget(ctx context.Context, ID string) error {
 	target, err := m.dbAccess.GetSceneTargetState(ID)
 	if err != nil {
 		return err
 	}
 
-	err = m.hueApiService.UpdateSceneState(ID, target)
+	err = m.hueApiService.UpdateSceneState(ctx, ID, target)
 	if err != nil {
 		return err
 	}
@@ -162,27 +510,152 @@ func (m *PhysicalStateManager) SetSceneStateToTarget(ID string) error {
 	return nil
 }
 
-func (m *PhysicalStateManager) SetAllLightAndSceneStatesToTarget(currentTime time.Time) error {
+func (m *PhysicalStateManager) SetAllLightAndSceneStatesToTarget(ctx context.Context, currentTime time.Time) error {
 	sceneIDs, err := m.dbAccess.GetAllSceneIDs()
 	if err != nil {
 		return err
 	}
 
-	tw := concurrency.NewThrottledWorker(func(arg string) error {
-		return m.SetSceneStateToTarget(arg)
-	})
-	tw.Run(sceneIDs)
+	results := []commandResult{}
+
+	for _, ID := range sceneIDs {
+		ID := ID
+		results = append(results, commandResult{
+			description: fmt.Sprintf("scene (%s)", ID),
+			result: m.scheduler.Submit(ctx, concurrency.LightCommand, sceneCommandKey(ID), func(ctx context.Context) error {
+				return m.setSceneStateToTarget(ctx, ID)
+			}),
+		})
+	}
 
 	lightIDs, err := m.dbAccess.GetAllControllingLightIDs()
 	if err != nil {
 		return err
 	}
 
-	tw = concurrency.NewThrottledWorker(func(arg string) error {
-		return m.SetLightStateToTarget(arg, currentTime)
-	})
-	tw.Run(lightIDs)
+	lightIDs, err = m.SetGroupedLightStatesToTarget(ctx, lightIDs, currentTime)
+	if err != nil {
+		m.logger.Error(err)
+	}
 
-	return nil
+	for _, lsID := range lightIDs {
+		lsID := lsID
+		results = append(results, commandResult{
+			description: fmt.Sprintf("light (%s)", lsID),
+			result: m.scheduler.Submit(ctx, concurrency.LightCommand, lightCommandKey(lsID), func(ctx context.Context) error {
+				return m.setLightStateToTarget(ctx, lsID, currentTime, scheduledTransition)
+			}),
+		})
+	}
+
+	powerups, err := m.setLightPowerupsToTarget(ctx, currentTime)
+	if err != nil {
+		m.logger.Error(err)
+	}
+	results = append(results, powerups...)
+
+	return waitForResults(ctx, results)
+
+}
+
+// the power-on behaviour last sent to a light
+type sentPowerup struct {
+	target models.LightState
+	at     time.Time
+}
+
+// keeps the power-on behaviour of every scheduled light at its target, so a light powered on at the wall comes on
+// right before hugh hears about it. It is stored in the bulb, so a light is only written when its target has changed
+// and no more often than the powerup interval.
+func (m *PhysicalStateManager) setLightPowerupsToTarget(ctx context.Context, currentTime time.Time) ([]commandResult, error) {
+	if m.powerupInterval <= 0 {
+		return nil, nil
+	}
+
+	lightIDs, err := m.dbAccess.GetAllLightIDs()
+	if err != nil {
+		return nil, err
+	}
+
+	results := []commandResult{}
+	for _, lsID := range lightIDs {
+		lsID := lsID
+		target, err := m.dbAccess.GetLightTargetState(lsID)
+		if err != nil {
+			m.logger.Error(err)
+			continue
+		}
+		if !target.On {
+			// the light still comes on when powered, as it was last set
+			continue
+		}
+
+		m.powerupsMu.Lock()
+		sent, found := m.powerups[lsID]
+		due := !found || (currentTime.Sub(sent.at) >= m.powerupInterval && !samePowerup(sent.target, target))
+		if due {
+			// a failed update isn't retried until the interval has passed
+			m.powerups[lsID] = sentPowerup{at: currentTime}
+		}
+		m.powerupsMu.Unlock()
+		if !due {
+			continue
+		}
+
+		results = append(results, commandResult{
+			description: fmt.Sprintf("light (%s) powerup", lsID),
+			result: m.scheduler.Submit(ctx, concurrency.LightCommand, powerupCommandKey(lsID), func(ctx context.Context) error {
+				if err := m.hueApiService.UpdateLightPowerup(ctx, lsID, target); err != nil {
+					return err
+				}
+				m.powerupsMu.Lock()
+				m.powerups[lsID] = sentPowerup{target: target, at: currentTime}
+				m.powerupsMu.Unlock()
+				return nil
+			}),
+		})
+	}
+
+	return results, nil
+}
+
+func samePowerup(a models.LightState, b models.LightState) bool {
+	return a.Brightness == b.Brightness && a.TemperatureMirek == b.TemperatureMirek && reflect.DeepEqual(a.Colour, b.Colour)
+}
+
+type commandResult struct {
+	description string
+	result      <-chan error
+}
+
+// waits for every queued command, combining the errors of any that failed
+func waitForResults(ctx context.Context, results []commandResult) error {
+	errs := []error{}
+	for _, r := range results {
+		select {
+		case err := <-r.result:
+			if err != nil {
+				errs = append(errs, fmt.Errorf("error updating %s: %w", r.description, err))
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func lightCommandKey(lsID string) string {
+	return fmt.Sprintf("light/%s", lsID)
+}
+
+func powerupCommandKey(lsID string) string {
+	return fmt.Sprintf("powerup/%s", lsID)
+}
+
+func groupCommandKey(groupedLightID string) string {
+	return fmt.Sprintf("grouped_light/%s", groupedLightID)
+}
 
+func sceneCommandKey(ID string) string {
+	return fmt.Sprintf("scene/%s", ID)
 }
@@ -1,14 +1,20 @@
 package physicalstatemanager_test
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"testing"
 	"time"
 
 	"github.com/charmbracelet/log"
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/wheelibin/hugh/internal/colour"
+	"github.com/wheelibin/hugh/internal/concurrency"
+	"github.com/wheelibin/hugh/internal/constants"
 	"github.com/wheelibin/hugh/internal/hue"
 	"github.com/wheelibin/hugh/internal/models"
 	physicalstatemanager "github.com/wheelibin/hugh/internal/physicalStateManager"
@@ -16,6 +22,15 @@ import (
 	"github.com/wheelibin/hugh/mocks"
 )
 
+// an unthrottled scheduler that runs for the duration of the test
+func newTestScheduler(t *testing.T) *concurrency.CommandScheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	scheduler := concurrency.NewCommandScheduler(concurrency.RateLimits{}, nil)
+	go scheduler.Run(ctx)
+	return scheduler
+}
+
 func Test_DiscoverLights(t *testing.T) {
 
 	t.Run("should return lights returned from hue service", func(t *testing.T) {
@@ -28,7 +43,7 @@ func Test_DiscoverLights(t *testing.T) {
 		logger := log.NewWithOptions(os.Stderr, log.Options{Level: log.FatalLevel})
 
 		// act
-		psm := physicalstatemanager.NewPhysicalStateManager(logger, mockHueService, mockDBAccess)
+		psm := physicalstatemanager.NewPhysicalStateManager(logger, mockHueService, mockDBAccess, newTestScheduler(t))
 
 		// assert
 		lights, _ := psm.DiscoverLights([]models.Schedule{})
@@ -83,7 +98,7 @@ func Test_DiscoverScenes(t *testing.T) {
 		mockHueService.On("GetScenes", mock.Anything).Return(foundScenes, nil)
 		mockDBAccess := mocks.NewMockPhysicalstatemanagerDbAccess(t)
 		logger := log.NewWithOptions(os.Stderr, log.Options{Level: log.FatalLevel})
-		psm := physicalstatemanager.NewPhysicalStateManager(logger, mockHueService, mockDBAccess)
+		psm := physicalstatemanager.NewPhysicalStateManager(logger, mockHueService, mockDBAccess, newTestScheduler(t))
 
 		// act
 		scenes, _ := psm.DiscoverScenes([]models.Schedule{{Name: "sch001"}, {Name: "mySchedule"}})
@@ -100,6 +115,29 @@ func Test_DiscoverScenes(t *testing.T) {
 func Test_SetLightStateToTarget(t *testing.T) {
 	lsID := "123456"
 
+	t.Run("should fade to the target over the configured event transition", func(t *testing.T) {
+		viper.Set("eventTransition", "400ms")
+		t.Cleanup(func() { viper.Set("eventTransition", nil) })
+
+		// arrange
+		mockDBAccess := mocks.NewMockPhysicalstatemanagerDbAccess(t)
+		mockHueService := mocks.NewMockPhysicalstatemanagerHueApiService(t)
+
+		// expectations
+		mockDBAccess.On("GetLightTargetState", lsID).Return(models.LightState{Brightness: 100, TemperatureMirek: 500, On: true, Transition: 5 * time.Second}, nil)
+		mockDBAccess.On("MarkLightAsUpdated", lsID).Return(nil)
+		mockHueService.On("UpdateLightState", mock.Anything, lsID, models.LightState{Brightness: 100, TemperatureMirek: 500, On: true, Transition: 400 * time.Millisecond}).Return(nil)
+
+		logger := log.NewWithOptions(os.Stderr, log.Options{Level: log.FatalLevel})
+		psm := physicalstatemanager.NewPhysicalStateManager(logger, mockHueService, mockDBAccess, newTestScheduler(t))
+
+		// act
+		err := psm.SetLightStateToTarget(context.Background(), lsID, time.Now())
+
+		// assert
+		assert.NoError(t, err)
+	})
+
 	t.Run("should call hue service to update the light", func(t *testing.T) {
 		t.Parallel()
 
@@ -110,13 +148,13 @@ func Test_SetLightStateToTarget(t *testing.T) {
 		// expectations
 		mockDBAccess.On("GetLightTargetState", lsID).Return(models.LightState{Brightness: 100, TemperatureMirek: 500, On: true}, nil)
 		mockDBAccess.On("MarkLightAsUpdated", lsID).Return(nil)
-		mockHueService.On("UpdateLightState", lsID, mock.Anything).Return(nil)
+		mockHueService.On("UpdateLightState", mock.Anything, lsID, mock.Anything).Return(nil)
 
 		logger := log.NewWithOptions(os.Stderr, log.Options{Level: log.FatalLevel})
-		psm := physicalstatemanager.NewPhysicalStateManager(logger, mockHueService, mockDBAccess)
+		psm := physicalstatemanager.NewPhysicalStateManager(logger, mockHueService, mockDBAccess, newTestScheduler(t))
 
 		// act
-		_ = psm.SetLightStateToTarget(lsID, time.Now())
+		_ = psm.SetLightStateToTarget(context.Background(), lsID, time.Now())
 
 	})
 
@@ -130,17 +168,36 @@ func Test_SetLightStateToTarget(t *testing.T) {
 		// expectations
 		mockDBAccess.On("GetLightTargetState", lsID).Return(models.LightState{Brightness: 100, TemperatureMirek: 500, On: true}, fmt.Errorf("an error"))
 		mockDBAccess.AssertNotCalled(t, "MarkLightAsUpdated", lsID)
-		mockHueService.AssertNotCalled(t, "UpdateLightState", lsID, mock.Anything)
+		mockHueService.AssertNotCalled(t, "UpdateLightState", mock.Anything, lsID, mock.Anything)
 
 		logger := log.NewWithOptions(os.Stderr, log.Options{Level: log.FatalLevel})
-		psm := physicalstatemanager.NewPhysicalStateManager(logger, mockHueService, mockDBAccess)
+		psm := physicalstatemanager.NewPhysicalStateManager(logger, mockHueService, mockDBAccess, newTestScheduler(t))
 
 		// act
-		err := psm.SetLightStateToTarget(lsID, time.Now())
+		err := psm.SetLightStateToTarget(context.Background(), lsID, time.Now())
 		assert.Equal(t, "an error", err.Error())
 
 	})
 
+	t.Run("cancelled before it is sent: should not update the light", func(t *testing.T) {
+		t.Parallel()
+
+		// arrange
+		mockDBAccess := mocks.NewMockPhysicalstatemanagerDbAccess(t)
+		mockHueService := mocks.NewMockPhysicalstatemanagerHueApiService(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		logger := log.NewWithOptions(os.Stderr, log.Options{Level: log.FatalLevel})
+		psm := physicalstatemanager.NewPhysicalStateManager(logger, mockHueService, mockDBAccess, newTestScheduler(t))
+
+		// act
+		err := psm.SetLightStateToTarget(ctx, lsID, time.Now())
+
+		// assert, neither mock expects any calls
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+
 	t.Run("light unreachable: should set as unreachable in db", func(t *testing.T) {
 		t.Parallel()
 
@@ -151,14 +208,62 @@ func Test_SetLightStateToTarget(t *testing.T) {
 		// expectations
 		mockDBAccess.On("GetLightTargetState", lsID).Return(models.LightState{Brightness: 100, TemperatureMirek: 500, On: true}, nil)
 		mockDBAccess.On("MarkLightAsUpdated", lsID).Return(nil)
-		mockHueService.On("UpdateLightState", lsID, mock.Anything).Return(fmt.Errorf("unreachable"))
+		mockHueService.On("UpdateLightState", mock.Anything, lsID, mock.Anything).Return(fmt.Errorf("wrapped: %w", &hue.APIError{Kind: hue.ErrorKindUnreachable, StatusCode: 207}))
 		mockDBAccess.On("SetLightUnreachable", lsID).Return(nil)
 
 		logger := log.NewWithOptions(os.Stderr, log.Options{Level: log.FatalLevel})
-		psm := physicalstatemanager.NewPhysicalStateManager(logger, mockHueService, mockDBAccess)
+		psm := physicalstatemanager.NewPhysicalStateManager(logger, mockHueService, mockDBAccess, newTestScheduler(t))
 
 		// act
-		_ = psm.SetLightStateToTarget(lsID, time.Now())
+		_ = psm.SetLightStateToTarget(context.Background(), lsID, time.Now())
+
+	})
+
+	t.Run("light not found: should remove it from the db without marking it", func(t *testing.T) {
+		t.Parallel()
+
+		// arrange
+		mockDBAccess := mocks.NewMockPhysicalstatemanagerDbAccess(t)
+		mockHueService := mocks.NewMockPhysicalstatemanagerHueApiService(t)
+
+		// expectations
+		mockDBAccess.On("GetLightTargetState", lsID).Return(models.LightState{Brightness: 100, TemperatureMirek: 500, On: true}, nil)
+		mockHueService.On("UpdateLightState", mock.Anything, lsID, mock.Anything).Return(fmt.Errorf("light (%s): %w", lsID, hue.ErrNotFound))
+		mockDBAccess.On("RemoveLight", lsID).Return(nil)
+		mockDBAccess.AssertNotCalled(t, "MarkLightAsUpdated", lsID)
+
+		logger := log.NewWithOptions(os.Stderr, log.Options{Level: log.FatalLevel})
+		psm := physicalstatemanager.NewPhysicalStateManager(logger, mockHueService, mockDBAccess, newTestScheduler(t))
+
+		// act
+		err := psm.SetLightStateToTarget(context.Background(), lsID, time.Now())
+
+		// assert
+		assert.NoError(t, err)
+
+	})
+
+	t.Run("other api error: should return the error without marking the light", func(t *testing.T) {
+		t.Parallel()
+
+		// arrange
+		mockDBAccess := mocks.NewMockPhysicalstatemanagerDbAccess(t)
+		mockHueService := mocks.NewMockPhysicalstatemanagerHueApiService(t)
+
+		// expectations
+		mockDBAccess.On("GetLightTargetState", lsID).Return(models.LightState{Brightness: 100, TemperatureMirek: 500, On: true}, nil)
+		mockHueService.On("UpdateLightState", mock.Anything, lsID, mock.Anything).Return(&hue.APIError{Kind: hue.ErrorKindValidation, StatusCode: 400})
+		mockDBAccess.AssertNotCalled(t, "SetLightUnreachable", lsID)
+		mockDBAccess.AssertNotCalled(t, "MarkLightAsUpdated", lsID)
+
+		logger := log.NewWithOptions(os.Stderr, log.Options{Level: log.FatalLevel})
+		psm := physicalstatemanager.NewPhysicalStateManager(logger, mockHueService, mockDBAccess, newTestScheduler(t))
+
+		// act
+		err := psm.SetLightStateToTarget(context.Background(), lsID, time.Now())
+
+		// assert
+		assert.ErrorIs(t, err, hue.ErrValidation)
 
 	})
 
@@ -179,13 +284,13 @@ func Test_SetLightStateToTarget(t *testing.T) {
 			AutoOnTo:         "11:00",
 		}, nil)
 		mockDBAccess.AssertNotCalled(t, "MarkLightAsUpdated", lsID)
-		mockHueService.AssertNotCalled(t, "UpdateLightState", lsID, mock.Anything)
+		mockHueService.AssertNotCalled(t, "UpdateLightState", mock.Anything, lsID, mock.Anything)
 
 		logger := log.NewWithOptions(os.Stderr, log.Options{Level: log.FatalLevel})
-		psm := physicalstatemanager.NewPhysicalStateManager(logger, mockHueService, mockDBAccess)
+		psm := physicalstatemanager.NewPhysicalStateManager(logger, mockHueService, mockDBAccess, newTestScheduler(t))
 
 		// act
-		_ = psm.SetLightStateToTarget(lsID, time.Date(2023, 1, 1, 8, 0, 0, 0, time.Local))
+		_ = psm.SetLightStateToTarget(context.Background(), lsID, time.Date(2023, 1, 1, 8, 0, 0, 0, time.Local))
 
 	})
 
@@ -206,13 +311,13 @@ func Test_SetLightStateToTarget(t *testing.T) {
 			AutoOnTo:         "11:00",
 		}, nil)
 		mockDBAccess.On("MarkLightAsUpdated", lsID).Return(nil)
-		mockHueService.On("UpdateLightState", lsID, mock.Anything).Return(nil)
+		mockHueService.On("UpdateLightState", mock.Anything, lsID, mock.Anything).Return(nil)
 
 		logger := log.NewWithOptions(os.Stderr, log.Options{Level: log.FatalLevel})
-		psm := physicalstatemanager.NewPhysicalStateManager(logger, mockHueService, mockDBAccess)
+		psm := physicalstatemanager.NewPhysicalStateManager(logger, mockHueService, mockDBAccess, newTestScheduler(t))
 
 		// act
-		_ = psm.SetLightStateToTarget(lsID, time.Date(2023, 1, 1, 10, 30, 0, 0, time.Local))
+		_ = psm.SetLightStateToTarget(context.Background(), lsID, time.Date(2023, 1, 1, 10, 30, 0, 0, time.Local))
 
 	})
 
@@ -231,13 +336,13 @@ func Test_SetLightStateToTarget(t *testing.T) {
 			CurrentOnState:   false,
 		}, nil)
 		mockDBAccess.On("MarkLightAsUpdated", lsID).Return(nil)
-		mockHueService.On("UpdateLightState", lsID, mock.Anything).Return(nil)
+		mockHueService.On("UpdateLightState", mock.Anything, lsID, mock.Anything).Return(nil)
 
 		logger := log.NewWithOptions(os.Stderr, log.Options{Level: log.FatalLevel})
-		psm := physicalstatemanager.NewPhysicalStateManager(logger, mockHueService, mockDBAccess)
+		psm := physicalstatemanager.NewPhysicalStateManager(logger, mockHueService, mockDBAccess, newTestScheduler(t))
 
 		// act
-		_ = psm.SetLightStateToTarget(lsID, time.Date(2023, 1, 1, 10, 30, 0, 0, time.Local))
+		_ = psm.SetLightStateToTarget(context.Background(), lsID, time.Date(2023, 1, 1, 10, 30, 0, 0, time.Local))
 
 	})
 
@@ -256,13 +361,13 @@ func Test_SetLightStateToTarget(t *testing.T) {
 			CurrentOnState:   true,
 		}, nil)
 		mockDBAccess.On("MarkLightAsUpdated", lsID).Return(nil)
-		mockHueService.On("UpdateLightState", lsID, mock.Anything).Return(nil)
+		mockHueService.On("UpdateLightState", mock.Anything, lsID, mock.Anything).Return(nil)
 
 		logger := log.NewWithOptions(os.Stderr, log.Options{Level: log.FatalLevel})
-		psm := physicalstatemanager.NewPhysicalStateManager(logger, mockHueService, mockDBAccess)
+		psm := physicalstatemanager.NewPhysicalStateManager(logger, mockHueService, mockDBAccess, newTestScheduler(t))
 
 		// act
-		_ = psm.SetLightStateToTarget(lsID, time.Date(2023, 1, 1, 10, 30, 0, 0, time.Local))
+		_ = psm.SetLightStateToTarget(context.Background(), lsID, time.Date(2023, 1, 1, 10, 30, 0, 0, time.Local))
 
 	})
 }
@@ -279,13 +384,13 @@ func Test_SetSceneStateToTarget(t *testing.T) {
 
 		// expectations
 		mockDBAccess.On("GetSceneTargetState", id).Return(models.LightState{Brightness: 100, TemperatureMirek: 500, On: true}, nil)
-		mockHueService.On("UpdateSceneState", id, mock.Anything).Return(nil)
+		mockHueService.On("UpdateSceneState", mock.Anything, id, mock.Anything).Return(nil)
 
 		logger := log.NewWithOptions(os.Stderr, log.Options{Level: log.FatalLevel})
-		psm := physicalstatemanager.NewPhysicalStateManager(logger, mockHueService, mockDBAccess)
+		psm := physicalstatemanager.NewPhysicalStateManager(logger, mockHueService, mockDBAccess, newTestScheduler(t))
 
 		// act
-		_ = psm.SetSceneStateToTarget(id)
+		_ = psm.SetSceneStateToTarget(context.Background(), id)
 
 	})
 
@@ -298,16 +403,267 @@ func Test_SetSceneStateToTarget(t *testing.T) {
 
 		// expectations
 		mockDBAccess.On("GetSceneTargetState", id).Return(models.LightState{Brightness: 100, TemperatureMirek: 500, On: true}, fmt.Errorf("an error"))
-		mockHueService.AssertNotCalled(t, "UpdateSceneState", id, mock.Anything)
+		mockHueService.AssertNotCalled(t, "UpdateSceneState", mock.Anything, id, mock.Anything)
 
 		logger := log.NewWithOptions(os.Stderr, log.Options{Level: log.FatalLevel})
-		psm := physicalstatemanager.NewPhysicalStateManager(logger, mockHueService, mockDBAccess)
+		psm := physicalstatemanager.NewPhysicalStateManager(logger, mockHueService, mockDBAccess, newTestScheduler(t))
 
 		// act
-		err := psm.SetSceneStateToTarget(id)
+		err := psm.SetSceneStateToTarget(context.Background(), id)
 
 		// assert
 		assert.Equal(t, "an error", err.Error())
 
 	})
 }
+
+func Test_SetGroupedLightStatesToTarget(t *testing.T) {
+	group := models.HughGroup{GroupedLightServiceId: "grp1", LightServiceIds: []string{"ls1", "ls2"}}
+
+	t.Run("all lights in group share a target: should send a single grouped light update", func(t *testing.T) {
+		t.Parallel()
+
+		// arrange
+		mockDBAccess := mocks.NewMockPhysicalstatemanagerDbAccess(t)
+		mockHueService := mocks.NewMockPhysicalstatemanagerHueApiService(t)
+		target := models.LightState{Brightness: 100, TemperatureMirek: 300, On: true, CurrentOnState: true}
+
+		// expectations
+		mockDBAccess.On("GetGroupedLights").Return([]models.HughGroup{group}, nil)
+		mockDBAccess.On("GetLightTargetState", "ls1").Return(target, nil)
+		mockDBAccess.On("GetLightTargetState", "ls2").Return(target, nil)
+		// scheduled updates fade continuously until the next update
+		expected := target
+		expected.Transition = constants.MainUpdateInterval
+		mockHueService.On("UpdateGroupedLightState", mock.Anything, "grp1", expected).Return(nil).Once()
+		mockDBAccess.On("MarkLightAsUpdated", "ls1").Return(nil)
+		mockDBAccess.On("MarkLightAsUpdated", "ls2").Return(nil)
+
+		logger := log.NewWithOptions(os.Stderr, log.Options{Level: log.FatalLevel})
+		psm := physicalstatemanager.NewPhysicalStateManager(logger, mockHueService, mockDBAccess, newTestScheduler(t))
+
+		// act
+		remaining, err := psm.SetGroupedLightStatesToTarget(context.Background(), []string{"ls1", "ls2", "ls3"}, time.Now())
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"ls3"}, remaining)
+	})
+
+	t.Run("the pattern step sets a transition: should fade over the step's transition", func(t *testing.T) {
+		t.Parallel()
+
+		// arrange
+		mockDBAccess := mocks.NewMockPhysicalstatemanagerDbAccess(t)
+		mockHueService := mocks.NewMockPhysicalstatemanagerHueApiService(t)
+		target := models.LightState{Brightness: 100, TemperatureMirek: 300, On: true, CurrentOnState: true, Transition: 5 * time.Second}
+
+		// expectations
+		mockDBAccess.On("GetGroupedLights").Return([]models.HughGroup{group}, nil)
+		mockDBAccess.On("GetLightTargetState", "ls1").Return(target, nil)
+		mockDBAccess.On("GetLightTargetState", "ls2").Return(target, nil)
+		mockHueService.On("UpdateGroupedLightState", mock.Anything, "grp1", target).Return(nil).Once()
+		mockDBAccess.On("MarkLightAsUpdated", "ls1").Return(nil)
+		mockDBAccess.On("MarkLightAsUpdated", "ls2").Return(nil)
+
+		logger := log.NewWithOptions(os.Stderr, log.Options{Level: log.FatalLevel})
+		psm := physicalstatemanager.NewPhysicalStateManager(logger, mockHueService, mockDBAccess, newTestScheduler(t))
+
+		// act
+		remaining, err := psm.SetGroupedLightStatesToTarget(context.Background(), []string{"ls1", "ls2"}, time.Now())
+
+		// assert
+		assert.NoError(t, err)
+		assert.Empty(t, remaining)
+	})
+
+	t.Run("a light in the group has an override: should fall back to individual updates", func(t *testing.T) {
+		t.Parallel()
+
+		// arrange
+		mockDBAccess := mocks.NewMockPhysicalstatemanagerDbAccess(t)
+		mockHueService := mocks.NewMockPhysicalstatemanagerHueApiService(t)
+
+		// expectations
+		mockDBAccess.On("GetGroupedLights").Return([]models.HughGroup{group}, nil)
+		mockHueService.AssertNotCalled(t, "UpdateGroupedLightState", mock.Anything, mock.Anything, mock.Anything)
+
+		logger := log.NewWithOptions(os.Stderr, log.Options{Level: log.FatalLevel})
+		psm := physicalstatemanager.NewPhysicalStateManager(logger, mockHueService, mockDBAccess, newTestScheduler(t))
+
+		// act (ls2 is overridden so isn't in the list of lights to update)
+		remaining, err := psm.SetGroupedLightStatesToTarget(context.Background(), []string{"ls1"}, time.Now())
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"ls1"}, remaining)
+	})
+
+	t.Run("lights in the group have different targets: should fall back to individual updates", func(t *testing.T) {
+		t.Parallel()
+
+		// arrange
+		mockDBAccess := mocks.NewMockPhysicalstatemanagerDbAccess(t)
+		mockHueService := mocks.NewMockPhysicalstatemanagerHueApiService(t)
+
+		// expectations
+		mockDBAccess.On("GetGroupedLights").Return([]models.HughGroup{group}, nil)
+		mockDBAccess.On("GetLightTargetState", "ls1").Return(models.LightState{Brightness: 100, TemperatureMirek: 300, On: true, CurrentOnState: true}, nil)
+		// clamped to the light's minimum colour temp
+		mockDBAccess.On("GetLightTargetState", "ls2").Return(models.LightState{Brightness: 100, TemperatureMirek: 153, On: true, CurrentOnState: true}, nil)
+		mockHueService.AssertNotCalled(t, "UpdateGroupedLightState", mock.Anything, mock.Anything, mock.Anything)
+
+		logger := log.NewWithOptions(os.Stderr, log.Options{Level: log.FatalLevel})
+		psm := physicalstatemanager.NewPhysicalStateManager(logger, mockHueService, mockDBAccess, newTestScheduler(t))
+
+		// act
+		remaining, err := psm.SetGroupedLightStatesToTarget(context.Background(), []string{"ls1", "ls2"}, time.Now())
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"ls1", "ls2"}, remaining)
+	})
+
+	t.Run("the target is a colour: should fall back to individual updates", func(t *testing.T) {
+		t.Parallel()
+
+		// arrange
+		mockDBAccess := mocks.NewMockPhysicalstatemanagerDbAccess(t)
+		mockHueService := mocks.NewMockPhysicalstatemanagerHueApiService(t)
+		target := models.LightState{Brightness: 100, TemperatureMirek: 300, Colour: &colour.XY{X: 0.6, Y: 0.3}, On: true, CurrentOnState: true}
+
+		// expectations
+		mockDBAccess.On("GetGroupedLights").Return([]models.HughGroup{group}, nil)
+		mockDBAccess.On("GetLightTargetState", "ls1").Return(target, nil)
+		mockHueService.AssertNotCalled(t, "UpdateGroupedLightState", mock.Anything, mock.Anything, mock.Anything)
+
+		logger := log.NewWithOptions(os.Stderr, log.Options{Level: log.FatalLevel})
+		psm := physicalstatemanager.NewPhysicalStateManager(logger, mockHueService, mockDBAccess, newTestScheduler(t))
+
+		// act
+		remaining, err := psm.SetGroupedLightStatesToTarget(context.Background(), []string{"ls1", "ls2"}, time.Now())
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"ls1", "ls2"}, remaining)
+	})
+}
+
+func Test_ReconcileLightStates(t *testing.T) {
+
+	known := models.LightStatus{ZigbeeServiceID: "zb", On: true, Brightness: 50, TemperatureMirek: 400, Reachable: true}
+
+	tests := []struct {
+		name     string
+		known    models.LightStatus
+		current  models.LightStatus
+		expected []models.EventData
+	}{
+		{
+			name:    "light unchanged: should return no events",
+			known:   known,
+			current: models.LightStatus{On: true, Brightness: 51, TemperatureMirek: 398, Reachable: true},
+		},
+		{
+			name:    "light switched off: should return an on event",
+			known:   known,
+			current: models.LightStatus{On: false, Brightness: 50, TemperatureMirek: 400, Reachable: true},
+			expected: []models.EventData{{Id: "ls1", Type: "light", On: &struct {
+				On bool `json:"on"`
+			}{On: false}}},
+		},
+		{
+			name:    "light dimmed: should return a dimming event",
+			known:   known,
+			current: models.LightStatus{On: true, Brightness: 20, TemperatureMirek: 400, Reachable: true},
+			expected: []models.EventData{{Id: "ls1", Type: "light", Dimming: &struct {
+				Brightness float64 `json:"brightness"`
+			}{Brightness: 20}}},
+		},
+		{
+			name:    "light showing a colour: should ignore the colour temperature",
+			known:   known,
+			current: models.LightStatus{On: true, Brightness: 50, TemperatureMirek: 0, Reachable: true},
+		},
+		{
+			name:     "light became unreachable: should return a connectivity event",
+			known:    known,
+			current:  models.LightStatus{On: true, Brightness: 50, TemperatureMirek: 400, Reachable: false},
+			expected: []models.EventData{{Id: "zb", Type: "zigbee_connectivity", Status: "connectivity_issue"}},
+		},
+		{
+			name:     "light powered back on: should only return a connectivity event",
+			known:    models.LightStatus{ZigbeeServiceID: "zb", On: true, Brightness: 50, TemperatureMirek: 400, Reachable: false},
+			current:  models.LightStatus{On: true, Brightness: 100, TemperatureMirek: 366, Reachable: true},
+			expected: []models.EventData{{Id: "zb", Type: "zigbee_connectivity", Status: "connected"}},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			// arrange
+			mockDBAccess := mocks.NewMockPhysicalstatemanagerDbAccess(t)
+			mockHueService := mocks.NewMockPhysicalstatemanagerHueApiService(t)
+			test.known.LightServiceId = "ls1"
+			test.current.LightServiceId = "ls1"
+			// lights on the bridge that hugh doesn't control are ignored
+			other := models.LightStatus{LightServiceId: "ls2", On: true, Reachable: true}
+
+			mockDBAccess.On("GetLightStatuses").Return([]models.LightStatus{test.known}, nil)
+			mockHueService.On("GetLightStatuses").Return([]models.LightStatus{test.current, other}, nil)
+
+			logger := log.NewWithOptions(os.Stderr, log.Options{Level: log.FatalLevel})
+			psm := physicalstatemanager.NewPhysicalStateManager(logger, mockHueService, mockDBAccess, newTestScheduler(t))
+
+			// act
+			eventTime := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+			event, err := psm.ReconcileLightStates(eventTime)
+
+			// assert
+			assert.NoError(t, err)
+			if test.expected == nil {
+				assert.Nil(t, event)
+				return
+			}
+			events := []models.Event{}
+			assert.NoError(t, json.Unmarshal(event.Data, &events))
+			assert.Equal(t, []models.Event{{CreationTime: eventTime, Type: "update", Data: test.expected}}, events)
+		})
+	}
+}
+
+func Test_SetAllLightAndSceneStatesToTarget_Powerup(t *testing.T) {
+	// arrange
+	mockDBAccess := mocks.NewMockPhysicalstatemanagerDbAccess(t)
+	mockHueService := mocks.NewMockPhysicalstatemanagerHueApiService(t)
+	first := models.LightState{Brightness: 100, TemperatureMirek: 300, On: true}
+	later := models.LightState{Brightness: 80, TemperatureMirek: 350, On: true}
+
+	// expectations
+	mockDBAccess.On("GetAllSceneIDs").Return([]string{}, nil)
+	mockDBAccess.On("GetAllControllingLightIDs").Return([]string{}, nil)
+	mockDBAccess.On("GetGroupedLights").Return([]models.HughGroup{}, nil)
+	mockDBAccess.On("GetAllLightIDs").Return([]string{"ls1"}, nil)
+	mockDBAccess.On("GetLightTargetState", "ls1").Return(first, nil).Once()
+	mockDBAccess.On("GetLightTargetState", "ls1").Return(later, nil)
+	mockHueService.On("UpdateLightPowerup", mock.Anything, "ls1", first).Return(nil).Once()
+	mockHueService.On("UpdateLightPowerup", mock.Anything, "ls1", later).Return(nil).Once()
+
+	logger := log.NewWithOptions(os.Stderr, log.Options{Level: log.FatalLevel})
+	psm := physicalstatemanager.NewPhysicalStateManager(logger, mockHueService, mockDBAccess, newTestScheduler(t))
+
+	// act
+	now := time.Now()
+	assert.NoError(t, psm.SetAllLightAndSceneStatesToTarget(context.Background(), now))
+	// the target has changed but it is too soon to write the powerup again
+	assert.NoError(t, psm.SetAllLightAndSceneStatesToTarget(context.Background(), now.Add(time.Minute)))
+	assert.NoError(t, psm.SetAllLightAndSceneStatesToTarget(context.Background(), now.Add(constants.DefaultPowerupInterval)))
+	// unchanged, so not written again
+	assert.NoError(t, psm.SetAllLightAndSceneStatesToTarget(context.Background(), now.Add(3*constants.DefaultPowerupInterval)))
+
+	// assert
+	mockHueService.AssertNumberOfCalls(t, "UpdateLightPowerup", 2)
+}
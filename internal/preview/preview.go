@@ -0,0 +1,154 @@
+// Package preview works out what a schedule does to its lights through a day, minute by minute, for tuning day
+// patterns without waiting a day to watch the lights.
+package preview
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/wheelibin/hugh/internal/models"
+	"github.com/wheelibin/hugh/internal/schedule"
+)
+
+// Sample is the target state of the schedule's lights at a minute of the day
+type Sample struct {
+	Time  time.Time
+	State models.LightState
+	// a calendar event disables the schedule, leaving the lights alone
+	Disabled bool
+}
+
+// Kelvin returns the colour temperature of the sample's state, 0 when the lights are off
+func (s Sample) Kelvin() int {
+	if !s.State.On || s.Disabled || s.State.TemperatureMirek <= 0 {
+		return 0
+	}
+	return 1000000 / s.State.TemperatureMirek
+}
+
+// Event is a time marked on the timeline, e.g. sunrise
+type Event struct {
+	Name string
+	Time time.Time
+	// when the sun's event happens, if the day pattern's min/max for it moved it
+	Unclamped *time.Time
+}
+
+// Day is the preview of a schedule on a date
+type Day struct {
+	Schedule string
+	// the start of the day, at the day boundary
+	Date       time.Time
+	DayPattern string
+	// one for each minute of the day, from its start
+	Samples []Sample
+	// the sun's events the day pattern uses, in time order
+	Events []Event
+	// the window the lights are turned on in, nil without one
+	AutoOn *Window
+}
+
+// Window is a period of the day
+type Window struct {
+	From time.Time
+	To   time.Time
+}
+
+// ForDay previews the schedule on the (local) date, from the day boundary (midnight unless it's configured)
+func ForDay(s *schedule.ScheduleService, sch models.Schedule, date time.Time) (Day, error) {
+	// the lighting day, from the day boundary on the date to the next
+	date = date.Local()
+	start := schedule.TimeFromConfigTimeString(schedule.DayBoundary(), date)
+	end := time.Date(start.Year(), start.Month(), start.Day()+1, start.Hour(), start.Minute(), 0, 0, time.Local)
+	day := Day{Schedule: sch.Name, Date: start}
+
+	for t := start; t.Before(end); t = t.Add(time.Minute) {
+		interval, err := s.GetScheduleIntervalForTime(sch, t)
+		if errors.Is(err, schedule.ErrScheduleDisabled) {
+			day.Samples = append(day.Samples, Sample{Time: t, Disabled: true})
+			continue
+		}
+		if err != nil {
+			return Day{}, fmt.Errorf("error previewing %s at %s: %w", sch.Name, t.Format("2006-01-02 15:04"), err)
+		}
+		day.Samples = append(day.Samples, Sample{Time: t, State: interval.CalculateTargetLightState(t)})
+	}
+
+	// the pattern of the middle of the day, that of the schedule rather than a calendar event's if it's disabled
+	noon := start.Add(12 * time.Hour)
+	patternName, err := s.DayPatternNameFor(sch, noon)
+	if err != nil {
+		patternName = schedule.DayPatternName(sch.DayPattern, start)
+	}
+	day.DayPattern = patternName
+	dayPattern, err := s.DayPattern(patternName)
+	if err != nil {
+		return Day{}, err
+	}
+	day.Events = sunEvents(dayPattern, start)
+
+	if sch.AutoOn != nil {
+		day.AutoOn = &Window{
+			From: schedule.ClockTimeInDay(sch.AutoOn.From, start),
+			To:   schedule.ClockTimeInDay(sch.AutoOn.To, start),
+		}
+	}
+
+	return day, nil
+}
+
+// ForYear previews the schedule on the 21st of each month of the year, taking in the solstices and equinoxes
+func ForYear(s *schedule.ScheduleService, sch models.Schedule, year int) ([]Day, error) {
+	days := []Day{}
+	for month := time.January; month <= time.December; month++ {
+		day, err := ForDay(s, sch, time.Date(year, month, 21, 0, 0, 0, 0, time.Local))
+		if err != nil {
+			return nil, err
+		}
+		days = append(days, day)
+	}
+	return days, nil
+}
+
+// the sun's events the day pattern follows: sunrise and sunset, and any others its steps are relative to
+func sunEvents(dayPattern models.DayPattern, date time.Time) []Event {
+	if dayPattern.Type != "dynamic" && dayPattern.Type != "solar" {
+		return nil
+	}
+
+	lat, lng := schedule.GeoLocation()
+	sun := schedule.CalculateSunTimes(lat, lng, date)
+	clamped := schedule.ClampSunTimes(sun, dayPattern, date)
+
+	used := map[string]bool{"sunrise": true, "sunset": true}
+	if dayPattern.Type == "solar" {
+		used["solarnoon"] = true
+	}
+	for _, step := range dayPattern.Pattern {
+		for _, anchor := range sun.Anchors() {
+			if strings.HasPrefix(strings.ToLower(step.Time), strings.ToLower(anchor.Name)) {
+				used[strings.ToLower(anchor.Name)] = true
+			}
+		}
+	}
+
+	events := []Event{}
+	unclamped := sun.Anchors()
+	for i, anchor := range clamped.Anchors() {
+		if !used[strings.ToLower(anchor.Name)] {
+			continue
+		}
+		event := Event{Name: anchor.Name, Time: anchor.Time}
+		if !unclamped[i].Time.Equal(anchor.Time) {
+			event.Unclamped = &unclamped[i].Time
+		}
+		events = append(events, event)
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Time.Before(events[j].Time)
+	})
+	return events
+}
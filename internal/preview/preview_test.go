@@ -0,0 +1,184 @@
+package preview_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wheelibin/hugh/internal/models"
+	"github.com/wheelibin/hugh/internal/preview"
+	"github.com/wheelibin/hugh/internal/schedule"
+)
+
+// sunrise at 0,0 on the date is 05:59, clamped to 06:30
+const config = `
+geoLocation: 0,0
+dayPatterns:
+  evening:
+    type: dynamic
+    sunriseMin: "06:30"
+    default:
+      temperature: 2000
+      brightness: 20
+    pattern:
+      - time: sunrise
+        temperature: 2700
+        brightness: 50
+      - time: "12:00"
+        temperature: 4000
+        brightness: 100
+      - time: "22:00"
+        off: true
+`
+
+func previewDay(t *testing.T) preview.Day {
+	return previewDayFrom(t, "")
+}
+
+// previews the day with the day boundary set
+func previewDayFrom(t *testing.T, dayBoundary string) preview.Day {
+	viper.Reset()
+	viper.SetConfigType("yaml")
+	require.NoError(t, viper.ReadConfig(strings.NewReader(config)))
+	viper.Set("dayBoundary", dayBoundary)
+	t.Cleanup(viper.Reset)
+
+	sch := models.Schedule{
+		Name:       "Kitchen",
+		DayPattern: models.DayPatternSelector{models.DayPatternDefault: "evening"},
+		AutoOn: &struct {
+			From string `json:"from"`
+			To   string `json:"to"`
+		}{From: "08:00", To: "20:00"},
+	}
+	srv := schedule.NewScheduleService(log.NewWithOptions(os.Stderr, log.Options{Level: log.FatalLevel}), nil)
+
+	day, err := preview.ForDay(srv, sch, time.Date(2023, 1, 1, 15, 0, 0, 0, time.Local))
+	require.NoError(t, err)
+	return day
+}
+
+func Test_ForDay(t *testing.T) {
+	day := previewDay(t)
+
+	assert.Equal(t, "evening", day.DayPattern)
+	assert.Equal(t, time.Date(2023, 1, 1, 0, 0, 0, 0, time.Local), day.Date)
+	require.Len(t, day.Samples, 24*60)
+
+	at := func(hour int, min int) preview.Sample {
+		return day.Samples[hour*60+min]
+	}
+	assert.Equal(t, 20, at(0, 0).State.Brightness)
+	assert.Equal(t, 50, at(6, 30).State.Brightness)
+	assert.InDelta(t, 2700, at(6, 30).Kelvin(), 5)
+	assert.Equal(t, 100, at(12, 0).State.Brightness)
+	assert.False(t, at(22, 0).State.On)
+	assert.Equal(t, 0, at(23, 59).Kelvin())
+
+	// sunrise is clamped, sunset isn't
+	require.Len(t, day.Events, 2)
+	assert.Equal(t, "sunrise", day.Events[0].Name)
+	assert.Equal(t, "06:30", day.Events[0].Time.Format("15:04"))
+	require.NotNil(t, day.Events[0].Unclamped)
+	assert.Equal(t, "05:59", day.Events[0].Unclamped.Format("15:04"))
+	assert.Equal(t, "sunset", day.Events[1].Name)
+	assert.Nil(t, day.Events[1].Unclamped)
+
+	require.NotNil(t, day.AutoOn)
+	assert.Equal(t, "08:00", day.AutoOn.From.Format("15:04"))
+	assert.Equal(t, "20:00", day.AutoOn.To.Format("15:04"))
+}
+
+func Test_ForDay_DayBoundary(t *testing.T) {
+	day := previewDayFrom(t, "04:00")
+
+	assert.Equal(t, time.Date(2023, 1, 1, 4, 0, 0, 0, time.Local), day.Date)
+	require.Len(t, day.Samples, 24*60)
+	assert.Equal(t, "04:00", day.Samples[0].Time.Format("15:04"))
+	last := day.Samples[len(day.Samples)-1]
+	assert.Equal(t, "2023-01-02 03:59", last.Time.Format("2006-01-02 15:04"))
+	// off from 22:00 until the boundary
+	assert.False(t, last.State.On)
+}
+
+func Test_WriteTimeline(t *testing.T) {
+	day := previewDay(t)
+
+	var out bytes.Buffer
+	require.NoError(t, preview.WriteTimeline(&out, day, time.Hour))
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+
+	assert.Equal(t, "Kitchen on Sun 1 Jan 2023, day pattern evening", lines[0])
+	assert.Contains(t, lines, "  sunrise 06:30 (clamped, 05:59 without)")
+	assert.Contains(t, lines, "  autoOn 08:00 to 20:00")
+	// the heading, then a row an hour
+	rows := lines[len(lines)-24:]
+	assert.True(t, strings.HasPrefix(rows[0], "00:00  █████ "), rows[0])
+	assert.Contains(t, rows[6], "sunrise 06:30 (clamped, 05:59 without)")
+	assert.Contains(t, rows[8], "autoOn from 08:00")
+	assert.Contains(t, rows[12], "100%")
+	assert.Equal(t, "22:00  off", rows[22])
+}
+
+func Test_WriteYearTimeline(t *testing.T) {
+	day := previewDay(t)
+
+	var out bytes.Buffer
+	require.NoError(t, preview.WriteYearTimeline(&out, []preview.Day{day, day}, time.Hour))
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+
+	require.Len(t, lines, 5)
+	assert.Equal(t, "Kitchen through 2023, a character each 1h (brightness to 25% ░, 50% ▒, 75% ▓, 100% █, · off, - disabled)", lines[0])
+	assert.Equal(t, "         00 03 06 09 12 15 18 21", lines[2])
+	assert.Equal(t, "Jan 1    ░░▒▒▒▒▒▓▓▓█████▓▓▒▒▒░░··  sunrise 06:30  sunset 18:06", lines[3])
+}
+
+func Test_WriteCSV(t *testing.T) {
+	day := previewDay(t)
+
+	var out bytes.Buffer
+	require.NoError(t, preview.WriteCSV(&out, []preview.Day{day}))
+	records, err := csv.NewReader(&out).ReadAll()
+	require.NoError(t, err)
+
+	require.Len(t, records, 24*60+1)
+	assert.Equal(t, []string{"date", "time", "on", "brightness", "kelvin", "x", "y", "disabled"}, records[0])
+	assert.Equal(t, []string{"2023-01-01", "00:00", "true", "20", "2000", "", "", "false"}, records[1])
+	assert.Equal(t, []string{"2023-01-01", "22:00", "false", "0", "0", "", "", "false"}, records[22*60+1])
+}
+
+func Test_WriteSVG(t *testing.T) {
+	day := previewDay(t)
+
+	var out bytes.Buffer
+	require.NoError(t, preview.WriteSVG(&out, []preview.Day{day}))
+
+	// well formed, with the curves, off period, autoOn window and sun's events
+	elements := map[string]int{}
+	decoder := xml.NewDecoder(bytes.NewReader(out.Bytes()))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		if start, ok := token.(xml.StartElement); ok {
+			elements[start.Name.Local]++
+		}
+	}
+	assert.Equal(t, 2, elements["polyline"])
+	assert.Contains(t, out.String(), "<title>off 22:00 to 00:00</title>")
+	assert.Contains(t, out.String(), "<title>autoOn</title>")
+	assert.Contains(t, out.String(), "sunrise 06:30")
+	assert.Contains(t, out.String(), "<title>sunrise without clamping 05:59</title>")
+}
@@ -0,0 +1,357 @@
+package preview
+
+import (
+	"encoding/csv"
+	"fmt"
+	"html"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// the width of the brightness bar of the timeline, each character 4%
+const barWidth = 25
+
+// WriteTimeline writes the day as an ASCII timeline, a row each `every` showing the state at its start and the
+// events (sun, clamps, autoOn) during it
+func WriteTimeline(w io.Writer, day Day, every time.Duration) error {
+	fmt.Fprintf(w, "%s on %s, day pattern %s\n", day.Schedule, day.Date.Format("Mon 2 Jan 2006"), day.DayPattern)
+	for _, e := range day.Events {
+		fmt.Fprintf(w, "  %s\n", describeEvent(e))
+	}
+	if day.AutoOn != nil {
+		fmt.Fprintf(w, "  autoOn %s to %s\n", day.AutoOn.From.Format("15:04"), day.AutoOn.To.Format("15:04"))
+	}
+	fmt.Fprintf(w, "\n%-5s  %-*s %4s %6s\n", "time", barWidth, "brightness", "", "temp")
+
+	for i := 0; i < len(day.Samples); {
+		sample := day.Samples[i]
+		rowEnd := sample.Time.Add(every)
+
+		notes := []string{}
+		for _, e := range day.Events {
+			if within(e.Time, sample.Time, rowEnd) {
+				notes = append(notes, describeEvent(e))
+			}
+		}
+		if day.AutoOn != nil {
+			if within(day.AutoOn.From, sample.Time, rowEnd) {
+				notes = append(notes, "autoOn from "+day.AutoOn.From.Format("15:04"))
+			}
+			if within(day.AutoOn.To, sample.Time, rowEnd) {
+				notes = append(notes, "autoOn to "+day.AutoOn.To.Format("15:04"))
+			}
+		}
+		if sample.State.Colour != nil {
+			notes = append(notes, fmt.Sprintf("colour xy %.4f,%.4f", sample.State.Colour.X, sample.State.Colour.Y))
+		}
+
+		var state string
+		switch {
+		case sample.Disabled:
+			state = fmt.Sprintf("%-*s", barWidth+12, "disabled by a calendar event")
+		case !sample.State.On:
+			state = fmt.Sprintf("%-*s", barWidth+12, "off")
+		default:
+			bar := strings.Repeat("█", (sample.State.Brightness+2)/4)
+			state = fmt.Sprintf("%s%s %3d%% %5dK", bar, strings.Repeat(" ", barWidth-len([]rune(bar))), sample.State.Brightness, sample.Kelvin())
+		}
+		row := fmt.Sprintf("%s  %s", sample.Time.Format("15:04"), state)
+		if len(notes) > 0 {
+			row += "  " + strings.Join(notes, ", ")
+		}
+		if _, err := fmt.Fprintln(w, strings.TrimRight(row, " ")); err != nil {
+			return err
+		}
+
+		for i < len(day.Samples) && day.Samples[i].Time.Before(rowEnd) {
+			i++
+		}
+	}
+	return nil
+}
+
+// WriteYearTimeline writes the days as rows of characters, each `every` of the day shaded by its brightness, to show
+// how the day shifts through the year
+func WriteYearTimeline(w io.Writer, days []Day, every time.Duration) error {
+	if len(days) == 0 {
+		return nil
+	}
+	perHour := int(time.Hour / every)
+	fmt.Fprintf(w, "%s through %d, a character each %s (brightness to 25%% ░, 50%% ▒, 75%% ▓, 100%% █, · off, - disabled)\n\n",
+		days[0].Schedule, days[0].Date.Year(), shortDuration(every))
+
+	header := []rune(strings.Repeat(" ", 24*perHour))
+	for hour := 0; hour < 24; hour += 3 {
+		copy(header[hour*perHour:], []rune(days[0].Date.Add(time.Duration(hour)*time.Hour).Format("15")))
+	}
+	fmt.Fprintf(w, "%-8s %s\n", "", strings.TrimRight(string(header), " "))
+
+	for _, day := range days {
+		cells := []rune{}
+		for i := 0; i < len(day.Samples); {
+			sample := day.Samples[i]
+			cells = append(cells, shade(sample))
+			rowEnd := sample.Time.Add(every)
+			for i < len(day.Samples) && day.Samples[i].Time.Before(rowEnd) {
+				i++
+			}
+		}
+		events := []string{}
+		for _, e := range day.Events {
+			events = append(events, fmt.Sprintf("%s %s", e.Name, e.Time.Format("15:04")))
+		}
+		row := fmt.Sprintf("%-8s %s  %s", day.Date.Format("Jan 2"), string(cells), strings.Join(events, "  "))
+		if _, err := fmt.Fprintln(w, strings.TrimRight(row, " ")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// the duration without its zero units, e.g. 30m rather than 30m0s
+func shortDuration(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
+
+func shade(sample Sample) rune {
+	switch {
+	case sample.Disabled:
+		return '-'
+	case !sample.State.On || sample.State.Brightness <= 0:
+		return '·'
+	case sample.State.Brightness <= 25:
+		return '░'
+	case sample.State.Brightness <= 50:
+		return '▒'
+	case sample.State.Brightness <= 75:
+		return '▓'
+	}
+	return '█'
+}
+
+func describeEvent(e Event) string {
+	description := fmt.Sprintf("%s %s", e.Name, e.Time.Format("15:04"))
+	if e.Unclamped != nil {
+		description += fmt.Sprintf(" (clamped, %s without)", e.Unclamped.Format("15:04"))
+	}
+	return description
+}
+
+// whether t is in [from, to)
+func within(t time.Time, from time.Time, to time.Time) bool {
+	return !t.Before(from) && t.Before(to)
+}
+
+// WriteCSV writes every minute of the days, one a row
+func WriteCSV(w io.Writer, days []Day) error {
+	out := csv.NewWriter(w)
+	if err := out.Write([]string{"date", "time", "on", "brightness", "kelvin", "x", "y", "disabled"}); err != nil {
+		return err
+	}
+	for _, day := range days {
+		for _, sample := range day.Samples {
+			x, y := "", ""
+			if sample.State.Colour != nil {
+				x = strconv.FormatFloat(sample.State.Colour.X, 'f', 4, 64)
+				y = strconv.FormatFloat(sample.State.Colour.Y, 'f', 4, 64)
+			}
+			on := sample.State.On && !sample.Disabled
+			if err := out.Write([]string{
+				sample.Time.Format("2006-01-02"),
+				sample.Time.Format("15:04"),
+				strconv.FormatBool(on),
+				strconv.Itoa(sample.State.Brightness),
+				strconv.Itoa(sample.Kelvin()),
+				x,
+				y,
+				strconv.FormatBool(sample.Disabled),
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// the layout of the svg chart
+const (
+	svgLeft       = 60
+	svgTop        = 50
+	svgPlotWidth  = 960
+	svgPlotHeight = 240
+	svgWidth      = svgLeft + svgPlotWidth + 70
+	svgHeight     = svgTop + svgPlotHeight + 70
+	minKelvin     = 2000
+	maxKelvin     = 6500
+)
+
+// WriteSVG writes a chart of the brightness (solid) and colour temperature (dashed) through the days. A single day
+// also shows when the lights are off, the autoOn window and the sun's events, several are overlaid a colour each.
+func WriteSVG(w io.Writer, days []Day) error {
+	if len(days) == 0 {
+		return nil
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif" font-size="11">`+"\n",
+		svgWidth, svgHeight, svgWidth, svgHeight)
+	fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="white"/>`+"\n", svgWidth, svgHeight)
+
+	title := fmt.Sprintf("%s on %s, day pattern %s", days[0].Schedule, days[0].Date.Format("Mon 2 Jan 2006"), days[0].DayPattern)
+	if len(days) > 1 {
+		title = fmt.Sprintf("%s through %d", days[0].Schedule, days[0].Date.Year())
+	}
+	fmt.Fprintf(&b, `<text x="%d" y="18" font-size="14">%s</text>`+"\n", svgLeft, html.EscapeString(title))
+
+	single := len(days) == 1
+	if single {
+		writeSVGPeriods(&b, days[0])
+	}
+	// a window ending before it starts is never reached
+	if days[0].AutoOn != nil && days[0].AutoOn.To.After(days[0].AutoOn.From) {
+		from, to := svgX(days[0], days[0].AutoOn.From), svgX(days[0], days[0].AutoOn.To)
+		fmt.Fprintf(&b, `<rect x="%.1f" y="%d" width="%.1f" height="8" fill="#b7e4c7"><title>autoOn</title></rect>`+"\n", from, svgTop-12, to-from)
+		fmt.Fprintf(&b, `<text x="%.1f" y="%d" fill="#2d6a4f">autoOn</text>`+"\n", from+2, svgTop-15)
+	}
+
+	// the axes: hours along the bottom, brightness on the left and kelvin on the right
+	for hour := 0; hour <= 24; hour += 3 {
+		x := float64(svgLeft) + float64(hour)/24*svgPlotWidth
+		fmt.Fprintf(&b, `<line x1="%.1f" y1="%d" x2="%.1f" y2="%d" stroke="#ddd"/>`+"\n", x, svgTop, x, svgTop+svgPlotHeight)
+		fmt.Fprintf(&b, `<text x="%.1f" y="%d" text-anchor="middle">%s</text>`+"\n",
+			x, svgTop+svgPlotHeight+15, days[0].Date.Add(time.Duration(hour)*time.Hour).Format("15:04"))
+	}
+	for _, brightness := range []int{0, 50, 100} {
+		fmt.Fprintf(&b, `<text x="%d" y="%.1f" text-anchor="end">%d%%</text>`+"\n", svgLeft-5, svgBrightnessY(brightness)+4, brightness)
+	}
+	for _, kelvin := range []int{minKelvin, 4250, maxKelvin} {
+		fmt.Fprintf(&b, `<text x="%d" y="%.1f" fill="#e76f51">%dK</text>`+"\n", svgLeft+svgPlotWidth+5, svgKelvinY(kelvin)+4, kelvin)
+	}
+	fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%d" height="%d" fill="none" stroke="#999"/>`+"\n", svgLeft, svgTop, svgPlotWidth, svgPlotHeight)
+
+	for i, day := range days {
+		brightnessColour, kelvinColour := "#264653", "#e76f51"
+		if !single {
+			brightnessColour = fmt.Sprintf("hsl(%d, 60%%, 40%%)", i*360/len(days))
+			kelvinColour = brightnessColour
+			fmt.Fprintf(&b, `<text x="%d" y="%d" fill="%s">%s</text>`+"\n",
+				svgLeft+i*45, svgTop+svgPlotHeight+50, brightnessColour, day.Date.Format("Jan 2"))
+		}
+
+		writeSVGLine(&b, day, brightnessColour, "", func(s Sample) (float64, bool) {
+			if s.Disabled {
+				return 0, false
+			}
+			if !s.State.On {
+				return svgBrightnessY(0), true
+			}
+			return svgBrightnessY(s.State.Brightness), true
+		})
+		writeSVGLine(&b, day, kelvinColour, "6 3", func(s Sample) (float64, bool) {
+			if s.Kelvin() == 0 {
+				return 0, false
+			}
+			return svgKelvinY(s.Kelvin()), true
+		})
+
+		for _, e := range day.Events {
+			writeSVGEvent(&b, day, e, single, brightnessColour)
+		}
+	}
+
+	if single {
+		fmt.Fprintf(&b, `<text x="%d" y="%d"><tspan fill="#264653">── brightness</tspan>  <tspan fill="#e76f51">- - kelvin</tspan>  <tspan fill="#999">▇ off</tspan></text>`+"\n",
+			svgLeft, svgTop+svgPlotHeight+50)
+	}
+	b.WriteString("</svg>\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// shades the periods the lights are off, or the schedule disabled
+func writeSVGPeriods(b *strings.Builder, day Day) {
+	for i := 0; i < len(day.Samples); {
+		sample := day.Samples[i]
+		if sample.State.On && !sample.Disabled {
+			i++
+			continue
+		}
+		j := i
+		for j < len(day.Samples) && day.Samples[j].Disabled == sample.Disabled && (!day.Samples[j].State.On || sample.Disabled) {
+			j++
+		}
+		fill, name := "#eee", "off"
+		if sample.Disabled {
+			fill, name = "#fde2e4", "disabled by a calendar event"
+		}
+		end := day.Samples[len(day.Samples)-1].Time.Add(time.Minute)
+		if j < len(day.Samples) {
+			end = day.Samples[j].Time
+		}
+		from, to := svgX(day, sample.Time), svgX(day, end)
+		fmt.Fprintf(b, `<rect x="%.1f" y="%d" width="%.1f" height="%d" fill="%s"><title>%s %s to %s</title></rect>`+"\n",
+			from, svgTop, to-from, svgPlotHeight, fill, name, sample.Time.Format("15:04"), end.Format("15:04"))
+		i = j
+	}
+}
+
+// draws the value through the day, broken where there isn't one
+func writeSVGLine(b *strings.Builder, day Day, stroke string, dash string, value func(Sample) (float64, bool)) {
+	points := []string{}
+	flush := func() {
+		if len(points) > 1 {
+			fmt.Fprintf(b, `<polyline fill="none" stroke="%s" stroke-width="1.5" stroke-dasharray="%s" points="%s"/>`+"\n",
+				stroke, dash, strings.Join(points, " "))
+		}
+		points = points[:0]
+	}
+	for _, sample := range day.Samples {
+		y, ok := value(sample)
+		if !ok {
+			flush()
+			continue
+		}
+		points = append(points, fmt.Sprintf("%.1f,%.1f", svgX(day, sample.Time), y))
+	}
+	flush()
+}
+
+// marks the sun's event, and where it would be without the day pattern's clamps. Several days only get a tick.
+func writeSVGEvent(b *strings.Builder, day Day, e Event, single bool, colour string) {
+	x := svgX(day, e.Time)
+	if !single {
+		fmt.Fprintf(b, `<line x1="%.1f" y1="%d" x2="%.1f" y2="%d" stroke="%s"><title>%s %s %s</title></line>`+"\n",
+			x, svgTop, x, svgTop+8, colour, day.Date.Format("Jan 2"), e.Name, e.Time.Format("15:04"))
+		return
+	}
+	fmt.Fprintf(b, `<line x1="%.1f" y1="%d" x2="%.1f" y2="%d" stroke="#f4a261" stroke-dasharray="4 3"/>`+"\n",
+		x, svgTop, x, svgTop+svgPlotHeight)
+	fmt.Fprintf(b, `<text x="%.1f" y="%d" fill="#f4a261">%s %s</text>`+"\n", x+3, svgTop+svgPlotHeight-5, e.Name, e.Time.Format("15:04"))
+	if e.Unclamped != nil {
+		ux := svgX(day, *e.Unclamped)
+		fmt.Fprintf(b, `<line x1="%.1f" y1="%d" x2="%.1f" y2="%d" stroke="#bbb" stroke-dasharray="1 3"><title>%s without clamping %s</title></line>`+"\n",
+			ux, svgTop, ux, svgTop+svgPlotHeight, e.Name, e.Unclamped.Format("15:04"))
+	}
+}
+
+func svgX(day Day, t time.Time) float64 {
+	return svgLeft + t.Sub(day.Date).Minutes()/(24*60)*svgPlotWidth
+}
+
+func svgBrightnessY(brightness int) float64 {
+	return svgTop + float64(100-brightness)/100*svgPlotHeight
+}
+
+func svgKelvinY(kelvin int) float64 {
+	return svgTop + float64(maxKelvin-kelvin)/(maxKelvin-minKelvin)*svgPlotHeight
+}
@@ -3,16 +3,23 @@ package repos
 import (
 	"database/sql"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/charmbracelet/log"
+	"github.com/samber/lo"
+	"github.com/wheelibin/hugh/internal/colour"
 	"github.com/wheelibin/hugh/internal/constants"
 	"github.com/wheelibin/hugh/internal/models"
 )
 
+// the tables are dropped and created afresh at startup, their rows are rediscovered from the bridges so this is all
+// an on-disk database from an older version needs to pick up new columns and keys
 const initSchema = `
-  CREATE TABLE IF NOT EXISTS light (
-    serviceid_light VARCHAR(36) PRIMARY KEY, 
+  DROP TABLE IF EXISTS light;
+  CREATE TABLE light (
+    bridge TEXT,
+    serviceid_light VARCHAR(36), 
     serviceid_zigbee VARCHAR(36), 
     name TEXT, 
     controlled_by_schedule VARCHAR(36),
@@ -23,9 +30,14 @@ const initSchema = `
     target_brightness INTEGER,
     target_colour_temp INTEGER,
     target_on_state INTEGER,
+    target_transition INTEGER, -- milliseconds
+    target_colour_x REAL,      -- null unless the schedule sets a colour
+    target_colour_y REAL,
     last_update_time TIMESTAMP,
     last_update_brightness INTEGER,
     last_update_colour_temp INTEGER,
+    last_update_colour_x REAL,
+    last_update_colour_y REAL,
     last_update_on_state INTEGER,
     override_brightness INTEGER,
     override_target_brightness INTEGER, -- target at time of override
@@ -35,24 +47,76 @@ const initSchema = `
     override_on_state INTEGER,
     override_target_on_state INTEGER,    -- target at time of override
     min_colour_temp INTEGER,
-    max_colour_temp INTEGER
+    max_colour_temp INTEGER,
+    PRIMARY KEY (bridge, serviceid_light)
   );
 
-  CREATE TABLE IF NOT EXISTS scene (
-    id VARCHAR(36) PRIMARY KEY,
+  DROP TABLE IF EXISTS scene;
+  CREATE TABLE scene (
+    bridge TEXT,
+    id VARCHAR(36),
     controlled_by_schedule VARCHAR(36),
     target_brightness INTEGER,
     target_colour_temp INTEGER,
-    target_on_state INTEGER
+    target_colour_x REAL,
+    target_colour_y REAL,
+    target_on_state INTEGER,
+    PRIMARY KEY (bridge, id)
+  );
+
+  DROP TABLE IF EXISTS grouped_light;
+  CREATE TABLE grouped_light (
+    bridge TEXT,
+    id VARCHAR(36),
+    serviceid_light VARCHAR(36),
+    PRIMARY KEY (bridge, id, serviceid_light)
+  );
+
+  DROP TABLE IF EXISTS sensor;
+  CREATE TABLE sensor (
+    bridge TEXT,
+    id VARCHAR(36),            -- the motion/contact service id
+    controlled_by_schedule VARCHAR(36),
+    PRIMARY KEY (bridge, id, controlled_by_schedule)
+  );
+
+  -- the switch buttons and dials bound to actions on schedules
+  DROP TABLE IF EXISTS binding;
+  CREATE TABLE binding (
+    bridge TEXT,
+    id VARCHAR(36),            -- the button/relative_rotary service id
+    event TEXT,
+    action TEXT,
+    controlled_by_schedule VARCHAR(36),
+    duration INTEGER,          -- nanoseconds
+    PRIMARY KEY (bridge, id, event, action, controlled_by_schedule)
+  );
+
+  -- the latest ambient light reading for schedules with daylight dimming
+  DROP TABLE IF EXISTS ambient_light;
+  CREATE TABLE ambient_light (
+    bridge TEXT,
+    controlled_by_schedule VARCHAR(36),
+    lux REAL,
+    updated_time TIMESTAMP,
+    PRIMARY KEY (bridge, controlled_by_schedule)
   );
 
-  DELETE FROM light;
-  DELETE FROM scene;
+  -- the schedules a calendar event has disabled, their lights and scenes are left alone until it ends
+  DROP TABLE IF EXISTS disabled_schedule;
+  CREATE TABLE disabled_schedule (
+    bridge TEXT,
+    controlled_by_schedule VARCHAR(36),
+    PRIMARY KEY (bridge, controlled_by_schedule)
+  );
 `
 
+// LightRepo stores lights, scenes and groups keyed by the bridge they are on and their service id,
+// each method only sees the rows for the repo's bridge (see ForBridge)
 type LightRepo struct {
 	logger *log.Logger
 	db     *sql.DB
+	bridge string
 }
 
 func NewLightRepo(logger *log.Logger, db *sql.DB) (*LightRepo, error) {
@@ -65,33 +129,133 @@ func NewLightRepo(logger *log.Logger, db *sql.DB) (*LightRepo, error) {
 	return &LightRepo{logger: logger, db: db}, nil
 }
 
+// ForBridge returns a repo sharing the same database that reads and writes the named bridge's rows
+func (r *LightRepo) ForBridge(bridge string) *LightRepo {
+	return &LightRepo{logger: r.logger, db: r.db, bridge: bridge}
+}
+
 func (r *LightRepo) Add(lights []models.HughLight) error {
 	tx, _ := r.db.Begin()
 	for _, light := range lights {
+		err := r.insertLight(tx, light)
+		if err != nil {
+			return err
+		}
+	}
+	err := tx.Commit()
+	if err != nil {
+		return fmt.Errorf("Error adding lights: %w", err)
+	}
+
+	return nil
+}
+
+func (r *LightRepo) insertLight(tx *sql.Tx, light models.HughLight) error {
+	_, err := tx.Exec(
+		`INSERT INTO light 
+      (bridge, serviceid_light, serviceid_zigbee, name, controlled_by_schedule, on_state, min_colour_temp, max_colour_temp, auto_on_from, auto_on_to) 
+     VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10);`,
+		r.bridge,
+		light.LightServiceId,
+		light.ZigbeeServiceID,
+		light.Name,
+		light.ScheduleName,
+		light.On,
+		light.MinColorTemperatuerMirek,
+		light.MaxColorTemperatuerMirek,
+		light.AutoOnFrom,
+		light.AutoOnTo,
+	)
+	if err != nil {
+		return fmt.Errorf("Error adding light (%s): %w", light.Name, err)
+	}
+	return nil
+}
+
+// SyncLights brings the stored lights in line with those just discovered, adding new lights, removing those that
+// have gone and updating the rest in place (keeping their state and overrides). Returns the ids of the added and
+// removed lights
+func (r *LightRepo) SyncLights(lights []models.HughLight) ([]string, []string, error) {
+	existing, err := r.lightIDs()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, nil, fmt.Errorf("Error syncing lights: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	added := []string{}
+	for _, light := range lights {
+		if !existing[light.LightServiceId] {
+			err := r.insertLight(tx, light)
+			if err != nil {
+				return nil, nil, err
+			}
+			added = append(added, light.LightServiceId)
+			continue
+		}
+
+		delete(existing, light.LightServiceId)
 		_, err := tx.Exec(
-			`INSERT INTO light 
-      (serviceid_light, serviceid_zigbee, name, controlled_by_schedule, on_state, min_colour_temp, max_colour_temp, auto_on_from, auto_on_to) 
-     VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9);`,
-			light.LightServiceId,
+			`UPDATE light 
+       SET serviceid_zigbee       = $1,
+           name                   = $2,
+           controlled_by_schedule = $3,
+           min_colour_temp        = $4,
+           max_colour_temp        = $5,
+           auto_on_from           = $6,
+           auto_on_to             = $7
+       WHERE serviceid_light = $8 AND bridge = $9`,
 			light.ZigbeeServiceID,
 			light.Name,
 			light.ScheduleName,
-			light.On,
 			light.MinColorTemperatuerMirek,
 			light.MaxColorTemperatuerMirek,
 			light.AutoOnFrom,
 			light.AutoOnTo,
+			light.LightServiceId,
+			r.bridge,
 		)
 		if err != nil {
-			return fmt.Errorf("Error adding light (%s): %w", light.Name, err)
+			return nil, nil, fmt.Errorf("Error updating light (%s): %w", light.Name, err)
 		}
 	}
-	err := tx.Commit()
+
+	// anything left is no longer on the bridge or no longer in a scheduled room/zone
+	removed := lo.Keys(existing)
+	sort.Strings(removed)
+	for _, lsID := range removed {
+		_, err := tx.Exec("DELETE FROM light WHERE serviceid_light = $1 AND bridge = $2", lsID, r.bridge)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Error removing light (%s): %w", lsID, err)
+		}
+	}
+
+	err = tx.Commit()
 	if err != nil {
-		return fmt.Errorf("Error adding lights: %w", err)
+		return nil, nil, fmt.Errorf("Error syncing lights: %w", err)
 	}
 
-	return nil
+	return added, removed, nil
+}
+
+func (r *LightRepo) lightIDs() (map[string]bool, error) {
+	rows, err := r.db.Query("SELECT serviceid_light FROM light WHERE bridge = $1", r.bridge)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading light ids: %w", err)
+	}
+	defer rows.Close()
+
+	ids := map[string]bool{}
+	for rows.Next() {
+		var id string
+		_ = rows.Scan(&id)
+		ids[id] = true
+	}
+	return ids, nil
 }
 
 func (r *LightRepo) AddScenes(scenes []models.HughScene) error {
@@ -99,8 +263,9 @@ func (r *LightRepo) AddScenes(scenes []models.HughScene) error {
 	for _, scene := range scenes {
 		_, err := tx.Exec(
 			`INSERT INTO scene 
-      (id, controlled_by_schedule)
-     VALUES ($1,$2);`,
+      (bridge, id, controlled_by_schedule)
+     VALUES ($1,$2,$3);`,
+			r.bridge,
 			scene.ID,
 			scene.ScheduleName,
 		)
@@ -117,8 +282,329 @@ func (r *LightRepo) AddScenes(scenes []models.HughScene) error {
 
 }
 
+// SyncScenes replaces the stored scenes with those just discovered, keeping the targets of scenes already known
+func (r *LightRepo) SyncScenes(scenes []models.HughScene) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("Error syncing scenes: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	_, err = tx.Exec("UPDATE scene SET controlled_by_schedule = NULL WHERE bridge = $1", r.bridge)
+	if err != nil {
+		return fmt.Errorf("Error syncing scenes: %w", err)
+	}
+	for _, scene := range scenes {
+		_, err := tx.Exec(
+			`INSERT INTO scene 
+      (bridge, id, controlled_by_schedule)
+     VALUES ($1,$2,$3)
+     ON CONFLICT (bridge, id) DO UPDATE SET controlled_by_schedule = excluded.controlled_by_schedule;`,
+			r.bridge,
+			scene.ID,
+			scene.ScheduleName,
+		)
+		if err != nil {
+			return fmt.Errorf("Error adding scene (%s): %w", scene.ID, err)
+		}
+	}
+	_, err = tx.Exec("DELETE FROM scene WHERE controlled_by_schedule IS NULL AND bridge = $1", r.bridge)
+	if err != nil {
+		return fmt.Errorf("Error removing scenes: %w", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("Error syncing scenes: %w", err)
+	}
+
+	return nil
+}
+
+func (r *LightRepo) AddGroups(groups []models.HughGroup) error {
+	tx, _ := r.db.Begin()
+	err := r.insertGroups(tx, groups)
+	if err != nil {
+		return err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("Error adding groups: %w", err)
+	}
+
+	return nil
+}
+
+// SyncGroups replaces the stored groups with those just discovered
+func (r *LightRepo) SyncGroups(groups []models.HughGroup) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("Error syncing groups: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	_, err = tx.Exec("DELETE FROM grouped_light WHERE bridge = $1", r.bridge)
+	if err != nil {
+		return fmt.Errorf("Error removing groups: %w", err)
+	}
+	err = r.insertGroups(tx, groups)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("Error syncing groups: %w", err)
+	}
+
+	return nil
+}
+
+func (r *LightRepo) insertGroups(tx *sql.Tx, groups []models.HughGroup) error {
+	for _, group := range groups {
+		for _, lsID := range group.LightServiceIds {
+			_, err := tx.Exec(
+				`INSERT OR IGNORE INTO grouped_light 
+        (bridge, id, serviceid_light)
+       VALUES ($1,$2,$3);`,
+				r.bridge,
+				group.GroupedLightServiceId,
+				lsID,
+			)
+			if err != nil {
+				return fmt.Errorf("Error adding group (%s): %w", group.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (r *LightRepo) AddSensors(sensors []models.HughSensor) error {
+	tx, _ := r.db.Begin()
+	err := r.insertSensors(tx, sensors)
+	if err != nil {
+		return err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("Error adding sensors: %w", err)
+	}
+
+	return nil
+}
+
+// SyncSensors replaces the stored sensors with those just discovered
+func (r *LightRepo) SyncSensors(sensors []models.HughSensor) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("Error syncing sensors: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	_, err = tx.Exec("DELETE FROM sensor WHERE bridge = $1", r.bridge)
+	if err != nil {
+		return fmt.Errorf("Error removing sensors: %w", err)
+	}
+	err = r.insertSensors(tx, sensors)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("Error syncing sensors: %w", err)
+	}
+
+	return nil
+}
+
+func (r *LightRepo) insertSensors(tx *sql.Tx, sensors []models.HughSensor) error {
+	for _, sensor := range sensors {
+		_, err := tx.Exec(
+			`INSERT OR IGNORE INTO sensor 
+      (bridge, id, controlled_by_schedule)
+     VALUES ($1,$2,$3);`,
+			r.bridge,
+			sensor.ID,
+			sensor.ScheduleName,
+		)
+		if err != nil {
+			return fmt.Errorf("Error adding sensor (%s): %w", sensor.ID, err)
+		}
+	}
+	return nil
+}
+
+// GetSensorSchedules returns the names of the schedules using the sensor for occupancy
+func (r *LightRepo) GetSensorSchedules(id string) ([]string, error) {
+	rows, err := r.db.Query("SELECT controlled_by_schedule FROM sensor WHERE id = $1 AND bridge = $2 ORDER BY controlled_by_schedule", id, r.bridge)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading schedules for sensor (%s): %w", id, err)
+	}
+	defer rows.Close()
+
+	names := []string{}
+	for rows.Next() {
+		var name string
+		_ = rows.Scan(&name)
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+func (r *LightRepo) AddBindings(bindings []models.HughBinding) error {
+	tx, _ := r.db.Begin()
+	err := r.insertBindings(tx, bindings)
+	if err != nil {
+		return err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("Error adding bindings: %w", err)
+	}
+
+	return nil
+}
+
+// SyncBindings replaces the stored bindings with those just discovered
+func (r *LightRepo) SyncBindings(bindings []models.HughBinding) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("Error syncing bindings: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	_, err = tx.Exec("DELETE FROM binding WHERE bridge = $1", r.bridge)
+	if err != nil {
+		return fmt.Errorf("Error removing bindings: %w", err)
+	}
+	err = r.insertBindings(tx, bindings)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("Error syncing bindings: %w", err)
+	}
+
+	return nil
+}
+
+func (r *LightRepo) insertBindings(tx *sql.Tx, bindings []models.HughBinding) error {
+	for _, binding := range bindings {
+		_, err := tx.Exec(
+			`INSERT OR IGNORE INTO binding 
+      (bridge, id, event, action, controlled_by_schedule, duration)
+     VALUES ($1,$2,$3,$4,$5,$6);`,
+			r.bridge,
+			binding.ID,
+			binding.Event,
+			binding.Action,
+			binding.ScheduleName,
+			binding.Duration,
+		)
+		if err != nil {
+			return fmt.Errorf("Error adding binding (%s): %w", binding.ID, err)
+		}
+	}
+	return nil
+}
+
+// GetBindings returns the actions bound to the button or dial
+func (r *LightRepo) GetBindings(id string) ([]models.HughBinding, error) {
+	rows, err := r.db.Query("SELECT id, event, action, controlled_by_schedule, duration FROM binding WHERE id = $1 AND bridge = $2 ORDER BY controlled_by_schedule, action", id, r.bridge)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading bindings for (%s): %w", id, err)
+	}
+	defer rows.Close()
+
+	bindings := []models.HughBinding{}
+	for rows.Next() {
+		var binding models.HughBinding
+		_ = rows.Scan(&binding.ID, &binding.Event, &binding.Action, &binding.ScheduleName, &binding.Duration)
+		bindings = append(bindings, binding)
+	}
+	return bindings, nil
+}
+
+func (r *LightRepo) SetAmbientLux(scheduleName string, lux float64) error {
+	_, err := r.db.Exec(
+		`INSERT INTO ambient_light 
+      (bridge, controlled_by_schedule, lux, updated_time)
+     VALUES ($1,$2,$3,$4)
+     ON CONFLICT (bridge, controlled_by_schedule) DO UPDATE SET lux = excluded.lux, updated_time = excluded.updated_time;`,
+		r.bridge, scheduleName, lux, time.Now())
+	if err != nil {
+		return fmt.Errorf("Error setting ambient light for schedule (%s) to %v: %w", scheduleName, lux, err)
+	}
+	return nil
+}
+
+// SetScheduleDisabled records whether a calendar event disables the schedule, while it does the schedule's lights
+// and scenes aren't listed for updating
+func (r *LightRepo) SetScheduleDisabled(scheduleName string, disabled bool) error {
+	var err error
+	if disabled {
+		_, err = r.db.Exec(
+			"INSERT INTO disabled_schedule (bridge, controlled_by_schedule) VALUES ($1,$2) ON CONFLICT DO NOTHING",
+			r.bridge, scheduleName)
+	} else {
+		_, err = r.db.Exec("DELETE FROM disabled_schedule WHERE controlled_by_schedule = $1 AND bridge = $2", scheduleName, r.bridge)
+	}
+	if err != nil {
+		return fmt.Errorf("Error setting schedule (%s) disabled to %t: %w", scheduleName, disabled, err)
+	}
+	return nil
+}
+
+// GetAmbientLux returns the latest ambient light reading for the schedule, nil if there hasn't been one
+func (r *LightRepo) GetAmbientLux(scheduleName string) (*float64, error) {
+	row := r.db.QueryRow("SELECT lux FROM ambient_light WHERE controlled_by_schedule = $1 AND bridge = $2", scheduleName, r.bridge)
+	var lux float64
+	err := row.Scan(&lux)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		} else {
+			return nil, fmt.Errorf("Error reading ambient light for schedule (%s): %w", scheduleName, err)
+		}
+	}
+	return &lux, nil
+}
+
+// returns every known grouped_light with the light service ids it controls, largest groups first
+func (r *LightRepo) GetGroupedLights() ([]models.HughGroup, error) {
+	rows, err := r.db.Query(`
+    SELECT g.id, g.serviceid_light
+    FROM grouped_light g
+    JOIN (SELECT id, count(*) AS size FROM grouped_light WHERE bridge = $1 GROUP BY id) s ON s.id = g.id
+    WHERE g.bridge = $1
+    ORDER BY s.size DESC, g.id, g.serviceid_light
+  `, r.bridge)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading grouped lights: %w", err)
+	}
+	defer rows.Close()
+
+	groups := []models.HughGroup{}
+
+	for rows.Next() {
+		var id, lsID string
+		_ = rows.Scan(&id, &lsID)
+
+		if len(groups) == 0 || groups[len(groups)-1].GroupedLightServiceId != id {
+			groups = append(groups, models.HughGroup{GroupedLightServiceId: id})
+		}
+		groups[len(groups)-1].LightServiceIds = append(groups[len(groups)-1].LightServiceIds, lsID)
+	}
+
+	return groups, nil
+}
+
 func (r *LightRepo) SetLightOnState(lsID string, on bool) error {
-	_, err := r.db.Exec("UPDATE light SET on_state = $1 WHERE serviceid_light = $2", on, lsID)
+	_, err := r.db.Exec("UPDATE light SET on_state = $1 WHERE serviceid_light = $2 AND bridge = $3", on, lsID, r.bridge)
 	if err != nil {
 		return fmt.Errorf("Error setting light (%s) on state to %t: %w", lsID, on, err)
 	}
@@ -126,7 +612,7 @@ func (r *LightRepo) SetLightOnState(lsID string, on bool) error {
 }
 
 func (r *LightRepo) SetLightOnStateOverride(lsID string, on bool, targetOn bool) error {
-	_, err := r.db.Exec("UPDATE light SET override_on_state = $1, on_state = $1, override_time = $2, override_target_on_state = $3 WHERE serviceid_light = $4", on, time.Now(), targetOn, lsID)
+	_, err := r.db.Exec("UPDATE light SET override_on_state = $1, on_state = $1, override_time = $2, override_target_on_state = $3 WHERE serviceid_light = $4 AND bridge = $5", on, time.Now(), targetOn, lsID, r.bridge)
 	if err != nil {
 		return fmt.Errorf("Error setting light (%s) override on state to %t: %w", lsID, on, err)
 	}
@@ -134,7 +620,7 @@ func (r *LightRepo) SetLightOnStateOverride(lsID string, on bool, targetOn bool)
 }
 
 func (r *LightRepo) SetLightBrightnessOverride(lsID string, brightness int, targetBrightness int) error {
-	_, err := r.db.Exec("UPDATE light SET override_brightness = $1, override_time = $2, override_target_brightness = $3 WHERE serviceid_light = $4", brightness, time.Now(), targetBrightness, lsID)
+	_, err := r.db.Exec("UPDATE light SET override_brightness = $1, override_time = $2, override_target_brightness = $3 WHERE serviceid_light = $4 AND bridge = $5", brightness, time.Now(), targetBrightness, lsID, r.bridge)
 	if err != nil {
 		return fmt.Errorf("Error setting light (%s) override brightness to %v: %w", lsID, brightness, err)
 	}
@@ -142,19 +628,28 @@ func (r *LightRepo) SetLightBrightnessOverride(lsID string, brightness int, targ
 }
 
 func (r *LightRepo) SetLightColourTempOverride(lsID string, colourTemp int, targetColourTemp int) error {
-	_, err := r.db.Exec("UPDATE light SET override_colour_temp = $1, override_time = $2, override_target_colour_temp = $3 WHERE serviceid_light = $4", colourTemp, time.Now(), targetColourTemp, lsID)
+	_, err := r.db.Exec("UPDATE light SET override_colour_temp = $1, override_time = $2, override_target_colour_temp = $3 WHERE serviceid_light = $4 AND bridge = $5", colourTemp, time.Now(), targetColourTemp, lsID, r.bridge)
 	if err != nil {
 		return fmt.Errorf("Error setting light (%s) override colour temp to %v: %w", lsID, colourTemp, err)
 	}
 	return nil
 }
 
+// RemoveLight forgets a light the bridge no longer has, until the next discovery
+func (r *LightRepo) RemoveLight(lsID string) error {
+	_, err := r.db.Exec("DELETE FROM light WHERE serviceid_light = $1 AND bridge = $2", lsID, r.bridge)
+	if err != nil {
+		return fmt.Errorf("Error removing light (%s): %w", lsID, err)
+	}
+	return nil
+}
+
 func (r *LightRepo) SetLightUnreachable(lsID string) error {
 	// when setting light to unreachable, also clear any overrides as they are now redundant
 	_, err := r.db.Exec(`
     UPDATE light 
     SET unreachable = true
-    WHERE serviceid_light = $1`, lsID)
+    WHERE serviceid_light = $1 AND bridge = $2`, lsID, r.bridge)
 	if err != nil {
 		return fmt.Errorf("Error setting light (%s) to unreachable: %w", lsID, err)
 	}
@@ -163,13 +658,17 @@ func (r *LightRepo) SetLightUnreachable(lsID string) error {
 }
 
 func (r *LightRepo) UpdateTargetState(scheduleName string, target models.LightState) error {
+	colourX, colourY := colourColumns(target.Colour)
 	_, err := r.db.Exec(
 		`UPDATE light 
      SET target_brightness  = $1, 
          target_colour_temp = $2,
-         target_on_state    = $3
-     WHERE controlled_by_schedule = $4`,
-		target.Brightness, target.TemperatureMirek, target.On, scheduleName)
+         target_on_state    = $3,
+         target_transition  = $4,
+         target_colour_x    = $5,
+         target_colour_y    = $6
+     WHERE controlled_by_schedule = $7 AND bridge = $8`,
+		target.Brightness, target.TemperatureMirek, target.On, target.Transition.Milliseconds(), colourX, colourY, scheduleName, r.bridge)
 
 	if err != nil {
 		return fmt.Errorf("Error updating targets for lights in schedule (%s) to: %v: %w", scheduleName, target, err)
@@ -179,9 +678,11 @@ func (r *LightRepo) UpdateTargetState(scheduleName string, target models.LightSt
 		`UPDATE scene 
      SET target_brightness  = $1, 
          target_colour_temp = $2,
-         target_on_state    = $3
-     WHERE controlled_by_schedule = $4`,
-		target.Brightness, target.TemperatureMirek, target.On, scheduleName)
+         target_on_state    = $3,
+         target_colour_x    = $4,
+         target_colour_y    = $5
+     WHERE controlled_by_schedule = $6 AND bridge = $7`,
+		target.Brightness, target.TemperatureMirek, target.On, colourX, colourY, scheduleName, r.bridge)
 
 	if err != nil {
 		return fmt.Errorf("Error updating targets for scenes in schedule (%s) to: %v: %w", scheduleName, target, err)
@@ -196,6 +697,9 @@ func (r *LightRepo) GetLightTargetState(lsID string) (models.LightState, error)
     SELECT target_brightness, 
            target_colour_temp, 
            target_on_state, 
+           coalesce(target_transition, 0),
+           target_colour_x,
+           target_colour_y,
            min_colour_temp, 
            max_colour_temp,
            auto_on_from,
@@ -203,18 +707,21 @@ func (r *LightRepo) GetLightTargetState(lsID string) (models.LightState, error)
            on_state
     FROM light 
     WHERE 
-      serviceid_light = $1`, lsID)
+      serviceid_light = $1 AND bridge = $2`, lsID, r.bridge)
 	var (
 		b        int
 		t        int
 		o        bool
+		tr       int64
+		x        sql.NullFloat64
+		y        sql.NullFloat64
 		mint     int
 		maxt     int
 		autoOnFr string
 		autoOnTo string
 		on       bool
 	)
-	err := row.Scan(&b, &t, &o, &mint, &maxt, &autoOnFr, &autoOnTo, &on)
+	err := row.Scan(&b, &t, &o, &tr, &x, &y, &mint, &maxt, &autoOnFr, &autoOnTo, &on)
 	if err != nil {
 		return models.LightState{}, fmt.Errorf("Error reading target state for light (%s): %w", lsID, err)
 	}
@@ -232,6 +739,8 @@ func (r *LightRepo) GetLightTargetState(lsID string) (models.LightState, error)
 		Brightness:       b,
 		TemperatureMirek: constrainedTemp,
 		On:               o,
+		Colour:           colourFromColumns(x, y),
+		Transition:       time.Duration(tr) * time.Millisecond,
 		AutoOnFrom:       autoOnFr,
 		AutoOnTo:         autoOnTo,
 		CurrentOnState:   on,
@@ -239,25 +748,47 @@ func (r *LightRepo) GetLightTargetState(lsID string) (models.LightState, error)
 }
 
 func (r *LightRepo) GetSceneTargetState(ID string) (models.LightState, error) {
-	row := r.db.QueryRow("SELECT target_brightness, target_colour_temp, target_on_state FROM scene WHERE id = $1", ID)
+	row := r.db.QueryRow("SELECT target_brightness, target_colour_temp, target_on_state, target_colour_x, target_colour_y FROM scene WHERE id = $1 AND bridge = $2", ID, r.bridge)
 	var (
 		b int
 		t int
 		o bool
+		x sql.NullFloat64
+		y sql.NullFloat64
 	)
-	err := row.Scan(&b, &t, &o)
+	err := row.Scan(&b, &t, &o, &x, &y)
 	if err != nil {
 		return models.LightState{}, fmt.Errorf("Error reading target state for scene (%s): %w", ID, err)
 	}
 	return models.LightState{
 		Brightness:       b,
 		TemperatureMirek: t,
+		Colour:           colourFromColumns(x, y),
 		On:               o,
 	}, nil
 }
 
+// the target colour columns, null for a colour temperature target
+func colourColumns(c *colour.XY) (sql.NullFloat64, sql.NullFloat64) {
+	if c == nil {
+		return sql.NullFloat64{}, sql.NullFloat64{}
+	}
+	return sql.NullFloat64{Float64: c.X, Valid: true}, sql.NullFloat64{Float64: c.Y, Valid: true}
+}
+
+func colourFromColumns(x sql.NullFloat64, y sql.NullFloat64) *colour.XY {
+	if !x.Valid || !y.Valid {
+		return nil
+	}
+	return &colour.XY{X: x.Float64, Y: y.Float64}
+}
+
 func (r *LightRepo) IsScheduledLight(lsID string) (bool, error) {
-	row := r.db.QueryRow("SELECT serviceid_light FROM light WHERE serviceid_light = $1", lsID)
+	row := r.db.QueryRow(`
+    SELECT serviceid_light FROM light
+    WHERE serviceid_light = $1 AND bridge = $2
+      AND NOT EXISTS (SELECT 1 FROM disabled_schedule d WHERE d.bridge = light.bridge AND d.controlled_by_schedule = light.controlled_by_schedule)
+    `, lsID, r.bridge)
 	var id string
 	err := row.Scan(&id)
 
@@ -272,7 +803,7 @@ func (r *LightRepo) IsScheduledLight(lsID string) (bool, error) {
 }
 
 func (r *LightRepo) GetLightServiceIDForZigbeeID(zigbeeID string) (string, error) {
-	row := r.db.QueryRow("SELECT serviceid_light FROM light WHERE serviceid_zigbee = $1", zigbeeID)
+	row := r.db.QueryRow("SELECT serviceid_light FROM light WHERE serviceid_zigbee = $1 AND bridge = $2", zigbeeID, r.bridge)
 	var id string
 	err := row.Scan(&id)
 
@@ -297,6 +828,8 @@ func (r *LightRepo) GetAllControllingLightIDs() ([]string, error) {
        (    target_brightness  != coalesce(last_update_brightness, -1)
          OR target_colour_temp != coalesce(last_update_colour_temp, -1) 
          OR target_on_state    != coalesce(last_update_on_state, -1)
+         OR coalesce(target_colour_x, -1) != coalesce(last_update_colour_x, -1)
+         OR coalesce(target_colour_y, -1) != coalesce(last_update_colour_y, -1)
        )
 
       AND (
@@ -305,7 +838,12 @@ func (r *LightRepo) GetAllControllingLightIDs() ([]string, error) {
         -- or the override was over [$1] minutes ago
         OR ((strftime('%s') - strftime('%s',override_time))/60 > $1)
       )
-    `, constants.MaxLightOverrideMinutes)
+
+      -- and the schedule isn't disabled by a calendar event
+      AND NOT EXISTS (SELECT 1 FROM disabled_schedule d WHERE d.bridge = light.bridge AND d.controlled_by_schedule = light.controlled_by_schedule)
+
+      AND bridge = $2
+    `, constants.MaxLightOverrideMinutes, r.bridge)
 	if err != nil {
 		return nil, fmt.Errorf("Error reading ids for all lights: %w", err)
 	}
@@ -324,8 +862,72 @@ func (r *LightRepo) GetAllControllingLightIDs() ([]string, error) {
 
 }
 
+// GetControllingLightIDsForSchedule returns the lights of the schedule that haven't been changed by hand
+func (r *LightRepo) GetControllingLightIDsForSchedule(scheduleName string) ([]string, error) {
+	rows, err := r.db.Query(`
+    SELECT serviceid_light 
+    FROM light 
+    WHERE
+      controlled_by_schedule = $1
+
+      AND (
+        (override_brightness IS NULL AND override_colour_temp IS NULL AND override_on_state IS NULL) 
+        OR ((strftime('%s') - strftime('%s',override_time))/60 > $2)
+      )
+
+      AND NOT EXISTS (SELECT 1 FROM disabled_schedule d WHERE d.bridge = light.bridge AND d.controlled_by_schedule = light.controlled_by_schedule)
+
+      AND bridge = $3
+    `, scheduleName, constants.MaxLightOverrideMinutes, r.bridge)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading ids for lights in schedule (%s): %w", scheduleName, err)
+	}
+	defer rows.Close()
+
+	ids := []string{}
+
+	for rows.Next() {
+		var lsID string
+		_ = rows.Scan(&lsID)
+
+		ids = append(ids, lsID)
+	}
+
+	return ids, nil
+}
+
+// GetAllLightIDs returns the ids of every scheduled light, whether or not it needs updating, other than those of
+// schedules disabled by a calendar event
+func (r *LightRepo) GetAllLightIDs() ([]string, error) {
+	rows, err := r.db.Query(`
+    SELECT serviceid_light FROM light
+    WHERE bridge = $1
+      AND NOT EXISTS (SELECT 1 FROM disabled_schedule d WHERE d.bridge = light.bridge AND d.controlled_by_schedule = light.controlled_by_schedule)
+    ORDER BY serviceid_light
+    `, r.bridge)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading ids for all lights: %w", err)
+	}
+	defer rows.Close()
+
+	ids := []string{}
+
+	for rows.Next() {
+		var lsID string
+		_ = rows.Scan(&lsID)
+
+		ids = append(ids, lsID)
+	}
+
+	return ids, nil
+}
+
 func (r *LightRepo) GetAllSceneIDs() ([]string, error) {
-	rows, err := r.db.Query("SELECT id FROM scene")
+	rows, err := r.db.Query(`
+    SELECT id FROM scene
+    WHERE bridge = $1
+      AND NOT EXISTS (SELECT 1 FROM disabled_schedule d WHERE d.bridge = scene.bridge AND d.controlled_by_schedule = scene.controlled_by_schedule)
+    `, r.bridge)
 	if err != nil {
 		return nil, fmt.Errorf("Error reading ids for all scenes: %w", err)
 	}
@@ -350,10 +952,12 @@ func (r *LightRepo) MarkLightAsUpdated(lsID string) error {
     SET last_update_time = $1,
         last_update_brightness = target_brightness,
         last_update_colour_temp = target_colour_temp,
+        last_update_colour_x = target_colour_x,
+        last_update_colour_y = target_colour_y,
         last_update_on_state = target_on_state,
         unreachable = null
-    WHERE serviceid_light = $2
-  `, time.Now(), lsID)
+    WHERE serviceid_light = $2 AND bridge = $3
+  `, time.Now(), lsID, r.bridge)
 	if err != nil {
 		return fmt.Errorf("Error marking light (%s) as updated: %w", lsID, err)
 	}
@@ -363,7 +967,7 @@ func (r *LightRepo) MarkLightAsUpdated(lsID string) error {
 }
 
 func (r *LightRepo) GetLightLastUpdate(lsID string) (*time.Time, error) {
-	row := r.db.QueryRow("SELECT last_update_time FROM light WHERE serviceid_light = $1", lsID)
+	row := r.db.QueryRow("SELECT last_update_time FROM light WHERE serviceid_light = $1 AND bridge = $2", lsID, r.bridge)
 	var lastUpdated time.Time
 	err := row.Scan(&lastUpdated)
 
@@ -377,6 +981,59 @@ func (r *LightRepo) GetLightLastUpdate(lsID string) (*time.Time, error) {
 	return &lastUpdated, nil
 }
 
+// GetLightStatuses returns the state each light should be in as far as hugh knows, the override if it has been
+// changed since hugh last updated it
+func (r *LightRepo) GetLightStatuses() ([]models.LightStatus, error) {
+	rows, err := r.db.Query(`
+    SELECT serviceid_light,
+           serviceid_zigbee,
+           coalesce(override_on_state, last_update_on_state, on_state, 0),
+           coalesce(override_brightness, last_update_brightness, 0),
+           coalesce(override_colour_temp, last_update_colour_temp, 0),
+           coalesce(unreachable, 0)
+    FROM light
+    WHERE bridge = $1`, r.bridge)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading light statuses: %w", err)
+	}
+	defer rows.Close()
+
+	statuses := []models.LightStatus{}
+	for rows.Next() {
+		var (
+			status      models.LightStatus
+			unreachable bool
+		)
+		err := rows.Scan(&status.LightServiceId, &status.ZigbeeServiceID, &status.On, &status.Brightness, &status.TemperatureMirek, &unreachable)
+		if err != nil {
+			return nil, fmt.Errorf("Error reading light statuses: %w", err)
+		}
+		status.Reachable = !unreachable
+		statuses = append(statuses, status)
+	}
+
+	return statuses, rows.Err()
+}
+
+// ClearScheduleOverrides clears the overrides of every light controlled by the schedule
+func (r *LightRepo) ClearScheduleOverrides(scheduleName string) error {
+	_, err := r.db.Exec(`
+    UPDATE light 
+    SET override_brightness = null, 
+        override_colour_temp = null,
+        override_target_brightness = null, 
+        override_target_colour_temp = null, 
+        override_target_on_state = null, 
+        override_time = null,
+        override_on_state = null
+    WHERE controlled_by_schedule = $1 AND bridge = $2
+  `, scheduleName, r.bridge)
+	if err != nil {
+		return fmt.Errorf("Error clearing overrides for schedule (%s): %w", scheduleName, err)
+	}
+	return nil
+}
+
 func (r *LightRepo) ClearLightOverrides(lsID string) error {
 	_, err := r.db.Exec(`
     UPDATE light 
@@ -388,8 +1045,8 @@ func (r *LightRepo) ClearLightOverrides(lsID string) error {
         override_brightness = null, 
         override_time = null,
         override_on_state = null
-    WHERE serviceid_light = $1
-  `, lsID)
+    WHERE serviceid_light = $1 AND bridge = $2
+  `, lsID, r.bridge)
 	if err != nil {
 		return fmt.Errorf("Error clearing overrides for light (%s): %w", lsID, err)
 	}
@@ -0,0 +1,273 @@
+package repos_test
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/log"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wheelibin/hugh/internal/colour"
+	"github.com/wheelibin/hugh/internal/models"
+	"github.com/wheelibin/hugh/internal/repos"
+)
+
+// the schema of the first versions of hugh, before bridges, transitions and colours
+const baselineSchema = `
+  CREATE TABLE IF NOT EXISTS light (
+    serviceid_light VARCHAR(36) PRIMARY KEY,
+    serviceid_zigbee VARCHAR(36),
+    name TEXT,
+    controlled_by_schedule VARCHAR(36),
+    auto_on_from TEXT,
+    auto_on_to TEXT,
+    unreachable INTEGER,
+    on_state INTEGER,
+    target_brightness INTEGER,
+    target_colour_temp INTEGER,
+    target_on_state INTEGER,
+    last_update_time TIMESTAMP,
+    last_update_brightness INTEGER,
+    last_update_colour_temp INTEGER,
+    last_update_on_state INTEGER,
+    override_brightness INTEGER,
+    override_target_brightness INTEGER,
+    override_colour_temp INTEGER,
+    override_target_colour_temp INTEGER,
+    override_time TIMESTAMP,
+    override_on_state INTEGER,
+    override_target_on_state INTEGER,
+    min_colour_temp INTEGER,
+    max_colour_temp INTEGER
+  );
+
+  CREATE TABLE IF NOT EXISTS scene (
+    id VARCHAR(36) PRIMARY KEY,
+    controlled_by_schedule VARCHAR(36),
+    target_brightness INTEGER,
+    target_colour_temp INTEGER,
+    target_on_state INTEGER
+  );
+
+  INSERT INTO light (serviceid_light, name) VALUES ('light-1', 'Old light');
+  INSERT INTO scene (id) VALUES ('scene-1');
+`
+
+func Test_NewLightRepo_UpgradesBaselineDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hugh.db")
+	old, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	_, err = old.Exec(baselineSchema)
+	require.NoError(t, err)
+	require.NoError(t, old.Close())
+
+	db, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	repo, err := repos.NewLightRepo(log.NewWithOptions(os.Stderr, log.Options{Level: log.FatalLevel}), db)
+	require.NoError(t, err)
+	house, garage := repo.ForBridge("house"), repo.ForBridge("garage")
+
+	// the old rows are gone, and the same light id can be on two bridges
+	light := models.HughLight{LightServiceId: "light-1", Name: "Kitchen", ScheduleName: "Downstairs", AutoOnFrom: "07:00", AutoOnTo: "22:00"}
+	require.NoError(t, house.Add([]models.HughLight{light}))
+	require.NoError(t, garage.Add([]models.HughLight{light}))
+	require.NoError(t, house.AddScenes([]models.HughScene{{ID: "scene-1", ScheduleName: "Downstairs"}}))
+
+	ids, err := house.GetAllLightIDs()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"light-1"}, ids)
+	ids, err = garage.GetAllLightIDs()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"light-1"}, ids)
+
+	// the columns added since are there
+	target := models.LightState{Brightness: 60, TemperatureMirek: 370, On: true, Transition: 400 * time.Millisecond, Colour: &colour.XY{X: 0.5, Y: 0.4}}
+	require.NoError(t, house.UpdateTargetState("Downstairs", target))
+
+	state, err := house.GetLightTargetState("light-1")
+	require.NoError(t, err)
+	assert.Equal(t, 60, state.Brightness)
+	assert.Equal(t, 400*time.Millisecond, state.Transition)
+	assert.Equal(t, &colour.XY{X: 0.5, Y: 0.4}, state.Colour)
+
+	sceneState, err := house.GetSceneTargetState("scene-1")
+	require.NoError(t, err)
+	assert.Equal(t, &colour.XY{X: 0.5, Y: 0.4}, sceneState.Colour)
+}
+
+func newTestRepo(t *testing.T) (*repos.LightRepo, *sql.DB) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	// every connection to :memory: is a database of its own
+	db.SetMaxOpenConns(1)
+
+	repo, err := repos.NewLightRepo(log.NewWithOptions(os.Stderr, log.Options{Level: log.FatalLevel}), db)
+	require.NoError(t, err)
+	return repo, db
+}
+
+func Test_LightRepo_SyncLights(t *testing.T) {
+	repo, db := newTestRepo(t)
+	house, garage := repo.ForBridge("house"), repo.ForBridge("garage")
+
+	require.NoError(t, house.Add([]models.HughLight{
+		{LightServiceId: "ls1", ZigbeeServiceID: "zb1", Name: "Kitchen 1", ScheduleName: "Kitchen"},
+		{LightServiceId: "ls2", ZigbeeServiceID: "zb2", Name: "Kitchen 2", ScheduleName: "Kitchen"},
+		{LightServiceId: "ls3", ZigbeeServiceID: "zb3", Name: "Kitchen 3", ScheduleName: "Kitchen"},
+	}))
+	require.NoError(t, garage.Add([]models.HughLight{{LightServiceId: "ls2", Name: "Garage", ScheduleName: "Garage"}}))
+	require.NoError(t, house.SetLightBrightnessOverride("ls3", 20, 80))
+
+	// ls1 renamed and moved to another room, ls2 removed and ls4 new
+	added, removed, err := house.SyncLights([]models.HughLight{
+		{LightServiceId: "ls1", ZigbeeServiceID: "zb1b", Name: "Hall ceiling", ScheduleName: "Downstairs"},
+		{LightServiceId: "ls3", ZigbeeServiceID: "zb3", Name: "Kitchen 3", ScheduleName: "Kitchen"},
+		{LightServiceId: "ls4", ZigbeeServiceID: "zb4", Name: "Kitchen 4", ScheduleName: "Kitchen"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ls4"}, added)
+	assert.Equal(t, []string{"ls2"}, removed)
+
+	ids, err := house.GetAllLightIDs()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ls1", "ls3", "ls4"}, ids)
+
+	var name string
+	require.NoError(t, db.QueryRow("SELECT name FROM light WHERE serviceid_light = 'ls1' AND bridge = 'house'").Scan(&name))
+	assert.Equal(t, "Hall ceiling", name)
+	lsID, err := house.GetLightServiceIDForZigbeeID("zb1b")
+	require.NoError(t, err)
+	assert.Equal(t, "ls1", lsID)
+	downstairs, err := house.GetControllingLightIDsForSchedule("Downstairs")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ls1"}, downstairs)
+
+	// the override on the light that stayed is kept
+	kitchen, err := house.GetControllingLightIDsForSchedule("Kitchen")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ls4"}, kitchen)
+
+	// the other bridge's light of the same id is left alone
+	ids, err = garage.GetAllLightIDs()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ls2"}, ids)
+}
+
+func Test_LightRepo_SyncScenes(t *testing.T) {
+	repo, _ := newTestRepo(t)
+	house, garage := repo.ForBridge("house"), repo.ForBridge("garage")
+
+	require.NoError(t, house.AddScenes([]models.HughScene{{ID: "s1", ScheduleName: "Kitchen"}, {ID: "s2", ScheduleName: "Kitchen"}}))
+	require.NoError(t, garage.AddScenes([]models.HughScene{{ID: "s2", ScheduleName: "Garage"}}))
+
+	require.NoError(t, house.SyncScenes([]models.HughScene{{ID: "s1", ScheduleName: "Downstairs"}, {ID: "s3", ScheduleName: "Kitchen"}}))
+
+	ids, err := house.GetAllSceneIDs()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"s1", "s3"}, ids)
+	ids, err = garage.GetAllSceneIDs()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"s2"}, ids)
+
+	// s1 now follows the schedule it moved to
+	require.NoError(t, house.UpdateTargetState("Downstairs", models.LightState{Brightness: 40, TemperatureMirek: 400, On: true}))
+	state, err := house.GetSceneTargetState("s1")
+	require.NoError(t, err)
+	assert.Equal(t, 40, state.Brightness)
+}
+
+func Test_LightRepo_SyncGroups(t *testing.T) {
+	repo, _ := newTestRepo(t)
+	house, garage := repo.ForBridge("house"), repo.ForBridge("garage")
+
+	require.NoError(t, house.AddGroups([]models.HughGroup{
+		{GroupedLightServiceId: "g1", LightServiceIds: []string{"ls1", "ls2"}},
+		{GroupedLightServiceId: "g2", LightServiceIds: []string{"ls3"}},
+	}))
+	require.NoError(t, garage.AddGroups([]models.HughGroup{{GroupedLightServiceId: "g2", LightServiceIds: []string{"ls4"}}}))
+
+	// a light taken out of g1, g2 gone and g3 new
+	require.NoError(t, house.SyncGroups([]models.HughGroup{
+		{GroupedLightServiceId: "g1", LightServiceIds: []string{"ls1"}},
+		{GroupedLightServiceId: "g3", LightServiceIds: []string{"ls2", "ls3"}},
+	}))
+
+	groups, err := house.GetGroupedLights()
+	require.NoError(t, err)
+	assert.Equal(t, []models.HughGroup{
+		{GroupedLightServiceId: "g3", LightServiceIds: []string{"ls2", "ls3"}},
+		{GroupedLightServiceId: "g1", LightServiceIds: []string{"ls1"}},
+	}, groups)
+	groups, err = garage.GetGroupedLights()
+	require.NoError(t, err)
+	assert.Equal(t, []models.HughGroup{{GroupedLightServiceId: "g2", LightServiceIds: []string{"ls4"}}}, groups)
+}
+
+func Test_LightRepo_RemoveLight(t *testing.T) {
+	repo, _ := newTestRepo(t)
+	house, garage := repo.ForBridge("house"), repo.ForBridge("garage")
+
+	require.NoError(t, house.Add([]models.HughLight{{LightServiceId: "ls1", ScheduleName: "Kitchen"}, {LightServiceId: "ls2", ScheduleName: "Kitchen"}}))
+	require.NoError(t, garage.Add([]models.HughLight{{LightServiceId: "ls1", ScheduleName: "Garage"}}))
+
+	require.NoError(t, house.RemoveLight("ls1"))
+
+	ids, err := house.GetAllLightIDs()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ls2"}, ids)
+	ids, err = garage.GetAllLightIDs()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ls1"}, ids)
+}
+
+func Test_LightRepo_SetScheduleDisabled(t *testing.T) {
+	repo, _ := newTestRepo(t)
+	house, garage := repo.ForBridge("house"), repo.ForBridge("garage")
+
+	require.NoError(t, house.Add([]models.HughLight{{LightServiceId: "ls1", ScheduleName: "Kitchen"}, {LightServiceId: "ls2", ScheduleName: "Hall"}}))
+	require.NoError(t, house.AddScenes([]models.HughScene{{ID: "s1", ScheduleName: "Kitchen"}, {ID: "s2", ScheduleName: "Hall"}}))
+	require.NoError(t, garage.Add([]models.HughLight{{LightServiceId: "ls1", ScheduleName: "Kitchen"}}))
+	require.NoError(t, house.UpdateTargetState("Kitchen", models.LightState{Brightness: 40, TemperatureMirek: 400, On: true}))
+	require.NoError(t, house.UpdateTargetState("Hall", models.LightState{Brightness: 40, TemperatureMirek: 400, On: true}))
+
+	// a calendar event disables the kitchen on the house bridge
+	require.NoError(t, house.SetScheduleDisabled("Kitchen", true))
+
+	ids, err := house.GetAllControllingLightIDs()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ls2"}, ids)
+	ids, err = house.GetAllLightIDs()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ls2"}, ids)
+	ids, err = house.GetAllSceneIDs()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"s2"}, ids)
+	ids, err = house.GetControllingLightIDsForSchedule("Kitchen")
+	require.NoError(t, err)
+	assert.Empty(t, ids)
+	scheduled, err := house.IsScheduledLight("ls1")
+	require.NoError(t, err)
+	assert.False(t, scheduled)
+	ids, err = garage.GetAllLightIDs()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ls1"}, ids)
+
+	// and the event ends
+	require.NoError(t, house.SetScheduleDisabled("Kitchen", false))
+
+	ids, err = house.GetAllControllingLightIDs()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"ls1", "ls2"}, ids)
+	ids, err = house.GetAllSceneIDs()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"s1", "s2"}, ids)
+}
@@ -0,0 +1,237 @@
+package schedule
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/spf13/viper"
+	"github.com/wheelibin/hugh/internal/models"
+)
+
+// DayPattern reads the named day pattern from config, resolving what it extends and the fragments it includes
+func (s *ScheduleService) DayPattern(patternName string) (models.DayPattern, error) {
+	var patterns map[string]map[string]any
+	if err := viper.UnmarshalKey("dayPatterns", &patterns); err != nil {
+		return models.DayPattern{}, fmt.Errorf("error reading day patterns from config: %w", err)
+	}
+	var fragments map[string][]models.ScheduleDayPatternStep
+	if err := viper.UnmarshalKey("patternFragments", &fragments); err != nil {
+		return models.DayPattern{}, fmt.Errorf("error reading pattern fragments from config: %w", err)
+	}
+
+	return ResolveDayPattern(patternName, patterns, fragments)
+}
+
+// ResolveDayPattern returns the named pattern from the configured patterns (as read from the config, keyed by name)
+// and fragments, with what it extends and the fragments it includes resolved
+func ResolveDayPattern(
+	patternName string,
+	patterns map[string]map[string]any,
+	fragments map[string][]models.ScheduleDayPatternStep,
+) (models.DayPattern, error) {
+	fields, steps, err := resolveDayPattern(patternName, patterns, fragments, nil)
+	if err != nil {
+		return models.DayPattern{}, err
+	}
+
+	var dayPattern models.DayPattern
+	if err := decode(fields, &dayPattern); err != nil {
+		return models.DayPattern{}, fmt.Errorf("day pattern %s: %w", patternName, err)
+	}
+	dayPattern.Pattern = steps
+	return dayPattern, nil
+}
+
+// the fields (less its steps) and steps of the day pattern, those it doesn't set coming from the pattern it extends.
+// Its steps replace those of the extended pattern with the same time (or remove them), other steps going after
+// the step before them.
+func resolveDayPattern(
+	name string,
+	patterns map[string]map[string]any,
+	fragments map[string][]models.ScheduleDayPatternStep,
+	extendedBy []string,
+) (map[string]any, []models.ScheduleDayPatternStep, error) {
+
+	chain := append(append([]string{}, extendedBy...), name)
+	for _, n := range extendedBy {
+		if strings.EqualFold(n, name) {
+			return nil, nil, fmt.Errorf("day pattern %s: extends itself (%s)", extendedBy[0], strings.Join(chain, " -> "))
+		}
+	}
+
+	raw, found := findDayPattern(patterns, name)
+	if !found {
+		if len(extendedBy) > 0 {
+			return nil, nil, fmt.Errorf("day pattern %s: extends unknown day pattern %s", extendedBy[len(extendedBy)-1], name)
+		}
+		return nil, nil, fmt.Errorf("unknown day pattern %s", name)
+	}
+
+	fields := map[string]any{}
+	var ownSteps []models.ScheduleDayPatternStep
+	extends := ""
+	for key, value := range raw {
+		switch strings.ToLower(key) {
+		case "pattern":
+			if err := decode(value, &ownSteps); err != nil {
+				return nil, nil, fmt.Errorf("day pattern %s: %w", name, err)
+			}
+		case "extends":
+			extends, _ = value.(string)
+		default:
+			fields[strings.ToLower(key)] = value
+		}
+	}
+
+	ownSteps, err := includeFragments(ownSteps, fragments, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("day pattern %s: %w", name, err)
+	}
+
+	if extends == "" {
+		return fields, mergeSteps(nil, ownSteps), nil
+	}
+
+	parentFields, parentSteps, err := resolveDayPattern(extends, patterns, fragments, chain)
+	if err != nil {
+		return nil, nil, err
+	}
+	return mergeFields(parentFields, fields), mergeSteps(parentSteps, ownSteps), nil
+}
+
+// the named day pattern, matched ignoring case if there isn't one with the exact name (viper lower cases the names)
+func findDayPattern(patterns map[string]map[string]any, name string) (map[string]any, bool) {
+	if raw, found := patterns[name]; found {
+		return raw, true
+	}
+	for n, raw := range patterns {
+		if strings.EqualFold(n, name) {
+			return raw, true
+		}
+	}
+	return nil, false
+}
+
+// the named pattern fragment, matched ignoring case (viper lower cases the names)
+func findFragment(fragments map[string][]models.ScheduleDayPatternStep, name string) ([]models.ScheduleDayPatternStep, bool) {
+	for n, steps := range fragments {
+		if strings.EqualFold(n, name) {
+			return steps, true
+		}
+	}
+	return nil, false
+}
+
+// replaces the steps including a fragment with the fragment's steps
+func includeFragments(
+	steps []models.ScheduleDayPatternStep,
+	fragments map[string][]models.ScheduleDayPatternStep,
+	includedBy []string,
+) ([]models.ScheduleDayPatternStep, error) {
+
+	result := []models.ScheduleDayPatternStep{}
+	for _, step := range steps {
+		if step.Include == "" {
+			result = append(result, step)
+			continue
+		}
+
+		for _, n := range includedBy {
+			if strings.EqualFold(n, step.Include) {
+				return nil, fmt.Errorf("pattern fragment %s includes itself (%s)", step.Include, strings.Join(append(includedBy, step.Include), " -> "))
+			}
+		}
+		fragment, found := findFragment(fragments, step.Include)
+		if !found {
+			return nil, fmt.Errorf("unknown pattern fragment %s", step.Include)
+		}
+		included, err := includeFragments(fragment, fragments, append(includedBy, step.Include))
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, included...)
+	}
+	return result, nil
+}
+
+func mergeSteps(parent []models.ScheduleDayPatternStep, child []models.ScheduleDayPatternStep) []models.ScheduleDayPatternStep {
+	merged := append([]models.ScheduleDayPatternStep{}, parent...)
+	// where the next new step goes, after the last step the child set
+	next := 0
+	for _, step := range child {
+		i := -1
+		for j, s := range merged {
+			if strings.EqualFold(s.Time, step.Time) {
+				i = j
+				break
+			}
+		}
+
+		switch {
+		case i >= 0 && step.Remove:
+			merged = append(merged[:i], merged[i+1:]...)
+			next = i
+		case i >= 0:
+			merged[i] = step
+			next = i + 1
+		case step.Remove:
+			// nothing to remove
+		default:
+			merged = append(merged[:next], append([]models.ScheduleDayPatternStep{step}, merged[next:]...)...)
+			next++
+		}
+	}
+	return merged
+}
+
+// the parent's fields with those the child sets replacing them, nested fields (e.g. default) one by one
+func mergeFields(parent map[string]any, child map[string]any) map[string]any {
+	merged := map[string]any{}
+	for key, value := range parent {
+		merged[key] = value
+	}
+	for key, value := range child {
+		parentValue, parentIsMap := toMap(merged[key])
+		childValue, childIsMap := toMap(value)
+		if parentIsMap && childIsMap {
+			merged[key] = mergeFields(parentValue, childValue)
+			continue
+		}
+		merged[key] = value
+	}
+	return merged
+}
+
+// the value as a map with lower case keys, false if it isn't a map
+func toMap(value any) (map[string]any, bool) {
+	m, ok := value.(map[string]any)
+	if !ok {
+		return nil, false
+	}
+	lower := map[string]any{}
+	for key, v := range m {
+		lower[strings.ToLower(key)] = v
+	}
+	return lower, true
+}
+
+// Decode decodes config values the way viper does, e.g. a list of single key maps into a map
+func Decode(input any, output any) error {
+	return decode(input, output)
+}
+
+func decode(input any, output any) error {
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook: mapstructure.ComposeDecodeHookFunc(
+			mapstructure.StringToTimeDurationHookFunc(),
+			mapstructure.StringToSliceHookFunc(","),
+		),
+		WeaklyTypedInput: true,
+		Result:           output,
+	})
+	if err != nil {
+		return err
+	}
+	return decoder.Decode(input)
+}
@@ -0,0 +1,41 @@
+package schedule
+
+import (
+	"math"
+
+	"github.com/wheelibin/hugh/internal/constants"
+	"github.com/wheelibin/hugh/internal/models"
+)
+
+// LuxFromLightLevel converts the light_level reported by a hue sensor (10000*log10(lux)+1) to lux
+func LuxFromLightLevel(lightLevel int) float64 {
+	if lightLevel <= 0 {
+		return 0
+	}
+	return math.Pow(10, float64(lightLevel-1)/10000)
+}
+
+// DaylightBrightness scales the brightness down by the share of the target lux that daylight already provides,
+// keeping it within the configured min/max, the min never raising it above the schedule's own brightness
+func DaylightBrightness(brightness int, ambientLux float64, daylight models.ScheduleDaylight) int {
+	if daylight.TargetLux <= 0 {
+		return brightness
+	}
+
+	needed := math.Max(0, math.Min(1, 1-ambientLux/daylight.TargetLux))
+	dimmed := int(math.Round(float64(brightness) * needed))
+
+	maxBrightness := daylight.MaxBrightness
+	if maxBrightness <= 0 {
+		maxBrightness = 100
+	}
+	return max(min(brightness, daylight.MinBrightness), min(maxBrightness, dimmed))
+}
+
+// DaylightHysteresis returns how far the light level has to move before the brightness follows it
+func DaylightHysteresis(daylight models.ScheduleDaylight) float64 {
+	if daylight.Hysteresis > 0 {
+		return daylight.Hysteresis
+	}
+	return daylight.TargetLux * constants.DefaultDaylightHysteresis
+}
@@ -0,0 +1,50 @@
+package schedule_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wheelibin/hugh/internal/models"
+	"github.com/wheelibin/hugh/internal/schedule"
+)
+
+func Test_LuxFromLightLevel(t *testing.T) {
+	assert.Equal(t, 0.0, schedule.LuxFromLightLevel(0))
+	assert.InDelta(t, 1, schedule.LuxFromLightLevel(1), 0.001)
+	assert.InDelta(t, 100, schedule.LuxFromLightLevel(20001), 0.001)
+	assert.InDelta(t, 1000, schedule.LuxFromLightLevel(30001), 0.01)
+}
+
+func Test_DaylightBrightness(t *testing.T) {
+	daylight := models.ScheduleDaylight{TargetLux: 400, MinBrightness: 10, MaxBrightness: 90}
+
+	tests := []struct {
+		name       string
+		brightness int
+		lux        float64
+		expected   int
+	}{
+		{name: "dark: full brightness, clamped to max", brightness: 100, lux: 0, expected: 90},
+		{name: "a quarter of the target from daylight", brightness: 80, lux: 100, expected: 60},
+		{name: "half the target from daylight", brightness: 80, lux: 200, expected: 40},
+		{name: "brighter than the target: clamped to min", brightness: 80, lux: 1000, expected: 10},
+		{name: "a step below the min: never raised above it", brightness: 5, lux: 0, expected: 5},
+		{name: "a step below the min dimmed by daylight: floored at the step", brightness: 5, lux: 200, expected: 5},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, schedule.DaylightBrightness(test.brightness, test.lux, daylight))
+		})
+	}
+
+	t.Run("max defaults to 100", func(t *testing.T) {
+		assert.Equal(t, 100, schedule.DaylightBrightness(100, 0, models.ScheduleDaylight{TargetLux: 400}))
+	})
+}
+
+func Test_DaylightHysteresis(t *testing.T) {
+	assert.Equal(t, 40.0, schedule.DaylightHysteresis(models.ScheduleDaylight{TargetLux: 400}))
+	assert.Equal(t, 15.0, schedule.DaylightHysteresis(models.ScheduleDaylight{TargetLux: 400, Hysteresis: 15}))
+}
@@ -0,0 +1,46 @@
+package schedule
+
+import "math"
+
+// how quickly an interval moves from its start to its end, each taking the progress through the interval (0 to 1)
+// to the share of the change made by then
+var easings = map[string]func(p float64) float64{
+	"linear":  func(p float64) float64 { return p },
+	"easeIn":  func(p float64) float64 { return p * p },
+	"easeOut": func(p float64) float64 { return 1 - (1-p)*(1-p) },
+	"easeInOut": func(p float64) float64 {
+		if p < 0.5 {
+			return 2 * p * p
+		}
+		return 1 - math.Pow(-2*p+2, 2)/2
+	},
+	// a logistic curve scaled to start at 0 and end at 1, slow at either end and quickest halfway through
+	"sigmoid": func(p float64) float64 {
+		logistic := func(x float64) float64 { return 1 / (1 + math.Exp(-sigmoidSteepness*(x-0.5))) }
+		return (logistic(p) - logistic(0)) / (logistic(1) - logistic(0))
+	},
+	// holds the start until the next step
+	"step": func(p float64) float64 {
+		if p < 1 {
+			return 0
+		}
+		return 1
+	},
+}
+
+const sigmoidSteepness = 10
+
+// IsEasing returns whether the name is one of the easings a step can have, an empty name being linear
+func IsEasing(name string) bool {
+	_, found := easings[name]
+	return name == "" || found
+}
+
+// Ease applies the named easing to the progress through an interval, unknown (or no) easings are linear
+func Ease(name string, progress float64) float64 {
+	progress = math.Max(0, math.Min(1, progress))
+	if ease, found := easings[name]; found {
+		return ease(progress)
+	}
+	return progress
+}
@@ -4,6 +4,7 @@ import (
 	"math"
 	"time"
 
+	"github.com/wheelibin/hugh/internal/colour"
 	"github.com/wheelibin/hugh/internal/models"
 )
 
@@ -13,6 +14,23 @@ type IntervalStep struct {
 	TemperatureKelvin int
 	TransitionAt      int // when this step should begin transitioning to the next step (percentage value for now)
 	Off               bool
+	Transition        time.Duration // how long each update during the step takes to fade in
+	Colour            *colour.XY    // replaces the temperature for colour capable lights
+	Easing            string        // how the interval moves from this step to the next, linear if empty
+	// interpolate the brightness in CIE L* and the temperature in mired rather than kelvin
+	PerceptualBrightness bool
+	MiredTemperature     bool
+}
+
+// the chromaticity of the step, false if it has none (i.e. it's an off step)
+func (s IntervalStep) chromaticity() (colour.XY, bool) {
+	if s.Colour != nil {
+		return *s.Colour, true
+	}
+	if s.Off || s.TemperatureKelvin <= 0 {
+		return colour.XY{}, false
+	}
+	return colour.FromKelvin(s.TemperatureKelvin), true
 }
 
 type Interval struct {
@@ -30,6 +48,7 @@ func (i Interval) CalculateTargetLightState(timestamp time.Time) models.LightSta
 			Brightness:       0,
 			TemperatureMirek: 0,
 			On:               !i.Start.Off,
+			Transition:       i.Start.Transition,
 		}
 	}
 
@@ -40,14 +59,42 @@ func (i Interval) CalculateTargetLightState(timestamp time.Time) models.LightSta
 	if percentProgress < (float64(i.Start.TransitionAt) / 100) {
 		percentProgress = 0
 	}
+	percentProgress = Ease(i.Start.Easing, percentProgress)
 
 	temperatureDiff := i.End.TemperatureKelvin - i.Start.TemperatureKelvin
 	temperaturePercentageValue := float64(temperatureDiff) * percentProgress
 	targetTemperature := i.Start.TemperatureKelvin + int(temperaturePercentageValue)
+	if i.Start.MiredTemperature && i.Start.TemperatureKelvin > 0 && i.End.TemperatureKelvin > 0 {
+		startMirek := 1000000 / float64(i.Start.TemperatureKelvin)
+		endMirek := 1000000 / float64(i.End.TemperatureKelvin)
+		targetTemperature = int(1000000 / (startMirek + (endMirek-startMirek)*percentProgress))
+	}
 
 	brightnessDiff := i.End.Brightness - i.Start.Brightness
 	brightnessPercentageValue := float64(brightnessDiff) * percentProgress
 	targetBrightness := int(math.Floor(float64(i.Start.Brightness) + brightnessPercentageValue))
+	if i.Start.PerceptualBrightness {
+		startLightness := colour.Lightness(float64(i.Start.Brightness))
+		endLightness := colour.Lightness(float64(i.End.Brightness))
+		lightness := startLightness + (endLightness-startLightness)*percentProgress
+		targetBrightness = int(math.Round(colour.BrightnessFromLightness(lightness)))
+	}
+
+	// a colour step blends with its neighbour in a perceptual colour space (a temperature neighbour is treated
+	// as the colour of that temperature), lights without colour use the nearest temperature to the colour
+	var targetColour *colour.XY
+	if i.Start.Colour != nil || i.End.Colour != nil {
+		start, hasStart := i.Start.chromaticity()
+		end, hasEnd := i.End.chromaticity()
+		c := start
+		if hasEnd {
+			c = colour.Interpolate(start, end, percentProgress)
+		}
+		if hasStart {
+			targetColour = &c
+			targetTemperature = c.Kelvin()
+		}
+	}
 
 	if targetTemperature < 2000 {
 		targetTemperature = 2000
@@ -58,6 +105,8 @@ func (i Interval) CalculateTargetLightState(timestamp time.Time) models.LightSta
 	return models.LightState{
 		Brightness:       targetBrightness,
 		TemperatureMirek: tempInMirek,
+		Colour:           targetColour,
 		On:               !i.Start.Off,
+		Transition:       i.Start.Transition,
 	}
 }
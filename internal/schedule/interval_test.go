@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/wheelibin/hugh/internal/colour"
 	"github.com/wheelibin/hugh/internal/schedule"
 )
 
@@ -26,6 +27,11 @@ func Test_CalculateTargetLightState(t *testing.T) {
 		End:   schedule.IntervalStep{Time: time.Date(2023, 1, 1, 6, 0, 0, 0, time.Local), TemperatureKelvin: 3000, Brightness: 100},
 	}
 
+	intervalWithTransition := schedule.Interval{
+		Start: schedule.IntervalStep{Time: time.Date(2023, 1, 1, 0, 0, 0, 0, time.Local), TemperatureKelvin: 3000, Brightness: 100, Transition: 30 * time.Second},
+		End:   schedule.IntervalStep{Time: time.Date(2023, 1, 1, 6, 0, 0, 0, time.Local), TemperatureKelvin: 3000, Brightness: 100},
+	}
+
 	tests := []struct {
 		name                string
 		interval            schedule.Interval
@@ -33,6 +39,7 @@ func Test_CalculateTargetLightState(t *testing.T) {
 		expectedTemperature int
 		expectedBrightness  int
 		expectedOn          bool
+		expectedTransition  time.Duration
 	}{
 		{
 			name:                "sixHourInterval: start of interval",
@@ -138,6 +145,15 @@ func Test_CalculateTargetLightState(t *testing.T) {
 			expectedBrightness:  0,
 			expectedOn:          false,
 		},
+		{
+			name:                "intervalWithTransition: half way",
+			interval:            intervalWithTransition,
+			timestamp:           time.Date(2023, 1, 1, 3, 0, 0, 0, time.Local),
+			expectedTemperature: 333,
+			expectedBrightness:  100,
+			expectedOn:          true,
+			expectedTransition:  30 * time.Second,
+		},
 	}
 
 	for _, test := range tests {
@@ -148,7 +164,112 @@ func Test_CalculateTargetLightState(t *testing.T) {
 			assert.Equal(t, test.expectedTemperature, ls.TemperatureMirek)
 			assert.EqualValues(t, test.expectedBrightness, ls.Brightness)
 			assert.Equal(t, test.expectedOn, ls.On)
+			assert.Equal(t, test.expectedTransition, ls.Transition)
 		})
 	}
 
 }
+
+func Test_CalculateTargetLightState_Colour(t *testing.T) {
+
+	red, _ := colour.Parse("red")
+	blue, _ := colour.Parse("blue")
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.Local)
+	end := time.Date(2023, 1, 1, 6, 0, 0, 0, time.Local)
+
+	t.Run("should not set a colour for temperature steps", func(t *testing.T) {
+		interval := schedule.Interval{
+			Start: schedule.IntervalStep{Time: start, TemperatureKelvin: 3000, Brightness: 100},
+			End:   schedule.IntervalStep{Time: end, TemperatureKelvin: 4000, Brightness: 100},
+		}
+		assert.Nil(t, interval.CalculateTargetLightState(start.Add(3*time.Hour)).Colour)
+	})
+
+	t.Run("should blend between colours", func(t *testing.T) {
+		interval := schedule.Interval{
+			Start: schedule.IntervalStep{Time: start, Brightness: 100, Colour: &red},
+			End:   schedule.IntervalStep{Time: end, Brightness: 100, Colour: &blue},
+		}
+
+		atStart := interval.CalculateTargetLightState(start)
+		halfWay := interval.CalculateTargetLightState(start.Add(3 * time.Hour))
+
+		assert.InDelta(t, red.X, atStart.Colour.X, 0.0001)
+		assert.InDelta(t, red.Y, atStart.Colour.Y, 0.0001)
+		assert.Equal(t, colour.Interpolate(red, blue, 0.5), *halfWay.Colour)
+		assert.Equal(t, int(1000000/float64(max(2000, halfWay.Colour.Kelvin()))), halfWay.TemperatureMirek, "the mirek should be the nearest temperature to the colour")
+	})
+
+	t.Run("should blend from a colour into a temperature", func(t *testing.T) {
+		interval := schedule.Interval{
+			Start: schedule.IntervalStep{Time: start, Brightness: 100, Colour: &red},
+			End:   schedule.IntervalStep{Time: end, TemperatureKelvin: 2700, Brightness: 100},
+		}
+
+		atEnd := interval.CalculateTargetLightState(end)
+
+		assert.InDelta(t, colour.FromKelvin(2700).X, atEnd.Colour.X, 0.0001)
+		assert.InDelta(t, colour.FromKelvin(2700).Y, atEnd.Colour.Y, 0.0001)
+		assert.InDelta(t, 370, atEnd.TemperatureMirek, 4)
+	})
+
+	t.Run("should hold the colour when the next step is off", func(t *testing.T) {
+		interval := schedule.Interval{
+			Start: schedule.IntervalStep{Time: start, Brightness: 100, Colour: &red},
+			End:   schedule.IntervalStep{Time: end, Off: true},
+		}
+
+		assert.Equal(t, red, *interval.CalculateTargetLightState(start.Add(3 * time.Hour)).Colour)
+	})
+}
+
+func Test_CalculateTargetLightState_Easing(t *testing.T) {
+
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.Local)
+	end := time.Date(2023, 1, 1, 6, 0, 0, 0, time.Local)
+	oneHourIn := start.Add(time.Hour)
+
+	tests := []struct {
+		easing             string
+		expectedBrightness int
+	}{
+		{easing: "", expectedBrightness: 16},
+		{easing: "linear", expectedBrightness: 16},
+		{easing: "easeIn", expectedBrightness: 2},
+		{easing: "easeOut", expectedBrightness: 30},
+		{easing: "easeInOut", expectedBrightness: 5},
+		{easing: "sigmoid", expectedBrightness: 2},
+		{easing: "step", expectedBrightness: 0},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.easing, func(t *testing.T) {
+			interval := schedule.Interval{
+				Start: schedule.IntervalStep{Time: start, TemperatureKelvin: 3000, Brightness: 0, Easing: test.easing},
+				End:   schedule.IntervalStep{Time: end, TemperatureKelvin: 3000, Brightness: 100},
+			}
+			assert.Equal(t, test.expectedBrightness, interval.CalculateTargetLightState(oneHourIn).Brightness)
+			// every easing starts and ends at the steps
+			assert.Equal(t, 0, interval.CalculateTargetLightState(start).Brightness)
+			assert.Equal(t, 100, interval.CalculateTargetLightState(end).Brightness)
+		})
+	}
+
+	t.Run("perceptual brightness: should be halfway in lightness", func(t *testing.T) {
+		interval := schedule.Interval{
+			Start: schedule.IntervalStep{Time: start, TemperatureKelvin: 3000, Brightness: 0, PerceptualBrightness: true},
+			End:   schedule.IntervalStep{Time: end, TemperatureKelvin: 3000, Brightness: 100},
+		}
+		assert.Equal(t, 18, interval.CalculateTargetLightState(start.Add(3*time.Hour)).Brightness)
+		assert.Equal(t, 100, interval.CalculateTargetLightState(end).Brightness)
+	})
+
+	t.Run("mired temperature: should be halfway in mired", func(t *testing.T) {
+		interval := schedule.Interval{
+			Start: schedule.IntervalStep{Time: start, TemperatureKelvin: 2000, Brightness: 100, MiredTemperature: true},
+			End:   schedule.IntervalStep{Time: end, TemperatureKelvin: 6500, Brightness: 100},
+		}
+		assert.InDelta(t, (500+153)/2, interval.CalculateTargetLightState(start.Add(3*time.Hour)).TemperatureMirek, 1)
+	})
+}
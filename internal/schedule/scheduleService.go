@@ -1,14 +1,18 @@
 package schedule
 
 import (
+	"errors"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/charmbracelet/log"
-	"github.com/nathan-osman/go-sunrise"
+	"github.com/samber/lo"
 	"github.com/spf13/viper"
+	"github.com/wheelibin/hugh/internal/colour"
+	"github.com/wheelibin/hugh/internal/constants"
 	"github.com/wheelibin/hugh/internal/models"
 )
 
@@ -16,62 +20,148 @@ type lightRepo interface {
 	UpdateTargetState(scheduleName string, target models.LightState) error
 }
 
+// picks the mode a schedule is in from the events on in a calendar
+type calendar interface {
+	ModeFor(scheduleName string, t time.Time) *models.CalendarMode
+}
+
+// ErrScheduleDisabled is returned for the times a calendar event disables the schedule
+var ErrScheduleDisabled = errors.New("schedule disabled by calendar event")
+
 type ScheduleService struct {
 	logger    *log.Logger
 	lightRepo lightRepo
+	calendar  calendar
 }
 
 func NewScheduleService(logger *log.Logger, lightRepo lightRepo) *ScheduleService {
 	return &ScheduleService{logger: logger, lightRepo: lightRepo}
 }
 
-func (s *ScheduleService) getDayPattern(patternName string) models.DayPattern {
-	var dayPatterns map[string]models.DayPattern
-	if err := viper.UnmarshalKey("dayPatterns", &dayPatterns); err != nil {
-		s.logger.Fatalf("error reading day patterns from config, unable to continue: %v", err)
-	}
-	return dayPatterns[patternName]
+// SetCalendar has the schedules follow the modes of the events in the calendar
+func (s *ScheduleService) SetCalendar(c calendar) {
+	s.calendar = c
 }
 
 func (s *ScheduleService) CalculateSunriseSunset(dayPattern models.DayPattern, baseDate time.Time) (time.Time, time.Time, error) {
-	latLng := strings.Split(viper.GetString("geoLocation"), ",")
-	lat, _ := strconv.ParseFloat(latLng[0], 64)
-	lng, _ := strconv.ParseFloat(latLng[1], 64)
-	sunrise, sunset := sunrise.SunriseSunset(
-		lat, lng,
-		baseDate.Year(), baseDate.Month(), baseDate.Day(),
-	)
+	sun, err := s.CalculateSunTimes(dayPattern, baseDate)
+	return sun.Sunrise, sun.Sunset, err
+}
+
+// CalculateSunTimes works out the times of the sun's events on the date of the lighting day starting at dayStart, at
+// the configured location, each kept within the day pattern's min/max for it
+func (s *ScheduleService) CalculateSunTimes(dayPattern models.DayPattern, dayStart time.Time) (SunTimes, error) {
+	lat, lng := GeoLocation()
+	sun := CalculateSunTimes(lat, lng, dayStart)
+	if sun.Polar != NotPolar {
+		s.logger.Info("The sun doesn't rise and set today", "polar", sun.Polar)
+	}
 	s.logger.Info("Calculated local sunrise and sunset",
-		"sunrise", sunrise.Local().Format("15:04"),
-		"sunset", sunset.Local().Format("15:04"),
+		"sunrise", sun.Sunrise.Local().Format("15:04"),
+		"sunset", sun.Sunset.Local().Format("15:04"),
 	)
 
-	sunriseMin := TimeFromConfigTimeString(dayPattern.SunriseMin, baseDate)
-	sunriseMax := TimeFromConfigTimeString(dayPattern.SunriseMax, baseDate)
-	sunsetMin := TimeFromConfigTimeString(dayPattern.SunsetMin, baseDate)
-	sunsetMax := TimeFromConfigTimeString(dayPattern.SunsetMax, baseDate)
+	return ClampSunTimes(sun, dayPattern, dayStart), nil
+}
+
+// ClampSunTimes keeps each of the sun's events within the day pattern's min/max for it, in the lighting day starting
+// at dayStart
+func ClampSunTimes(sun SunTimes, dayPattern models.DayPattern, dayStart time.Time) SunTimes {
+	sun.Sunrise = clampTime(sun.Sunrise, dayPattern.SunriseMin, dayPattern.SunriseMax, dayStart)
+	sun.Sunset = clampTime(sun.Sunset, dayPattern.SunsetMin, dayPattern.SunsetMax, dayStart)
+	sun.CivilDawn = clampTime(sun.CivilDawn, dayPattern.CivilDawnMin, dayPattern.CivilDawnMax, dayStart)
+	sun.CivilDusk = clampTime(sun.CivilDusk, dayPattern.CivilDuskMin, dayPattern.CivilDuskMax, dayStart)
+	sun.NauticalDawn = clampTime(sun.NauticalDawn, dayPattern.NauticalDawnMin, dayPattern.NauticalDawnMax, dayStart)
+	sun.NauticalDusk = clampTime(sun.NauticalDusk, dayPattern.NauticalDuskMin, dayPattern.NauticalDuskMax, dayStart)
+	sun.SolarNoon = clampTime(sun.SolarNoon, dayPattern.SolarNoonMin, dayPattern.SolarNoonMax, dayStart)
+	sun.GoldenHour = clampTime(sun.GoldenHour, dayPattern.GoldenHourMin, dayPattern.GoldenHourMax, dayStart)
+	return sun
+}
+
+// GeoLocation returns the configured latitude and longitude
+func GeoLocation() (float64, float64) {
+	latLng := strings.Split(viper.GetString("geoLocation"), ",")
+	lat, _ := strconv.ParseFloat(latLng[0], 64)
+	lng, _ := strconv.ParseFloat(latLng[1], 64)
+	return lat, lng
+}
 
-	if sunrise.Before(sunriseMin) {
-		sunrise = sunriseMin
+// keeps t within the min and max times (e.g. "06:30") of the lighting day starting at dayStart, either can be left empty
+func clampTime(t time.Time, minTime string, maxTime string, dayStart time.Time) time.Time {
+	if minTime != "" {
+		if m := ClockTimeInDay(minTime, dayStart); t.Before(m) {
+			t = m
+		}
 	}
-	if sunrise.After(sunriseMax) {
-		sunrise = sunriseMax
+	if maxTime != "" {
+		if m := ClockTimeInDay(maxTime, dayStart); t.After(m) {
+			t = m
+		}
 	}
-	if sunset.Before(sunsetMin) {
-		sunset = sunsetMin
+	return t
+}
+
+// DayPatternName returns the name of the day pattern the schedule uses on the (local) date of t: the pattern for the
+// date, else for its weekday (ignoring case, the lower case key first if there are several), else the default
+func DayPatternName(selector models.DayPatternSelector, t time.Time) string {
+	t = t.Local()
+	if name, found := selector[t.Format("2006-01-02")]; found {
+		return name
 	}
-	if sunset.After(sunsetMax) {
-		sunset = sunsetMax
+	weekday := strings.ToLower(t.Weekday().String()[:3])
+	if name, found := selector[weekday]; found {
+		return name
+	}
+	keys := lo.Keys(selector)
+	sort.Strings(keys)
+	for _, key := range keys {
+		if strings.ToLower(key) == weekday {
+			return selector[key]
+		}
 	}
-	return sunrise, sunset, nil
+	return selector[models.DayPatternDefault]
+}
 
+// DayPatternNameFor returns the name of the day pattern the schedule uses at t (that of the lighting day t is in), that
+// of the calendar event on at t if it changes it, ErrScheduleDisabled if the event disables the schedule
+func (s *ScheduleService) DayPatternNameFor(sch models.Schedule, t time.Time) (string, error) {
+	patternName := DayPatternName(sch.DayPattern, LightingDay(t, DayBoundary()))
+	if s.calendar != nil {
+		if mode := s.calendar.ModeFor(sch.Name, t); mode != nil {
+			if mode.Disable {
+				return "", fmt.Errorf("%s: %w (%s)", sch.Name, ErrScheduleDisabled, mode.Match)
+			}
+			if mode.DayPattern != "" {
+				patternName = mode.DayPattern
+			}
+		}
+	}
+	return patternName, nil
 }
 
 func (s *ScheduleService) GetScheduleIntervalForTime(sch models.Schedule, t time.Time) (Interval, error) {
 
-	schPattern := s.getDayPattern(sch.DayPattern)
+	// the pattern times are local, so the date they are on is too, the day starting at the day boundary
+	t = t.Local()
+	dayStart := LightingDay(t, DayBoundary())
+	patternName, err := s.DayPatternNameFor(sch, t)
+	if err != nil {
+		return Interval{}, err
+	}
+	schPattern, err := s.DayPattern(patternName)
+	if err != nil {
+		return Interval{}, err
+	}
+
+	if schPattern.Type == "solar" {
+		return s.solarInterval(sch, patternName, schPattern, t)
+	}
+
+	if len(schPattern.Pattern) == 0 {
+		return Interval{}, fmt.Errorf("day pattern %s has no steps", patternName)
+	}
 
-	// insert midnight->firstStep
+	// insert start of day->firstStep
 	if schPattern.Pattern[0].Time != "startofday" {
 		schPattern.Pattern = append([]models.ScheduleDayPatternStep{
 			{
@@ -93,12 +183,9 @@ func (s *ScheduleService) GetScheduleIntervalForTime(sch models.Schedule, t time
 		})
 	}
 
-	var (
-		sunrise, sunset time.Time
-		err             error
-	)
+	var sun SunTimes
 	if schPattern.Type == "dynamic" {
-		sunrise, sunset, err = s.CalculateSunriseSunset(schPattern, t)
+		sun, err = s.CalculateSunTimes(schPattern, dayStart)
 		if err != nil {
 			s.logger.Fatal("error calculating sunrise and sunset", err.Error())
 		}
@@ -112,20 +199,40 @@ func (s *ScheduleService) GetScheduleIntervalForTime(sch models.Schedule, t time
 		}
 
 		startStep := patternStep
-		startTime := TimeFromPattern(startStep.Time, sunrise, sunset, t)
+		startTime := TimeFromPattern(startStep.Time, sun, dayStart)
 
 		endStep := schPattern.Pattern[i+1]
-		endTime := TimeFromPattern(endStep.Time, sunrise, sunset, t)
+		endTime := TimeFromPattern(endStep.Time, sun, dayStart)
 
-		if t.Compare(startTime) > -1 && t.Before(endTime) {
+		// the last interval runs up to the day boundary, when the next day's pattern takes over
+		until := endTime
+		if i == len(schPattern.Pattern)-2 {
+			until = nextLightingDay(dayStart)
+		}
+
+		if t.Compare(startTime) > -1 && t.Before(until) {
 			// we are in this day pattern interval
+			startColour, err := stepColour(startStep)
+			if err != nil {
+				return Interval{}, fmt.Errorf("day pattern %s, step %s: %w", patternName, startStep.Time, err)
+			}
+			endColour, err := stepColour(endStep)
+			if err != nil {
+				return Interval{}, fmt.Errorf("day pattern %s, step %s: %w", patternName, endStep.Time, err)
+			}
+
 			currentInterval := Interval{
 				Start: IntervalStep{
-					Time:              startTime,
-					Brightness:        startStep.Brightness,
-					TemperatureKelvin: startStep.Temperature,
-					TransitionAt:      startStep.TransitionAt,
-					Off:               startStep.Off,
+					Time:                 startTime,
+					Brightness:           startStep.Brightness,
+					TemperatureKelvin:    startStep.Temperature,
+					TransitionAt:         startStep.TransitionAt,
+					Off:                  startStep.Off,
+					Transition:           startStep.Transition,
+					Colour:               startColour,
+					Easing:               startStep.Easing,
+					PerceptualBrightness: startStep.PerceptualBrightness,
+					MiredTemperature:     startStep.MiredTemperature,
 				},
 				End: IntervalStep{
 					Time:              endTime,
@@ -133,6 +240,8 @@ func (s *ScheduleService) GetScheduleIntervalForTime(sch models.Schedule, t time
 					TemperatureKelvin: endStep.Temperature,
 					TransitionAt:      startStep.TransitionAt,
 					Off:               endStep.Off,
+					Transition:        endStep.Transition,
+					Colour:            endColour,
 				},
 			}
 			s.logger.Info("The currently active pattern interval is", "from", currentInterval.Start, "to", currentInterval.End)
@@ -148,31 +257,132 @@ func (s *ScheduleService) GetScheduleIntervalForTime(sch models.Schedule, t time
 	return Interval{}, fmt.Errorf("No interval found")
 }
 
-func TimeFromPattern(patternTime string, sunrise time.Time, sunset time.Time, baseDate time.Time) time.Time {
+// reads the colour of a step, nil if the step uses a colour temperature
+func stepColour(step models.ScheduleDayPatternStep) (*colour.XY, error) {
+	if len(step.XY) > 0 {
+		if step.Colour != "" {
+			return nil, fmt.Errorf("only one of xy or colour can be set")
+		}
+		if len(step.XY) != 2 || step.XY[1] <= 0 {
+			return nil, fmt.Errorf("invalid xy %v, expected [x, y]", step.XY)
+		}
+		return &colour.XY{X: step.XY[0], Y: step.XY[1]}, nil
+	}
 
-	// sunrise or sunrise offset
-	if strings.Contains(patternTime, "sunrise") {
-		return timeFromAstronomicalPatternTime(patternTime, "sunrise", sunrise)
+	if step.Colour != "" {
+		xy, err := colour.Parse(step.Colour)
+		if err != nil {
+			return nil, err
+		}
+		return &xy, nil
 	}
 
-	// sunset or sunset offset
-	if strings.Contains(patternTime, "sunset") {
-		return timeFromAstronomicalPatternTime(patternTime, "sunset", sunset)
+	return nil, nil
+}
+
+// TimeFromPattern returns the time of the pattern step time in the lighting day starting at dayStart: a time of day
+// earlier than the start is on the next date, so a pattern can run past midnight
+func TimeFromPattern(patternTime string, sun SunTimes, dayStart time.Time) time.Time {
+
+	// a sun event (e.g. sunrise, civilDusk), or an offset from one
+	for _, anchor := range sun.Anchors() {
+		if strings.HasPrefix(strings.ToLower(patternTime), strings.ToLower(anchor.Name)) {
+			return timeFromAstronomicalPatternTime(patternTime, anchor.Name, anchor.Time)
+		}
 	}
 
 	// start of day
 	if strings.Contains(patternTime, "startofday") {
-		return time.Date(baseDate.Year(), baseDate.Month(), baseDate.Day(), 0, 0, 0, 0, time.Local)
+		return dayStart
 	}
 
 	// end of day
 	if strings.Contains(patternTime, "endofday") {
-		return time.Date(baseDate.Year(), baseDate.Month(), baseDate.Day(), 23, 59, 59, 999999, time.Local)
+		end := nextLightingDay(dayStart)
+		return time.Date(end.Year(), end.Month(), end.Day(), end.Hour(), end.Minute()-1, 59, 999999, time.Local)
 	}
 
 	// time e.g 19:30
-	return TimeFromConfigTimeString(patternTime, baseDate)
+	return ClockTimeInDay(patternTime, dayStart)
+
+}
+
+// DayBoundary returns the configured time of day (e.g. "04:00") the lighting day starts at, midnight if it isn't set
+func DayBoundary() string {
+	if boundary := viper.GetString("dayBoundary"); boundary != "" {
+		return boundary
+	}
+	return constants.DefaultDayBoundary
+}
+
+// LightingDay returns the start of the lighting day t is in: the boundary on t's (local) date, or on the date before
+// if t is earlier in the day than the boundary
+func LightingDay(t time.Time, boundary string) time.Time {
+	t = t.Local()
+	start := TimeFromConfigTimeString(boundary, t)
+	if t.Before(start) {
+		start = TimeFromConfigTimeString(boundary, time.Date(t.Year(), t.Month(), t.Day()-1, 0, 0, 0, 0, time.Local))
+	}
+	return start
+}
 
+// the start of the lighting day after the one starting at dayStart
+func nextLightingDay(dayStart time.Time) time.Time {
+	return time.Date(dayStart.Year(), dayStart.Month(), dayStart.Day()+1, dayStart.Hour(), dayStart.Minute(), 0, 0, time.Local)
+}
+
+// ClockTimeInDay returns the time of day (e.g. "01:30") in the lighting day starting at dayStart, on the next date if
+// it is earlier in the day than the start
+func ClockTimeInDay(timeString string, dayStart time.Time) time.Time {
+	t := TimeFromConfigTimeString(timeString, dayStart)
+	if t.Before(dayStart) {
+		t = TimeFromConfigTimeString(timeString, time.Date(dayStart.Year(), dayStart.Month(), dayStart.Day()+1, 0, 0, 0, 0, time.Local))
+	}
+	return t
+}
+
+// ParsePatternTime checks a pattern step's time, returning whether it is (or is an offset from) one of the sun's events
+func ParsePatternTime(patternTime string) (bool, error) {
+	switch strings.ToLower(patternTime) {
+	case "startofday", "endofday":
+		return false, nil
+	}
+
+	for _, anchor := range (SunTimes{}).Anchors() {
+		if !strings.HasPrefix(strings.ToLower(patternTime), strings.ToLower(anchor.Name)) {
+			continue
+		}
+		offset := patternTime[len(anchor.Name):]
+		if offset == "" {
+			return true, nil
+		}
+		if !strings.HasPrefix(offset, "+") && !strings.HasPrefix(offset, "-") {
+			return true, fmt.Errorf("invalid time %q, expected an offset like %s+1h or %s-30m", patternTime, anchor.Name, anchor.Name)
+		}
+		if _, err := time.ParseDuration(offset); err != nil {
+			return true, fmt.Errorf("invalid offset %q in %q, expected a duration like +1h30m", offset, patternTime)
+		}
+		return true, nil
+	}
+
+	return false, ParseClockTime(patternTime)
+}
+
+// ParseClockTime checks a time of day, e.g. "06:30"
+func ParseClockTime(timeString string) error {
+	hour, mins, found := strings.Cut(timeString, ":")
+	if !found {
+		return fmt.Errorf("invalid time %q, expected HH:MM or one of the sun's events", timeString)
+	}
+	h, err := strconv.Atoi(hour)
+	if err != nil || h < 0 || h > 23 {
+		return fmt.Errorf("invalid hour in %q", timeString)
+	}
+	m, err := strconv.Atoi(mins)
+	if err != nil || m < 0 || m > 59 || len(mins) != 2 {
+		return fmt.Errorf("invalid minutes in %q", timeString)
+	}
+	return nil
 }
 
 // returns a Time object built from the supplied time string (e.g. "06:30") and a base date
@@ -187,7 +397,7 @@ func TimeFromConfigTimeString(timeString string, baseDate time.Time) time.Time {
 // returns an adjusted eventTime e.g ("sunset-1h", "sunset", 2023-06-27 21:43:18) -> 2023-06-27 20:43:18
 func timeFromAstronomicalPatternTime(patternTime string, event string, eventTime time.Time) time.Time {
 	var result time.Time
-	if patternTime == event {
+	if len(patternTime) == len(event) {
 		result = eventTime
 	} else {
 		offset, _ := time.ParseDuration(patternTime[len(event):])
@@ -2,14 +2,18 @@ package schedule_test
 
 import (
 	"encoding/json"
+	"math"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/charmbracelet/log"
+	"github.com/nathan-osman/go-sunrise"
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 
+	"github.com/wheelibin/hugh/internal/colour"
 	"github.com/wheelibin/hugh/internal/models"
 	"github.com/wheelibin/hugh/internal/schedule"
 	"github.com/wheelibin/hugh/mocks"
@@ -238,3 +242,490 @@ func Test_ScheduleService_DynamicSchedule_GetScheduleIntervalForTime(t *testing.
 	}
 
 }
+
+func Test_ScheduleService_GetScheduleIntervalForTime_Colour(t *testing.T) {
+
+	testDayPattern := []byte(`
+    {
+      "default": {
+        "time": "00:00",
+        "temperature": 2000,
+        "brightness": 20
+      },
+      "pattern": [
+        { "time": "08:00", "xy": [0.3, 0.6], "brightness": 50 },
+        { "time": "12:00", "colour": "#0000ff", "brightness": 50 },
+        { "time": "16:00", "colour": "sunset", "brightness": 50 },
+        { "time": "20:00", "temperature": 2700, "brightness": 50 }
+      ]
+    }`)
+
+	var dp models.DayPattern
+	_ = json.Unmarshal(testDayPattern, &dp)
+	viper.Set("dayPatterns", map[string]models.DayPattern{"colourPattern": dp})
+
+	srv := schedule.NewScheduleService(log.NewWithOptions(os.Stderr, log.Options{Level: log.FatalLevel}), mocks.NewMockScheduleLightRepo(t))
+	sch := models.Schedule{DayPattern: models.DayPatternSelector{"default": "colourPattern"}}
+
+	interval, err := srv.GetScheduleIntervalForTime(sch, time.Date(2023, 1, 1, 9, 0, 0, 0, time.Local))
+	assert.NoError(t, err)
+	assert.Equal(t, &colour.XY{X: 0.3, Y: 0.6}, interval.Start.Colour)
+	assert.Equal(t, &colour.XY{X: 0.1500, Y: 0.0600}, roundXY(interval.End.Colour))
+
+	interval, err = srv.GetScheduleIntervalForTime(sch, time.Date(2023, 1, 1, 7, 0, 0, 0, time.Local))
+	assert.NoError(t, err)
+	assert.Nil(t, interval.Start.Colour, "the default step has no colour")
+
+	_, err = srv.GetScheduleIntervalForTime(sch, time.Date(2023, 1, 1, 13, 0, 0, 0, time.Local))
+	assert.ErrorContains(t, err, "sunset", "an invalid colour should be reported")
+}
+
+func roundXY(xy *colour.XY) *colour.XY {
+	return &colour.XY{X: math.Round(xy.X*10000) / 10000, Y: math.Round(xy.Y*10000) / 10000}
+}
+
+func Test_DayPatternName(t *testing.T) {
+	var selector models.DayPatternSelector
+	err := json.Unmarshal([]byte(`{"default": "circadian", "sat": "weekend", "Sun": "weekend", "2026-12-25": "holiday"}`), &selector)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		timestamp time.Time
+		expected  string
+	}{
+		{name: "a weekday: should use the default", timestamp: time.Date(2026, 12, 18, 12, 0, 0, 0, time.Local), expected: "circadian"},
+		{name: "a weekend day: should use the weekday's pattern", timestamp: time.Date(2026, 12, 19, 12, 0, 0, 0, time.Local), expected: "weekend"},
+		{name: "weekday keys: should ignore case", timestamp: time.Date(2026, 12, 20, 12, 0, 0, 0, time.Local), expected: "weekend"},
+		{name: "a date (a friday): should take precedence over its weekday", timestamp: time.Date(2026, 12, 25, 12, 0, 0, 0, time.Local), expected: "holiday"},
+		{name: "the last moment of friday: should be friday's pattern", timestamp: time.Date(2026, 12, 18, 23, 59, 59, 999999999, time.Local), expected: "circadian"},
+		{name: "midnight: should be the new day's pattern", timestamp: time.Date(2026, 12, 19, 0, 0, 0, 0, time.Local), expected: "weekend"},
+	}
+
+	for _, c := range tests {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, c.expected, schedule.DayPatternName(selector, c.timestamp))
+		})
+	}
+
+	t.Run("a weekday given in two cases: should always use the lower case key", func(t *testing.T) {
+		var both models.DayPatternSelector
+		err := json.Unmarshal([]byte(`{"default": "circadian", "Sat": "lie-in", "sat": "weekend", "SAT": "party"}`), &both)
+		assert.NoError(t, err)
+		for i := 0; i < 20; i++ {
+			assert.Equal(t, "weekend", schedule.DayPatternName(both, time.Date(2026, 12, 19, 12, 0, 0, 0, time.Local)))
+		}
+	})
+
+	t.Run("a single pattern name: should be used every day", func(t *testing.T) {
+		var single models.DayPatternSelector
+		err := json.Unmarshal([]byte(`"circadian"`), &single)
+		assert.NoError(t, err)
+		assert.Equal(t, "circadian", schedule.DayPatternName(single, time.Date(2026, 12, 19, 12, 0, 0, 0, time.Local)))
+	})
+}
+
+func Test_ScheduleService_GetScheduleIntervalForTime_DayPatternSelection(t *testing.T) {
+
+	weekday := models.DayPattern{Pattern: []models.ScheduleDayPatternStep{{Time: "07:00", Temperature: 4000, Brightness: 100}, {Time: "23:00", Temperature: 2000, Brightness: 10}}}
+	weekday.Default.Temperature = 2000
+	weekday.Default.Brightness = 10
+	weekend := models.DayPattern{Pattern: []models.ScheduleDayPatternStep{{Time: "09:00", Temperature: 4000, Brightness: 100}}}
+	weekend.Default.Temperature = 2200
+	weekend.Default.Brightness = 30
+	viper.Set("dayPatterns", map[string]models.DayPattern{"weekday": weekday, "weekend": weekend})
+
+	srv := schedule.NewScheduleService(log.NewWithOptions(os.Stderr, log.Options{Level: log.FatalLevel}), mocks.NewMockScheduleLightRepo(t))
+	sch := models.Schedule{DayPattern: models.DayPatternSelector{"default": "weekday", "sat": "weekend", "sun": "weekend"}}
+
+	// friday evening, right up to midnight
+	interval, err := srv.GetScheduleIntervalForTime(sch, time.Date(2026, 12, 18, 23, 59, 59, 999999999, time.Local))
+	assert.NoError(t, err)
+	assert.Equal(t, "2026-12-18 23:00", interval.Start.Time.Format(dateTimeFormat))
+	assert.Equal(t, 10, interval.Start.Brightness)
+
+	// saturday starts with the weekend pattern
+	interval, err = srv.GetScheduleIntervalForTime(sch, time.Date(2026, 12, 19, 0, 0, 0, 0, time.Local))
+	assert.NoError(t, err)
+	assert.Equal(t, "2026-12-19 00:00", interval.Start.Time.Format(dateTimeFormat))
+	assert.Equal(t, 30, interval.Start.Brightness)
+	assert.Equal(t, "2026-12-19 09:00", interval.End.Time.Format(dateTimeFormat))
+
+	// the date is that of the local time, whatever the time zone of the timestamp
+	interval, err = srv.GetScheduleIntervalForTime(sch, time.Date(2026, 12, 19, 8, 0, 0, 0, time.Local).UTC())
+	assert.NoError(t, err)
+	assert.Equal(t, 30, interval.Start.Brightness)
+}
+
+func Test_ScheduleService_GetScheduleIntervalForTime_Calendar(t *testing.T) {
+
+	normal := models.DayPattern{Pattern: []models.ScheduleDayPatternStep{{Time: "07:00", Temperature: 4000, Brightness: 100}}}
+	normal.Default.Temperature = 2000
+	normal.Default.Brightness = 10
+	party := models.DayPattern{Pattern: []models.ScheduleDayPatternStep{{Time: "07:00", Temperature: 2500, Brightness: 60}}}
+	party.Default.Temperature = 2500
+	party.Default.Brightness = 60
+	viper.Set("dayPatterns", map[string]models.DayPattern{"normal": normal, "party": party})
+
+	sch := models.Schedule{Name: "Kitchen", DayPattern: models.DayPatternSelector{"default": "normal"}}
+	at := time.Date(2026, 12, 18, 12, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		name               string
+		mode               *models.CalendarMode
+		expectedBrightness int
+		expectedErr        error
+	}{
+		{name: "no event on: should use the schedule's pattern", mode: nil, expectedBrightness: 100},
+		{name: "event with a day pattern: should use the event's pattern", mode: &models.CalendarMode{Match: "party", DayPattern: "party"}, expectedBrightness: 60},
+		{name: "event disabling the schedule: should return ErrScheduleDisabled", mode: &models.CalendarMode{Match: "holiday", Disable: true}, expectedErr: schedule.ErrScheduleDisabled},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mockCalendar := mocks.NewMockScheduleCalendar(t)
+			mockCalendar.On("ModeFor", "Kitchen", at).Return(test.mode)
+
+			srv := schedule.NewScheduleService(log.NewWithOptions(os.Stderr, log.Options{Level: log.FatalLevel}), mocks.NewMockScheduleLightRepo(t))
+			srv.SetCalendar(mockCalendar)
+
+			interval, err := srv.GetScheduleIntervalForTime(sch, at)
+			if test.expectedErr != nil {
+				assert.ErrorIs(t, err, test.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedBrightness, interval.Start.Brightness)
+		})
+	}
+}
+
+func Test_CalculateSunTimes(t *testing.T) {
+
+	t.Run("sun rises and sets: should order the events through the day", func(t *testing.T) {
+		sun := schedule.CalculateSunTimes(51.5, -0.1, time.Date(2026, 3, 20, 0, 0, 0, 0, time.Local))
+
+		assert.Equal(t, schedule.NotPolar, sun.Polar)
+		ordered := []time.Time{sun.NauticalDawn, sun.CivilDawn, sun.Sunrise, sun.SolarNoon, sun.GoldenHour, sun.Sunset, sun.CivilDusk, sun.NauticalDusk}
+		for i := 1; i < len(ordered); i++ {
+			assert.True(t, ordered[i-1].Before(ordered[i]), "event %d should be before event %d", i-1, i)
+		}
+		// about half an hour between sunrise and civil dawn at this latitude in march
+		assert.InDelta(t, 33, sun.Sunrise.Sub(sun.CivilDawn).Minutes(), 5)
+	})
+
+	t.Run("midsummer in the arctic: should report polar day", func(t *testing.T) {
+		date := time.Date(2026, 6, 21, 0, 0, 0, 0, time.Local)
+		sun := schedule.CalculateSunTimes(78.2, 15.6, date)
+
+		assert.Equal(t, schedule.PolarDay, sun.Polar)
+		assert.Equal(t, time.Date(2026, 6, 21, 0, 0, 0, 0, time.Local), sun.Sunrise)
+		assert.Equal(t, "2026-06-21 23:59", sun.Sunset.Format(dateTimeFormat))
+	})
+
+	t.Run("midwinter in the arctic: should report polar night", func(t *testing.T) {
+		sun := schedule.CalculateSunTimes(78.2, 15.6, time.Date(2026, 12, 21, 0, 0, 0, 0, time.Local))
+
+		assert.Equal(t, schedule.PolarNight, sun.Polar)
+		assert.False(t, sun.Sunrise.IsZero())
+		assert.Equal(t, sun.SolarNoon, sun.Sunrise)
+		assert.Equal(t, sun.SolarNoon, sun.Sunset)
+	})
+}
+
+func Test_TimeFromPattern_SunAnchors(t *testing.T) {
+
+	baseDate := time.Date(2026, 3, 20, 0, 0, 0, 0, time.Local)
+	at := func(hour int, min int) time.Time { return time.Date(2026, 3, 20, hour, min, 0, 0, time.Local) }
+	sun := schedule.SunTimes{
+		Sunrise: at(6, 0), Sunset: at(18, 0),
+		CivilDawn: at(5, 30), CivilDusk: at(18, 30),
+		NauticalDawn: at(5, 0), NauticalDusk: at(19, 0),
+		SolarNoon: at(12, 10), GoldenHour: at(17, 0),
+	}
+
+	tests := []struct {
+		patternTime string
+		expected    time.Time
+	}{
+		{patternTime: "sunrise", expected: at(6, 0)},
+		{patternTime: "sunset-1h", expected: at(17, 0)},
+		{patternTime: "civilDawn", expected: at(5, 30)},
+		{patternTime: "civilDusk+30m", expected: at(19, 0)},
+		{patternTime: "nauticalDawn-15m", expected: at(4, 45)},
+		{patternTime: "nauticalDusk", expected: at(19, 0)},
+		{patternTime: "solarNoon+1h30m", expected: at(13, 40)},
+		{patternTime: "goldenHour", expected: at(17, 0)},
+		{patternTime: "21:15", expected: at(21, 15)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.patternTime, func(t *testing.T) {
+			assert.Equal(t, test.expected, schedule.TimeFromPattern(test.patternTime, sun, baseDate))
+		})
+	}
+}
+
+func Test_CalculateSunTimes_Clamps(t *testing.T) {
+
+	viper.Set("geoLocation", "51.5,-0.1")
+	srv := schedule.NewScheduleService(log.NewWithOptions(os.Stderr, log.Options{Level: log.FatalLevel}), mocks.NewMockScheduleLightRepo(t))
+	baseDate := time.Date(2026, 3, 20, 0, 0, 0, 0, time.Local)
+	unclamped := schedule.CalculateSunTimes(51.5, -0.1, baseDate)
+
+	sun, err := srv.CalculateSunTimes(models.DayPattern{CivilDuskMax: "05:00", SolarNoonMax: "00:30", GoldenHourMin: "00:00"}, baseDate)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "05:00", sun.CivilDusk.Format(timeFormat))
+	assert.Equal(t, "00:30", sun.SolarNoon.Format(timeFormat))
+	// within its min and no max
+	assert.Equal(t, unclamped.GoldenHour, sun.GoldenHour)
+	// no clamps
+	assert.Equal(t, unclamped.NauticalDawn, sun.NauticalDawn)
+}
+
+func Test_ScheduleService_GetScheduleIntervalForTime_Solar(t *testing.T) {
+
+	// on the equator at the equinox the sun rises at about 06:00 utc and is overhead at about midday
+	viper.Set("geoLocation", "0,0")
+	solar := models.DayPattern{Type: "solar", Elevations: []models.SolarPatternPoint{
+		{Elevation: 30, Temperature: 4000, Brightness: 100},
+		{Elevation: -6, Temperature: 2000, Brightness: 10},
+		{Elevation: 0, Temperature: 2500, Brightness: 40},
+	}}
+	viper.Set("dayPatterns", map[string]models.DayPattern{"solar": solar, "empty": {Type: "solar"}})
+
+	srv := schedule.NewScheduleService(log.NewWithOptions(os.Stderr, log.Options{Level: log.FatalLevel}), mocks.NewMockScheduleLightRepo(t))
+	sch := models.Schedule{DayPattern: models.DayPatternSelector{"default": "solar"}, Rooms: []string{"Kitchen"}}
+
+	tests := []struct {
+		name               string
+		t                  time.Time
+		expectedKelvin     int
+		expectedBrightness int
+	}{
+		{name: "night, below the lowest point: should use the lowest point", t: time.Date(2026, 3, 20, 0, 0, 0, 0, time.UTC), expectedKelvin: 2000, expectedBrightness: 10},
+		{name: "midday, above the highest point: should use the highest point", t: time.Date(2026, 3, 20, 12, 0, 0, 0, time.UTC), expectedKelvin: 4000, expectedBrightness: 100},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			interval, err := srv.GetScheduleIntervalForTime(sch, test.t)
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedKelvin, interval.Start.TemperatureKelvin)
+			assert.Equal(t, test.expectedBrightness, interval.Start.Brightness)
+			assert.Equal(t, []string{"Kitchen"}, interval.Rooms)
+		})
+	}
+
+	t.Run("sun between points: should interpolate, following it until the next update", func(t *testing.T) {
+		at := time.Date(2026, 3, 20, 6, 30, 0, 0, time.UTC)
+		elevation := sunrise.Elevation(0, 0, at)
+		assert.True(t, elevation > 0 && elevation < 30)
+
+		interval, err := srv.GetScheduleIntervalForTime(sch, at)
+
+		assert.NoError(t, err)
+		assert.InDelta(t, 2500+1500*elevation/30, interval.Start.TemperatureKelvin, 1)
+		assert.InDelta(t, 40+60*elevation/30, interval.Start.Brightness, 1)
+		// the sun is rising
+		assert.True(t, at.Add(time.Minute).Equal(interval.End.Time))
+		assert.Greater(t, interval.End.TemperatureKelvin, interval.Start.TemperatureKelvin)
+		state := interval.CalculateTargetLightState(at)
+		assert.Equal(t, interval.Start.Brightness, state.Brightness)
+	})
+
+	t.Run("no elevations: should error", func(t *testing.T) {
+		_, err := srv.GetScheduleIntervalForTime(models.Schedule{DayPattern: models.DayPatternSelector{"default": "empty"}}, time.Now())
+		assert.Error(t, err)
+	})
+}
+
+func Test_ScheduleService_GetScheduleIntervalForTime_Extends(t *testing.T) {
+
+	viper.Reset()
+	viper.SetConfigType("yaml")
+	err := viper.ReadConfig(strings.NewReader(`
+patternFragments:
+  evening:
+    - time: "21:00"
+      temperature: 2200
+      brightness: 30
+    - include: lightsOut
+  lightsOut:
+    - time: "22:00"
+      off: true
+  loop:
+    - include: loop
+dayPatterns:
+  - base:
+      default:
+        temperature: 2000
+        brightness: 20
+      pattern:
+        - time: "07:00"
+          temperature: 4000
+          brightness: 100
+        - time: "18:00"
+          temperature: 3000
+          brightness: 80
+        - time: "23:30"
+          off: true
+  - child:
+      extends: base
+      default:
+        brightness: 0
+      pattern:
+        - time: "18:00"
+          temperature: 2700
+          brightness: 60
+        - include: evening
+        - time: "23:30"
+          remove: true
+  - grandchild:
+      extends: child
+      pattern:
+        - time: "07:00"
+          temperature: 5000
+          brightness: 100
+        - time: "08:00"
+          temperature: 4000
+          brightness: 90
+  - a:
+      extends: b
+  - b:
+      extends: a
+  - orphan:
+      extends: missing
+  - looping:
+      pattern:
+        - include: loop
+`))
+	assert.NoError(t, err)
+
+	srv := schedule.NewScheduleService(log.NewWithOptions(os.Stderr, log.Options{Level: log.FatalLevel}), mocks.NewMockScheduleLightRepo(t))
+	at := func(pattern string, hour int, min int) (schedule.Interval, error) {
+		sch := models.Schedule{DayPattern: models.DayPatternSelector{"default": pattern}}
+		return srv.GetScheduleIntervalForTime(sch, time.Date(2026, 12, 18, hour, min, 0, 0, time.Local))
+	}
+
+	t.Run("should inherit the fields the pattern doesn't set, including zero values it does", func(t *testing.T) {
+		interval, err := at("child", 3, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, 2000, interval.Start.TemperatureKelvin)
+		assert.Equal(t, 0, interval.Start.Brightness)
+	})
+
+	t.Run("should replace steps by time and include fragments after them", func(t *testing.T) {
+		interval, err := at("child", 19, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, 2700, interval.Start.TemperatureKelvin)
+		assert.Equal(t, 60, interval.Start.Brightness)
+		assert.Equal(t, "2026-12-18 21:00", interval.End.Time.Format(dateTimeFormat))
+	})
+
+	t.Run("should remove steps", func(t *testing.T) {
+		interval, err := at("child", 23, 45)
+		assert.NoError(t, err)
+		assert.True(t, interval.Start.Off)
+		assert.Equal(t, "2026-12-18 22:00", interval.Start.Time.Format(dateTimeFormat))
+	})
+
+	t.Run("should resolve the whole chain", func(t *testing.T) {
+		interval, err := at("grandchild", 7, 30)
+		assert.NoError(t, err)
+		assert.Equal(t, 5000, interval.Start.TemperatureKelvin)
+		assert.Equal(t, "2026-12-18 08:00", interval.End.Time.Format(dateTimeFormat))
+
+		interval, err = at("grandchild", 3, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, interval.Start.Brightness)
+	})
+
+	errorTests := []struct {
+		pattern  string
+		expected string
+	}{
+		{pattern: "a", expected: "day pattern a: extends itself (a -> b -> a)"},
+		{pattern: "orphan", expected: "day pattern orphan: extends unknown day pattern missing"},
+		{pattern: "unknown", expected: "unknown day pattern unknown"},
+		{pattern: "looping", expected: "day pattern looping: pattern fragment loop includes itself (loop -> loop)"},
+	}
+	for _, test := range errorTests {
+		t.Run("should report "+test.expected, func(t *testing.T) {
+			_, err := at(test.pattern, 12, 0)
+			assert.EqualError(t, err, test.expected)
+		})
+	}
+}
+
+func Test_LightingDay(t *testing.T) {
+	tests := []struct {
+		name      string
+		timestamp time.Time
+		boundary  string
+		expected  string
+	}{
+		{name: "midnight boundary: should be the date", timestamp: time.Date(2026, 12, 19, 0, 30, 0, 0, time.Local), boundary: "00:00", expected: "2026-12-19 00:00"},
+		{name: "after the boundary: should start on the date", timestamp: time.Date(2026, 12, 19, 4, 0, 0, 0, time.Local), boundary: "04:00", expected: "2026-12-19 04:00"},
+		{name: "before the boundary: should start the day before", timestamp: time.Date(2026, 12, 19, 3, 59, 0, 0, time.Local), boundary: "04:00", expected: "2026-12-18 04:00"},
+		{name: "before the boundary on the 1st: should start the month before", timestamp: time.Date(2026, 1, 1, 1, 0, 0, 0, time.Local), boundary: "04:30", expected: "2025-12-31 04:30"},
+	}
+
+	for _, c := range tests {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, c.expected, schedule.LightingDay(c.timestamp, c.boundary).Format(dateTimeFormat))
+		})
+	}
+}
+
+func Test_ScheduleService_GetScheduleIntervalForTime_DayBoundary(t *testing.T) {
+
+	weekday := models.DayPattern{Pattern: []models.ScheduleDayPatternStep{
+		{Time: "07:00", Temperature: 4000, Brightness: 100},
+		{Time: "23:30", Temperature: 2200, Brightness: 40},
+		{Time: "01:00", Off: true},
+	}}
+	weekday.Default.Temperature = 2000
+	weekday.Default.Brightness = 10
+	weekend := models.DayPattern{Pattern: []models.ScheduleDayPatternStep{{Time: "09:00", Temperature: 4000, Brightness: 100}}}
+	weekend.Default.Temperature = 2200
+	weekend.Default.Brightness = 30
+	viper.Set("dayPatterns", map[string]models.DayPattern{"weekday": weekday, "weekend": weekend})
+	viper.Set("dayBoundary", "04:00")
+	t.Cleanup(func() { viper.Set("dayBoundary", "") })
+
+	srv := schedule.NewScheduleService(log.NewWithOptions(os.Stderr, log.Options{Level: log.FatalLevel}), mocks.NewMockScheduleLightRepo(t))
+	sch := models.Schedule{DayPattern: models.DayPatternSelector{"default": "weekday", "sat": "weekend", "sun": "weekend"}}
+
+	t.Run("the evening fade should continue across midnight", func(t *testing.T) {
+		before, err := srv.GetScheduleIntervalForTime(sch, time.Date(2026, 12, 18, 23, 59, 59, 0, time.Local))
+		assert.NoError(t, err)
+		after, err := srv.GetScheduleIntervalForTime(sch, time.Date(2026, 12, 19, 0, 0, 0, 0, time.Local))
+		assert.NoError(t, err)
+
+		assert.Equal(t, before, after)
+		assert.Equal(t, "2026-12-18 23:30", after.Start.Time.Format(dateTimeFormat))
+		assert.Equal(t, "2026-12-19 01:00", after.End.Time.Format(dateTimeFormat))
+		assert.True(t, after.End.Off)
+	})
+
+	t.Run("steps after midnight should belong to the day before", func(t *testing.T) {
+		// early saturday is still friday's (weekday) pattern, off from 01:00 to the boundary
+		interval, err := srv.GetScheduleIntervalForTime(sch, time.Date(2026, 12, 19, 3, 59, 0, 0, time.Local))
+		assert.NoError(t, err)
+		assert.True(t, interval.Start.Off)
+		assert.Equal(t, "2026-12-19 01:00", interval.Start.Time.Format(dateTimeFormat))
+		assert.Equal(t, 10, interval.End.Brightness)
+	})
+
+	t.Run("the next day's pattern should start at the boundary", func(t *testing.T) {
+		interval, err := srv.GetScheduleIntervalForTime(sch, time.Date(2026, 12, 19, 4, 0, 0, 0, time.Local))
+		assert.NoError(t, err)
+		assert.Equal(t, "2026-12-19 04:00", interval.Start.Time.Format(dateTimeFormat))
+		assert.Equal(t, 30, interval.Start.Brightness)
+		assert.Equal(t, "2026-12-19 09:00", interval.End.Time.Format(dateTimeFormat))
+	})
+}
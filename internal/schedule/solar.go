@@ -0,0 +1,59 @@
+package schedule
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/nathan-osman/go-sunrise"
+	"github.com/wheelibin/hugh/internal/constants"
+	"github.com/wheelibin/hugh/internal/models"
+)
+
+// the interval of a solar day pattern from t until the next update, the lights following the sun's elevation
+// through the pattern's points
+func (s *ScheduleService) solarInterval(sch models.Schedule, patternName string, schPattern models.DayPattern, t time.Time) (Interval, error) {
+	if len(schPattern.Elevations) == 0 {
+		return Interval{}, fmt.Errorf("day pattern %s: a solar day pattern needs elevations", patternName)
+	}
+
+	lat, lng := GeoLocation()
+	step := func(at time.Time) IntervalStep {
+		temperature, brightness := solarPointAt(schPattern.Elevations, sunrise.Elevation(lat, lng, at))
+		return IntervalStep{Time: at, TemperatureKelvin: temperature, Brightness: brightness}
+	}
+
+	interval := Interval{
+		Start: step(t),
+		End:   step(t.Add(constants.MainUpdateInterval)),
+		Rooms: sch.Rooms,
+		Zones: sch.Zones,
+	}
+	s.logger.Debug("The sun's elevation sets the target", "elevation", sunrise.Elevation(lat, lng, t), "from", interval.Start, "to", interval.End)
+	return interval, nil
+}
+
+// the temperature and brightness at the elevation, interpolated between the points either side of it (those of the
+// lowest/highest point below/above them)
+func solarPointAt(points []models.SolarPatternPoint, elevation float64) (int, int) {
+	sorted := make([]models.SolarPatternPoint, len(points))
+	copy(sorted, points)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Elevation < sorted[j].Elevation })
+
+	if elevation <= sorted[0].Elevation {
+		return sorted[0].Temperature, sorted[0].Brightness
+	}
+	for i := 1; i < len(sorted); i++ {
+		below, above := sorted[i-1], sorted[i]
+		if elevation > above.Elevation {
+			continue
+		}
+		progress := (elevation - below.Elevation) / (above.Elevation - below.Elevation)
+		temperature := float64(below.Temperature) + float64(above.Temperature-below.Temperature)*progress
+		brightness := float64(below.Brightness) + float64(above.Brightness-below.Brightness)*progress
+		return int(math.Round(temperature)), int(math.Round(brightness))
+	}
+	last := sorted[len(sorted)-1]
+	return last.Temperature, last.Brightness
+}
@@ -0,0 +1,122 @@
+package schedule
+
+import (
+	"math"
+	"time"
+
+	"github.com/nathan-osman/go-sunrise"
+)
+
+// the elevations of the sun (in degrees) its events happen at
+const (
+	elevationSunrise    = -0.833
+	elevationCivil      = -6
+	elevationNautical   = -12
+	elevationGoldenHour = 6
+)
+
+// Polar says whether the sun stays on one side of an elevation all day
+type Polar int
+
+const (
+	NotPolar Polar = iota
+	// the sun stays above the elevation all day
+	PolarDay
+	// the sun stays below the elevation all day
+	PolarNight
+)
+
+func (p Polar) String() string {
+	switch p {
+	case PolarDay:
+		return "polar day"
+	case PolarNight:
+		return "polar night"
+	}
+	return "none"
+}
+
+// SunTimes are the times of the sun's events on a day
+type SunTimes struct {
+	Sunrise      time.Time
+	Sunset       time.Time
+	CivilDawn    time.Time
+	CivilDusk    time.Time
+	NauticalDawn time.Time
+	NauticalDusk time.Time
+	SolarNoon    time.Time
+	// the start of the evening golden hour, when the sun drops below 6 degrees
+	GoldenHour time.Time
+	// whether the sun rises and sets
+	Polar Polar
+}
+
+// SunEvent is one of the sun's events, by the name pattern times use for it
+type SunEvent struct {
+	Name string
+	Time time.Time
+}
+
+// Anchors returns the sun's events pattern times can be relative to
+func (s SunTimes) Anchors() []SunEvent {
+	return []SunEvent{
+		{"sunrise", s.Sunrise},
+		{"sunset", s.Sunset},
+		{"civilDawn", s.CivilDawn},
+		{"civilDusk", s.CivilDusk},
+		{"nauticalDawn", s.NauticalDawn},
+		{"nauticalDusk", s.NauticalDusk},
+		{"solarNoon", s.SolarNoon},
+		{"goldenHour", s.GoldenHour},
+	}
+}
+
+// CalculateSunTimes works out the times of the sun's events on the (local) date at the location
+func CalculateSunTimes(lat float64, lng float64, date time.Time) SunTimes {
+	var sun SunTimes
+	sun.SolarNoon = SolarNoon(lng, date)
+	sun.Sunrise, sun.Sunset, sun.Polar = SunElevationTimes(lat, lng, elevationSunrise, date)
+	sun.CivilDawn, sun.CivilDusk, _ = SunElevationTimes(lat, lng, elevationCivil, date)
+	sun.NauticalDawn, sun.NauticalDusk, _ = SunElevationTimes(lat, lng, elevationNautical, date)
+	_, sun.GoldenHour, _ = SunElevationTimes(lat, lng, elevationGoldenHour, date)
+	return sun
+}
+
+// SolarNoon returns when the sun is highest on the (local) date at the longitude
+func SolarNoon(lng float64, date time.Time) time.Time {
+	transit, _ := solarTransit(lng, date)
+	return sunrise.JulianDayToTime(transit).Local()
+}
+
+// SunElevationTimes returns when the sun passes the elevation (in degrees) in the morning and evening of the (local)
+// date at the location. When it doesn't, the morning and evening are the start and end of the day for polar day,
+// and both solar noon for polar night.
+func SunElevationTimes(lat float64, lng float64, elevation float64, date time.Time) (time.Time, time.Time, Polar) {
+	date = date.Local()
+	transit, declination := solarTransit(lng, date)
+
+	cosHourAngle := (math.Sin(elevation*sunrise.Degree) - math.Sin(lat*sunrise.Degree)*math.Sin(declination*sunrise.Degree)) /
+		(math.Cos(lat*sunrise.Degree) * math.Cos(declination*sunrise.Degree))
+
+	switch {
+	case cosHourAngle < -1:
+		return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.Local),
+			time.Date(date.Year(), date.Month(), date.Day(), 23, 59, 59, 999999, time.Local),
+			PolarDay
+	case cosHourAngle > 1:
+		noon := sunrise.JulianDayToTime(transit).Local()
+		return noon, noon, PolarNight
+	}
+
+	frac := math.Acos(cosHourAngle) / (2 * math.Pi)
+	return sunrise.JulianDayToTime(transit - frac).Local(), sunrise.JulianDayToTime(transit + frac).Local(), NotPolar
+}
+
+// the julian day of solar noon and the declination of the sun (in degrees) on the date
+func solarTransit(lng float64, date time.Time) (float64, float64) {
+	d := sunrise.MeanSolarNoon(lng, date.Year(), date.Month(), date.Day())
+	solarAnomaly := sunrise.SolarMeanAnomaly(d)
+	equationOfCenter := sunrise.EquationOfCenter(solarAnomaly)
+	eclipticLongitude := sunrise.EclipticLongitude(solarAnomaly, equationOfCenter, d)
+	return sunrise.SolarTransit(d, solarAnomaly, eclipticLongitude), sunrise.Declination(eclipticLongitude)
+}
@@ -0,0 +1,728 @@
+// Package validate checks the config file, reporting each problem with the line it is on.
+package validate
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wheelibin/hugh/internal/colour"
+	"github.com/wheelibin/hugh/internal/constants"
+	"github.com/wheelibin/hugh/internal/models"
+	"github.com/wheelibin/hugh/internal/schedule"
+	"gopkg.in/yaml.v3"
+)
+
+// the colour temperatures hue lights can show
+const (
+	minKelvin = 2000
+	maxKelvin = 6500
+)
+
+// Problem is something wrong with the config, at a line of the config file
+type Problem struct {
+	File    string
+	Line    int
+	Column  int
+	Message string
+	// a warning is reported but hugh still runs
+	Warning bool
+}
+
+func (p Problem) String() string {
+	level := "error"
+	if p.Warning {
+		level = "warning"
+	}
+	return fmt.Sprintf("%s:%d:%d: %s: %s", p.File, p.Line, p.Column, level, p.Message)
+}
+
+// HasErrors returns whether any of the problems stop hugh from running
+func HasErrors(problems []Problem) bool {
+	for _, p := range problems {
+		if !p.Warning {
+			return true
+		}
+	}
+	return false
+}
+
+// File checks the config file, returning every problem found in it
+func File(path string) ([]Problem, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	problems, err := Config(data)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", path, err)
+	}
+	for i := range problems {
+		problems[i].File = path
+	}
+	return problems, nil
+}
+
+// Config checks the config (as yaml), returning every problem found in it
+func Config(data []byte) ([]Problem, error) {
+	doc := yaml.Node{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	c := &checker{}
+	if len(doc.Content) == 0 {
+		return c.problems, nil
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		c.errorf(root, "the config should be a mapping of settings")
+		return c.problems, nil
+	}
+
+	c.checkGeoLocation(root)
+	c.checkDayBoundary(root)
+	c.checkDayPatterns(root)
+	c.checkSchedules(root)
+	c.checkBindings(root)
+	c.checkCalendar(root)
+
+	sort.SliceStable(c.problems, func(i, j int) bool {
+		return c.problems[i].Line < c.problems[j].Line
+	})
+	return c.problems, nil
+}
+
+type checker struct {
+	problems []Problem
+
+	lat, lng    float64
+	hasLocation bool
+	// the time of day the lighting day starts at
+	dayBoundary string
+	// the key nodes of the day patterns, fragments and schedules by name (lower case, viper ignoring case)
+	patterns  map[string]*yaml.Node
+	fragments map[string]*yaml.Node
+	schedules map[string]*yaml.Node
+}
+
+func (c *checker) errorf(node *yaml.Node, format string, args ...any) {
+	c.problems = append(c.problems, Problem{Line: node.Line, Column: node.Column, Message: fmt.Sprintf(format, args...)})
+}
+
+func (c *checker) warnf(node *yaml.Node, format string, args ...any) {
+	c.problems = append(c.problems, Problem{Line: node.Line, Column: node.Column, Message: fmt.Sprintf(format, args...), Warning: true})
+}
+
+func (c *checker) checkGeoLocation(root *yaml.Node) {
+	_, node := mappingValue(root, "geoLocation")
+	if node == nil {
+		return
+	}
+	latLng := strings.Split(node.Value, ",")
+	if len(latLng) != 2 {
+		c.errorf(node, "invalid geoLocation %q, expected latitude,longitude", node.Value)
+		return
+	}
+	lat, latErr := strconv.ParseFloat(strings.TrimSpace(latLng[0]), 64)
+	lng, lngErr := strconv.ParseFloat(strings.TrimSpace(latLng[1]), 64)
+	if latErr != nil || lngErr != nil || lat < -90 || lat > 90 || lng < -180 || lng > 180 {
+		c.errorf(node, "invalid geoLocation %q, expected latitude,longitude", node.Value)
+		return
+	}
+	c.lat, c.lng, c.hasLocation = lat, lng, true
+}
+
+func (c *checker) checkDayBoundary(root *yaml.Node) {
+	c.dayBoundary = constants.DefaultDayBoundary
+	_, node := mappingValue(root, "dayBoundary")
+	if node == nil {
+		return
+	}
+	if err := schedule.ParseClockTime(node.Value); err != nil {
+		c.errorf(node, "dayBoundary: %v", err)
+		return
+	}
+	c.dayBoundary = node.Value
+}
+
+var patternKeys = keySet("name", "extends", "type", "default", "pattern", "elevations",
+	"sunriseMin", "sunriseMax", "sunsetMin", "sunsetMax", "civilDawnMin", "civilDawnMax", "civilDuskMin", "civilDuskMax",
+	"nauticalDawnMin", "nauticalDawnMax", "nauticalDuskMin", "nauticalDuskMax", "solarNoonMin", "solarNoonMax",
+	"goldenHourMin", "goldenHourMax")
+
+var scheduleKeys = keySet("name", "disabled", "bridge", "rooms", "zones", "dayPattern", "autoOn", "occupancy", "daylight")
+
+var occupancyKeys = keySet("sensors", "timeout")
+
+var daylightKeys = keySet("sensor", "targetLux", "minBrightness", "maxBrightness", "hysteresis")
+
+var stepKeys = keySet("time", "temperature", "brightness", "transitionAt", "off", "transition", "xy", "colour",
+	"easing", "perceptualBrightness", "miredTemperature", "include", "remove")
+
+func (c *checker) checkDayPatterns(root *yaml.Node) {
+	c.patterns = map[string]*yaml.Node{}
+	c.fragments = map[string]*yaml.Node{}
+	patternValues := map[string]*yaml.Node{}
+
+	_, fragmentsNode := mappingValue(root, "patternFragments")
+	for _, pair := range pairs(fragmentsNode) {
+		c.fragments[strings.ToLower(pair.key.Value)] = pair.key
+	}
+
+	_, patternsNode := mappingValue(root, "dayPatterns")
+	if patternsNode == nil {
+		c.errorf(root, "no dayPatterns configured")
+		return
+	}
+	// either a list of single pattern maps or a map of patterns
+	patternPairs := pairs(patternsNode)
+	if patternsNode.Kind == yaml.SequenceNode {
+		patternPairs = nil
+		for _, item := range patternsNode.Content {
+			if item.Kind != yaml.MappingNode {
+				c.errorf(item, "expected a day pattern, e.g. `- name: {type: dynamic, pattern: [...]}`")
+				continue
+			}
+			patternPairs = append(patternPairs, pairs(item)...)
+		}
+	}
+	for _, pair := range patternPairs {
+		name := strings.ToLower(pair.key.Value)
+		if _, found := c.patterns[name]; found {
+			c.errorf(pair.key, "day pattern %s is defined more than once", pair.key.Value)
+			continue
+		}
+		c.patterns[name] = pair.key
+		patternValues[name] = pair.value
+	}
+
+	for _, pair := range pairs(fragmentsNode) {
+		if pair.value.Kind != yaml.SequenceNode {
+			c.errorf(pair.value, "pattern fragment %s should be a list of steps", pair.key.Value)
+			continue
+		}
+		for _, step := range pair.value.Content {
+			c.checkStep(step)
+		}
+	}
+
+	// resolving the patterns needs them as viper reads them
+	var patterns map[string]map[string]any
+	var fragments map[string][]models.ScheduleDayPatternStep
+	var raw any
+	if err := patternsNode.Decode(&raw); err == nil {
+		_ = schedule.Decode(raw, &patterns)
+	}
+	if fragmentsNode != nil {
+		if err := fragmentsNode.Decode(&raw); err == nil {
+			_ = schedule.Decode(raw, &fragments)
+		}
+	}
+
+	for _, pair := range patternPairs {
+		if c.patterns[strings.ToLower(pair.key.Value)] != pair.key {
+			// a duplicate
+			continue
+		}
+		before := len(c.problems)
+		c.checkDayPattern(pair.key, pair.value)
+		if HasErrors(c.problems[before:]) {
+			// the pattern can't be resolved until its errors are fixed
+			continue
+		}
+
+		dayPattern, err := schedule.ResolveDayPattern(pair.key.Value, patterns, fragments)
+		if err != nil {
+			c.errorf(pair.key, "%v", err)
+			continue
+		}
+		c.checkResolvedDayPattern(pair.key, pair.value, dayPattern)
+	}
+}
+
+func (c *checker) checkDayPattern(key *yaml.Node, value *yaml.Node) {
+	if value.Kind != yaml.MappingNode {
+		c.errorf(value, "day pattern %s should be a mapping", key.Value)
+		return
+	}
+	c.checkKeys(value, patternKeys, "day pattern "+key.Value)
+
+	_, typeNode := mappingValue(value, "type")
+	if typeNode != nil {
+		switch typeNode.Value {
+		case "", "dynamic", "solar":
+		default:
+			c.errorf(typeNode, "unknown day pattern type %q, expected dynamic or solar (or none for fixed times)", typeNode.Value)
+		}
+	}
+
+	if _, extends := mappingValue(value, "extends"); extends != nil {
+		if _, found := c.patterns[strings.ToLower(extends.Value)]; !found {
+			c.errorf(extends, "day pattern %s extends unknown day pattern %s", key.Value, extends.Value)
+		}
+	}
+
+	for _, pair := range pairs(value) {
+		if strings.HasSuffix(pair.key.Value, "Min") || strings.HasSuffix(pair.key.Value, "Max") {
+			if err := schedule.ParseClockTime(pair.value.Value); err != nil {
+				c.errorf(pair.value, "%s: %v", pair.key.Value, err)
+			}
+		}
+	}
+
+	if _, def := mappingValue(value, "default"); def != nil {
+		if _, t := mappingValue(def, "time"); t != nil {
+			if err := schedule.ParseClockTime(t.Value); err != nil {
+				c.errorf(t, "%v", err)
+			}
+		}
+		c.checkTemperature(def)
+		c.checkRange(def, "brightness", 0, 100)
+	}
+
+	if _, steps := mappingValue(value, "pattern"); steps != nil {
+		if steps.Kind != yaml.SequenceNode {
+			c.errorf(steps, "the pattern of day pattern %s should be a list of steps", key.Value)
+		} else {
+			for _, step := range steps.Content {
+				c.checkStep(step)
+			}
+		}
+	}
+
+	if _, elevations := mappingValue(value, "elevations"); elevations != nil {
+		if elevations.Kind != yaml.SequenceNode {
+			c.errorf(elevations, "the elevations of day pattern %s should be a list", key.Value)
+			return
+		}
+		for _, point := range elevations.Content {
+			c.checkRange(point, "elevation", -90, 90)
+			c.checkTemperature(point)
+			c.checkRange(point, "brightness", 0, 100)
+		}
+	}
+}
+
+func (c *checker) checkStep(step *yaml.Node) {
+	if step.Kind != yaml.MappingNode {
+		c.errorf(step, "expected a step, e.g. `- time: sunset, temperature: 2700, brightness: 80`")
+		return
+	}
+	c.checkKeys(step, stepKeys, "step")
+
+	_, include := mappingValue(step, "include")
+	if include != nil {
+		if _, found := c.fragments[strings.ToLower(include.Value)]; !found {
+			c.errorf(include, "unknown pattern fragment %s", include.Value)
+		}
+		return
+	}
+
+	_, t := mappingValue(step, "time")
+	if t == nil {
+		c.errorf(step, "step has no time")
+	} else if _, err := schedule.ParsePatternTime(t.Value); err != nil {
+		c.errorf(t, "%v", err)
+	}
+
+	c.checkTemperature(step)
+	c.checkRange(step, "brightness", 0, 100)
+	c.checkRange(step, "transitionAt", 0, 100)
+
+	if _, transition := mappingValue(step, "transition"); transition != nil {
+		if _, err := time.ParseDuration(transition.Value); err != nil {
+			c.errorf(transition, "invalid transition %q, expected a duration like 30s", transition.Value)
+		}
+	}
+	if _, easing := mappingValue(step, "easing"); easing != nil && !schedule.IsEasing(easing.Value) {
+		c.errorf(easing, "unknown easing %q, expected linear, easeIn, easeOut, easeInOut, sigmoid or step", easing.Value)
+	}
+
+	_, colourNode := mappingValue(step, "colour")
+	_, xy := mappingValue(step, "xy")
+	if colourNode != nil {
+		if _, err := colour.Parse(colourNode.Value); err != nil {
+			c.errorf(colourNode, "%v", err)
+		}
+		if xy != nil {
+			c.errorf(xy, "only one of xy or colour can be set")
+		}
+	}
+	if xy != nil && (xy.Kind != yaml.SequenceNode || len(xy.Content) != 2) {
+		c.errorf(xy, "invalid xy, expected [x, y]")
+	}
+}
+
+// checks the pattern once what it extends and includes is resolved, the order of its steps through the year
+func (c *checker) checkResolvedDayPattern(key *yaml.Node, value *yaml.Node, dayPattern models.DayPattern) {
+	if dayPattern.Type == "solar" {
+		if len(dayPattern.Elevations) == 0 {
+			c.errorf(key, "solar day pattern %s has no elevations", key.Value)
+		}
+		if !c.hasLocation {
+			c.errorf(key, "solar day pattern %s needs the geoLocation to be set", key.Value)
+		}
+		return
+	}
+	if len(dayPattern.Pattern) == 0 {
+		c.errorf(key, "day pattern %s has no steps", key.Value)
+		return
+	}
+
+	dynamic := dayPattern.Type == "dynamic"
+	for _, step := range dayPattern.Pattern {
+		usesSun, _ := schedule.ParsePatternTime(step.Time)
+		if usesSun && !dynamic {
+			c.errorf(c.stepNode(key, value, step.Time), "step %s uses the sun but day pattern %s isn't dynamic", step.Time, key.Value)
+			return
+		}
+	}
+	if dynamic && !c.hasLocation {
+		c.errorf(key, "dynamic day pattern %s needs the geoLocation to be set", key.Value)
+		return
+	}
+
+	// a week apart through the year, the sun's events moving the steps
+	dates := []time.Time{time.Date(time.Now().Year(), 1, 1, 0, 0, 0, 0, time.Local)}
+	if dynamic {
+		for i := 1; i < 53; i++ {
+			dates = append(dates, dates[0].AddDate(0, 0, 7*i))
+		}
+	}
+
+	reported := map[int]bool{}
+	for _, date := range dates {
+		date = schedule.TimeFromConfigTimeString(c.dayBoundary, date)
+		sun := schedule.ClampSunTimes(schedule.CalculateSunTimes(c.lat, c.lng, date), dayPattern, date)
+		for i := 1; i < len(dayPattern.Pattern); i++ {
+			earlier, later := dayPattern.Pattern[i-1], dayPattern.Pattern[i]
+			earlierTime := schedule.TimeFromPattern(earlier.Time, sun, date)
+			laterTime := schedule.TimeFromPattern(later.Time, sun, date)
+			if !laterTime.Before(earlierTime) || reported[i] {
+				continue
+			}
+			reported[i] = true
+			c.warnf(c.stepNode(key, value, later.Time), "day pattern %s: step %s (%s) comes before the step %s (%s) on %s",
+				key.Value, later.Time, laterTime.Format("15:04"), earlier.Time, earlierTime.Format("15:04"), date.Format("2006-01-02"))
+		}
+	}
+}
+
+// the node of the pattern's own step with the time, the pattern's if the step comes from a pattern it extends or a fragment
+func (c *checker) stepNode(key *yaml.Node, value *yaml.Node, stepTime string) *yaml.Node {
+	_, steps := mappingValue(value, "pattern")
+	if steps != nil {
+		for _, step := range steps.Content {
+			if _, t := mappingValue(step, "time"); t != nil && strings.EqualFold(t.Value, stepTime) {
+				return t
+			}
+		}
+	}
+	return key
+}
+
+var dateKey = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+
+func (c *checker) checkSchedules(root *yaml.Node) {
+	c.schedules = map[string]*yaml.Node{}
+	_, schedules := mappingValue(root, "schedules")
+	if schedules == nil {
+		c.errorf(root, "no schedules configured")
+		return
+	}
+	if schedules.Kind != yaml.SequenceNode {
+		c.errorf(schedules, "schedules should be a list")
+		return
+	}
+
+	type place struct {
+		schedule string
+		bridge   string
+	}
+	// the rooms and zones of the enabled schedules
+	places := map[string][]place{}
+	// and the schedules with any, validate can't see which lights are in which room or zone so can only tell
+	// a room and a zone might share lights
+	placeLists := map[string][]place{}
+
+	for _, sch := range schedules.Content {
+		if sch.Kind != yaml.MappingNode {
+			c.errorf(sch, "expected a schedule")
+			continue
+		}
+
+		_, nameNode := mappingValue(sch, "name")
+		name := ""
+		if nameNode == nil {
+			c.errorf(sch, "schedule has no name")
+		} else {
+			name = nameNode.Value
+			if _, found := c.schedules[strings.ToLower(name)]; found {
+				c.errorf(nameNode, "schedule name %s is used more than once", name)
+			}
+			c.schedules[strings.ToLower(name)] = nameNode
+		}
+		c.checkKeys(sch, scheduleKeys, "schedule "+name)
+
+		_, dayPattern := mappingValue(sch, "dayPattern")
+		switch {
+		case dayPattern == nil:
+			c.errorf(sch, "schedule %s has no dayPattern", name)
+		case dayPattern.Kind == yaml.MappingNode:
+			hasDefault := false
+			selectors := map[string]bool{}
+			for _, pair := range pairs(dayPattern) {
+				selector := strings.ToLower(pair.key.Value)
+				if selectors[selector] {
+					c.errorf(pair.key, "day pattern selector %s is used more than once in schedule %s", pair.key.Value, name)
+				}
+				selectors[selector] = true
+				switch {
+				case selector == models.DayPatternDefault:
+					hasDefault = true
+				case isWeekday(selector):
+				case dateKey.MatchString(selector):
+					if _, err := time.Parse("2006-01-02", selector); err != nil {
+						c.errorf(pair.key, "invalid date %s", pair.key.Value)
+					}
+				default:
+					c.errorf(pair.key, "unknown day pattern selector %s, expected default, a weekday (mon..sun) or a date (2006-01-02)", pair.key.Value)
+				}
+				c.checkPatternName(pair.value)
+			}
+			if !hasDefault {
+				c.warnf(dayPattern, "schedule %s has no default day pattern, its lights are left alone on the other days", name)
+			}
+		default:
+			c.checkPatternName(dayPattern)
+		}
+
+		if _, autoOn := mappingValue(sch, "autoOn"); autoOn != nil {
+			c.checkAutoOn(name, autoOn)
+		}
+		if _, occupancy := mappingValue(sch, "occupancy"); occupancy != nil {
+			c.checkKeys(occupancy, occupancyKeys, "the occupancy of schedule "+name)
+		}
+		if _, daylight := mappingValue(sch, "daylight"); daylight != nil {
+			c.checkKeys(daylight, daylightKeys, "the daylight of schedule "+name)
+			c.checkRange(daylight, "minBrightness", 0, 100)
+			c.checkRange(daylight, "maxBrightness", 0, 100)
+		}
+
+		if _, disabled := mappingValue(sch, "disabled"); disabled != nil && disabled.Value == "true" {
+			continue
+		}
+		bridge := ""
+		if _, b := mappingValue(sch, "bridge"); b != nil {
+			bridge = b.Value
+		}
+		for _, kind := range []string{"rooms", "zones"} {
+			_, list := mappingValue(sch, kind)
+			if list == nil {
+				continue
+			}
+			for _, item := range list.Content {
+				key := kind + "/" + strings.ToLower(item.Value)
+				for _, p := range places[key] {
+					// rooms with the same name on different bridges are different rooms
+					if p.bridge == "" || bridge == "" || p.bridge == bridge {
+						c.warnf(item, "%s %s is in schedules %s and %s, their lights will fight", strings.TrimSuffix(kind, "s"), item.Value, p.schedule, name)
+						break
+					}
+				}
+				places[key] = append(places[key], place{schedule: name, bridge: bridge})
+			}
+
+			if len(list.Content) == 0 {
+				continue
+			}
+			other := map[string]string{"rooms": "zones", "zones": "rooms"}[kind]
+			for _, p := range placeLists[other] {
+				if p.bridge == "" || bridge == "" || p.bridge == bridge {
+					c.warnf(list, "the %s of schedule %s and the %s of schedule %s may share lights, which would fight (validate can't see which lights they have)",
+						kind, name, other, p.schedule)
+				}
+			}
+			placeLists[kind] = append(placeLists[kind], place{schedule: name, bridge: bridge})
+		}
+	}
+}
+
+func (c *checker) checkPatternName(node *yaml.Node) {
+	if _, found := c.patterns[strings.ToLower(node.Value)]; !found {
+		c.errorf(node, "unknown day pattern %s", node.Value)
+	}
+}
+
+func (c *checker) checkAutoOn(scheduleName string, autoOn *yaml.Node) {
+	_, from := mappingValue(autoOn, "from")
+	_, to := mappingValue(autoOn, "to")
+	if from == nil || to == nil {
+		c.errorf(autoOn, "the autoOn window of schedule %s needs a from and to", scheduleName)
+		return
+	}
+	fromErr := schedule.ParseClockTime(from.Value)
+	if fromErr != nil {
+		c.errorf(from, "%v", fromErr)
+	}
+	toErr := schedule.ParseClockTime(to.Value)
+	if toErr != nil {
+		c.errorf(to, "%v", toErr)
+	}
+	if fromErr != nil || toErr != nil {
+		return
+	}
+
+	// the window doesn't wrap around midnight
+	date := time.Now()
+	if schedule.TimeFromConfigTimeString(from.Value, date).After(schedule.TimeFromConfigTimeString(to.Value, date)) {
+		c.warnf(autoOn, "the autoOn window of schedule %s (%s to %s) is never reached, it can't cross midnight", scheduleName, from.Value, to.Value)
+	}
+}
+
+func (c *checker) checkBindings(root *yaml.Node) {
+	_, bindings := mappingValue(root, "bindings")
+	if bindings == nil {
+		return
+	}
+	for _, binding := range bindings.Content {
+		if _, sch := mappingValue(binding, "schedule"); sch != nil {
+			c.checkScheduleName(sch)
+		} else {
+			c.errorf(binding, "binding has no schedule")
+		}
+
+		_, action := mappingValue(binding, "action")
+		switch {
+		case action == nil:
+			c.errorf(binding, "binding has no action")
+		case action.Value != constants.ActionResume && action.Value != constants.ActionPause && action.Value != constants.ActionNextStep &&
+			action.Value != constants.ActionWindDown && action.Value != constants.ActionBrightness:
+			c.errorf(action, "unknown action %s, expected resume, pause, next, windDown or brightness", action.Value)
+		}
+	}
+}
+
+func (c *checker) checkCalendar(root *yaml.Node) {
+	_, calendar := mappingValue(root, "calendar")
+	if calendar == nil {
+		return
+	}
+	_, file := mappingValue(calendar, "file")
+	_, url := mappingValue(calendar, "url")
+	if file == nil && url == nil {
+		c.errorf(calendar, "the calendar needs a file or url")
+	}
+
+	_, modes := mappingValue(calendar, "modes")
+	if modes == nil {
+		return
+	}
+	for _, mode := range modes.Content {
+		if _, match := mappingValue(mode, "match"); match == nil || match.Value == "" {
+			c.errorf(mode, "calendar mode has nothing to match")
+		}
+		if _, dayPattern := mappingValue(mode, "dayPattern"); dayPattern != nil {
+			c.checkPatternName(dayPattern)
+		}
+		if _, schedules := mappingValue(mode, "schedules"); schedules != nil {
+			for _, sch := range schedules.Content {
+				c.checkScheduleName(sch)
+			}
+		}
+	}
+}
+
+func (c *checker) checkScheduleName(node *yaml.Node) {
+	if _, found := c.schedules[strings.ToLower(node.Value)]; !found {
+		c.errorf(node, "unknown schedule %s", node.Value)
+	}
+}
+
+// checks a colour temperature, which can be left out (or 0) for off steps and colours
+func (c *checker) checkTemperature(mapping *yaml.Node) {
+	_, node := mappingValue(mapping, "temperature")
+	if node == nil {
+		return
+	}
+	kelvin, err := strconv.Atoi(node.Value)
+	if err != nil {
+		c.errorf(node, "invalid temperature %q, expected kelvin", node.Value)
+		return
+	}
+	if kelvin != 0 && (kelvin < minKelvin || kelvin > maxKelvin) {
+		c.errorf(node, "temperature %dK is out of range, hue lights show %dK to %dK", kelvin, minKelvin, maxKelvin)
+	}
+}
+
+func (c *checker) checkRange(mapping *yaml.Node, key string, min float64, max float64) {
+	_, node := mappingValue(mapping, key)
+	if node == nil {
+		return
+	}
+	value, err := strconv.ParseFloat(node.Value, 64)
+	if err != nil {
+		c.errorf(node, "invalid %s %q, expected a number", key, node.Value)
+		return
+	}
+	if value < min || value > max {
+		c.errorf(node, "%s %v is out of range, expected %v to %v", key, value, min, max)
+	}
+}
+
+// warns about keys that aren't known, most likely typos
+func (c *checker) checkKeys(mapping *yaml.Node, known map[string]bool, what string) {
+	for _, pair := range pairs(mapping) {
+		if !known[strings.ToLower(pair.key.Value)] {
+			c.warnf(pair.key, "unknown key %s in %s, it is ignored", pair.key.Value, what)
+		}
+	}
+}
+
+func keySet(keys ...string) map[string]bool {
+	set := map[string]bool{}
+	for _, k := range keys {
+		set[strings.ToLower(k)] = true
+	}
+	return set
+}
+
+func isWeekday(s string) bool {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.ToLower(d.String()[:3]) == s {
+			return true
+		}
+	}
+	return false
+}
+
+type pair struct {
+	key   *yaml.Node
+	value *yaml.Node
+}
+
+// the key/value pairs of a mapping, none if it isn't one
+func pairs(mapping *yaml.Node) []pair {
+	if mapping == nil || mapping.Kind != yaml.MappingNode {
+		return nil
+	}
+	result := []pair{}
+	for i := 0; i < len(mapping.Content)-1; i += 2 {
+		result = append(result, pair{key: mapping.Content[i], value: mapping.Content[i+1]})
+	}
+	return result
+}
+
+// the key and value nodes of the key in the mapping (ignoring case, as viper does), nil if it isn't set
+func mappingValue(mapping *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	for _, p := range pairs(mapping) {
+		if strings.EqualFold(p.key.Value, key) {
+			return p.key, p.value
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,274 @@
+package validate_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/wheelibin/hugh/internal/validate"
+)
+
+func Test_File(t *testing.T) {
+	problems, err := validate.File("testdata/problems.yaml")
+	require.NoError(t, err)
+
+	expected := []struct {
+		line    int
+		warning bool
+		message string
+	}{
+		{8, true, "autoOn window of schedule Kitchen (22:00 to 07:00) is never reached"},
+		{13, false, "unknown day pattern missing"},
+		{14, false, "unknown day pattern selector someday"},
+		{16, true, "room Kitchen is in schedules Kitchen and Hall"},
+		{17, false, "schedule name Hall is used more than once"},
+		{24, false, "unknown action jump"},
+		{25, false, "unknown schedule Garden"},
+		{29, false, "temperature 9000K is out of range"},
+		{30, false, "brightness 120 is out of range"},
+		{31, false, "unknown pattern fragment nowhere"},
+		{35, false, `sunsetMax: invalid hour in "25:00"`},
+		{40, false, `unknown easing "bouncy"`},
+		{41, false, `invalid offset "+1x" in "sunset+1x"`},
+		{48, true, "unknown key brigthness in step"},
+		{49, false, "step sunset uses the sun but day pattern fixed isn't dynamic"},
+		{56, false, "day pattern twice is defined more than once"},
+		{59, false, "day pattern loops: extends itself (loops -> loops)"},
+		{66, true, "day pattern ordered: step 18:00 (18:00) comes before the step sunset"},
+		{68, false, "solar day pattern empty has no elevations"},
+	}
+
+	require.Len(t, problems, len(expected))
+	for i, e := range expected {
+		p := problems[i]
+		assert.Equal(t, "testdata/problems.yaml", p.File)
+		assert.Equal(t, e.line, p.Line, p.String())
+		assert.Equal(t, e.warning, p.Warning, p.String())
+		assert.Contains(t, p.Message, e.message)
+	}
+	assert.True(t, validate.HasErrors(problems))
+}
+
+func Test_Config(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   string
+		problems []string
+	}{
+		{
+			name: "no problems",
+			config: `
+geoLocation: 53.480759,-2.242631
+schedules:
+  - name: Kitchen
+    dayPattern: circadian
+    bridge: house
+    rooms: [Kitchen]
+    autoOn: {from: "07:00", to: "22:00"}
+  - name: Garden
+    dayPattern: circadian
+    bridge: garage
+    rooms: [Kitchen]
+dayPatterns:
+  circadian:
+    type: dynamic
+    sunsetMax: "21:00"
+    pattern:
+      - time: sunrise
+        temperature: 2700
+        brightness: 50
+      - time: sunset
+        temperature: 2200
+        brightness: 40
+`,
+		},
+		{
+			name: "dynamic pattern without a location",
+			config: `
+schedules:
+  - name: Kitchen
+    dayPattern: circadian
+dayPatterns:
+  circadian:
+    type: dynamic
+    pattern:
+      - time: sunrise
+`,
+			problems: []string{"6:3: error: dynamic day pattern circadian needs the geoLocation to be set"},
+		},
+		{
+			name: "steps",
+			config: `
+schedules:
+  - name: Kitchen
+    dayPattern: fixed
+dayPatterns:
+  fixed:
+    type: daily
+    pattern:
+      - brightness: 50
+      - time: "07:00"
+        colour: amber
+        xy: [0.5, 0.4]
+`,
+			problems: []string{
+				`7:11: error: unknown day pattern type "daily"`,
+				"9:9: error: step has no time",
+				"12:13: error: only one of xy or colour can be set",
+			},
+		},
+		{
+			name: "inherited steps out of order",
+			config: `
+schedules:
+  - name: Kitchen
+    dayPattern: late
+dayPatterns:
+  early:
+    pattern:
+      - time: "07:00"
+      - time: "09:00"
+  late:
+    extends: early
+    pattern:
+      - time: "07:00"
+        remove: true
+      - time: "08:00"
+      - time: "10:00"
+      - time: "06:00"
+`,
+			problems: []string{"17:15: warning: day pattern late: step 06:00 (06:00) comes before the step 10:00 (10:00)"},
+		},
+		{
+			name: "steps after midnight with a day boundary",
+			config: `
+dayBoundary: "04:00"
+schedules:
+  - name: Kitchen
+    dayPattern: late
+dayPatterns:
+  late:
+    pattern:
+      - time: "23:30"
+      - time: "01:00"
+        off: true
+`,
+		},
+		{
+			name: "steps after midnight without a day boundary",
+			config: `
+schedules:
+  - name: Kitchen
+    dayPattern: late
+dayPatterns:
+  late:
+    pattern:
+      - time: "23:30"
+      - time: "01:00"
+        off: true
+`,
+			problems: []string{"9:15: warning: day pattern late: step 01:00 (01:00) comes before the step 23:30 (23:30)"},
+		},
+		{
+			name: "a weekday selector given twice",
+			config: `
+schedules:
+  - name: Kitchen
+    dayPattern:
+      default: fixed
+      Sat: fixed
+      sat: fixed
+dayPatterns:
+  fixed:
+    pattern:
+      - time: "07:00"
+`,
+			problems: []string{"7:7: error: day pattern selector sat is used more than once in schedule Kitchen"},
+		},
+		{
+			name: "unknown schedule keys",
+			config: `
+schedules:
+  - name: Kitchen
+    dayPatern: fixed
+    rooms: [Kitchen]
+    occupancy:
+      sensor: Kitchen sensor
+    daylight:
+      targetLux: 200
+      minBrightnes: 10
+dayPatterns:
+  fixed:
+    pattern:
+      - time: "07:00"
+`,
+			problems: []string{
+				"3:5: error: schedule Kitchen has no dayPattern",
+				"4:5: warning: unknown key dayPatern in schedule Kitchen, it is ignored",
+				"7:7: warning: unknown key sensor in the occupancy of schedule Kitchen, it is ignored",
+				"10:7: warning: unknown key minBrightnes in the daylight of schedule Kitchen, it is ignored",
+			},
+		},
+		{
+			name: "rooms and zones of different schedules",
+			config: `
+schedules:
+  - name: Kitchen
+    dayPattern: fixed
+    rooms: [Kitchen]
+  - name: Downstairs
+    dayPattern: fixed
+    zones: [Downstairs]
+  - name: Garage
+    dayPattern: fixed
+    bridge: garage
+    zones: [Workshop]
+  - name: Hall
+    dayPattern: fixed
+    bridge: garage
+    rooms: [Hall]
+dayPatterns:
+  fixed:
+    pattern:
+      - time: "07:00"
+`,
+			problems: []string{
+				"8:12: warning: the zones of schedule Downstairs and the rooms of schedule Kitchen may share lights",
+				"12:12: warning: the zones of schedule Garage and the rooms of schedule Kitchen may share lights",
+				"16:12: warning: the rooms of schedule Hall and the zones of schedule Downstairs may share lights",
+				"16:12: warning: the rooms of schedule Hall and the zones of schedule Garage may share lights",
+			},
+		},
+		{
+			name: "invalid day boundary",
+			config: `
+dayBoundary: "4am"
+schedules:
+  - name: Kitchen
+    dayPattern: late
+dayPatterns:
+  late:
+    pattern:
+      - time: "23:30"
+`,
+			problems: []string{`2:14: error: dayBoundary: invalid time "4am"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problems, err := validate.Config([]byte(tt.config))
+			require.NoError(t, err)
+			require.Len(t, problems, len(tt.problems), problems)
+			for i, p := range problems {
+				assert.Contains(t, p.String(), tt.problems[i])
+			}
+		})
+	}
+}
+
+func Test_File_ExampleConfig(t *testing.T) {
+	problems, err := validate.File("../../cmd/hugh/config.yaml")
+	require.NoError(t, err)
+	assert.False(t, validate.HasErrors(problems), problems)
+}
@@ -64,6 +64,90 @@ func (_c *MockLogicalstatemanagerDbAccess_Add_Call) RunAndReturn(run func([]mode
 	return _c
 }
 
+// AddBindings provides a mock function with given fields: bindings
+func (_m *MockLogicalstatemanagerDbAccess) AddBindings(bindings []models.HughBinding) error {
+	ret := _m.Called(bindings)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func([]models.HughBinding) error); ok {
+		r0 = rf(bindings)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockLogicalstatemanagerDbAccess_AddBindings_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'AddBindings'
+type MockLogicalstatemanagerDbAccess_AddBindings_Call struct {
+	*mock.Call
+}
+
+// AddBindings is a helper method to define mock.On call
+//   - bindings []models.HughBinding
+func (_e *MockLogicalstatemanagerDbAccess_Expecter) AddBindings(bindings interface{}) *MockLogicalstatemanagerDbAccess_AddBindings_Call {
+	return &MockLogicalstatemanagerDbAccess_AddBindings_Call{Call: _e.mock.On("AddBindings", bindings)}
+}
+
+func (_c *MockLogicalstatemanagerDbAccess_AddBindings_Call) Run(run func(bindings []models.HughBinding)) *MockLogicalstatemanagerDbAccess_AddBindings_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].([]models.HughBinding))
+	})
+	return _c
+}
+
+func (_c *MockLogicalstatemanagerDbAccess_AddBindings_Call) Return(_a0 error) *MockLogicalstatemanagerDbAccess_AddBindings_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockLogicalstatemanagerDbAccess_AddBindings_Call) RunAndReturn(run func([]models.HughBinding) error) *MockLogicalstatemanagerDbAccess_AddBindings_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// AddGroups provides a mock function with given fields: groups
+func (_m *MockLogicalstatemanagerDbAccess) AddGroups(groups []models.HughGroup) error {
+	ret := _m.Called(groups)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func([]models.HughGroup) error); ok {
+		r0 = rf(groups)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockLogicalstatemanagerDbAccess_AddGroups_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'AddGroups'
+type MockLogicalstatemanagerDbAccess_AddGroups_Call struct {
+	*mock.Call
+}
+
+// AddGroups is a helper method to define mock.On call
+//   - groups []models.HughGroup
+func (_e *MockLogicalstatemanagerDbAccess_Expecter) AddGroups(groups interface{}) *MockLogicalstatemanagerDbAccess_AddGroups_Call {
+	return &MockLogicalstatemanagerDbAccess_AddGroups_Call{Call: _e.mock.On("AddGroups", groups)}
+}
+
+func (_c *MockLogicalstatemanagerDbAccess_AddGroups_Call) Run(run func(groups []models.HughGroup)) *MockLogicalstatemanagerDbAccess_AddGroups_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].([]models.HughGroup))
+	})
+	return _c
+}
+
+func (_c *MockLogicalstatemanagerDbAccess_AddGroups_Call) Return(_a0 error) *MockLogicalstatemanagerDbAccess_AddGroups_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockLogicalstatemanagerDbAccess_AddGroups_Call) RunAndReturn(run func([]models.HughGroup) error) *MockLogicalstatemanagerDbAccess_AddGroups_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // AddScenes provides a mock function with given fields: scenes
 func (_m *MockLogicalstatemanagerDbAccess) AddScenes(scenes []models.HughScene) error {
 	ret := _m.Called(scenes)
@@ -106,6 +190,48 @@ func (_c *MockLogicalstatemanagerDbAccess_AddScenes_Call) RunAndReturn(run func(
 	return _c
 }
 
+// AddSensors provides a mock function with given fields: sensors
+func (_m *MockLogicalstatemanagerDbAccess) AddSensors(sensors []models.HughSensor) error {
+	ret := _m.Called(sensors)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func([]models.HughSensor) error); ok {
+		r0 = rf(sensors)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockLogicalstatemanagerDbAccess_AddSensors_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'AddSensors'
+type MockLogicalstatemanagerDbAccess_AddSensors_Call struct {
+	*mock.Call
+}
+
+// AddSensors is a helper method to define mock.On call
+//   - sensors []models.HughSensor
+func (_e *MockLogicalstatemanagerDbAccess_Expecter) AddSensors(sensors interface{}) *MockLogicalstatemanagerDbAccess_AddSensors_Call {
+	return &MockLogicalstatemanagerDbAccess_AddSensors_Call{Call: _e.mock.On("AddSensors", sensors)}
+}
+
+func (_c *MockLogicalstatemanagerDbAccess_AddSensors_Call) Run(run func(sensors []models.HughSensor)) *MockLogicalstatemanagerDbAccess_AddSensors_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].([]models.HughSensor))
+	})
+	return _c
+}
+
+func (_c *MockLogicalstatemanagerDbAccess_AddSensors_Call) Return(_a0 error) *MockLogicalstatemanagerDbAccess_AddSensors_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockLogicalstatemanagerDbAccess_AddSensors_Call) RunAndReturn(run func([]models.HughSensor) error) *MockLogicalstatemanagerDbAccess_AddSensors_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // ClearLightOverrides provides a mock function with given fields: lsID
 func (_m *MockLogicalstatemanagerDbAccess) ClearLightOverrides(lsID string) error {
 	ret := _m.Called(lsID)
@@ -148,6 +274,210 @@ func (_c *MockLogicalstatemanagerDbAccess_ClearLightOverrides_Call) RunAndReturn
 	return _c
 }
 
+// ClearScheduleOverrides provides a mock function with given fields: scheduleName
+func (_m *MockLogicalstatemanagerDbAccess) ClearScheduleOverrides(scheduleName string) error {
+	ret := _m.Called(scheduleName)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(scheduleName)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockLogicalstatemanagerDbAccess_ClearScheduleOverrides_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ClearScheduleOverrides'
+type MockLogicalstatemanagerDbAccess_ClearScheduleOverrides_Call struct {
+	*mock.Call
+}
+
+// ClearScheduleOverrides is a helper method to define mock.On call
+//   - scheduleName string
+func (_e *MockLogicalstatemanagerDbAccess_Expecter) ClearScheduleOverrides(scheduleName interface{}) *MockLogicalstatemanagerDbAccess_ClearScheduleOverrides_Call {
+	return &MockLogicalstatemanagerDbAccess_ClearScheduleOverrides_Call{Call: _e.mock.On("ClearScheduleOverrides", scheduleName)}
+}
+
+func (_c *MockLogicalstatemanagerDbAccess_ClearScheduleOverrides_Call) Run(run func(scheduleName string)) *MockLogicalstatemanagerDbAccess_ClearScheduleOverrides_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string))
+	})
+	return _c
+}
+
+func (_c *MockLogicalstatemanagerDbAccess_ClearScheduleOverrides_Call) Return(_a0 error) *MockLogicalstatemanagerDbAccess_ClearScheduleOverrides_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockLogicalstatemanagerDbAccess_ClearScheduleOverrides_Call) RunAndReturn(run func(string) error) *MockLogicalstatemanagerDbAccess_ClearScheduleOverrides_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetAmbientLux provides a mock function with given fields: scheduleName
+func (_m *MockLogicalstatemanagerDbAccess) GetAmbientLux(scheduleName string) (*float64, error) {
+	ret := _m.Called(scheduleName)
+
+	var r0 *float64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (*float64, error)); ok {
+		return rf(scheduleName)
+	}
+	if rf, ok := ret.Get(0).(func(string) *float64); ok {
+		r0 = rf(scheduleName)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*float64)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(scheduleName)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockLogicalstatemanagerDbAccess_GetAmbientLux_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetAmbientLux'
+type MockLogicalstatemanagerDbAccess_GetAmbientLux_Call struct {
+	*mock.Call
+}
+
+// GetAmbientLux is a helper method to define mock.On call
+//   - scheduleName string
+func (_e *MockLogicalstatemanagerDbAccess_Expecter) GetAmbientLux(scheduleName interface{}) *MockLogicalstatemanagerDbAccess_GetAmbientLux_Call {
+	return &MockLogicalstatemanagerDbAccess_GetAmbientLux_Call{Call: _e.mock.On("GetAmbientLux", scheduleName)}
+}
+
+func (_c *MockLogicalstatemanagerDbAccess_GetAmbientLux_Call) Run(run func(scheduleName string)) *MockLogicalstatemanagerDbAccess_GetAmbientLux_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string))
+	})
+	return _c
+}
+
+func (_c *MockLogicalstatemanagerDbAccess_GetAmbientLux_Call) Return(_a0 *float64, _a1 error) *MockLogicalstatemanagerDbAccess_GetAmbientLux_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockLogicalstatemanagerDbAccess_GetAmbientLux_Call) RunAndReturn(run func(string) (*float64, error)) *MockLogicalstatemanagerDbAccess_GetAmbientLux_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetBindings provides a mock function with given fields: id
+func (_m *MockLogicalstatemanagerDbAccess) GetBindings(id string) ([]models.HughBinding, error) {
+	ret := _m.Called(id)
+
+	var r0 []models.HughBinding
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) ([]models.HughBinding, error)); ok {
+		return rf(id)
+	}
+	if rf, ok := ret.Get(0).(func(string) []models.HughBinding); ok {
+		r0 = rf(id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.HughBinding)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockLogicalstatemanagerDbAccess_GetBindings_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetBindings'
+type MockLogicalstatemanagerDbAccess_GetBindings_Call struct {
+	*mock.Call
+}
+
+// GetBindings is a helper method to define mock.On call
+//   - id string
+func (_e *MockLogicalstatemanagerDbAccess_Expecter) GetBindings(id interface{}) *MockLogicalstatemanagerDbAccess_GetBindings_Call {
+	return &MockLogicalstatemanagerDbAccess_GetBindings_Call{Call: _e.mock.On("GetBindings", id)}
+}
+
+func (_c *MockLogicalstatemanagerDbAccess_GetBindings_Call) Run(run func(id string)) *MockLogicalstatemanagerDbAccess_GetBindings_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string))
+	})
+	return _c
+}
+
+func (_c *MockLogicalstatemanagerDbAccess_GetBindings_Call) Return(_a0 []models.HughBinding, _a1 error) *MockLogicalstatemanagerDbAccess_GetBindings_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockLogicalstatemanagerDbAccess_GetBindings_Call) RunAndReturn(run func(string) ([]models.HughBinding, error)) *MockLogicalstatemanagerDbAccess_GetBindings_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetControllingLightIDsForSchedule provides a mock function with given fields: scheduleName
+func (_m *MockLogicalstatemanagerDbAccess) GetControllingLightIDsForSchedule(scheduleName string) ([]string, error) {
+	ret := _m.Called(scheduleName)
+
+	var r0 []string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) ([]string, error)); ok {
+		return rf(scheduleName)
+	}
+	if rf, ok := ret.Get(0).(func(string) []string); ok {
+		r0 = rf(scheduleName)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(scheduleName)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockLogicalstatemanagerDbAccess_GetControllingLightIDsForSchedule_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetControllingLightIDsForSchedule'
+type MockLogicalstatemanagerDbAccess_GetControllingLightIDsForSchedule_Call struct {
+	*mock.Call
+}
+
+// GetControllingLightIDsForSchedule is a helper method to define mock.On call
+//   - scheduleName string
+func (_e *MockLogicalstatemanagerDbAccess_Expecter) GetControllingLightIDsForSchedule(scheduleName interface{}) *MockLogicalstatemanagerDbAccess_GetControllingLightIDsForSchedule_Call {
+	return &MockLogicalstatemanagerDbAccess_GetControllingLightIDsForSchedule_Call{Call: _e.mock.On("GetControllingLightIDsForSchedule", scheduleName)}
+}
+
+func (_c *MockLogicalstatemanagerDbAccess_GetControllingLightIDsForSchedule_Call) Run(run func(scheduleName string)) *MockLogicalstatemanagerDbAccess_GetControllingLightIDsForSchedule_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string))
+	})
+	return _c
+}
+
+func (_c *MockLogicalstatemanagerDbAccess_GetControllingLightIDsForSchedule_Call) Return(_a0 []string, _a1 error) *MockLogicalstatemanagerDbAccess_GetControllingLightIDsForSchedule_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockLogicalstatemanagerDbAccess_GetControllingLightIDsForSchedule_Call) RunAndReturn(run func(string) ([]string, error)) *MockLogicalstatemanagerDbAccess_GetControllingLightIDsForSchedule_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // GetLightLastUpdate provides a mock function with given fields: lsID
 func (_m *MockLogicalstatemanagerDbAccess) GetLightLastUpdate(lsID string) (*time.Time, error) {
 	ret := _m.Called(lsID)
@@ -306,6 +636,60 @@ func (_c *MockLogicalstatemanagerDbAccess_GetLightTargetState_Call) RunAndReturn
 	return _c
 }
 
+// GetSensorSchedules provides a mock function with given fields: id
+func (_m *MockLogicalstatemanagerDbAccess) GetSensorSchedules(id string) ([]string, error) {
+	ret := _m.Called(id)
+
+	var r0 []string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) ([]string, error)); ok {
+		return rf(id)
+	}
+	if rf, ok := ret.Get(0).(func(string) []string); ok {
+		r0 = rf(id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockLogicalstatemanagerDbAccess_GetSensorSchedules_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetSensorSchedules'
+type MockLogicalstatemanagerDbAccess_GetSensorSchedules_Call struct {
+	*mock.Call
+}
+
+// GetSensorSchedules is a helper method to define mock.On call
+//   - id string
+func (_e *MockLogicalstatemanagerDbAccess_Expecter) GetSensorSchedules(id interface{}) *MockLogicalstatemanagerDbAccess_GetSensorSchedules_Call {
+	return &MockLogicalstatemanagerDbAccess_GetSensorSchedules_Call{Call: _e.mock.On("GetSensorSchedules", id)}
+}
+
+func (_c *MockLogicalstatemanagerDbAccess_GetSensorSchedules_Call) Run(run func(id string)) *MockLogicalstatemanagerDbAccess_GetSensorSchedules_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string))
+	})
+	return _c
+}
+
+func (_c *MockLogicalstatemanagerDbAccess_GetSensorSchedules_Call) Return(_a0 []string, _a1 error) *MockLogicalstatemanagerDbAccess_GetSensorSchedules_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockLogicalstatemanagerDbAccess_GetSensorSchedules_Call) RunAndReturn(run func(string) ([]string, error)) *MockLogicalstatemanagerDbAccess_GetSensorSchedules_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // IsScheduledLight provides a mock function with given fields: lsID
 func (_m *MockLogicalstatemanagerDbAccess) IsScheduledLight(lsID string) (bool, error) {
 	ret := _m.Called(lsID)
@@ -358,6 +742,49 @@ func (_c *MockLogicalstatemanagerDbAccess_IsScheduledLight_Call) RunAndReturn(ru
 	return _c
 }
 
+// SetAmbientLux provides a mock function with given fields: scheduleName, lux
+func (_m *MockLogicalstatemanagerDbAccess) SetAmbientLux(scheduleName string, lux float64) error {
+	ret := _m.Called(scheduleName, lux)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, float64) error); ok {
+		r0 = rf(scheduleName, lux)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockLogicalstatemanagerDbAccess_SetAmbientLux_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SetAmbientLux'
+type MockLogicalstatemanagerDbAccess_SetAmbientLux_Call struct {
+	*mock.Call
+}
+
+// SetAmbientLux is a helper method to define mock.On call
+//   - scheduleName string
+//   - lux float64
+func (_e *MockLogicalstatemanagerDbAccess_Expecter) SetAmbientLux(scheduleName interface{}, lux interface{}) *MockLogicalstatemanagerDbAccess_SetAmbientLux_Call {
+	return &MockLogicalstatemanagerDbAccess_SetAmbientLux_Call{Call: _e.mock.On("SetAmbientLux", scheduleName, lux)}
+}
+
+func (_c *MockLogicalstatemanagerDbAccess_SetAmbientLux_Call) Run(run func(scheduleName string, lux float64)) *MockLogicalstatemanagerDbAccess_SetAmbientLux_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string), args[1].(float64))
+	})
+	return _c
+}
+
+func (_c *MockLogicalstatemanagerDbAccess_SetAmbientLux_Call) Return(_a0 error) *MockLogicalstatemanagerDbAccess_SetAmbientLux_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockLogicalstatemanagerDbAccess_SetAmbientLux_Call) RunAndReturn(run func(string, float64) error) *MockLogicalstatemanagerDbAccess_SetAmbientLux_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // SetLightBrightnessOverride provides a mock function with given fields: lsID, brightness, targetBrightness
 func (_m *MockLogicalstatemanagerDbAccess) SetLightBrightnessOverride(lsID string, brightness int, targetBrightness int) error {
 	ret := _m.Called(lsID, brightness, targetBrightness)
@@ -575,6 +1002,280 @@ func (_c *MockLogicalstatemanagerDbAccess_SetLightUnreachable_Call) RunAndReturn
 	return _c
 }
 
+// SetScheduleDisabled provides a mock function with given fields: scheduleName, disabled
+func (_m *MockLogicalstatemanagerDbAccess) SetScheduleDisabled(scheduleName string, disabled bool) error {
+	ret := _m.Called(scheduleName, disabled)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, bool) error); ok {
+		r0 = rf(scheduleName, disabled)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockLogicalstatemanagerDbAccess_SetScheduleDisabled_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SetScheduleDisabled'
+type MockLogicalstatemanagerDbAccess_SetScheduleDisabled_Call struct {
+	*mock.Call
+}
+
+// SetScheduleDisabled is a helper method to define mock.On call
+//   - scheduleName string
+//   - disabled bool
+func (_e *MockLogicalstatemanagerDbAccess_Expecter) SetScheduleDisabled(scheduleName interface{}, disabled interface{}) *MockLogicalstatemanagerDbAccess_SetScheduleDisabled_Call {
+	return &MockLogicalstatemanagerDbAccess_SetScheduleDisabled_Call{Call: _e.mock.On("SetScheduleDisabled", scheduleName, disabled)}
+}
+
+func (_c *MockLogicalstatemanagerDbAccess_SetScheduleDisabled_Call) Run(run func(scheduleName string, disabled bool)) *MockLogicalstatemanagerDbAccess_SetScheduleDisabled_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string), args[1].(bool))
+	})
+	return _c
+}
+
+func (_c *MockLogicalstatemanagerDbAccess_SetScheduleDisabled_Call) Return(_a0 error) *MockLogicalstatemanagerDbAccess_SetScheduleDisabled_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockLogicalstatemanagerDbAccess_SetScheduleDisabled_Call) RunAndReturn(run func(string, bool) error) *MockLogicalstatemanagerDbAccess_SetScheduleDisabled_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SyncBindings provides a mock function with given fields: bindings
+func (_m *MockLogicalstatemanagerDbAccess) SyncBindings(bindings []models.HughBinding) error {
+	ret := _m.Called(bindings)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func([]models.HughBinding) error); ok {
+		r0 = rf(bindings)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockLogicalstatemanagerDbAccess_SyncBindings_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SyncBindings'
+type MockLogicalstatemanagerDbAccess_SyncBindings_Call struct {
+	*mock.Call
+}
+
+// SyncBindings is a helper method to define mock.On call
+//   - bindings []models.HughBinding
+func (_e *MockLogicalstatemanagerDbAccess_Expecter) SyncBindings(bindings interface{}) *MockLogicalstatemanagerDbAccess_SyncBindings_Call {
+	return &MockLogicalstatemanagerDbAccess_SyncBindings_Call{Call: _e.mock.On("SyncBindings", bindings)}
+}
+
+func (_c *MockLogicalstatemanagerDbAccess_SyncBindings_Call) Run(run func(bindings []models.HughBinding)) *MockLogicalstatemanagerDbAccess_SyncBindings_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].([]models.HughBinding))
+	})
+	return _c
+}
+
+func (_c *MockLogicalstatemanagerDbAccess_SyncBindings_Call) Return(_a0 error) *MockLogicalstatemanagerDbAccess_SyncBindings_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockLogicalstatemanagerDbAccess_SyncBindings_Call) RunAndReturn(run func([]models.HughBinding) error) *MockLogicalstatemanagerDbAccess_SyncBindings_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SyncGroups provides a mock function with given fields: groups
+func (_m *MockLogicalstatemanagerDbAccess) SyncGroups(groups []models.HughGroup) error {
+	ret := _m.Called(groups)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func([]models.HughGroup) error); ok {
+		r0 = rf(groups)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockLogicalstatemanagerDbAccess_SyncGroups_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SyncGroups'
+type MockLogicalstatemanagerDbAccess_SyncGroups_Call struct {
+	*mock.Call
+}
+
+// SyncGroups is a helper method to define mock.On call
+//   - groups []models.HughGroup
+func (_e *MockLogicalstatemanagerDbAccess_Expecter) SyncGroups(groups interface{}) *MockLogicalstatemanagerDbAccess_SyncGroups_Call {
+	return &MockLogicalstatemanagerDbAccess_SyncGroups_Call{Call: _e.mock.On("SyncGroups", groups)}
+}
+
+func (_c *MockLogicalstatemanagerDbAccess_SyncGroups_Call) Run(run func(groups []models.HughGroup)) *MockLogicalstatemanagerDbAccess_SyncGroups_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].([]models.HughGroup))
+	})
+	return _c
+}
+
+func (_c *MockLogicalstatemanagerDbAccess_SyncGroups_Call) Return(_a0 error) *MockLogicalstatemanagerDbAccess_SyncGroups_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockLogicalstatemanagerDbAccess_SyncGroups_Call) RunAndReturn(run func([]models.HughGroup) error) *MockLogicalstatemanagerDbAccess_SyncGroups_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SyncLights provides a mock function with given fields: lights
+func (_m *MockLogicalstatemanagerDbAccess) SyncLights(lights []models.HughLight) ([]string, []string, error) {
+	ret := _m.Called(lights)
+
+	var r0 []string
+	var r1 []string
+	var r2 error
+	if rf, ok := ret.Get(0).(func([]models.HughLight) ([]string, []string, error)); ok {
+		return rf(lights)
+	}
+	if rf, ok := ret.Get(0).(func([]models.HughLight) []string); ok {
+		r0 = rf(lights)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func([]models.HughLight) []string); ok {
+		r1 = rf(lights)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(2).(func([]models.HughLight) error); ok {
+		r2 = rf(lights)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// MockLogicalstatemanagerDbAccess_SyncLights_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SyncLights'
+type MockLogicalstatemanagerDbAccess_SyncLights_Call struct {
+	*mock.Call
+}
+
+// SyncLights is a helper method to define mock.On call
+//   - lights []models.HughLight
+func (_e *MockLogicalstatemanagerDbAccess_Expecter) SyncLights(lights interface{}) *MockLogicalstatemanagerDbAccess_SyncLights_Call {
+	return &MockLogicalstatemanagerDbAccess_SyncLights_Call{Call: _e.mock.On("SyncLights", lights)}
+}
+
+func (_c *MockLogicalstatemanagerDbAccess_SyncLights_Call) Run(run func(lights []models.HughLight)) *MockLogicalstatemanagerDbAccess_SyncLights_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].([]models.HughLight))
+	})
+	return _c
+}
+
+func (_c *MockLogicalstatemanagerDbAccess_SyncLights_Call) Return(_a0 []string, _a1 []string, _a2 error) *MockLogicalstatemanagerDbAccess_SyncLights_Call {
+	_c.Call.Return(_a0, _a1, _a2)
+	return _c
+}
+
+func (_c *MockLogicalstatemanagerDbAccess_SyncLights_Call) RunAndReturn(run func([]models.HughLight) ([]string, []string, error)) *MockLogicalstatemanagerDbAccess_SyncLights_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SyncScenes provides a mock function with given fields: scenes
+func (_m *MockLogicalstatemanagerDbAccess) SyncScenes(scenes []models.HughScene) error {
+	ret := _m.Called(scenes)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func([]models.HughScene) error); ok {
+		r0 = rf(scenes)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockLogicalstatemanagerDbAccess_SyncScenes_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SyncScenes'
+type MockLogicalstatemanagerDbAccess_SyncScenes_Call struct {
+	*mock.Call
+}
+
+// SyncScenes is a helper method to define mock.On call
+//   - scenes []models.HughScene
+func (_e *MockLogicalstatemanagerDbAccess_Expecter) SyncScenes(scenes interface{}) *MockLogicalstatemanagerDbAccess_SyncScenes_Call {
+	return &MockLogicalstatemanagerDbAccess_SyncScenes_Call{Call: _e.mock.On("SyncScenes", scenes)}
+}
+
+func (_c *MockLogicalstatemanagerDbAccess_SyncScenes_Call) Run(run func(scenes []models.HughScene)) *MockLogicalstatemanagerDbAccess_SyncScenes_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].([]models.HughScene))
+	})
+	return _c
+}
+
+func (_c *MockLogicalstatemanagerDbAccess_SyncScenes_Call) Return(_a0 error) *MockLogicalstatemanagerDbAccess_SyncScenes_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockLogicalstatemanagerDbAccess_SyncScenes_Call) RunAndReturn(run func([]models.HughScene) error) *MockLogicalstatemanagerDbAccess_SyncScenes_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SyncSensors provides a mock function with given fields: sensors
+func (_m *MockLogicalstatemanagerDbAccess) SyncSensors(sensors []models.HughSensor) error {
+	ret := _m.Called(sensors)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func([]models.HughSensor) error); ok {
+		r0 = rf(sensors)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockLogicalstatemanagerDbAccess_SyncSensors_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SyncSensors'
+type MockLogicalstatemanagerDbAccess_SyncSensors_Call struct {
+	*mock.Call
+}
+
+// SyncSensors is a helper method to define mock.On call
+//   - sensors []models.HughSensor
+func (_e *MockLogicalstatemanagerDbAccess_Expecter) SyncSensors(sensors interface{}) *MockLogicalstatemanagerDbAccess_SyncSensors_Call {
+	return &MockLogicalstatemanagerDbAccess_SyncSensors_Call{Call: _e.mock.On("SyncSensors", sensors)}
+}
+
+func (_c *MockLogicalstatemanagerDbAccess_SyncSensors_Call) Run(run func(sensors []models.HughSensor)) *MockLogicalstatemanagerDbAccess_SyncSensors_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].([]models.HughSensor))
+	})
+	return _c
+}
+
+func (_c *MockLogicalstatemanagerDbAccess_SyncSensors_Call) Return(_a0 error) *MockLogicalstatemanagerDbAccess_SyncSensors_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockLogicalstatemanagerDbAccess_SyncSensors_Call) RunAndReturn(run func([]models.HughSensor) error) *MockLogicalstatemanagerDbAccess_SyncSensors_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // UpdateTargetState provides a mock function with given fields: scheduleName, target
 func (_m *MockLogicalstatemanagerDbAccess) UpdateTargetState(scheduleName string, target models.LightState) error {
 	ret := _m.Called(scheduleName, target)
@@ -3,9 +3,9 @@
 package mocks
 
 import (
-	time "time"
-
+	context "context"
 	mock "github.com/stretchr/testify/mock"
+	time "time"
 )
 
 // MockLogicalstatemanagerLightStateSetter is an autogenerated mock type for the lightStateSetter type
@@ -21,13 +21,58 @@ func (_m *MockLogicalstatemanagerLightStateSetter) EXPECT() *MockLogicalstateman
 	return &MockLogicalstatemanagerLightStateSetter_Expecter{mock: &_m.Mock}
 }
 
-// SetLightStateToTarget provides a mock function with given fields: lsID, currentTime
-func (_m *MockLogicalstatemanagerLightStateSetter) SetLightStateToTarget(lsID string, currentTime time.Time) error {
-	ret := _m.Called(lsID, currentTime)
+// FadeLightToTarget provides a mock function with given fields: ctx, lsID, currentTime, transition
+func (_m *MockLogicalstatemanagerLightStateSetter) FadeLightToTarget(ctx context.Context, lsID string, currentTime time.Time, transition time.Duration) error {
+	ret := _m.Called(ctx, lsID, currentTime, transition)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Time, time.Duration) error); ok {
+		r0 = rf(ctx, lsID, currentTime, transition)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockLogicalstatemanagerLightStateSetter_FadeLightToTarget_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'FadeLightToTarget'
+type MockLogicalstatemanagerLightStateSetter_FadeLightToTarget_Call struct {
+	*mock.Call
+}
+
+// FadeLightToTarget is a helper method to define mock.On call
+//   - ctx context.Context
+//   - lsID string
+//   - currentTime time.Time
+//   - transition time.Duration
+func (_e *MockLogicalstatemanagerLightStateSetter_Expecter) FadeLightToTarget(ctx interface{}, lsID interface{}, currentTime interface{}, transition interface{}) *MockLogicalstatemanagerLightStateSetter_FadeLightToTarget_Call {
+	return &MockLogicalstatemanagerLightStateSetter_FadeLightToTarget_Call{Call: _e.mock.On("FadeLightToTarget", ctx, lsID, currentTime, transition)}
+}
+
+func (_c *MockLogicalstatemanagerLightStateSetter_FadeLightToTarget_Call) Run(run func(ctx context.Context, lsID string, currentTime time.Time, transition time.Duration)) *MockLogicalstatemanagerLightStateSetter_FadeLightToTarget_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(time.Time), args[3].(time.Duration))
+	})
+	return _c
+}
+
+func (_c *MockLogicalstatemanagerLightStateSetter_FadeLightToTarget_Call) Return(_a0 error) *MockLogicalstatemanagerLightStateSetter_FadeLightToTarget_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockLogicalstatemanagerLightStateSetter_FadeLightToTarget_Call) RunAndReturn(run func(context.Context, string, time.Time, time.Duration) error) *MockLogicalstatemanagerLightStateSetter_FadeLightToTarget_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SetLightStateToTarget provides a mock function with given fields: ctx, lsID, currentTime
+func (_m *MockLogicalstatemanagerLightStateSetter) SetLightStateToTarget(ctx context.Context, lsID string, currentTime time.Time) error {
+	ret := _m.Called(ctx, lsID, currentTime)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(string, time.Time) error); ok {
-		r0 = rf(lsID, currentTime)
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Time) error); ok {
+		r0 = rf(ctx, lsID, currentTime)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -41,15 +86,16 @@ type MockLogicalstatemanagerLightStateSetter_SetLightStateToTarget_Call struct {
 }
 
 // SetLightStateToTarget is a helper method to define mock.On call
+//   - ctx context.Context
 //   - lsID string
 //   - currentTime time.Time
-func (_e *MockLogicalstatemanagerLightStateSetter_Expecter) SetLightStateToTarget(lsID interface{}, currentTime interface{}) *MockLogicalstatemanagerLightStateSetter_SetLightStateToTarget_Call {
-	return &MockLogicalstatemanagerLightStateSetter_SetLightStateToTarget_Call{Call: _e.mock.On("SetLightStateToTarget", lsID, currentTime)}
+func (_e *MockLogicalstatemanagerLightStateSetter_Expecter) SetLightStateToTarget(ctx interface{}, lsID interface{}, currentTime interface{}) *MockLogicalstatemanagerLightStateSetter_SetLightStateToTarget_Call {
+	return &MockLogicalstatemanagerLightStateSetter_SetLightStateToTarget_Call{Call: _e.mock.On("SetLightStateToTarget", ctx, lsID, currentTime)}
 }
 
-func (_c *MockLogicalstatemanagerLightStateSetter_SetLightStateToTarget_Call) Run(run func(lsID string, currentTime time.Time)) *MockLogicalstatemanagerLightStateSetter_SetLightStateToTarget_Call {
+func (_c *MockLogicalstatemanagerLightStateSetter_SetLightStateToTarget_Call) Run(run func(ctx context.Context, lsID string, currentTime time.Time)) *MockLogicalstatemanagerLightStateSetter_SetLightStateToTarget_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(string), args[1].(time.Time))
+		run(args[0].(context.Context), args[1].(string), args[2].(time.Time))
 	})
 	return _c
 }
@@ -59,7 +105,7 @@ func (_c *MockLogicalstatemanagerLightStateSetter_SetLightStateToTarget_Call) Re
 	return _c
 }
 
-func (_c *MockLogicalstatemanagerLightStateSetter_SetLightStateToTarget_Call) RunAndReturn(run func(string, time.Time) error) *MockLogicalstatemanagerLightStateSetter_SetLightStateToTarget_Call {
+func (_c *MockLogicalstatemanagerLightStateSetter_SetLightStateToTarget_Call) RunAndReturn(run func(context.Context, string, time.Time) error) *MockLogicalstatemanagerLightStateSetter_SetLightStateToTarget_Call {
 	_c.Call.Return(run)
 	return _c
 }
@@ -73,6 +73,59 @@ func (_c *MockPhysicalstatemanagerDbAccess_GetAllControllingLightIDs_Call) RunAn
 	return _c
 }
 
+// GetAllLightIDs provides a mock function with given fields:
+func (_m *MockPhysicalstatemanagerDbAccess) GetAllLightIDs() ([]string, error) {
+	ret := _m.Called()
+
+	var r0 []string
+	var r1 error
+	if rf, ok := ret.Get(0).(func() ([]string, error)); ok {
+		return rf()
+	}
+	if rf, ok := ret.Get(0).(func() []string); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockPhysicalstatemanagerDbAccess_GetAllLightIDs_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetAllLightIDs'
+type MockPhysicalstatemanagerDbAccess_GetAllLightIDs_Call struct {
+	*mock.Call
+}
+
+// GetAllLightIDs is a helper method to define mock.On call
+func (_e *MockPhysicalstatemanagerDbAccess_Expecter) GetAllLightIDs() *MockPhysicalstatemanagerDbAccess_GetAllLightIDs_Call {
+	return &MockPhysicalstatemanagerDbAccess_GetAllLightIDs_Call{Call: _e.mock.On("GetAllLightIDs")}
+}
+
+func (_c *MockPhysicalstatemanagerDbAccess_GetAllLightIDs_Call) Run(run func()) *MockPhysicalstatemanagerDbAccess_GetAllLightIDs_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *MockPhysicalstatemanagerDbAccess_GetAllLightIDs_Call) Return(_a0 []string, _a1 error) *MockPhysicalstatemanagerDbAccess_GetAllLightIDs_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockPhysicalstatemanagerDbAccess_GetAllLightIDs_Call) RunAndReturn(run func() ([]string, error)) *MockPhysicalstatemanagerDbAccess_GetAllLightIDs_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // GetAllSceneIDs provides a mock function with given fields:
 func (_m *MockPhysicalstatemanagerDbAccess) GetAllSceneIDs() ([]string, error) {
 	ret := _m.Called()
@@ -126,6 +179,112 @@ func (_c *MockPhysicalstatemanagerDbAccess_GetAllSceneIDs_Call) RunAndReturn(run
 	return _c
 }
 
+// GetGroupedLights provides a mock function with given fields:
+func (_m *MockPhysicalstatemanagerDbAccess) GetGroupedLights() ([]models.HughGroup, error) {
+	ret := _m.Called()
+
+	var r0 []models.HughGroup
+	var r1 error
+	if rf, ok := ret.Get(0).(func() ([]models.HughGroup, error)); ok {
+		return rf()
+	}
+	if rf, ok := ret.Get(0).(func() []models.HughGroup); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.HughGroup)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockPhysicalstatemanagerDbAccess_GetGroupedLights_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetGroupedLights'
+type MockPhysicalstatemanagerDbAccess_GetGroupedLights_Call struct {
+	*mock.Call
+}
+
+// GetGroupedLights is a helper method to define mock.On call
+func (_e *MockPhysicalstatemanagerDbAccess_Expecter) GetGroupedLights() *MockPhysicalstatemanagerDbAccess_GetGroupedLights_Call {
+	return &MockPhysicalstatemanagerDbAccess_GetGroupedLights_Call{Call: _e.mock.On("GetGroupedLights")}
+}
+
+func (_c *MockPhysicalstatemanagerDbAccess_GetGroupedLights_Call) Run(run func()) *MockPhysicalstatemanagerDbAccess_GetGroupedLights_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *MockPhysicalstatemanagerDbAccess_GetGroupedLights_Call) Return(_a0 []models.HughGroup, _a1 error) *MockPhysicalstatemanagerDbAccess_GetGroupedLights_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockPhysicalstatemanagerDbAccess_GetGroupedLights_Call) RunAndReturn(run func() ([]models.HughGroup, error)) *MockPhysicalstatemanagerDbAccess_GetGroupedLights_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetLightStatuses provides a mock function with given fields:
+func (_m *MockPhysicalstatemanagerDbAccess) GetLightStatuses() ([]models.LightStatus, error) {
+	ret := _m.Called()
+
+	var r0 []models.LightStatus
+	var r1 error
+	if rf, ok := ret.Get(0).(func() ([]models.LightStatus, error)); ok {
+		return rf()
+	}
+	if rf, ok := ret.Get(0).(func() []models.LightStatus); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.LightStatus)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockPhysicalstatemanagerDbAccess_GetLightStatuses_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetLightStatuses'
+type MockPhysicalstatemanagerDbAccess_GetLightStatuses_Call struct {
+	*mock.Call
+}
+
+// GetLightStatuses is a helper method to define mock.On call
+func (_e *MockPhysicalstatemanagerDbAccess_Expecter) GetLightStatuses() *MockPhysicalstatemanagerDbAccess_GetLightStatuses_Call {
+	return &MockPhysicalstatemanagerDbAccess_GetLightStatuses_Call{Call: _e.mock.On("GetLightStatuses")}
+}
+
+func (_c *MockPhysicalstatemanagerDbAccess_GetLightStatuses_Call) Run(run func()) *MockPhysicalstatemanagerDbAccess_GetLightStatuses_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *MockPhysicalstatemanagerDbAccess_GetLightStatuses_Call) Return(_a0 []models.LightStatus, _a1 error) *MockPhysicalstatemanagerDbAccess_GetLightStatuses_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockPhysicalstatemanagerDbAccess_GetLightStatuses_Call) RunAndReturn(run func() ([]models.LightStatus, error)) *MockPhysicalstatemanagerDbAccess_GetLightStatuses_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // GetLightTargetState provides a mock function with given fields: lsID
 func (_m *MockPhysicalstatemanagerDbAccess) GetLightTargetState(lsID string) (models.LightState, error) {
 	ret := _m.Called(lsID)
@@ -272,6 +431,48 @@ func (_c *MockPhysicalstatemanagerDbAccess_MarkLightAsUpdated_Call) RunAndReturn
 	return _c
 }
 
+// RemoveLight provides a mock function with given fields: lsID
+func (_m *MockPhysicalstatemanagerDbAccess) RemoveLight(lsID string) error {
+	ret := _m.Called(lsID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(lsID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockPhysicalstatemanagerDbAccess_RemoveLight_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RemoveLight'
+type MockPhysicalstatemanagerDbAccess_RemoveLight_Call struct {
+	*mock.Call
+}
+
+// RemoveLight is a helper method to define mock.On call
+//   - lsID string
+func (_e *MockPhysicalstatemanagerDbAccess_Expecter) RemoveLight(lsID interface{}) *MockPhysicalstatemanagerDbAccess_RemoveLight_Call {
+	return &MockPhysicalstatemanagerDbAccess_RemoveLight_Call{Call: _e.mock.On("RemoveLight", lsID)}
+}
+
+func (_c *MockPhysicalstatemanagerDbAccess_RemoveLight_Call) Run(run func(lsID string)) *MockPhysicalstatemanagerDbAccess_RemoveLight_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string))
+	})
+	return _c
+}
+
+func (_c *MockPhysicalstatemanagerDbAccess_RemoveLight_Call) Return(_a0 error) *MockPhysicalstatemanagerDbAccess_RemoveLight_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockPhysicalstatemanagerDbAccess_RemoveLight_Call) RunAndReturn(run func(string) error) *MockPhysicalstatemanagerDbAccess_RemoveLight_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // SetLightUnreachable provides a mock function with given fields: lsID
 func (_m *MockPhysicalstatemanagerDbAccess) SetLightUnreachable(lsID string) error {
 	ret := _m.Called(lsID)
@@ -3,9 +3,10 @@
 package mocks
 
 import (
+	context "context"
+	sse "github.com/r3labs/sse/v2"
 	mock "github.com/stretchr/testify/mock"
 	hue "github.com/wheelibin/hugh/internal/hue"
-
 	models "github.com/wheelibin/hugh/internal/models"
 )
 
@@ -22,6 +23,147 @@ func (_m *MockPhysicalstatemanagerHueApiService) EXPECT() *MockPhysicalstatemana
 	return &MockPhysicalstatemanagerHueApiService_Expecter{mock: &_m.Mock}
 }
 
+// ApplyEvent provides a mock function with given fields: event
+func (_m *MockPhysicalstatemanagerHueApiService) ApplyEvent(event *sse.Event) {
+	_m.Called(event)
+}
+
+// MockPhysicalstatemanagerHueApiService_ApplyEvent_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ApplyEvent'
+type MockPhysicalstatemanagerHueApiService_ApplyEvent_Call struct {
+	*mock.Call
+}
+
+// ApplyEvent is a helper method to define mock.On call
+//   - event *sse.Event
+func (_e *MockPhysicalstatemanagerHueApiService_Expecter) ApplyEvent(event interface{}) *MockPhysicalstatemanagerHueApiService_ApplyEvent_Call {
+	return &MockPhysicalstatemanagerHueApiService_ApplyEvent_Call{Call: _e.mock.On("ApplyEvent", event)}
+}
+
+func (_c *MockPhysicalstatemanagerHueApiService_ApplyEvent_Call) Run(run func(event *sse.Event)) *MockPhysicalstatemanagerHueApiService_ApplyEvent_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(*sse.Event))
+	})
+	return _c
+}
+
+func (_c *MockPhysicalstatemanagerHueApiService_ApplyEvent_Call) Return() *MockPhysicalstatemanagerHueApiService_ApplyEvent_Call {
+	_c.Call.Return()
+	return _c
+}
+
+func (_c *MockPhysicalstatemanagerHueApiService_ApplyEvent_Call) RunAndReturn(run func(*sse.Event)) *MockPhysicalstatemanagerHueApiService_ApplyEvent_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// DiscoverBindings provides a mock function with given fields: bindings
+func (_m *MockPhysicalstatemanagerHueApiService) DiscoverBindings(bindings []models.Binding) ([]models.HughBinding, error) {
+	ret := _m.Called(bindings)
+
+	var r0 []models.HughBinding
+	var r1 error
+	if rf, ok := ret.Get(0).(func([]models.Binding) ([]models.HughBinding, error)); ok {
+		return rf(bindings)
+	}
+	if rf, ok := ret.Get(0).(func([]models.Binding) []models.HughBinding); ok {
+		r0 = rf(bindings)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.HughBinding)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func([]models.Binding) error); ok {
+		r1 = rf(bindings)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockPhysicalstatemanagerHueApiService_DiscoverBindings_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DiscoverBindings'
+type MockPhysicalstatemanagerHueApiService_DiscoverBindings_Call struct {
+	*mock.Call
+}
+
+// DiscoverBindings is a helper method to define mock.On call
+//   - bindings []models.Binding
+func (_e *MockPhysicalstatemanagerHueApiService_Expecter) DiscoverBindings(bindings interface{}) *MockPhysicalstatemanagerHueApiService_DiscoverBindings_Call {
+	return &MockPhysicalstatemanagerHueApiService_DiscoverBindings_Call{Call: _e.mock.On("DiscoverBindings", bindings)}
+}
+
+func (_c *MockPhysicalstatemanagerHueApiService_DiscoverBindings_Call) Run(run func(bindings []models.Binding)) *MockPhysicalstatemanagerHueApiService_DiscoverBindings_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].([]models.Binding))
+	})
+	return _c
+}
+
+func (_c *MockPhysicalstatemanagerHueApiService_DiscoverBindings_Call) Return(_a0 []models.HughBinding, _a1 error) *MockPhysicalstatemanagerHueApiService_DiscoverBindings_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockPhysicalstatemanagerHueApiService_DiscoverBindings_Call) RunAndReturn(run func([]models.Binding) ([]models.HughBinding, error)) *MockPhysicalstatemanagerHueApiService_DiscoverBindings_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// DiscoverGroups provides a mock function with given fields: schedules
+func (_m *MockPhysicalstatemanagerHueApiService) DiscoverGroups(schedules []models.Schedule) ([]models.HughGroup, error) {
+	ret := _m.Called(schedules)
+
+	var r0 []models.HughGroup
+	var r1 error
+	if rf, ok := ret.Get(0).(func([]models.Schedule) ([]models.HughGroup, error)); ok {
+		return rf(schedules)
+	}
+	if rf, ok := ret.Get(0).(func([]models.Schedule) []models.HughGroup); ok {
+		r0 = rf(schedules)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.HughGroup)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func([]models.Schedule) error); ok {
+		r1 = rf(schedules)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockPhysicalstatemanagerHueApiService_DiscoverGroups_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DiscoverGroups'
+type MockPhysicalstatemanagerHueApiService_DiscoverGroups_Call struct {
+	*mock.Call
+}
+
+// DiscoverGroups is a helper method to define mock.On call
+//   - schedules []models.Schedule
+func (_e *MockPhysicalstatemanagerHueApiService_Expecter) DiscoverGroups(schedules interface{}) *MockPhysicalstatemanagerHueApiService_DiscoverGroups_Call {
+	return &MockPhysicalstatemanagerHueApiService_DiscoverGroups_Call{Call: _e.mock.On("DiscoverGroups", schedules)}
+}
+
+func (_c *MockPhysicalstatemanagerHueApiService_DiscoverGroups_Call) Run(run func(schedules []models.Schedule)) *MockPhysicalstatemanagerHueApiService_DiscoverGroups_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].([]models.Schedule))
+	})
+	return _c
+}
+
+func (_c *MockPhysicalstatemanagerHueApiService_DiscoverGroups_Call) Return(_a0 []models.HughGroup, _a1 error) *MockPhysicalstatemanagerHueApiService_DiscoverGroups_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockPhysicalstatemanagerHueApiService_DiscoverGroups_Call) RunAndReturn(run func([]models.Schedule) ([]models.HughGroup, error)) *MockPhysicalstatemanagerHueApiService_DiscoverGroups_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // DiscoverLights provides a mock function with given fields: schedules
 func (_m *MockPhysicalstatemanagerHueApiService) DiscoverLights(schedules []models.Schedule) ([]models.HughLight, error) {
 	ret := _m.Called(schedules)
@@ -76,6 +218,113 @@ func (_c *MockPhysicalstatemanagerHueApiService_DiscoverLights_Call) RunAndRetur
 	return _c
 }
 
+// DiscoverSensors provides a mock function with given fields: schedules
+func (_m *MockPhysicalstatemanagerHueApiService) DiscoverSensors(schedules []models.Schedule) ([]models.HughSensor, error) {
+	ret := _m.Called(schedules)
+
+	var r0 []models.HughSensor
+	var r1 error
+	if rf, ok := ret.Get(0).(func([]models.Schedule) ([]models.HughSensor, error)); ok {
+		return rf(schedules)
+	}
+	if rf, ok := ret.Get(0).(func([]models.Schedule) []models.HughSensor); ok {
+		r0 = rf(schedules)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.HughSensor)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func([]models.Schedule) error); ok {
+		r1 = rf(schedules)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockPhysicalstatemanagerHueApiService_DiscoverSensors_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DiscoverSensors'
+type MockPhysicalstatemanagerHueApiService_DiscoverSensors_Call struct {
+	*mock.Call
+}
+
+// DiscoverSensors is a helper method to define mock.On call
+//   - schedules []models.Schedule
+func (_e *MockPhysicalstatemanagerHueApiService_Expecter) DiscoverSensors(schedules interface{}) *MockPhysicalstatemanagerHueApiService_DiscoverSensors_Call {
+	return &MockPhysicalstatemanagerHueApiService_DiscoverSensors_Call{Call: _e.mock.On("DiscoverSensors", schedules)}
+}
+
+func (_c *MockPhysicalstatemanagerHueApiService_DiscoverSensors_Call) Run(run func(schedules []models.Schedule)) *MockPhysicalstatemanagerHueApiService_DiscoverSensors_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].([]models.Schedule))
+	})
+	return _c
+}
+
+func (_c *MockPhysicalstatemanagerHueApiService_DiscoverSensors_Call) Return(_a0 []models.HughSensor, _a1 error) *MockPhysicalstatemanagerHueApiService_DiscoverSensors_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockPhysicalstatemanagerHueApiService_DiscoverSensors_Call) RunAndReturn(run func([]models.Schedule) ([]models.HughSensor, error)) *MockPhysicalstatemanagerHueApiService_DiscoverSensors_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetLightStatuses provides a mock function with given fields:
+func (_m *MockPhysicalstatemanagerHueApiService) GetLightStatuses() ([]models.LightStatus, error) {
+	ret := _m.Called()
+
+	var r0 []models.LightStatus
+	var r1 error
+	if rf, ok := ret.Get(0).(func() ([]models.LightStatus, error)); ok {
+		return rf()
+	}
+	if rf, ok := ret.Get(0).(func() []models.LightStatus); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.LightStatus)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockPhysicalstatemanagerHueApiService_GetLightStatuses_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetLightStatuses'
+type MockPhysicalstatemanagerHueApiService_GetLightStatuses_Call struct {
+	*mock.Call
+}
+
+// GetLightStatuses is a helper method to define mock.On call
+func (_e *MockPhysicalstatemanagerHueApiService_Expecter) GetLightStatuses() *MockPhysicalstatemanagerHueApiService_GetLightStatuses_Call {
+	return &MockPhysicalstatemanagerHueApiService_GetLightStatuses_Call{Call: _e.mock.On("GetLightStatuses")}
+}
+
+func (_c *MockPhysicalstatemanagerHueApiService_GetLightStatuses_Call) Run(run func()) *MockPhysicalstatemanagerHueApiService_GetLightStatuses_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *MockPhysicalstatemanagerHueApiService_GetLightStatuses_Call) Return(_a0 []models.LightStatus, _a1 error) *MockPhysicalstatemanagerHueApiService_GetLightStatuses_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockPhysicalstatemanagerHueApiService_GetLightStatuses_Call) RunAndReturn(run func() ([]models.LightStatus, error)) *MockPhysicalstatemanagerHueApiService_GetLightStatuses_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // GetScenes provides a mock function with given fields:
 func (_m *MockPhysicalstatemanagerHueApiService) GetScenes() ([]hue.HueScene, error) {
 	ret := _m.Called()
@@ -129,13 +378,185 @@ func (_c *MockPhysicalstatemanagerHueApiService_GetScenes_Call) RunAndReturn(run
 	return _c
 }
 
-// UpdateLightState provides a mock function with given fields: lsID, targetState
-func (_m *MockPhysicalstatemanagerHueApiService) UpdateLightState(lsID string, targetState models.LightState) error {
-	ret := _m.Called(lsID, targetState)
+// LoadResources provides a mock function with given fields:
+func (_m *MockPhysicalstatemanagerHueApiService) LoadResources() error {
+	ret := _m.Called()
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func() error); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockPhysicalstatemanagerHueApiService_LoadResources_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'LoadResources'
+type MockPhysicalstatemanagerHueApiService_LoadResources_Call struct {
+	*mock.Call
+}
+
+// LoadResources is a helper method to define mock.On call
+func (_e *MockPhysicalstatemanagerHueApiService_Expecter) LoadResources() *MockPhysicalstatemanagerHueApiService_LoadResources_Call {
+	return &MockPhysicalstatemanagerHueApiService_LoadResources_Call{Call: _e.mock.On("LoadResources")}
+}
+
+func (_c *MockPhysicalstatemanagerHueApiService_LoadResources_Call) Run(run func()) *MockPhysicalstatemanagerHueApiService_LoadResources_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *MockPhysicalstatemanagerHueApiService_LoadResources_Call) Return(_a0 error) *MockPhysicalstatemanagerHueApiService_LoadResources_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockPhysicalstatemanagerHueApiService_LoadResources_Call) RunAndReturn(run func() error) *MockPhysicalstatemanagerHueApiService_LoadResources_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewEventStreamClient provides a mock function with given fields:
+func (_m *MockPhysicalstatemanagerHueApiService) NewEventStreamClient() *sse.Client {
+	ret := _m.Called()
+
+	var r0 *sse.Client
+	if rf, ok := ret.Get(0).(func() *sse.Client); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*sse.Client)
+		}
+	}
+
+	return r0
+}
+
+// MockPhysicalstatemanagerHueApiService_NewEventStreamClient_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'NewEventStreamClient'
+type MockPhysicalstatemanagerHueApiService_NewEventStreamClient_Call struct {
+	*mock.Call
+}
+
+// NewEventStreamClient is a helper method to define mock.On call
+func (_e *MockPhysicalstatemanagerHueApiService_Expecter) NewEventStreamClient() *MockPhysicalstatemanagerHueApiService_NewEventStreamClient_Call {
+	return &MockPhysicalstatemanagerHueApiService_NewEventStreamClient_Call{Call: _e.mock.On("NewEventStreamClient")}
+}
+
+func (_c *MockPhysicalstatemanagerHueApiService_NewEventStreamClient_Call) Run(run func()) *MockPhysicalstatemanagerHueApiService_NewEventStreamClient_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *MockPhysicalstatemanagerHueApiService_NewEventStreamClient_Call) Return(_a0 *sse.Client) *MockPhysicalstatemanagerHueApiService_NewEventStreamClient_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockPhysicalstatemanagerHueApiService_NewEventStreamClient_Call) RunAndReturn(run func() *sse.Client) *MockPhysicalstatemanagerHueApiService_NewEventStreamClient_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// UpdateGroupedLightState provides a mock function with given fields: ctx, groupedLightID, targetState
+func (_m *MockPhysicalstatemanagerHueApiService) UpdateGroupedLightState(ctx context.Context, groupedLightID string, targetState models.LightState) error {
+	ret := _m.Called(ctx, groupedLightID, targetState)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, models.LightState) error); ok {
+		r0 = rf(ctx, groupedLightID, targetState)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockPhysicalstatemanagerHueApiService_UpdateGroupedLightState_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpdateGroupedLightState'
+type MockPhysicalstatemanagerHueApiService_UpdateGroupedLightState_Call struct {
+	*mock.Call
+}
+
+// UpdateGroupedLightState is a helper method to define mock.On call
+//   - ctx context.Context
+//   - groupedLightID string
+//   - targetState models.LightState
+func (_e *MockPhysicalstatemanagerHueApiService_Expecter) UpdateGroupedLightState(ctx interface{}, groupedLightID interface{}, targetState interface{}) *MockPhysicalstatemanagerHueApiService_UpdateGroupedLightState_Call {
+	return &MockPhysicalstatemanagerHueApiService_UpdateGroupedLightState_Call{Call: _e.mock.On("UpdateGroupedLightState", ctx, groupedLightID, targetState)}
+}
+
+func (_c *MockPhysicalstatemanagerHueApiService_UpdateGroupedLightState_Call) Run(run func(ctx context.Context, groupedLightID string, targetState models.LightState)) *MockPhysicalstatemanagerHueApiService_UpdateGroupedLightState_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(models.LightState))
+	})
+	return _c
+}
+
+func (_c *MockPhysicalstatemanagerHueApiService_UpdateGroupedLightState_Call) Return(_a0 error) *MockPhysicalstatemanagerHueApiService_UpdateGroupedLightState_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockPhysicalstatemanagerHueApiService_UpdateGroupedLightState_Call) RunAndReturn(run func(context.Context, string, models.LightState) error) *MockPhysicalstatemanagerHueApiService_UpdateGroupedLightState_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// UpdateLightPowerup provides a mock function with given fields: ctx, lsID, targetState
+func (_m *MockPhysicalstatemanagerHueApiService) UpdateLightPowerup(ctx context.Context, lsID string, targetState models.LightState) error {
+	ret := _m.Called(ctx, lsID, targetState)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, models.LightState) error); ok {
+		r0 = rf(ctx, lsID, targetState)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockPhysicalstatemanagerHueApiService_UpdateLightPowerup_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpdateLightPowerup'
+type MockPhysicalstatemanagerHueApiService_UpdateLightPowerup_Call struct {
+	*mock.Call
+}
+
+// UpdateLightPowerup is a helper method to define mock.On call
+//   - ctx context.Context
+//   - lsID string
+//   - targetState models.LightState
+func (_e *MockPhysicalstatemanagerHueApiService_Expecter) UpdateLightPowerup(ctx interface{}, lsID interface{}, targetState interface{}) *MockPhysicalstatemanagerHueApiService_UpdateLightPowerup_Call {
+	return &MockPhysicalstatemanagerHueApiService_UpdateLightPowerup_Call{Call: _e.mock.On("UpdateLightPowerup", ctx, lsID, targetState)}
+}
+
+func (_c *MockPhysicalstatemanagerHueApiService_UpdateLightPowerup_Call) Run(run func(ctx context.Context, lsID string, targetState models.LightState)) *MockPhysicalstatemanagerHueApiService_UpdateLightPowerup_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(models.LightState))
+	})
+	return _c
+}
+
+func (_c *MockPhysicalstatemanagerHueApiService_UpdateLightPowerup_Call) Return(_a0 error) *MockPhysicalstatemanagerHueApiService_UpdateLightPowerup_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockPhysicalstatemanagerHueApiService_UpdateLightPowerup_Call) RunAndReturn(run func(context.Context, string, models.LightState) error) *MockPhysicalstatemanagerHueApiService_UpdateLightPowerup_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// UpdateLightState provides a mock function with given fields: ctx, lsID, targetState
+func (_m *MockPhysicalstatemanagerHueApiService) UpdateLightState(ctx context.Context, lsID string, targetState models.LightState) error {
+	ret := _m.Called(ctx, lsID, targetState)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(string, models.LightState) error); ok {
-		r0 = rf(lsID, targetState)
+	if rf, ok := ret.Get(0).(func(context.Context, string, models.LightState) error); ok {
+		r0 = rf(ctx, lsID, targetState)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -149,15 +570,16 @@ type MockPhysicalstatemanagerHueApiService_UpdateLightState_Call struct {
 }
 
 // UpdateLightState is a helper method to define mock.On call
+//   - ctx context.Context
 //   - lsID string
 //   - targetState models.LightState
-func (_e *MockPhysicalstatemanagerHueApiService_Expecter) UpdateLightState(lsID interface{}, targetState interface{}) *MockPhysicalstatemanagerHueApiService_UpdateLightState_Call {
-	return &MockPhysicalstatemanagerHueApiService_UpdateLightState_Call{Call: _e.mock.On("UpdateLightState", lsID, targetState)}
+func (_e *MockPhysicalstatemanagerHueApiService_Expecter) UpdateLightState(ctx interface{}, lsID interface{}, targetState interface{}) *MockPhysicalstatemanagerHueApiService_UpdateLightState_Call {
+	return &MockPhysicalstatemanagerHueApiService_UpdateLightState_Call{Call: _e.mock.On("UpdateLightState", ctx, lsID, targetState)}
 }
 
-func (_c *MockPhysicalstatemanagerHueApiService_UpdateLightState_Call) Run(run func(lsID string, targetState models.LightState)) *MockPhysicalstatemanagerHueApiService_UpdateLightState_Call {
+func (_c *MockPhysicalstatemanagerHueApiService_UpdateLightState_Call) Run(run func(ctx context.Context, lsID string, targetState models.LightState)) *MockPhysicalstatemanagerHueApiService_UpdateLightState_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(string), args[1].(models.LightState))
+		run(args[0].(context.Context), args[1].(string), args[2].(models.LightState))
 	})
 	return _c
 }
@@ -167,18 +589,18 @@ func (_c *MockPhysicalstatemanagerHueApiService_UpdateLightState_Call) Return(_a
 	return _c
 }
 
-func (_c *MockPhysicalstatemanagerHueApiService_UpdateLightState_Call) RunAndReturn(run func(string, models.LightState) error) *MockPhysicalstatemanagerHueApiService_UpdateLightState_Call {
+func (_c *MockPhysicalstatemanagerHueApiService_UpdateLightState_Call) RunAndReturn(run func(context.Context, string, models.LightState) error) *MockPhysicalstatemanagerHueApiService_UpdateLightState_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// UpdateSceneState provides a mock function with given fields: ID, targetState
-func (_m *MockPhysicalstatemanagerHueApiService) UpdateSceneState(ID string, targetState models.LightState) error {
-	ret := _m.Called(ID, targetState)
+// UpdateSceneState provides a mock function with given fields: ctx, ID, targetState
+func (_m *MockPhysicalstatemanagerHueApiService) UpdateSceneState(ctx context.Context, ID string, targetState models.LightState) error {
+	ret := _m.Called(ctx, ID, targetState)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(string, models.LightState) error); ok {
-		r0 = rf(ID, targetState)
+	if rf, ok := ret.Get(0).(func(context.Context, string, models.LightState) error); ok {
+		r0 = rf(ctx, ID, targetState)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -192,15 +614,16 @@ type MockPhysicalstatemanagerHueApiService_UpdateSceneState_Call struct {
 }
 
 // UpdateSceneState is a helper method to define mock.On call
+//   - ctx context.Context
 //   - ID string
 //   - targetState models.LightState
-func (_e *MockPhysicalstatemanagerHueApiService_Expecter) UpdateSceneState(ID interface{}, targetState interface{}) *MockPhysicalstatemanagerHueApiService_UpdateSceneState_Call {
-	return &MockPhysicalstatemanagerHueApiService_UpdateSceneState_Call{Call: _e.mock.On("UpdateSceneState", ID, targetState)}
+func (_e *MockPhysicalstatemanagerHueApiService_Expecter) UpdateSceneState(ctx interface{}, ID interface{}, targetState interface{}) *MockPhysicalstatemanagerHueApiService_UpdateSceneState_Call {
+	return &MockPhysicalstatemanagerHueApiService_UpdateSceneState_Call{Call: _e.mock.On("UpdateSceneState", ctx, ID, targetState)}
 }
 
-func (_c *MockPhysicalstatemanagerHueApiService_UpdateSceneState_Call) Run(run func(ID string, targetState models.LightState)) *MockPhysicalstatemanagerHueApiService_UpdateSceneState_Call {
+func (_c *MockPhysicalstatemanagerHueApiService_UpdateSceneState_Call) Run(run func(ctx context.Context, ID string, targetState models.LightState)) *MockPhysicalstatemanagerHueApiService_UpdateSceneState_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(string), args[1].(models.LightState))
+		run(args[0].(context.Context), args[1].(string), args[2].(models.LightState))
 	})
 	return _c
 }
@@ -210,7 +633,7 @@ func (_c *MockPhysicalstatemanagerHueApiService_UpdateSceneState_Call) Return(_a
 	return _c
 }
 
-func (_c *MockPhysicalstatemanagerHueApiService_UpdateSceneState_Call) RunAndReturn(run func(string, models.LightState) error) *MockPhysicalstatemanagerHueApiService_UpdateSceneState_Call {
+func (_c *MockPhysicalstatemanagerHueApiService_UpdateSceneState_Call) RunAndReturn(run func(context.Context, string, models.LightState) error) *MockPhysicalstatemanagerHueApiService_UpdateSceneState_Call {
 	_c.Call.Return(run)
 	return _c
 }